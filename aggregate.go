@@ -0,0 +1,164 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithAggregation holds back events for window and writes a single aggregated event per group of identical events,
+// reducing the volume of duplicates from e.g. retry storms. Events are identical when they share a device event class
+// ID, name & severity, and have the same values for keys, e.g. WithAggregation(time.Minute, "src", "duser").
+//
+// Once window has elapsed from the first event in a group, the group is written as one event with Type set to
+// AggregatedEventType, BaseEventCount to the number of events, StartTime & EndTime to when the first & last were logged,
+// and only the extensions in keys, as other extensions may differ between the events. Groups of a single event are
+// written unchanged. Flush & Close write any pending groups.
+func WithAggregation(window time.Duration, keys ...string) LoggerConfigOption {
+	return func(l *Logger) {
+		a := &aggregator{window: window, keys: make(map[string]bool, len(keys))}
+		for _, key := range keys {
+			if canonical, ok := legacyKeyNames[key]; ok {
+				key = canonical
+			}
+			a.keys[key] = true
+		}
+		l.aggregator = a
+	}
+}
+
+// aggregator collects identical events into groups until their window elapses
+type aggregator struct {
+	window time.Duration
+	keys   map[string]bool
+
+	mu     sync.Mutex
+	groups map[string]*aggregate
+}
+
+// aggregate is a group of identical events
+type aggregate struct {
+	dev                device
+	deviceEventClassId string
+	name               string
+	severity           string
+	extensions         Extensions // the first event's
+	count              int
+	first, last        time.Time
+	timer              *time.Timer
+}
+
+// clone returns an aggregator with the same settings & no pending groups
+func (a *aggregator) clone() *aggregator {
+	return &aggregator{window: a.window, keys: a.keys}
+}
+
+// add holds back the event, adding it to its group
+func (a *aggregator) add(l *Logger, dev device, deviceEventClassId, name, severity string, extensions Extensions) {
+	var b strings.Builder
+	b.WriteString(dev.vendor + "|" + dev.product + "|" + dev.version + "|" + deviceEventClassId + "|" + name + "|" + severity)
+	extensions.walk(func(key string, v fieldValue) bool {
+		if a.keeps(key) {
+			b.WriteString("|" + key + "=" + v.String())
+		}
+		return true
+	})
+	key := b.String()
+	count := extensions.BaseEventCount
+	if count < 1 {
+		count = 1
+	}
+	now := l.getTime()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if g, ok := a.groups[key]; ok {
+		g.count += count
+		g.last = now
+		return
+	}
+	if a.groups == nil {
+		a.groups = make(map[string]*aggregate)
+	}
+	g := &aggregate{dev: dev, deviceEventClassId: deviceEventClassId, name: name, severity: severity,
+		extensions: extensions.Clone(), count: count, first: now, last: now}
+	g.timer = time.AfterFunc(a.window, func() {
+		var err error
+		defer l.recoverPanic(&err) // runs on its own goroutine, where a panic would take down the host application
+		a.mu.Lock()
+		if a.groups[key] != g {
+			a.mu.Unlock()
+			return // already flushed
+		}
+		delete(a.groups, key)
+		a.mu.Unlock()
+		if err = a.write(l, g); err != nil {
+			l.handleError(err)
+		}
+	})
+	a.groups[key] = g
+}
+
+// keeps reports whether key, or the custom field labelled by key, is one of the aggregation keys
+func (a *aggregator) keeps(key string) bool {
+	if canonical, ok := legacyKeyNames[key]; ok {
+		key = canonical
+	}
+	return a.keys[key] || a.keys[strings.TrimSuffix(key, "Label")]
+}
+
+// write writes a group as a single event
+func (a *aggregator) write(l *Logger, g *aggregate) error {
+	extensions := g.extensions
+	if g.count > 1 {
+		var drop []string
+		extensions.walk(func(key string, v fieldValue) bool {
+			if !a.keeps(key) {
+				drop = append(drop, key)
+			}
+			return true
+		})
+		for _, key := range drop {
+			if canonical, ok := legacyKeyNames[key]; ok {
+				key = canonical
+			}
+			extensions.unset(key)
+		}
+		extensions.Type = AggregatedEventType
+		extensions.BaseEventCount = g.count
+		extensions.StartTime, extensions.EndTime = g.first, g.last
+	}
+	if l.hasher != nil {
+		extensions = l.hasher.apply(g.dev, g.deviceEventClassId, g.name, g.severity, extensions)
+	}
+	return l.write(context.Background(), g.dev, g.deviceEventClassId, g.name, g.severity, extensions)
+}
+
+// flush writes every pending group
+func (a *aggregator) flush(l *Logger) error {
+	a.mu.Lock()
+	groups := a.groups
+	a.groups = nil
+	a.mu.Unlock()
+	pending := slices.SortedFunc(maps.Values(groups), func(a, b *aggregate) int {
+		return a.first.Compare(b.first)
+	})
+	var errs []error
+	for _, g := range pending {
+		g.timer.Stop()
+		errs = append(errs, a.write(l, g))
+	}
+	return errors.Join(errs...)
+}
+
+// flushAggregates writes any groups held back by WithAggregation
+func (l *Logger) flushAggregates() error {
+	if l.aggregator == nil {
+		return nil
+	}
+	return l.aggregator.flush(l)
+}
@@ -0,0 +1,58 @@
+package cefevent
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAggregation(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithAggregation(time.Hour, "src", "cs1"))
+	now := testTime()
+	l.getTime = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	attacker, other := net.IPv4(192, 0, 2, 1), net.IPv4(192, 0, 2, 2)
+	for _, user := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, l.LogHigh("100", "login failed", Extensions{
+			SourceAddress:       attacker,
+			SourceUserName:      user,
+			DeviceCustomString1: Labeled("realm", "corp"),
+		}))
+	}
+	require.NoError(t, l.LogHigh("100", "login failed", Extensions{SourceAddress: other, SourceUserName: "dave"}))
+	require.NoError(t, l.LogHigh("100", "login failed", Extensions{SourceAddress: attacker, BaseEventCount: 2,
+		DeviceCustomString1: Labeled("realm", "corp")}))
+	require.NoError(t, l.LogLow("100", "login failed", Extensions{SourceAddress: attacker}))
+	assert.Empty(t, w.Records(), "events written before the window elapsed")
+
+	require.NoError(t, l.Flush())
+	assert.Equal(t, []string{
+		"CEF:1|v|p|1|100|login failed|High|cnt=5 end=1699530325000 type=1 start=1699530321000 src=192.0.2.1 cs1=corp cs1Label=realm",
+		"CEF:1|v|p|1|100|login failed|High|src=192.0.2.2 suser=dave",
+		"CEF:1|v|p|1|100|login failed|Low|src=192.0.2.1",
+	}, w.Records())
+}
+
+func TestWithAggregation_windowExpires(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithAggregation(20*time.Millisecond))
+	l.getTime = testTime
+
+	for range 3 {
+		require.NoError(t, l.LogHigh("200", "retry", Extensions{Message: "timeout"}))
+	}
+	require.Eventually(t, func() bool {
+		return len(w.Records()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"CEF:1|v|p|1|200|retry|High|cnt=3 end=1699530320000 type=1 start=1699530320000"}, w.Records())
+
+	require.NoError(t, l.Flush())
+	assert.Len(t, w.Records(), 1, "group written twice")
+}
@@ -0,0 +1,344 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// LoggerClosedErr error when logging to an asynchronous Logger after Close
+var LoggerClosedErr = errors.New("logger closed")
+
+// OverflowPolicy decides what happens when an asynchronous Logger's queue is full
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // Log waits for space in the queue
+	OverflowDropNewest                       // The event being logged is dropped
+	OverflowDropOldest                       // The oldest queued event is dropped to make room
+	OverflowDropLowest                       // The oldest of the least severe events, queued or being logged, is dropped
+)
+
+// WithAsync formats events in Log but writes them from a background goroutine, through a queue holding up to
+// queueSize events; sizes below 1 are raised to 1. policy decides what happens when the queue is full; dropped events
+// are counted by DroppedCount. Write errors are reported to the error handler set with WithErrorHandler, as Log has
+// already returned. Call Close on shutdown so queued events aren't lost.
+func WithAsync(queueSize int, policy OverflowPolicy, opts ...AsyncOption) LoggerConfigOption {
+	return func(l *Logger) {
+		l.async = &asyncQueue{size: max(queueSize, 1), policy: policy}
+		for _, opt := range opts {
+			opt(l.async)
+		}
+	}
+}
+
+// AsyncOption configures the queue of an asynchronous Logger
+type AsyncOption func(q *asyncQueue)
+
+// WithSeverityPriority writes queued events in order of severity rather than the order they were logged, so when the
+// queue backs up, e.g. during an event storm, High and Very-High events reach the SIEM ahead of the backlog. Events of
+// the same severity are written in order.
+func WithSeverityPriority() AsyncOption {
+	return func(q *asyncQueue) {
+		q.priority = true
+	}
+}
+
+// queuedRecord is a formatted event waiting to be written
+type queuedRecord struct {
+	deviceEventClassId string
+	record             []byte
+	// level is the event's severity level, see severityLevel
+	level int
+	// seq orders records by when they were queued
+	seq uint64
+}
+
+// asyncQueueLevels is the number of severity levels records are queued by: Unknown, then 0-10
+const asyncQueueLevels = 12
+
+// asyncQueue buffers formatted events for a background writer
+type asyncQueue struct {
+	size     int
+	policy   OverflowPolicy
+	priority bool
+
+	mu sync.Mutex
+	// levels holds the queued records by severity level, each oldest first
+	levels [asyncQueueLevels][]queuedRecord
+	n      int
+	seq    uint64
+	closed bool
+	// ready is signalled when a record is queued or the queue is closed
+	ready chan struct{}
+	// space is closed and replaced when a record is taken off the queue while enqueue is waiting for space
+	space   chan struct{}
+	waiting int
+	done    chan struct{}
+
+	// pending counts events enqueued but not yet written or dropped, for Flush
+	pendingMu sync.Mutex
+	pending   int
+	idle      *sync.Cond
+
+	dropped atomic.Uint64
+}
+
+// start starts the background writer. Called once the Logger is fully configured
+func (q *asyncQueue) start(l *Logger) {
+	q.ready = make(chan struct{}, 1)
+	q.space = make(chan struct{})
+	q.done = make(chan struct{})
+	q.idle = sync.NewCond(&q.pendingMu)
+	go q.run(l)
+}
+
+func (q *asyncQueue) run(l *Logger) {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		r, ok := q.pop()
+		closed, depth := q.closed, q.n
+		q.mu.Unlock()
+		if !ok {
+			if closed {
+				return
+			}
+			<-q.ready
+			continue
+		}
+		q.reportDepth(l, depth)
+		q.write(l, r)
+		q.finish()
+	}
+}
+
+func (q *asyncQueue) write(l *Logger, r queuedRecord) {
+	var err error
+	defer func() {
+		if err != nil {
+			l.handleError(err)
+		}
+	}()
+	defer l.recoverPanic(&err)
+	err = l.output(context.Background(), r.deviceEventClassId, r.record)
+}
+
+// enqueue adds a record to the queue, applying the overflow policy if it is full. With OverflowBlock, it gives up
+// waiting for space once ctx is done.
+func (q *asyncQueue) enqueue(ctx context.Context, l *Logger, r queuedRecord) error {
+	dropped, depth, err := q.push(ctx, r)
+	for range dropped {
+		q.drop(l)
+	}
+	if err != nil {
+		return err
+	}
+	q.reportDepth(l, depth)
+	return nil
+}
+
+// push adds a record to the queue, returning the number of records dropped to apply the overflow policy and the
+// resulting queue depth
+func (q *asyncQueue) push(ctx context.Context, r queuedRecord) (dropped, depth int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, 0, LoggerClosedErr
+	}
+	q.pendingMu.Lock()
+	q.pending++
+	q.pendingMu.Unlock()
+
+	for q.n >= q.size {
+		switch q.policy {
+		case OverflowDropNewest:
+			return 1, q.n, nil
+		case OverflowDropOldest:
+			q.pop()
+			dropped++
+			continue
+		case OverflowDropLowest:
+			lowest := q.lowest()
+			if r.level+1 < lowest {
+				return dropped + 1, q.n, nil
+			}
+			q.take(lowest)
+			dropped++
+			continue
+		}
+		space := q.space
+		q.waiting++
+		q.mu.Unlock()
+		select {
+		case <-space:
+		case <-ctx.Done():
+		}
+		q.mu.Lock()
+		q.waiting--
+		if q.closed {
+			q.finish()
+			return dropped, 0, LoggerClosedErr
+		}
+		if err := ctx.Err(); err != nil && q.n >= q.size {
+			q.finish()
+			return dropped, 0, err
+		}
+	}
+	r.seq = q.seq
+	q.seq++
+	q.levels[r.level+1] = append(q.levels[r.level+1], r)
+	q.n++
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return dropped, q.n, nil
+}
+
+// pop takes the next record to write off the queue: the oldest, or with priority the oldest of the most severe. q.mu
+// must be held.
+func (q *asyncQueue) pop() (queuedRecord, bool) {
+	next := -1
+	for i := len(q.levels) - 1; i >= 0; i-- {
+		if len(q.levels[i]) == 0 {
+			continue
+		}
+		if next < 0 || (!q.priority && q.levels[i][0].seq < q.levels[next][0].seq) {
+			next = i
+		}
+		if q.priority {
+			break
+		}
+	}
+	if next < 0 {
+		return queuedRecord{}, false
+	}
+	return q.take(next), true
+}
+
+// lowest returns the least severe level with queued records. q.mu must be held.
+func (q *asyncQueue) lowest() int {
+	for i, records := range q.levels {
+		if len(records) > 0 {
+			return i
+		}
+	}
+	return len(q.levels)
+}
+
+// take removes the oldest record of a severity level from the queue. q.mu must be held.
+func (q *asyncQueue) take(level int) queuedRecord {
+	r := q.levels[level][0]
+	q.levels[level][0] = queuedRecord{}
+	q.levels[level] = q.levels[level][1:]
+	q.n--
+	if q.waiting > 0 {
+		close(q.space)
+		q.space = make(chan struct{})
+	}
+	return r
+}
+
+func (q *asyncQueue) drop(l *Logger) {
+	q.dropped.Add(1)
+	l.dropped(DropQueueFull)
+	q.finish()
+}
+
+// reportDepth reports the number of queued records to the Logger's metrics
+func (q *asyncQueue) reportDepth(l *Logger, n int) {
+	if l.metrics != nil {
+		l.metrics.QueueDepth(n)
+	}
+}
+
+// finish marks a pending record as written or dropped
+func (q *asyncQueue) finish() {
+	q.pendingMu.Lock()
+	defer q.pendingMu.Unlock()
+	q.pending--
+	if q.pending == 0 {
+		q.idle.Broadcast()
+	}
+}
+
+// flush waits until every record enqueued so far has been written or dropped
+func (q *asyncQueue) flush() {
+	q.pendingMu.Lock()
+	defer q.pendingMu.Unlock()
+	for q.pending > 0 {
+		q.idle.Wait()
+	}
+}
+
+// close stops accepting records and waits for the queued records to be written
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.space)
+		q.space = make(chan struct{})
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+	q.mu.Unlock()
+	<-q.done
+}
+
+// Flush waits until every event logged so far has been written, including events held by WithBuffer & WithAggregation
+// and pending WithRepeatSuppression, WithDropSummary & WithSelfMonitoring events, returning any error writing them. It
+// returns immediately for synchronous, unbuffered Loggers
+func (l *Logger) Flush() error {
+	if l.parent != nil {
+		return l.parent.Flush()
+	}
+	err := errors.Join(l.flushAggregates(), l.flushSuppressed(), l.flushDropSummary(), l.flushMonitor())
+	if l.async != nil {
+		l.async.flush()
+	}
+	return errors.Join(err, l.flushBuffer(false))
+}
+
+// Close writes any queued, buffered, aggregated & pending WithRepeatSuppression, WithDropSummary & WithSelfMonitoring
+// events and stops the background writer of an asynchronous Logger, after which Log returns LoggerClosedErr. Events
+// logged to a buffered, synchronous Logger after Close are written unbuffered. The output writer isn't closed. Close is
+// safe to call more than once.
+func (l *Logger) Close() error {
+	if l.parent != nil {
+		return l.parent.Close()
+	}
+	err := errors.Join(l.flushAggregates(), l.flushSuppressed(), l.flushDropSummary(), l.flushMonitor())
+	if l.async != nil {
+		l.async.close()
+	}
+	return errors.Join(err, l.flushBuffer(true))
+}
+
+// flushBuffer writes out the records held by WithBuffer, if enabled
+func (l *Logger) flushBuffer(close bool) error {
+	if l.buffer == nil {
+		return nil
+	}
+	l.writeMu.RLock()
+	defer l.writeMu.RUnlock()
+	if err := l.buffer.flush(l, close); err != nil {
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+	return nil
+}
+
+// DroppedCount returns the number of events dropped because the asynchronous queue was full
+func (l *Logger) DroppedCount() uint64 {
+	if l.parent != nil {
+		return l.parent.DroppedCount()
+	}
+	if l.async == nil {
+		return 0
+	}
+	return l.async.dropped.Load()
+}
@@ -0,0 +1,166 @@
+package cefevent
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingWriter signals started on each write then blocks until release is closed
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestWithAsync(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", WithStaticHostname("host"), WithTimeFunc(testTime),
+		WithAsync(10, OverflowBlock))
+	for _, name := range []string{"a", "b", "c"} {
+		assert.NoError(t, l.LogLow("100", name, Extensions{}))
+	}
+	assert.NoError(t, l.Flush())
+	assert.Equal(t, 3, strings.Count(buf.String(), "CEF:1|v|p|1|100|"))
+	assert.NoError(t, l.Close())
+	assert.NoError(t, l.Close())
+	assert.ErrorIs(t, l.LogLow("100", "d", Extensions{}), LoggerClosedErr)
+	assert.Equal(t, uint64(0), l.DroppedCount())
+}
+
+func TestWithAsync_size(t *testing.T) {
+	for _, size := range []int{-1, 0} {
+		buf := &bytes.Buffer{}
+		l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithAsync(size, OverflowDropOldest))
+		assert.Equal(t, 1, l.async.size)
+		assert.NoError(t, l.LogLow("100", "a", Extensions{}))
+		assert.NoError(t, l.Close())
+		assert.Equal(t, "CEF:1|v|p|1|100|a|Low|", buf.String())
+	}
+}
+
+func TestWithAsync_overflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy OverflowPolicy
+		want   []string
+	}{
+		{"drop_newest", OverflowDropNewest, []string{"first", "second"}},
+		{"drop_oldest", OverflowDropOldest, []string{"first", "third"}},
+		{"drop_lowest", OverflowDropLowest, []string{"first", "third"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newBlockingWriter()
+			l := NewLogger(w, "v", "p", "1", WithAsync(1, tt.policy))
+			assert.NoError(t, l.LogLow("100", "first", Extensions{}))
+			<-w.started
+			assert.NoError(t, l.LogLow("100", "second", Extensions{}))
+			assert.NoError(t, l.LogLow("100", "third", Extensions{}))
+			assert.Equal(t, uint64(1), l.DroppedCount())
+			close(w.release)
+			assert.NoError(t, l.Close())
+
+			assert.Equal(t, tt.want, loggedNames(w))
+		})
+	}
+}
+
+// loggedNames returns the names of the events written to w, in order
+func loggedNames(w fmt.Stringer) []string {
+	var names []string
+	for _, record := range strings.Split(w.String(), "CEF:")[1:] {
+		names = append(names, strings.Split(record, "|")[5])
+	}
+	return names
+}
+
+func TestWithSeverityPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []AsyncOption
+		want []string
+	}{
+		{"fifo", nil, []string{"first", "low", "high", "medium", "unknown", "9", "low2"}},
+		{"priority", []AsyncOption{WithSeverityPriority()}, []string{"first", "9", "high", "medium", "low", "low2", "unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newBlockingWriter()
+			l := NewLogger(w, "v", "p", "1", WithAsync(10, OverflowBlock, tt.opts...))
+			assert.NoError(t, l.LogLow("100", "first", Extensions{}))
+			<-w.started
+			for _, e := range [][2]string{
+				{"low", LowSeverity}, {"high", HighSeverity}, {"medium", MediumSeverity}, {"unknown", UnknownSeverity},
+				{"9", "9"}, {"low2", LowSeverity},
+			} {
+				assert.NoError(t, l.Log("100", e[0], e[1], Extensions{}))
+			}
+			close(w.release)
+			assert.NoError(t, l.Close())
+			assert.Equal(t, tt.want, loggedNames(w))
+		})
+	}
+}
+
+func TestWithAsync_dropLowest(t *testing.T) {
+	w := newBlockingWriter()
+	l := NewLogger(w, "v", "p", "1", WithAsync(3, OverflowDropLowest))
+	assert.NoError(t, l.LogLow("100", "first", Extensions{}))
+	<-w.started
+	for _, e := range [][2]string{
+		{"medium", MediumSeverity}, {"low", LowSeverity}, {"low2", LowSeverity},
+		{"high", HighSeverity},          // evicts low
+		{"unknown", UnknownSeverity},    // less severe than everything queued
+		{"medium2", MediumSeverity},     // evicts low2
+		{"very-high", VeryHighSeverity}, // evicts medium
+	} {
+		assert.NoError(t, l.Log("100", e[0], e[1], Extensions{}))
+	}
+	assert.Equal(t, uint64(4), l.DroppedCount())
+	close(w.release)
+	assert.NoError(t, l.Close())
+	assert.Equal(t, []string{"first", "high", "medium2", "very-high"}, loggedNames(w))
+}
+
+func TestWithAsync_errorHandler(t *testing.T) {
+	var errs []error
+	l := NewLogger(errorWriter{}, "v", "p", "1", WithAsync(1, OverflowBlock),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	assert.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.NoError(t, l.Close())
+	if assert.Len(t, errs, 1) {
+		assert.ErrorContains(t, errs[0], "failed to write log")
+	}
+}
+
+func TestLogger_Flush_sync(t *testing.T) {
+	l := NewLogger(&bytes.Buffer{}, "v", "p", "1")
+	assert.NoError(t, l.Flush())
+	assert.NoError(t, l.Close())
+	assert.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Equal(t, uint64(0), l.DroppedCount())
+}
@@ -0,0 +1,336 @@
+package cefevent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AzureBufferFullErr error when an AzureMonitorWriter can't accept more events because ingestion is failing
+var AzureBufferFullErr = errors.New("azure monitor buffer full")
+
+// AzureRecordTooLargeErr error when a record's row is larger than the Logs Ingestion API accepts in one request
+var AzureRecordTooLargeErr = errors.New("record too large for azure monitor")
+
+const (
+	azureIngestionAPIVersion = "2023-01-01"
+	azureMonitorScope        = "https://monitor.azure.com/.default"
+	// azureMaxRequestBytes is the Logs Ingestion API's limit on a single request body
+	azureMaxRequestBytes = 1 << 20
+)
+
+// AzureClientCredentials authenticates to Microsoft Entra ID (Azure AD) with the client credentials flow, caching
+// tokens until shortly before they expire. It is safe to use from multiple goroutines.
+type AzureClientCredentials struct {
+	TenantId     string
+	ClientId     string
+	ClientSecret string
+
+	// AuthorityHost is the Entra ID endpoint. Defaults to https://login.microsoftonline.com
+	AuthorityHost string
+
+	// Client makes token requests. Defaults to http.DefaultClient
+	Client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns a bearer token for the Azure Monitor ingestion API
+func (c *AzureClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	authority := c.AuthorityHost
+	if authority == "" {
+		authority = "https://login.microsoftonline.com"
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.ClientId},
+		"client_secret": {c.ClientSecret},
+		"scope":         {azureMonitorScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(authority, "/")+"/"+url.PathEscape(c.TenantId)+"/oauth2/v2.0/token",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient(c.Client).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get azure token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get azure token: %s", responseError(resp))
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode azure token: %w", err)
+	}
+	c.token = body.AccessToken
+	c.expires = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// AzureTokenSource provides bearer tokens for the ingestion API. AzureClientCredentials is the standard
+// implementation; others can wrap managed identity or workload identity credentials.
+type AzureTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// AzureMonitorWriter sends records to the Azure Monitor Logs Ingestion API, from which a data collection rule (DCR)
+// can route them to Microsoft Sentinel. Each Write is one record; records are buffered and sent in batches in the
+// background. Batches failing with a network error, 401, 403, 408, 429 or a 5xx status are retried with the next send;
+// other failures drop the batch, reporting an error wrapping BatchRejectedErr. Use it as a Logger's output, and Close
+// it on shutdown to send the remaining records.
+type AzureMonitorWriter struct {
+	ingestURL     string
+	tokens        AzureTokenSource
+	client        *http.Client
+	mapRecord     func(record string, received time.Time) map[string]any
+	batchSize     int
+	maxBuffered   int
+	flushInterval time.Duration
+	errorHandler  func(err error)
+
+	mu      sync.Mutex
+	rows    []json.RawMessage
+	flushMu sync.Mutex
+	kick    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// AzureMonitorOption configures an AzureMonitorWriter
+type AzureMonitorOption func(w *AzureMonitorWriter)
+
+// WithAzureBatchSize sets the number of records that triggers a send. Defaults to 500
+func WithAzureBatchSize(n int) AzureMonitorOption {
+	return func(w *AzureMonitorWriter) {
+		w.batchSize = n
+	}
+}
+
+// WithAzureMaxBuffered sets how many records are kept while ingestion is failing before Write returns
+// AzureBufferFullErr. Defaults to 10 batches
+func WithAzureMaxBuffered(n int) AzureMonitorOption {
+	return func(w *AzureMonitorWriter) {
+		w.maxBuffered = n
+	}
+}
+
+// WithAzureFlushInterval sets how often buffered records are sent. Defaults to 5s
+func WithAzureFlushInterval(d time.Duration) AzureMonitorOption {
+	return func(w *AzureMonitorWriter) {
+		w.flushInterval = d
+	}
+}
+
+// WithAzureMapping sets how a record is mapped to a row of the DCR's stream. received is when the record was written.
+// By default rows have TimeGenerated and RawData columns, leaving parsing to the DCR transformation.
+func WithAzureMapping(fn func(record string, received time.Time) map[string]any) AzureMonitorOption {
+	return func(w *AzureMonitorWriter) {
+		w.mapRecord = fn
+	}
+}
+
+// WithAzureHTTPClient sets the client used for ingestion requests. Defaults to http.DefaultClient
+func WithAzureHTTPClient(c *http.Client) AzureMonitorOption {
+	return func(w *AzureMonitorWriter) {
+		w.client = c
+	}
+}
+
+// WithAzureErrorHandler sets a function called with errors from background sends, which are otherwise discarded
+func WithAzureErrorHandler(fn func(err error)) AzureMonitorOption {
+	return func(w *AzureMonitorWriter) {
+		w.errorHandler = fn
+	}
+}
+
+// NewAzureMonitorWriter creates an AzureMonitorWriter sending to stream (e.g. "Custom-CEF_CL") of the DCR with
+// immutable ID ruleId (e.g. "dcr-...") through endpoint, the logs ingestion endpoint of the DCR or its data
+// collection endpoint.
+func NewAzureMonitorWriter(endpoint, ruleId, stream string, tokens AzureTokenSource, opts ...AzureMonitorOption) *AzureMonitorWriter {
+	w := &AzureMonitorWriter{
+		ingestURL: strings.TrimSuffix(endpoint, "/") + "/dataCollectionRules/" + url.PathEscape(ruleId) +
+			"/streams/" + url.PathEscape(stream) + "?api-version=" + azureIngestionAPIVersion,
+		tokens:        tokens,
+		mapRecord:     azureRawRecord,
+		batchSize:     500,
+		flushInterval: 5 * time.Second,
+		kick:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.maxBuffered == 0 {
+		w.maxBuffered = 10 * w.batchSize
+	}
+	go w.run()
+	return w
+}
+
+// azureRawRecord is the default mapping of records to rows
+func azureRawRecord(record string, received time.Time) map[string]any {
+	return map[string]any{
+		"TimeGenerated": received.UTC().Format(time.RFC3339Nano),
+		"RawData":       record,
+	}
+}
+
+// Write buffers p as a single record. Records whose row couldn't be sent within the API's request size limit are
+// rejected with AzureRecordTooLargeErr.
+func (w *AzureMonitorWriter) Write(p []byte) (int, error) {
+	row, err := json.Marshal(w.mapRecord(strings.TrimRight(string(p), "\r\n"), time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to map record: %w", err)
+	}
+	if len(row)+2 > azureMaxRequestBytes {
+		return 0, fmt.Errorf("%w: %d bytes", AzureRecordTooLargeErr, len(row))
+	}
+	w.mu.Lock()
+	if len(w.rows) >= w.maxBuffered {
+		w.mu.Unlock()
+		return 0, AzureBufferFullErr
+	}
+	w.rows = append(w.rows, row)
+	full := len(w.rows) >= w.batchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends all buffered records. Records failing with a retryable error stay buffered; rejected batches are
+// dropped, and reported in the returned error.
+func (w *AzureMonitorWriter) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	w.mu.Lock()
+	rows := w.rows
+	w.rows = nil
+	w.mu.Unlock()
+
+	var errs []error
+	for len(rows) > 0 {
+		n := azureBatchLen(rows, w.batchSize)
+		err := w.send(ctx, rows[:n])
+		if err != nil && !errors.Is(err, BatchRejectedErr) {
+			w.mu.Lock()
+			w.rows = append(rows, w.rows...)
+			w.mu.Unlock()
+			return errors.Join(append(errs, err)...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%d records dropped: %w", n, err))
+		}
+		rows = rows[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops background sending and flushes the remaining records
+func (w *AzureMonitorWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+		<-w.stopped
+	})
+	return w.Flush(context.Background())
+}
+
+func (w *AzureMonitorWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-w.kick:
+		}
+		if err := w.Flush(context.Background()); err != nil && w.errorHandler != nil {
+			w.errorHandler(err)
+		}
+	}
+}
+
+// azureBatchLen returns how many of rows fit in one request, limited by count and the API's request size limit
+func azureBatchLen(rows []json.RawMessage, max int) int {
+	size := 2 // []
+	for i, row := range rows {
+		size += len(row) + 1
+		if i == max || (i > 0 && size > azureMaxRequestBytes) {
+			return i
+		}
+	}
+	return len(rows)
+}
+
+func (w *AzureMonitorWriter) send(ctx context.Context, rows []json.RawMessage) error {
+	body, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("%w: %w", BatchRejectedErr, err)
+	}
+	token, err := w.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.ingestURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient(w.client).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send to azure monitor: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case retryableStatus(resp.StatusCode):
+		return fmt.Errorf("failed to send to azure monitor: %s", responseError(resp))
+	}
+	return fmt.Errorf("%w by azure monitor: %s", BatchRejectedErr, responseError(resp))
+}
+
+func httpClient(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
+
+// responseError describes an unsuccessful response, including the start of its body
+func responseError(resp *http.Response) string {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return strings.TrimSpace(resp.Status + " " + string(msg))
+}
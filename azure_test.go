@@ -0,0 +1,179 @@
+package cefevent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAzure serves the Entra ID token and Logs Ingestion endpoints
+type fakeAzure struct {
+	mu         sync.Mutex
+	tokenCalls int
+	batches    [][]map[string]any
+	// ingestStatus is the status of ingestion responses, if set
+	ingestStatus int
+	authHeaders  []string
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/tenant/oauth2/v2.0/token":
+		f.tokenCalls++
+		_ = r.ParseForm()
+		if r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("scope") != azureMonitorScope {
+			http.Error(w, "invalid_client", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "token", "expires_in": 3600})
+	case "/dataCollectionRules/dcr-123/streams/Custom-CEF_CL":
+		if r.URL.Query().Get("api-version") != azureIngestionAPIVersion {
+			http.Error(w, "bad version", http.StatusBadRequest)
+			return
+		}
+		f.authHeaders = append(f.authHeaders, r.Header.Get("Authorization"))
+		if f.ingestStatus != 0 {
+			http.Error(w, "failed", f.ingestStatus)
+			return
+		}
+		var rows []map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&rows)
+		f.batches = append(f.batches, rows)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeAzure(t *testing.T) (*fakeAzure, *httptest.Server, *AzureClientCredentials) {
+	f := &fakeAzure{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	creds := &AzureClientCredentials{TenantId: "tenant", ClientId: "client", ClientSecret: "secret", AuthorityHost: srv.URL}
+	return f, srv, creds
+}
+
+func TestAzureClientCredentials_Token(t *testing.T) {
+	f, _, creds := newFakeAzure(t)
+	for i := 0; i < 2; i++ {
+		token, err := creds.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token", token)
+	}
+	assert.Equal(t, 1, f.tokenCalls)
+
+	creds = &AzureClientCredentials{TenantId: "tenant", ClientSecret: "wrong", AuthorityHost: creds.AuthorityHost}
+	_, err := creds.Token(context.Background())
+	assert.ErrorContains(t, err, "401 Unauthorized")
+}
+
+func TestAzureMonitorWriter(t *testing.T) {
+	f, srv, creds := newFakeAzure(t)
+	w := NewAzureMonitorWriter(srv.URL, "dcr-123", "Custom-CEF_CL", creds,
+		WithAzureBatchSize(2), WithAzureFlushInterval(time.Hour))
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	for _, msg := range []string{"one", "two", "three"} {
+		require.NoError(t, l.LogLow("100", "test", Extensions{Message: msg}))
+	}
+	require.NoError(t, w.Close())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var raw []string
+	for _, batch := range f.batches {
+		assert.LessOrEqual(t, len(batch), 2)
+		for _, row := range batch {
+			_, err := time.Parse(time.RFC3339Nano, row["TimeGenerated"].(string))
+			assert.NoError(t, err)
+			raw = append(raw, row["RawData"].(string))
+		}
+	}
+	assert.Equal(t, []string{
+		"CEF:1|testVendor|testProduct|1.0|100|test|Low|msg=one",
+		"CEF:1|testVendor|testProduct|1.0|100|test|Low|msg=two",
+		"CEF:1|testVendor|testProduct|1.0|100|test|Low|msg=three",
+	}, raw)
+	assert.Equal(t, "Bearer token", f.authHeaders[0])
+}
+
+func TestAzureMonitorWriter_failure(t *testing.T) {
+	f, srv, creds := newFakeAzure(t)
+	f.ingestStatus = http.StatusServiceUnavailable
+	w := NewAzureMonitorWriter(srv.URL, "dcr-123", "Custom-CEF_CL", creds,
+		WithAzureFlushInterval(time.Hour), WithAzureMaxBuffered(2),
+		WithAzureMapping(func(record string, _ time.Time) map[string]any { return map[string]any{"Msg": record} }))
+
+	for i := 0; i < 2; i++ {
+		_, err := w.Write([]byte("record\n"))
+		require.NoError(t, err)
+	}
+	_, err := w.Write([]byte("record\n"))
+	assert.ErrorIs(t, err, AzureBufferFullErr)
+	assert.ErrorContains(t, w.Flush(context.Background()), "503 Service Unavailable")
+
+	f.mu.Lock()
+	f.ingestStatus = 0
+	f.mu.Unlock()
+	require.NoError(t, w.Close())
+	require.Len(t, f.batches, 1)
+	assert.Equal(t, []map[string]any{{"Msg": "record"}, {"Msg": "record"}}, f.batches[0])
+}
+
+func TestAzureMonitorWriter_rejected(t *testing.T) {
+	f, srv, creds := newFakeAzure(t)
+	f.ingestStatus = http.StatusBadRequest
+	w := NewAzureMonitorWriter(srv.URL, "dcr-123", "Custom-CEF_CL", creds, WithAzureFlushInterval(time.Hour))
+
+	_, err := w.Write([]byte("record\n"))
+	require.NoError(t, err)
+	err = w.Flush(context.Background())
+	assert.ErrorIs(t, err, BatchRejectedErr)
+	assert.ErrorContains(t, err, "1 records dropped")
+
+	f.mu.Lock()
+	f.ingestStatus = 0
+	f.mu.Unlock()
+	require.NoError(t, w.Close())
+	assert.Empty(t, f.batches, "rejected batches aren't retried")
+	assert.Len(t, f.authHeaders, 1)
+}
+
+func TestAzureMonitorWriter_Write_tooLarge(t *testing.T) {
+	_, srv, creds := newFakeAzure(t)
+	w := NewAzureMonitorWriter(srv.URL, "dcr-123", "Custom-CEF_CL", creds, WithAzureFlushInterval(time.Hour))
+	defer w.Close()
+	_, err := w.Write(make([]byte, azureMaxRequestBytes))
+	assert.ErrorIs(t, err, AzureRecordTooLargeErr)
+	_, err = w.Write(make([]byte, 1000))
+	assert.NoError(t, err)
+}
+
+func Test_azureBatchLen(t *testing.T) {
+	small := json.RawMessage(`{}`)
+	big := json.RawMessage(make([]byte, azureMaxRequestBytes/2))
+	tests := []struct {
+		name string
+		rows []json.RawMessage
+		max  int
+		want int
+	}{
+		{"under max", []json.RawMessage{small, small}, 5, 2},
+		{"count limit", []json.RawMessage{small, small, small}, 2, 2},
+		{"size limit", []json.RawMessage{big, big, small}, 5, 1},
+		{"oversized row", []json.RawMessage{append(big, big...), small}, 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, azureBatchLen(tt.rows, tt.max))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithBuffer collects records in memory and writes them in batches of up to size bytes, so that small events don't
+// cost a system call each. Buffered records are written once flushInterval has passed since the first of them was
+// logged (zero to only write full batches), and by Flush & Close, so call Close on shutdown to avoid losing them.
+// Batching gives up the guarantee of one Write per record: use it with files & streams, with a record terminator set
+// by WithRecordTerminator, not with datagram sockets or writers which frame each Write, such as SyslogWriter. Records
+// larger than size are written on their own.
+func WithBuffer(size int, flushInterval time.Duration) LoggerConfigOption {
+	return func(l *Logger) {
+		l.buffer = &recordBuffer{size: size, interval: flushInterval}
+	}
+}
+
+// recordBuffer batches records for each of a Logger's writers, indexed as by routeIndex
+type recordBuffer struct {
+	size     int
+	interval time.Duration
+
+	mu     sync.Mutex
+	bufs   map[int][]byte
+	timer  *time.Timer
+	closed bool
+}
+
+// add buffers a record for the writer at route index i, writing out the buffer first if the record doesn't fit. Must
+// be called with l.writeMu held.
+func (b *recordBuffer) add(l *Logger, i int, record []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := l.outAt(i)
+	if b.closed {
+		return writeAll(out, record)
+	}
+	buf := b.bufs[i]
+	if len(buf) > 0 && len(buf)+len(record) > b.size {
+		// The buffer is emptied even on failure, so a broken writer can't make it grow without bound
+		b.bufs[i] = buf[:0]
+		if err := writeAll(out, buf); err != nil {
+			return err
+		}
+		buf = buf[:0]
+	}
+	if len(record) >= b.size {
+		return writeAll(out, record)
+	}
+	if b.bufs == nil {
+		b.bufs = make(map[int][]byte)
+	}
+	b.bufs[i] = append(buf, record...)
+	if b.interval > 0 && b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, func() {
+			l.writeMu.RLock()
+			err := b.flush(l, false)
+			l.writeMu.RUnlock()
+			if err != nil {
+				l.handleError(fmt.Errorf("failed to write log: %w", err))
+			}
+		})
+	}
+	return nil
+}
+
+// flush writes out every buffer, closing b if requested. Must be called with l.writeMu held.
+func (b *recordBuffer) flush(l *Logger, close bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.closed = b.closed || close
+	var errs []error
+	for i, buf := range b.bufs {
+		if len(buf) == 0 {
+			continue
+		}
+		b.bufs[i] = buf[:0]
+		errs = append(errs, writeAll(l.outAt(i), buf))
+	}
+	return errors.Join(errs...)
+}
+
+// flushRoute writes out the buffer of the route at index i, e.g. before its writer is replaced. Must be called with
+// l.writeMu held.
+func (b *recordBuffer) flushRoute(l *Logger, i int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	buf := b.bufs[i]
+	if len(buf) == 0 {
+		return nil
+	}
+	b.bufs[i] = buf[:0]
+	return writeAll(l.outAt(i), buf)
+}
+
+// writeAll writes p with a single Write call, reporting a short write as io.ErrShortWrite
+func writeAll(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package cefevent
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingBuffer is a bytes.Buffer counting Write calls, safe for concurrent use
+type countingBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *countingBuffer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *countingBuffer) state() (string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String(), c.writes
+}
+
+const bufferedRecord = "CEF:1|v|p|1|100|test|Low|\n"
+
+func TestWithBuffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		events     int
+		wantWrites int
+		wantLeft   int
+	}{
+		{"batches", 3 * len(bufferedRecord), 7, 2, 1},
+		{"exact_fit", 2 * len(bufferedRecord), 4, 1, 2},
+		{"record_larger_than_buffer", 10, 3, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &countingBuffer{}
+			l := NewLogger(out, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator),
+				WithBuffer(tt.size, 0))
+			for i := 0; i < tt.events; i++ {
+				require.NoError(t, l.LogLow("100", "test", Extensions{}))
+			}
+			got, writes := out.state()
+			assert.Equal(t, tt.wantWrites, writes)
+			assert.Equal(t, tt.events-tt.wantLeft, bytes.Count([]byte(got), []byte("\n")))
+
+			require.NoError(t, l.Flush())
+			got, _ = out.state()
+			assert.Equal(t, bytes.Repeat([]byte(bufferedRecord), tt.events), []byte(got))
+		})
+	}
+}
+
+func TestWithBuffer_interval(t *testing.T) {
+	out := &countingBuffer{}
+	l := NewLogger(out, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator),
+		WithBuffer(1<<10, 10*time.Millisecond))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Eventually(t, func() bool {
+		got, writes := out.state()
+		return got == bufferedRecord+bufferedRecord && writes == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestWithBuffer_Close(t *testing.T) {
+	out := &countingBuffer{}
+	l := NewLogger(out, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator),
+		WithBuffer(1<<10, time.Hour), WithAsync(10, OverflowBlock))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	require.NoError(t, l.Close())
+	got, _ := out.state()
+	assert.Equal(t, bufferedRecord, got)
+	assert.ErrorIs(t, l.LogLow("100", "test", Extensions{}), LoggerClosedErr)
+
+	syncLogger := NewLogger(out, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator),
+		WithBuffer(1<<10, time.Hour))
+	require.NoError(t, syncLogger.Close())
+	require.NoError(t, syncLogger.LogLow("100", "test", Extensions{}))
+	got, _ = out.state()
+	assert.Equal(t, bufferedRecord+bufferedRecord, got, "written through after Close")
+}
+
+func TestWithBuffer_SetOutput(t *testing.T) {
+	first, second := &countingBuffer{}, &countingBuffer{}
+	l := NewLogger(first, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator),
+		WithBuffer(1<<10, 0))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	l.SetOutput(second)
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	require.NoError(t, l.Flush())
+	got, _ := first.state()
+	assert.Equal(t, bufferedRecord, got)
+	got, _ = second.state()
+	assert.Equal(t, bufferedRecord, got)
+}
+
+func TestWithBuffer_routes(t *testing.T) {
+	out, routed := &countingBuffer{}, &countingBuffer{}
+	l := NewLogger(out, "v", "p", "1", OmitSyslogHeader(), WithBuffer(1<<10, 0),
+		WithClassRoutes(RouteClassPrefix("auth", routed)))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	require.NoError(t, l.LogLow("auth-1", "login", Extensions{}))
+	require.NoError(t, l.Flush())
+	got, _ := out.state()
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", got)
+	got, _ = routed.state()
+	assert.Equal(t, "CEF:1|v|p|1|auth-1|login|Low|", got)
+}
+
+func TestWithBuffer_error(t *testing.T) {
+	l := NewLogger(errorWriter{}, "v", "p", "1", WithBuffer(1<<10, 0))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.ErrorIs(t, l.Flush(), stubWriterError)
+	assert.NoError(t, l.Flush(), "failed records aren't retried")
+}
@@ -0,0 +1,188 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// InvalidGeoLocationErr error when a latitude is outside -90 to 90 or a longitude outside -180 to 180
+var InvalidGeoLocationErr = errors.New("invalid geo location")
+
+// ExtensionsBuilder builds Extensions, validating values as they are set and taking optional numeric fields by value,
+// e.g.
+//
+//	ext, err := NewExtensions().WithDestinationPort(22).WithMessage("login").WithCustom("tenant", "acme").Build()
+//
+// Invalid values are recorded as errors and not set; Build returns them all. Fields without a method can be set with
+// Set by dictionary key.
+type ExtensionsBuilder struct {
+	ext  Extensions
+	errs []error
+}
+
+// NewExtensions returns an empty ExtensionsBuilder
+func NewExtensions() *ExtensionsBuilder {
+	return &ExtensionsBuilder{}
+}
+
+// Build returns the Extensions, along with every problem found while building them joined into a single error (check
+// for specific problems with errors.Is). Custom fields set without a label are reported here.
+func (b *ExtensionsBuilder) Build() (Extensions, error) {
+	return b.ext.Clone(), errors.Join(append(b.errs, checkExtensions(b.ext)...)...)
+}
+
+// Set sets the field with the dictionary key, e.g. "src" or "deviceCustomDate1Label", decoding v as Parse does.
+// Custom keys are set with WithCustom instead.
+func (b *ExtensionsBuilder) Set(key, v string) *ExtensionsBuilder {
+	if !isStandardKey(key) {
+		b.errs = append(b.errs, &fieldError{key, fmt.Errorf("%w: not a standard key", InvalidExtensionKeyErr)})
+		return b
+	}
+	if err := b.ext.set(key, v); err != nil {
+		b.errs = append(b.errs, &fieldError{key, err})
+	}
+	return b
+}
+
+// WithCustom sets a CustomExtensions key, which must be a valid key that isn't a standard dictionary key
+func (b *ExtensionsBuilder) WithCustom(key, v string) *ExtensionsBuilder {
+	switch {
+	case !validExtensionKey(key):
+		b.errs = append(b.errs, &fieldError{key, InvalidExtensionKeyErr})
+	case isStandardKey(key):
+		b.errs = append(b.errs, &fieldError{key, ReservedExtensionKeyErr})
+	default:
+		if b.ext.CustomExtensions == nil {
+			b.ext.CustomExtensions = make(map[string]string)
+		}
+		b.ext.CustomExtensions[key] = v
+	}
+	return b
+}
+
+// WithMessage sets Message
+func (b *ExtensionsBuilder) WithMessage(msg string) *ExtensionsBuilder {
+	b.ext.Message = msg
+	return b
+}
+
+// WithBytesIn sets BytesIn
+func (b *ExtensionsBuilder) WithBytesIn(n uint) *ExtensionsBuilder {
+	b.ext.BytesIn = &n
+	return b
+}
+
+// WithBytesOut sets BytesOut
+func (b *ExtensionsBuilder) WithBytesOut(n uint) *ExtensionsBuilder {
+	b.ext.BytesOut = &n
+	return b
+}
+
+// WithEventId sets EventId
+func (b *ExtensionsBuilder) WithEventId(id int64) *ExtensionsBuilder {
+	b.ext.EventId = &id
+	return b
+}
+
+// WithDeviceDirection sets DeviceDirection, which must be 0 (inbound) or 1 (outbound)
+func (b *ExtensionsBuilder) WithDeviceDirection(direction int) *ExtensionsBuilder {
+	if direction != 0 && direction != 1 {
+		b.errs = append(b.errs, &fieldError{"deviceDirection", fmt.Errorf("%d: %w", direction, InvalidDeviceDirectionErr)})
+		return b
+	}
+	b.ext.DeviceDirection = ptr(uint8(direction))
+	return b
+}
+
+// WithDeviceProcessId sets DeviceProcessId
+func (b *ExtensionsBuilder) WithDeviceProcessId(pid uint) *ExtensionsBuilder {
+	b.ext.DeviceProcessId = &pid
+	return b
+}
+
+// WithSourcePort sets SourcePort, which must be between 0 & 65535
+func (b *ExtensionsBuilder) WithSourcePort(port int) *ExtensionsBuilder {
+	b.ext.SourcePort = b.port("spt", port, b.ext.SourcePort)
+	return b
+}
+
+// WithSourceTranslatedPort sets SourceTranslatedPort, which must be between 0 & 65535
+func (b *ExtensionsBuilder) WithSourceTranslatedPort(port int) *ExtensionsBuilder {
+	b.ext.SourceTranslatedPort = b.port("sourceTranslatedPort", port, b.ext.SourceTranslatedPort)
+	return b
+}
+
+// WithSourceProcessId sets SourceProcessId
+func (b *ExtensionsBuilder) WithSourceProcessId(pid int) *ExtensionsBuilder {
+	b.ext.SourceProcessId = &pid
+	return b
+}
+
+// WithSourceGeoLocation sets SourceGeoLatitude & SourceGeoLongitude
+func (b *ExtensionsBuilder) WithSourceGeoLocation(lat, long float64) *ExtensionsBuilder {
+	if b.checkGeoLocation("slat", "slong", lat, long) {
+		b.ext.SourceGeoLatitude, b.ext.SourceGeoLongitude = &lat, &long
+	}
+	return b
+}
+
+// WithDestinationPort sets DestinationPort, which must be between 0 & 65535
+func (b *ExtensionsBuilder) WithDestinationPort(port int) *ExtensionsBuilder {
+	b.ext.DestinationPort = b.port("dpt", port, b.ext.DestinationPort)
+	return b
+}
+
+// WithDestinationTranslatedPort sets DestinationTranslatedPort, which must be between 0 & 65535
+func (b *ExtensionsBuilder) WithDestinationTranslatedPort(port int) *ExtensionsBuilder {
+	b.ext.DestinationTranslatedPort = b.port("destinationTranslatedPort", port, b.ext.DestinationTranslatedPort)
+	return b
+}
+
+// WithDestinationProcessId sets DestinationProcessId
+func (b *ExtensionsBuilder) WithDestinationProcessId(pid uint) *ExtensionsBuilder {
+	b.ext.DestinationProcessId = &pid
+	return b
+}
+
+// WithDestinationGeoLocation sets DestinationGeoLatitude & DestinationGeoLongitude
+func (b *ExtensionsBuilder) WithDestinationGeoLocation(lat, long float64) *ExtensionsBuilder {
+	if b.checkGeoLocation("dlat", "dlong", lat, long) {
+		b.ext.DestinationGeoLatitude, b.ext.DestinationGeoLongitude = &lat, &long
+	}
+	return b
+}
+
+// WithFileSize sets FileSize
+func (b *ExtensionsBuilder) WithFileSize(size uint) *ExtensionsBuilder {
+	b.ext.FileSize = &size
+	return b
+}
+
+// WithOldFileSize sets OldFileSize
+func (b *ExtensionsBuilder) WithOldFileSize(size uint) *ExtensionsBuilder {
+	b.ext.OldFileSize = &size
+	return b
+}
+
+// port returns a pointer to port if it's valid, otherwise records an error and returns cur
+func (b *ExtensionsBuilder) port(key string, port int, cur *uint) *uint {
+	if port < 0 || port > 65535 {
+		b.errs = append(b.errs, &fieldError{key, fmt.Errorf("%d: %w", port, InvalidPortErr)})
+		return cur
+	}
+	return ptr(uint(port))
+}
+
+func (b *ExtensionsBuilder) checkGeoLocation(latKey, longKey string, lat, long float64) bool {
+	ok := true
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		b.errs = append(b.errs, &fieldError{latKey, fmt.Errorf("%v: %w", lat, InvalidGeoLocationErr)})
+		ok = false
+	}
+	if math.IsNaN(long) || long < -180 || long > 180 {
+		b.errs = append(b.errs, &fieldError{longKey, fmt.Errorf("%v: %w", long, InvalidGeoLocationErr)})
+		ok = false
+	}
+	return ok
+}
@@ -0,0 +1,78 @@
+package cefevent
+
+import (
+	"math"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtensionsBuilder(t *testing.T) {
+	ext, err := NewExtensions().
+		WithMessage("login").
+		WithDestinationPort(22).
+		WithSourcePort(50000).
+		WithDeviceDirection(1).
+		WithBytesIn(10).
+		WithSourceGeoLocation(51.5, -0.1).
+		WithCustom("tenant", "acme").
+		Set("src", "10.0.0.1").
+		Set("cs1", "deny").
+		Set("cs1Label", "policy").
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, Extensions{
+		Message:             "login",
+		DestinationPort:     ptr(uint(22)),
+		SourcePort:          ptr(uint(50000)),
+		DeviceDirection:     ptr(uint8(1)),
+		BytesIn:             ptr(uint(10)),
+		SourceGeoLatitude:   ptr(51.5),
+		SourceGeoLongitude:  ptr(-0.1),
+		SourceAddress:       net.ParseIP("10.0.0.1"),
+		DeviceCustomString1: Labeled("policy", "deny"),
+		CustomExtensions:    map[string]string{"tenant": "acme"},
+	}, ext)
+}
+
+func TestExtensionsBuilder_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *ExtensionsBuilder) *ExtensionsBuilder
+		want  error
+	}{
+		{"port_high", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithDestinationPort(70000) }, InvalidPortErr},
+		{"port_negative", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithSourceTranslatedPort(-1) }, InvalidPortErr},
+		{"port_parsed", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.Set("dpt", "70000") }, InvalidPortErr},
+		{"direction", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithDeviceDirection(2) }, InvalidDeviceDirectionErr},
+		{"latitude", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithDestinationGeoLocation(91, 0) }, InvalidGeoLocationErr},
+		{"longitude_nan", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithSourceGeoLocation(0, math.NaN()) }, InvalidGeoLocationErr},
+		{"custom_invalid", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithCustom("a b", "v") }, InvalidExtensionKeyErr},
+		{"custom_reserved", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.WithCustom("msg", "v") }, ReservedExtensionKeyErr},
+		{"set_unknown", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.Set("tenant", "acme") }, InvalidExtensionKeyErr},
+		{"missing_label", func(b *ExtensionsBuilder) *ExtensionsBuilder { return b.Set("cn1", "3") }, MissingLabelErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.build(NewExtensions()).Build()
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestExtensionsBuilder_invalidNotSet(t *testing.T) {
+	ext, err := NewExtensions().WithDestinationPort(443).WithDestinationPort(-5).Set("spt", "x").Build()
+	assert.ErrorIs(t, err, InvalidPortErr)
+	assert.Equal(t, ptr(uint(443)), ext.DestinationPort)
+	assert.Nil(t, ext.SourcePort)
+}
+
+func TestExtensionsBuilder_Build_copies(t *testing.T) {
+	b := NewExtensions().WithCustom("k", "v")
+	ext, err := b.Build()
+	require.NoError(t, err)
+	b.WithCustom("k", "changed")
+	assert.Equal(t, "v", ext.CustomExtensions["k"])
+}
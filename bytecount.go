@@ -0,0 +1,116 @@
+package cefevent
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+)
+
+// ByteCounter reports a number of bytes transferred
+type ByteCounter interface {
+	Count() uint64
+}
+
+// CountingReader counts the bytes read through it. Count is safe to call while reads are in progress.
+type CountingReader struct {
+	r io.Reader
+	n atomic.Uint64
+}
+
+// NewCountingReader wraps r to count the bytes read from it
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{r: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(uint64(n))
+	return n, err
+}
+
+// Count returns the number of bytes read so far
+func (c *CountingReader) Count() uint64 {
+	return c.n.Load()
+}
+
+// CountingWriter counts the bytes written through it. Count is safe to call while writes are in progress.
+type CountingWriter struct {
+	w io.Writer
+	n atomic.Uint64
+}
+
+// NewCountingWriter wraps w to count the bytes written to it
+func NewCountingWriter(w io.Writer) *CountingWriter {
+	return &CountingWriter{w: w}
+}
+
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(uint64(n))
+	return n, err
+}
+
+// Count returns the number of bytes written so far
+func (c *CountingWriter) Count() uint64 {
+	return c.n.Load()
+}
+
+// CountingConn counts the bytes read from and written to a connection, from the point of view of the device: bytes
+// read are inbound and bytes written are outbound.
+type CountingConn struct {
+	net.Conn
+	in  atomic.Uint64
+	out atomic.Uint64
+}
+
+// NewCountingConn wraps conn to count the bytes transferred over it
+func NewCountingConn(conn net.Conn) *CountingConn {
+	return &CountingConn{Conn: conn}
+}
+
+func (c *CountingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.in.Add(uint64(n))
+	return n, err
+}
+
+func (c *CountingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.out.Add(uint64(n))
+	return n, err
+}
+
+// BytesIn returns the number of bytes read so far
+func (c *CountingConn) BytesIn() uint64 {
+	return c.in.Load()
+}
+
+// BytesOut returns the number of bytes written so far
+func (c *CountingConn) BytesOut() uint64 {
+	return c.out.Load()
+}
+
+// AddByteCounts returns ext with BytesIn & BytesOut set from the connection's counts, e.g. for a session end event.
+// Combine with FromConn to describe the whole session.
+func (c *CountingConn) AddByteCounts(ext Extensions) Extensions {
+	return AddByteCounts(ext, counterFunc(c.BytesIn), counterFunc(c.BytesOut))
+}
+
+// AddByteCounts returns ext with BytesIn set from in and BytesOut set from out. Either counter may be nil to leave the
+// field unchanged.
+func AddByteCounts(ext Extensions, in, out ByteCounter) Extensions {
+	if in != nil {
+		ext.BytesIn = ptr(uint(in.Count()))
+	}
+	if out != nil {
+		ext.BytesOut = ptr(uint(out.Count()))
+	}
+	return ext
+}
+
+// counterFunc adapts a function to ByteCounter
+type counterFunc func() uint64
+
+func (f counterFunc) Count() uint64 {
+	return f()
+}
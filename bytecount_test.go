@@ -0,0 +1,46 @@
+package cefevent
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountingReaderWriter(t *testing.T) {
+	r := NewCountingReader(strings.NewReader("hello world"))
+	var buf bytes.Buffer
+	w := NewCountingWriter(&buf)
+	n, err := io.Copy(w, r)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), n)
+	assert.Equal(t, uint64(11), r.Count())
+	assert.Equal(t, uint64(11), w.Count())
+
+	ext := AddByteCounts(Extensions{BytesOut: ptr(uint(1))}, r, nil)
+	assert.Equal(t, "in=11 out=1", ext.String())
+}
+
+func TestCountingConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := NewCountingConn(server)
+	defer conn.Close()
+
+	go func() {
+		_, _ = client.Write([]byte("request"))
+		_, _ = io.ReadFull(client, make([]byte, 12))
+	}()
+	_, err := io.ReadFull(conn, make([]byte, 7))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("the response"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(7), conn.BytesIn())
+	assert.Equal(t, uint64(12), conn.BytesOut())
+	assert.Equal(t, "in=7 out=12 outcome=success", conn.AddByteCounts(Extensions{Outcome: "success"}).String())
+}
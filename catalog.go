@@ -0,0 +1,130 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownEventClassErr error when logging a class ID which isn't in the Logger's catalog
+var UnknownEventClassErr = errors.New("unknown event class")
+
+// DuplicateEventClassErr error when registering a class ID which is already in a catalog
+var DuplicateEventClassErr = errors.New("duplicate event class")
+
+// EventClass describes a class of event an application emits
+type EventClass struct {
+	// Id is the device event class ID in the CEF header
+	Id string
+	// Name is the human-readable event name in the CEF header
+	Name string
+	// Severity is the severity events of this class are logged with
+	Severity string
+	// Description explains when the event is emitted. Only used for the generated event dictionary
+	Description string
+}
+
+// Catalog is a registry of the event classes an application emits, guaranteeing consistent names and severities across
+// a codebase. A Catalog is safe to use from multiple goroutines.
+type Catalog struct {
+	mu      sync.RWMutex
+	classes map[string]EventClass
+}
+
+// NewCatalog creates an empty Catalog
+func NewCatalog() *Catalog {
+	return &Catalog{classes: make(map[string]EventClass)}
+}
+
+// Register adds event classes to the catalog. Returns DuplicateEventClassErr if an ID is already registered, or
+// InvalidSeverityError if a class has an invalid severity. Nothing is registered if an error is returned.
+func (c *Catalog) Register(classes ...EventClass) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[string]bool, len(classes))
+	for _, ec := range classes {
+		if _, ok := c.classes[ec.Id]; ok || seen[ec.Id] {
+			return fmt.Errorf("%q: %w", ec.Id, DuplicateEventClassErr)
+		}
+		if err := validateSeverity(ec.Severity); err != nil {
+			return fmt.Errorf("%q: %w", ec.Id, err)
+		}
+		seen[ec.Id] = true
+	}
+	for _, ec := range classes {
+		c.classes[ec.Id] = ec
+	}
+	return nil
+}
+
+// MustRegister is like Register but panics on error. Useful for declaring classes at startup.
+func (c *Catalog) MustRegister(classes ...EventClass) {
+	if err := c.Register(classes...); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the event class registered with id
+func (c *Catalog) Lookup(id string) (EventClass, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ec, ok := c.classes[id]
+	return ec, ok
+}
+
+// Classes returns all registered event classes ordered by ID
+func (c *Catalog) Classes() []EventClass {
+	c.mu.RLock()
+	classes := make([]EventClass, 0, len(c.classes))
+	for _, ec := range c.classes {
+		classes = append(classes, ec)
+	}
+	c.mu.RUnlock()
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Id < classes[j].Id
+	})
+	return classes
+}
+
+// WriteDictionary writes a markdown table of the registered event classes to w, for publishing as an event dictionary
+// for the SOC.
+func (c *Catalog) WriteDictionary(w io.Writer) error {
+	b := strings.Builder{}
+	b.WriteString("| ID | Name | Severity | Description |\n")
+	b.WriteString("|----|------|----------|-------------|\n")
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	for _, ec := range c.Classes() {
+		b.WriteString("| " + cell.Replace(ec.Id) + " | " + cell.Replace(ec.Name) + " | " + cell.Replace(ec.Severity) + " | " +
+			cell.Replace(ec.Description) + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WithCatalog sets the catalog used by LogClass
+func WithCatalog(c *Catalog) LoggerConfigOption {
+	return func(l *Logger) {
+		l.catalog = c
+	}
+}
+
+// LogClass logs CEF event of a class registered in the Logger's catalog, taking the name and severity from the catalog.
+// Returns UnknownEventClassErr if the class isn't registered.
+func (l *Logger) LogClass(deviceEventClassId string, extensions Extensions) error {
+	if l.catalog == nil {
+		return fmt.Errorf("%q: %w: logger has no catalog", deviceEventClassId, UnknownEventClassErr)
+	}
+	ec, ok := l.catalog.Lookup(deviceEventClassId)
+	if !ok {
+		return fmt.Errorf("%q: %w", deviceEventClassId, UnknownEventClassErr)
+	}
+	return l.Log(ec.Id, ec.Name, ec.Severity, extensions)
+}
+
+// LogClass logs CEF event of a registered class with default logger
+func LogClass(deviceEventClassId string, extensions Extensions) error {
+	return defaultLogger.Load().LogClass(deviceEventClassId, extensions)
+}
@@ -0,0 +1,50 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCatalog() *Catalog {
+	c := NewCatalog()
+	c.MustRegister(
+		EventClass{Id: "2001", Name: "login failed", Severity: MediumSeverity, Description: "A user failed to authenticate"},
+		EventClass{Id: "1001", Name: "login", Severity: LowSeverity, Description: "A user | session started"},
+	)
+	return c
+}
+
+func TestCatalog_Register(t *testing.T) {
+	c := testCatalog()
+	assert.ErrorIs(t, c.Register(EventClass{Id: "1001", Severity: LowSeverity}), DuplicateEventClassErr)
+	assert.ErrorIs(t, c.Register(EventClass{Id: "3001", Severity: LowSeverity}, EventClass{Id: "3001", Severity: LowSeverity}), DuplicateEventClassErr)
+	assert.ErrorIs(t, c.Register(EventClass{Id: "3002", Severity: "bad"}), InvalidSeverityError)
+	_, ok := c.Lookup("3001")
+	assert.False(t, ok, "failed registrations shouldn't be partially applied")
+	assert.Panics(t, func() {
+		c.MustRegister(EventClass{Id: "1001", Severity: LowSeverity})
+	})
+}
+
+func TestLogger_LogClass(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithCatalog(testCatalog()))
+	require.NoError(t, l.LogClass("2001", Extensions{DestinationUserId: "0"}))
+	assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|2001|login failed|Medium|duid=0", buf.String())
+
+	assert.ErrorIs(t, l.LogClass("9999", Extensions{}), UnknownEventClassErr)
+	assert.ErrorIs(t, NewLogger(buf, "testVendor", "testProduct", "1.0").LogClass("2001", Extensions{}), UnknownEventClassErr)
+}
+
+func TestCatalog_WriteDictionary(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, testCatalog().WriteDictionary(buf))
+	assert.Equal(t, `| ID | Name | Severity | Description |
+|----|------|----------|-------------|
+| 1001 | login | Low | A user \| session started |
+| 2001 | login failed | Medium | A user failed to authenticate |
+`, buf.String())
+}
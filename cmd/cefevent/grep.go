@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/dmtaylor/cefevent"
+)
+
+// grep writes the records matching a query
+type grep struct {
+	match         cefevent.Predicate
+	invert        bool
+	asJSON        bool
+	octetCounting bool
+	out           *bufio.Writer
+	matched       int
+}
+
+func grepCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	octetCounting := fs.Bool("octet-counting", false, "read and write RFC 6587 octet counted records rather than lines")
+	asJSON := fs.Bool("json", false, "write matching events as JSON, one per line, rather than as read")
+	invert := fs.Bool("v", false, "write the events which don't match")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: cefevent grep [-octet-counting] [-json] [-v] expression [file ...]")
+		return 2
+	}
+	match, err := cefevent.CompileQuery(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cefevent: %v\n", err)
+		return 2
+	}
+	var opts []cefevent.ScannerOption
+	if *octetCounting {
+		opts = append(opts, cefevent.WithOctetCounting())
+	}
+
+	g := &grep{match: match, invert: *invert, asJSON: *asJSON, octetCounting: *octetCounting, out: bufio.NewWriter(stdout)}
+	files := fs.Args()[1:]
+	if len(files) == 0 {
+		if err := g.read(stdin, opts...); err != nil {
+			g.out.Flush()
+			fmt.Fprintf(stderr, "cefevent: %v\n", err)
+			return 2
+		}
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			g.out.Flush()
+			fmt.Fprintf(stderr, "cefevent: %v\n", err)
+			return 2
+		}
+		err = g.read(f, opts...)
+		f.Close()
+		if err != nil {
+			g.out.Flush()
+			fmt.Fprintf(stderr, "cefevent: %s: %v\n", name, err)
+			return 2
+		}
+	}
+	if err := g.out.Flush(); err != nil {
+		fmt.Fprintf(stderr, "cefevent: %v\n", err)
+		return 2
+	}
+	// Like grep, exit 1 when nothing matched
+	if g.matched == 0 {
+		return 1
+	}
+	return 0
+}
+
+// read writes the matching records read from r. Records with malformed headers never match; those with malformed
+// extensions are matched as far as they could be parsed.
+func (g *grep) read(r io.Reader, opts ...cefevent.ScannerOption) error {
+	sc := cefevent.NewScanner(r, opts...)
+	for sc.Scan() {
+		event, err := sc.Event()
+		if err != nil && cefevent.FieldErrors(err) == nil {
+			continue
+		}
+		if g.match(&event) == g.invert {
+			continue
+		}
+		g.matched++
+		if err := g.write(sc.Text(), event); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// write writes a matching record as read, framed as it was read, or its event as JSON
+func (g *grep) write(text string, event cefevent.Event) error {
+	if g.asJSON {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		g.out.Write(b)
+		return g.out.WriteByte('\n')
+	}
+	if g.octetCounting {
+		g.out.WriteString(strconv.Itoa(len(text)))
+		g.out.WriteByte(' ')
+		_, err := g.out.WriteString(text)
+		return err
+	}
+	g.out.WriteString(text)
+	return g.out.WriteByte('\n')
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const grepInput = `CEF:0|v|p|1|100|login|Low|src=10.0.0.1 dst=10.1.1.1 act=allow
+CEF:0|v|p|1|200|denied|High|src=192.0.2.1 dst=10.2.2.2 act=deny
+not cef
+CEF:0|v|p|1|200|denied|Very-High|src=10.0.0.2 dst=198.51.100.1 act=deny
+CEF:0|v|p|1|300|scan|High|dst=10.3.3.3 dpt=https
+`
+
+func TestGrepCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+		code int
+	}{
+		{"match", []string{"dst=10.0.0.0/8 AND severity>=High"},
+			"CEF:0|v|p|1|200|denied|High|src=192.0.2.1 dst=10.2.2.2 act=deny\n" +
+				"CEF:0|v|p|1|300|scan|High|dst=10.3.3.3 dpt=https\n", 0},
+		{"malformed_extension", []string{"dst=10.3.3.3"}, "CEF:0|v|p|1|300|scan|High|dst=10.3.3.3 dpt=https\n", 0},
+		{"invert", []string{"-v", `act~"^(allow|deny)$"`}, "CEF:0|v|p|1|300|scan|High|dst=10.3.3.3 dpt=https\n", 0},
+		{"no_match", []string{"act=drop"}, "", 1},
+		{"json", []string{"-json", "name=login"},
+			`{"version":0,"deviceVendor":"v","deviceProduct":"p","deviceVersion":"1","deviceEventClassId":"100",` +
+				`"name":"login","severity":"Low","act":"allow","dst":"10.1.1.1","src":"10.0.0.1"}` + "\n",
+			0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(append([]string{"grep"}, tt.args...), strings.NewReader(grepInput), stdout, stderr)
+			assert.Equal(t, tt.code, code, stderr.String())
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestGrepCommand_files(t *testing.T) {
+	dir := t.TempDir()
+	record := "CEF:0|v|p|1|100|multi|Low|msg=line one\nline two"
+	other := "CEF:0|v|p|1|100|x|Low|"
+	framed := strconv.Itoa(len(record)) + " " + record + strconv.Itoa(len(other)) + " " + other
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.log"), []byte(framed), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.log"), []byte(framed), 0o600))
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"grep", "-octet-counting", "msg CONTAINS two", filepath.Join(dir, "a.log"),
+		filepath.Join(dir, "b.log")}, nil, stdout, stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, strconv.Itoa(len(record))+" "+record+strconv.Itoa(len(record))+" "+record, stdout.String())
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"grep", "act=deny", filepath.Join(dir, "missing.log")}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "missing.log")
+}
+
+func TestGrepCommand_usage(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, 2, run([]string{"grep"}, nil, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "usage: cefevent grep")
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"grep", "act="}, nil, &bytes.Buffer{}, stderr))
+	assert.Equal(t, "cefevent: invalid query: unexpected end of query\n", stderr.String())
+}
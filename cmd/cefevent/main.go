@@ -0,0 +1,80 @@
+// Command cefevent inspects CEF event logs.
+//
+// Usage:
+//
+//	cefevent stats [-octet-counting] [-top n] [-host source|device] [file ...]
+//
+// stats profiles the events in the files, or standard input if none are given, printing the number of events per
+// device event class ID, severity and source host, and the time range they cover. -host device counts events per
+// reporting device instead.
+//
+//	cefevent grep [-octet-counting] [-json] [-v] expression [file ...]
+//
+// grep writes the events in the files, or standard input if none are given, which match a query expression such as
+// "dst=10.0.0.0/8 AND severity>=High", as described by cefevent.CompileQuery. Events are written as read, or with -json
+// as JSON objects, one per line. -v writes the events which don't match instead. Like grep(1) it exits with 1 if no
+// events were written.
+//
+//	cefevent relay -config file
+//
+// relay forwards the events received on UDP or TCP listeners, or appended to files, to syslog servers, files or
+// standard output, filtering and transforming them as configured, until interrupted. The config file is JSON:
+//
+//	{
+//		"listen": [
+//			{"network": "udp", "address": ":514"},
+//			{"network": "tcp", "address": ":6514", "octetCounting": true},
+//			{"network": "file", "address": "/var/log/app/cef.log"}
+//		],
+//		"rules": [{"id": "admin-denied", "detection": {"s": {"act": "deny", "suser|startswith": "admin"}}}],
+//		"filter": {"classIds": ["100", "200"], "minSeverity": "Medium", "fields": {"act": "deny"}},
+//		"transforms": [{"rename": "cs1", "to": "suser"}, {"drop": ["cs2", "cs2Label"]}],
+//		"fields": {"deviceHostName": "relay-1.example"},
+//		"sinks": [{"network": "tcp", "address": "siem.example:514"}, {"network": "file", "address": "relay.log"}]
+//	}
+//
+// Files are followed from their end unless "fromStart" is set, across truncation and rotation. Transforms are
+// described by cefevent.LoadTransforms, and fields are set on every event. The filter's "query" takes an expression
+// such as "dst=10.0.0.0/8 AND severity>=High", as described by cefevent.CompileQuery. Rules, as described by
+// cefevent.Rule, see every event before the filter, and their correlation events are written straight to the sinks.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand named by args[0], returning the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	switch args[0] {
+	case "stats":
+		return statsCommand(args[1:], stdin, stdout, stderr)
+	case "grep":
+		return grepCommand(args[1:], stdin, stdout, stderr)
+	case "relay":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return relayCommand(ctx, args[1:], stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "cefevent: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cefevent stats [-octet-counting] [-top n] [-host source|device] [file ...]")
+	fmt.Fprintln(w, "       cefevent grep [-octet-counting] [-json] [-v] expression [file ...]")
+	fmt.Fprintln(w, "       cefevent relay -config file")
+}
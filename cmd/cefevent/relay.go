@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dmtaylor/cefevent"
+)
+
+// relayConfig is the config file of the relay command
+type relayConfig struct {
+	Listen     []relayListener          `json:"listen"`
+	Rules      []cefevent.Rule          `json:"rules"`
+	Filter     relayFilter              `json:"filter"`
+	Transforms []cefevent.TransformRule `json:"transforms"`
+	Fields     map[string]string        `json:"fields"`
+	Sinks      []relaySink              `json:"sinks"`
+}
+
+// relayListener is a source of records: a UDP or TCP listener, or a file to follow
+type relayListener struct {
+	Network       string `json:"network"` // udp, tcp or file
+	Address       string `json:"address"` // the address to listen on, or the path of the file
+	OctetCounting bool   `json:"octetCounting,omitempty"`
+	FromStart     bool   `json:"fromStart,omitempty"` // read files from the start rather than only new records
+}
+
+// relayFilter selects the events relayed. Unset conditions match every event.
+type relayFilter struct {
+	ClassIds    []string          `json:"classIds,omitempty"`
+	MinSeverity string            `json:"minSeverity,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	Query       string            `json:"query,omitempty"` // as cefevent.CompileQuery
+}
+
+// relaySink is a destination for relayed events: a syslog server, a file or standard output
+type relaySink struct {
+	Network string `json:"network"` // udp, tcp, tls, file or stdout
+	Address string `json:"address,omitempty"`
+}
+
+// tailInterval is how often followed files are checked for new records
+var tailInterval = 250 * time.Millisecond
+
+func relayCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("relay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "read the relay configuration from `file`")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *configPath == "" || fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: cefevent relay -config file")
+		return 2
+	}
+	config, err := loadRelayConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "cefevent: %s: %v\n", *configPath, err)
+		return 2
+	}
+	out, err := openSinks(config.Sinks, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "cefevent: %v\n", err)
+		return 1
+	}
+	defer out.close()
+	stages, err := config.stages(out)
+	if err != nil {
+		fmt.Fprintf(stderr, "cefevent: %s: %v\n", *configPath, err)
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+	sources, err := startListeners(ctx, config.Listen, pw)
+	if err != nil {
+		fmt.Fprintf(stderr, "cefevent: %v\n", err)
+		return 1
+	}
+	go func() {
+		sources.Wait()
+		pw.Close()
+	}()
+	var errMu sync.Mutex
+	p := cefevent.NewPipeline(cefevent.NewScanner(pr), out, cefevent.WithStages(stages...),
+		cefevent.WithPipelineErrors(func(err *cefevent.PipelineError) {
+			errMu.Lock()
+			defer errMu.Unlock()
+			fmt.Fprintf(stderr, "cefevent: %v: %q\n", err.Err, err.Raw)
+		}))
+	stop := context.AfterFunc(ctx, func() {
+		pr.Close()
+	})
+	defer stop()
+
+	err = p.Run(ctx)
+	cancel()
+	sources.Wait()
+	stats := p.Stats()
+	fmt.Fprintf(stderr, "cefevent: relayed %d events, dropped %d, failed %d\n", stats.Processed, stats.Dropped,
+		stats.Errors)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, io.ErrClosedPipe) {
+		fmt.Fprintf(stderr, "cefevent: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func loadRelayConfig(path string) (*relayConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var config relayConfig
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, err
+	}
+	if len(config.Listen) == 0 {
+		return nil, errors.New("no listen entries")
+	}
+	if len(config.Sinks) == 0 {
+		return nil, errors.New("no sinks")
+	}
+	return &config, nil
+}
+
+// stages returns the pipeline stages the config describes: the rules, writing correlation events to alerts, then the
+// filter, then the transforms, then the fields added
+func (c *relayConfig) stages(alerts cefevent.EventWriter) ([]cefevent.Stage, error) {
+	var stages []cefevent.Stage
+	if len(c.Rules) > 0 {
+		rules, err := cefevent.NewRuleMatcher(c.Rules, cefevent.WithCorrelationEvents(alerts))
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, rules.Stage())
+	}
+	var match []cefevent.Predicate
+	if len(c.Filter.ClassIds) > 0 {
+		match = append(match, cefevent.MatchClassID(c.Filter.ClassIds...))
+	}
+	if c.Filter.MinSeverity != "" {
+		if _, err := cefevent.WithMinSeverity(c.Filter.MinSeverity); err != nil {
+			return nil, fmt.Errorf("filter minSeverity %q: %w", c.Filter.MinSeverity, err)
+		}
+		match = append(match, cefevent.MatchSeverityAtLeast(c.Filter.MinSeverity))
+	}
+	for k, v := range c.Filter.Fields {
+		match = append(match, cefevent.MatchField(k, v))
+	}
+	if c.Filter.Query != "" {
+		query, err := cefevent.CompileQuery(c.Filter.Query)
+		if err != nil {
+			return nil, fmt.Errorf("filter query: %w", err)
+		}
+		match = append(match, query)
+	}
+	if len(match) > 0 {
+		stages = append(stages, cefevent.Filter(cefevent.And(match...)))
+	}
+	transforms, err := cefevent.TransformStages(c.Transforms)
+	if err != nil {
+		return nil, err
+	}
+	stages = append(stages, transforms...)
+	if len(c.Fields) > 0 {
+		add, err := cefevent.AddFields(c.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("fields: %w", err)
+		}
+		stages = append(stages, add)
+	}
+	return stages, nil
+}
+
+// sinks writes each event to every sink, through a Logger per sink
+type sinks struct {
+	loggers []*cefevent.Logger
+	closers []io.Closer
+}
+
+func openSinks(config []relaySink, stdout io.Writer) (*sinks, error) {
+	s := &sinks{}
+	for _, sink := range config {
+		var out io.Writer
+		opts := []cefevent.LoggerConfigOption{cefevent.OmitSyslogHeader(),
+			cefevent.WithRecordTerminator(cefevent.NewlineTerminator)}
+		switch sink.Network {
+		case "udp", "tcp", "tls":
+			w, err := cefevent.NewSyslogWriter(sink.Network, sink.Address, nil)
+			if err != nil {
+				s.close()
+				return nil, fmt.Errorf("sink %s %s: %w", sink.Network, sink.Address, err)
+			}
+			out, opts = w, nil
+			s.closers = append(s.closers, w)
+		case "file":
+			f, err := os.OpenFile(sink.Address, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+			if err != nil {
+				s.close()
+				return nil, fmt.Errorf("sink %w", err)
+			}
+			out = f
+			s.closers = append(s.closers, f)
+		case "stdout":
+			out = stdout
+		default:
+			s.close()
+			return nil, fmt.Errorf("sink network must be udp, tcp, tls, file or stdout, not %q", sink.Network)
+		}
+		s.loggers = append(s.loggers, cefevent.NewLogger(out, "cefevent", "relay", "1", opts...))
+	}
+	return s, nil
+}
+
+// WriteEvent writes e to every sink, returning the errors of those that failed
+func (s *sinks) WriteEvent(ctx context.Context, e cefevent.Event) error {
+	var errs []error
+	for _, l := range s.loggers {
+		if err := l.WriteEvent(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sinks) close() {
+	for _, l := range s.loggers {
+		_ = l.Close()
+	}
+	for _, c := range s.closers {
+		_ = c.Close()
+	}
+}
+
+// recordWriter merges the records of every source into one newline delimited stream
+type recordWriter struct {
+	w io.Writer // an io.Pipe, which serializes concurrent writes
+}
+
+// write writes one record, escaping any newlines within it so it stays on one line
+func (w recordWriter) write(record []byte) error {
+	record = bytes.TrimRight(record, "\r\n")
+	if len(bytes.TrimSpace(record)) == 0 {
+		return nil
+	}
+	line := make([]byte, 0, len(record)+1)
+	for _, c := range record {
+		if c == '\n' {
+			line = append(line, '\\', 'n')
+		} else {
+			line = append(line, c)
+		}
+	}
+	_, err := w.w.Write(append(line, '\n'))
+	return err
+}
+
+// startListeners starts reading records from each listener into w until ctx is done
+func startListeners(ctx context.Context, config []relayListener, w io.Writer) (*sync.WaitGroup, error) {
+	var wg sync.WaitGroup
+	out := recordWriter{w}
+	for _, l := range config {
+		var run func()
+		switch l.Network {
+		case "udp":
+			conn, err := net.ListenPacket("udp", l.Address)
+			if err != nil {
+				return nil, err
+			}
+			context.AfterFunc(ctx, func() { conn.Close() })
+			run = func() { readDatagrams(conn, out) }
+		case "tcp":
+			ln, err := net.Listen("tcp", l.Address)
+			if err != nil {
+				return nil, err
+			}
+			context.AfterFunc(ctx, func() { ln.Close() })
+			split := cefevent.ScanRecords
+			if l.OctetCounting {
+				split = cefevent.ScanOctetCounted
+			}
+			run = func() { acceptStreams(ctx, ln, split, out) }
+		case "file":
+			split := cefevent.ScanRecords
+			if l.OctetCounting {
+				split = cefevent.ScanOctetCounted
+			}
+			f, err := os.Open(l.Address)
+			if err != nil {
+				return nil, err
+			}
+			if !l.FromStart {
+				if _, err := f.Seek(0, io.SeekEnd); err != nil {
+					f.Close()
+					return nil, err
+				}
+			}
+			run = func() { tailFile(ctx, f, split, out) }
+		default:
+			return nil, fmt.Errorf("listen network must be udp, tcp or file, not %q", l.Network)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
+	return &wg, nil
+}
+
+// readDatagrams writes each datagram received on conn as a record until conn is closed
+func readDatagrams(conn net.PacketConn, out recordWriter) {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if out.write(buf[:n]) != nil {
+			return
+		}
+	}
+}
+
+// acceptStreams writes the records sent over each connection accepted on ln until it is closed
+func acceptStreams(ctx context.Context, ln net.Listener, split bufio.SplitFunc, out recordWriter) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer stop()
+			defer conn.Close()
+			s := bufio.NewScanner(conn)
+			s.Split(split)
+			for s.Scan() {
+				if out.write(s.Bytes()) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// tailFile writes the records appended to f until ctx is done, like tail -F: if the file is truncated it is read from
+// the start, and if it is replaced, e.g. by log rotation, the new file is followed
+func tailFile(ctx context.Context, f *os.File, split bufio.SplitFunc, out recordWriter) {
+	defer func() { f.Close() }()
+	var pending []byte
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		pending = append(pending, buf[:n]...)
+		for len(pending) > 0 {
+			advance, record, serr := split(pending, false)
+			if serr != nil {
+				return
+			}
+			if advance == 0 {
+				break
+			}
+			if record != nil && out.write(record) != nil {
+				return
+			}
+			pending = pending[advance:]
+		}
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tailInterval):
+		}
+		if reopened := followFile(f); reopened != f {
+			f.Close()
+			f, pending = reopened, nil
+		}
+	}
+}
+
+// followFile returns the file to read next: f seeked back to the start if it has been truncated, a newly opened file
+// if its path now names another file, or f
+func followFile(f *os.File) *os.File {
+	current, err := f.Stat()
+	if err != nil {
+		return f
+	}
+	latest, err := os.Stat(f.Name())
+	if err == nil && !os.SameFile(current, latest) {
+		if reopened, err := os.Open(f.Name()); err == nil {
+			return reopened
+		}
+		return f
+	}
+	if offset, err := f.Seek(0, io.SeekCurrent); err == nil && current.Size() < offset {
+		_, _ = f.Seek(0, io.SeekStart)
+	}
+	return f
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startRelay runs the relay command with config in the background, returning a function which stops it and returns
+// its exit code and standard error
+func startRelay(t *testing.T, config string) func() (int, string) {
+	t.Helper()
+	tailInterval = 5 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "relay.json")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+	ctx, cancel := context.WithCancel(context.Background())
+	stderr := &bytes.Buffer{}
+	done := make(chan int, 1)
+	go func() {
+		done <- relayCommand(ctx, []string{"-config", path}, &bytes.Buffer{}, stderr)
+	}()
+	return func() (int, string) {
+		cancel()
+		select {
+		case code := <-done:
+			return code, stderr.String()
+		case <-time.After(5 * time.Second):
+			t.Fatal("relay didn't stop")
+			return 0, ""
+		}
+	}
+}
+
+// waitForLines waits for path to hold n lines, returning them
+func waitForLines(t *testing.T, path string, n int) []string {
+	t.Helper()
+	var lines []string
+	require.Eventually(t, func() bool {
+		b, _ := os.ReadFile(path)
+		lines = strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		return len(b) > 0 && len(lines) >= n
+	}, 5*time.Second, 5*time.Millisecond)
+	return lines
+}
+
+// freeAddr returns a local address with a port that was free for network
+func freeAddr(t *testing.T, network string) string {
+	t.Helper()
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+		return conn.LocalAddr().String()
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestRelayCommand_file(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.log"), filepath.Join(dir, "out.log")
+	require.NoError(t, os.WriteFile(in, []byte(`CEF:0|v|p|1|100|login|High|act=deny cs1=alice cs1Label=user
+CEF:0|v|p|1|100|login|Low|act=deny cs1=bob cs1Label=user
+CEF:0|v|p|1|200|logout|High|act=deny
+CEF:0|v|p|1|100|login|Very-High|act=allow
+not cef
+`), 0o600))
+	stop := startRelay(t, fmt.Sprintf(`{
+		"listen": [{"network": "file", "address": %q, "fromStart": true}],
+		"rules": [{"id": "allowed", "title": "Allowed login", "detection": {"s": {"act": "allow"}}}],
+		"filter": {"classIds": ["100"], "minSeverity": "High", "query": "act=deny AND NOT name=logout"},
+		"transforms": [{"rename": "cs1", "to": "suser"}],
+		"fields": {"deviceHostName": "relay.example"},
+		"sinks": [{"network": "file", "address": %q}]
+	}`, in, out))
+
+	lines := waitForLines(t, out, 2)
+	assert.Equal(t, []string{
+		"CEF:1|v|p|1|100|login|High|act=deny dcvhost=relay.example suser=alice",
+		"CEF:1|cefevent|relay|1|allowed|Allowed login|Medium|act=allow type=2",
+	}, lines)
+
+	// Records appended later are followed
+	f, err := os.OpenFile(in, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("CEF:0|v|p|1|100|later|High|act=deny\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	lines = waitForLines(t, out, 3)
+	assert.Equal(t, "CEF:1|v|p|1|100|later|High|act=deny dcvhost=relay.example", lines[2])
+
+	code, stderr := stop()
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, `cefevent: malformed cef event: missing CEF: prefix: "not cef"`)
+	assert.Contains(t, stderr, "cefevent: relayed 2 events, dropped 3, failed 1\n")
+}
+
+func TestRelayCommand_tail(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.log"), filepath.Join(dir, "out.log")
+	require.NoError(t, os.WriteFile(in, []byte("CEF:0|v|p|1|100|old|Low|\n"), 0o600))
+	stop := startRelay(t, fmt.Sprintf(`{
+		"listen": [{"network": "file", "address": %q}],
+		"sinks": [{"network": "file", "address": %q}]
+	}`, in, out))
+
+	// Wait for the relay to follow the file, then replace it as log rotation would
+	require.Eventually(t, func() bool {
+		f, err := os.OpenFile(in, os.O_WRONLY|os.O_APPEND, 0)
+		require.NoError(t, err)
+		defer f.Close()
+		_, err = f.WriteString("CEF:0|v|p|1|100|new|Low|\n")
+		require.NoError(t, err)
+		b, _ := os.ReadFile(out)
+		return len(b) > 0
+	}, 5*time.Second, 20*time.Millisecond)
+	require.NoError(t, os.Rename(in, in+".1"))
+	require.NoError(t, os.WriteFile(in, []byte("CEF:0|v|p|1|100|rotated|Low|\n"), 0o600))
+	require.Eventually(t, func() bool {
+		b, _ := os.ReadFile(out)
+		return strings.HasSuffix(string(b), "|rotated|Low|\n")
+	}, 5*time.Second, 5*time.Millisecond)
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "|old|")
+
+	code, stderr := stop()
+	assert.Equal(t, 0, code, stderr)
+}
+
+func TestRelayCommand_network(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+	udp, tcp, framed := freeAddr(t, "udp"), freeAddr(t, "tcp"), freeAddr(t, "tcp")
+	stop := startRelay(t, fmt.Sprintf(`{
+		"listen": [
+			{"network": "udp", "address": %q},
+			{"network": "tcp", "address": %q},
+			{"network": "tcp", "address": %q, "octetCounting": true}
+		],
+		"sinks": [{"network": "file", "address": %q}]
+	}`, udp, tcp, framed, out))
+
+	send := func(network, addr, payload string) {
+		var conn net.Conn
+		require.Eventually(t, func() bool {
+			var err error
+			conn, err = net.Dial(network, addr)
+			return err == nil
+		}, 5*time.Second, 5*time.Millisecond)
+		defer conn.Close()
+		_, err := conn.Write([]byte(payload))
+		require.NoError(t, err)
+	}
+	send("tcp", tcp, "CEF:0|v|p|1|100|tcp|Low|\nCEF:0|v|p|1|100|tcp2|Low|\n")
+	waitForLines(t, out, 2)
+	record := "CEF:0|v|p|1|100|framed|Low|msg=line one\nline two"
+	send("tcp", framed, strconv.Itoa(len(record))+" "+record)
+	waitForLines(t, out, 3)
+	send("udp", udp, "CEF:0|v|p|1|100|udp|Low|\n")
+	lines := waitForLines(t, out, 4)
+	assert.Equal(t, []string{
+		"CEF:1|v|p|1|100|tcp|Low|",
+		"CEF:1|v|p|1|100|tcp2|Low|",
+		`CEF:1|v|p|1|100|framed|Low|msg=line one\nline two`,
+		"CEF:1|v|p|1|100|udp|Low|",
+	}, lines)
+
+	code, stderr := stop()
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "relayed 4 events")
+}
+
+func TestRelayCommand_config(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		config  string
+		code    int
+		wantErr string
+	}{
+		{"syntax", `{`, 2, "unexpected EOF"},
+		{"unknown_field", `{"listen": [], "sinks": [], "filters": {}}`, 2, `unknown field "filters"`},
+		{"no_listen", `{"sinks": [{"network": "stdout"}]}`, 2, "no listen entries"},
+		{"no_sinks", `{"listen": [{"network": "udp", "address": "127.0.0.1:0"}]}`, 2, "no sinks"},
+		{"severity", `{"listen": [{"network": "udp"}], "sinks": [{"network": "stdout"}], "filter": {"minSeverity": "Critical"}}`,
+			2, `filter minSeverity "Critical": invalid severity`},
+		{"query", `{"listen": [{"network": "udp"}], "sinks": [{"network": "stdout"}], "filter": {"query": "act="}}`,
+			2, "filter query: invalid query: unexpected end of query"},
+		{"rule", `{"listen": [{"network": "udp"}], "sinks": [{"network": "stdout"}], "rules": [{"id": "r"}]}`,
+			2, `rule "r": missing condition`},
+		{"transform", `{"listen": [{"network": "udp"}], "sinks": [{"network": "stdout"}], "transforms": [{}]}`,
+			2, "transform 1: transform must have one of rename, rewrite or drop"},
+		{"fields", `{"listen": [{"network": "udp"}], "sinks": [{"network": "stdout"}], "fields": {"dpt": "https"}}`,
+			2, `fields: "dpt"`},
+		{"sink", `{"listen": [{"network": "udp"}], "sinks": [{"network": "kafka"}]}`,
+			1, `sink network must be udp, tcp, tls, file or stdout, not "kafka"`},
+		{"listen", `{"listen": [{"network": "sctp"}], "sinks": [{"network": "stdout"}]}`,
+			1, `listen network must be udp, tcp or file, not "sctp"`},
+		{"missing_file", `{"listen": [{"network": "file", "address": "` + filepath.Join(dir, "missing") + `"}],
+			"sinks": [{"network": "stdout"}]}`, 1, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.config), 0o600))
+			stderr := &bytes.Buffer{}
+			code := relayCommand(context.Background(), []string{"-config", path}, &bytes.Buffer{}, stderr)
+			assert.Equal(t, tt.code, code)
+			assert.Contains(t, stderr.String(), tt.wantErr)
+		})
+	}
+
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, 2, run([]string{"relay"}, nil, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "usage: cefevent relay -config file")
+}
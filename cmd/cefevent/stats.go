@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dmtaylor/cefevent"
+)
+
+// stats are the distributions of the events read
+type stats struct {
+	records    int
+	malformed  int
+	classes    map[string]int
+	severities map[string]int
+	hosts      map[string]int
+	first      time.Time
+	last       time.Time
+	// host picks the host events are counted against, sourceHost or deviceHost
+	host func(ext cefevent.Extensions) string
+}
+
+func newStats(host func(ext cefevent.Extensions) string) *stats {
+	return &stats{
+		classes:    make(map[string]int),
+		severities: make(map[string]int),
+		hosts:      make(map[string]int),
+		host:       host,
+	}
+}
+
+// hostGroupings are the values of the -host flag
+var hostGroupings = map[string]func(ext cefevent.Extensions) string{
+	"source": sourceHost,
+	"device": deviceHost,
+}
+
+func statsCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	octetCounting := fs.Bool("octet-counting", false, "read RFC 6587 octet counted records rather than lines")
+	top := fs.Int("top", 10, "number of values to list per distribution, 0 for all")
+	host := fs.String("host", "source", "count events per `host`: source (shost or src) or device (dvchost or dvc)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	hostKey, ok := hostGroupings[*host]
+	if !ok {
+		fmt.Fprintf(stderr, "cefevent: -host must be source or device, not %q\n", *host)
+		return 2
+	}
+	var opts []cefevent.ScannerOption
+	if *octetCounting {
+		opts = append(opts, cefevent.WithOctetCounting())
+	}
+
+	s := newStats(hostKey)
+	if fs.NArg() == 0 {
+		if err := s.read(stdin, opts...); err != nil {
+			fmt.Fprintf(stderr, "cefevent: %v\n", err)
+			return 1
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "cefevent: %v\n", err)
+			return 1
+		}
+		err = s.read(f, opts...)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "cefevent: %s: %v\n", name, err)
+			return 1
+		}
+	}
+	if err := s.write(stdout, *top); err != nil {
+		fmt.Fprintf(stderr, "cefevent: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// read adds every record read from r
+func (s *stats) read(r io.Reader, opts ...cefevent.ScannerOption) error {
+	sc := cefevent.NewScanner(r, opts...)
+	for sc.Scan() {
+		s.add(sc.Event())
+	}
+	return sc.Err()
+}
+
+// add counts an event. Events with malformed headers are only counted as malformed; those with malformed extensions are
+// counted as far as they could be parsed.
+func (s *stats) add(event cefevent.Event, err error) {
+	s.records++
+	if err != nil {
+		s.malformed++
+		if cefevent.FieldErrors(err) == nil {
+			return
+		}
+	}
+	s.classes[event.DeviceEventClassId]++
+	s.severities[event.Severity]++
+	s.hosts[s.host(event.Extensions)]++
+	if t := eventTime(event.Extensions); !t.IsZero() {
+		if s.first.IsZero() || t.Before(s.first) {
+			s.first = t
+		}
+		if t.After(s.last) {
+			s.last = t
+		}
+	}
+}
+
+// sourceHost is the host the event originated from, from shost or src, or "-" if neither is set
+func sourceHost(ext cefevent.Extensions) string {
+	switch {
+	case ext.SourceHostName != "":
+		return ext.SourceHostName
+	case ext.SourceAddress != nil:
+		return ext.SourceAddress.String()
+	}
+	return "-"
+}
+
+// deviceHost is the host that reported the event, from dvchost or dvc, or "-" if neither is set
+func deviceHost(ext cefevent.Extensions) string {
+	switch {
+	case ext.DeviceHostName != "":
+		return ext.DeviceHostName
+	case ext.DeviceAddress != nil:
+		return ext.DeviceAddress.String()
+	}
+	return "-"
+}
+
+// eventTime is when the event was received (rt), falling back to when it ended or started
+func eventTime(ext cefevent.Extensions) time.Time {
+	for _, t := range []time.Time{ext.DeviceReceiptTime, ext.EndTime, ext.StartTime} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// write prints the stats, listing the top most frequent values of each distribution
+func (s *stats) write(w io.Writer, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "records\t%d\n", s.records)
+	fmt.Fprintf(tw, "malformed\t%d\n", s.malformed)
+	if !s.first.IsZero() {
+		fmt.Fprintf(tw, "first\t%s\n", s.first.UTC().Format(time.RFC3339))
+		fmt.Fprintf(tw, "last\t%s\n", s.last.UTC().Format(time.RFC3339))
+	}
+	for _, d := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"class id", s.classes},
+		{"severity", s.severities},
+		{"host", s.hosts},
+	} {
+		fmt.Fprintf(tw, "\n%s\tevents\n", d.title)
+		for _, k := range topKeys(d.counts, top) {
+			fmt.Fprintf(tw, "%s\t%d\n", k, d.counts[k])
+		}
+		if n := len(d.counts) - top; top > 0 && n > 0 {
+			fmt.Fprintf(tw, "(%d more)\n", n)
+		}
+	}
+	return tw.Flush()
+}
+
+// topKeys returns the n keys with the highest counts, most frequent first and ties in key order. n <= 0 returns all
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
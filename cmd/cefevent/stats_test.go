@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statsInput = `Nov 9 11:45:20 host CEF:1|v|p|1|100|login|Low|dvchost=web-1 shost=client-1 rt=1699530320000
+CEF:1|v|p|1|100|login|Low|dvchost=web-2 src=10.1.1.1 rt=1699530380000
+CEF:1|v|p|1|200|denied|High|dvc=10.0.0.1 end=1699530200000 src=bad
+not cef
+CEF:1|v|p|1|100|login|Low|dvchost=web-1 shost=client-1
+`
+
+func TestStatsCommand(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	require.Equal(t, 0, run([]string{"stats", "-top", "1"}, strings.NewReader(statsInput), stdout, stderr), stderr.String())
+	assert.Equal(t, `records    5
+malformed  2
+first      2023-11-09T11:43:20Z
+last       2023-11-09T11:46:20Z
+
+class id  events
+100       3
+(1 more)
+
+severity  events
+Low       3
+(1 more)
+
+host      events
+client-1  2
+(2 more)
+`, stdout.String())
+}
+
+func TestStatsCommand_host(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"source", "host      events\nclient-1  2\n-         1\n10.1.1.1  1\n"},
+		{"device", "host      events\nweb-1     2\n10.0.0.1  1\nweb-2     1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			require.Equal(t, 0, run([]string{"stats", "-host", tt.host}, strings.NewReader(statsInput), stdout, stderr),
+				stderr.String())
+			_, hosts, _ := strings.Cut(stdout.String(), "\n\nhost")
+			assert.Equal(t, tt.want, "host"+hosts)
+		})
+	}
+
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, 2, run([]string{"stats", "-host", "dest"}, strings.NewReader(statsInput), &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), "-host must be source or device")
+}
+
+func TestStatsCommand_files(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	require.NoError(t, os.WriteFile(a, []byte("CEF:1|v|p|1|100|n|Low|\n"), 0o600))
+	record := "CEF:1|v|p|1|200|n|Very-High|"
+	require.NoError(t, os.WriteFile(b, []byte(strconv.Itoa(len(record))+" "+record), 0o600))
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	require.Equal(t, 0, run([]string{"stats", a}, nil, stdout, stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "records    1\n")
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"stats", "-octet-counting", "-top", "0", b}, nil, stdout, stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "200       1\n")
+	assert.Contains(t, stdout.String(), "Very-High  1\n")
+
+	assert.Equal(t, 1, run([]string{"stats", filepath.Join(dir, "missing.log")}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "missing.log")
+}
+
+func TestRun_usage(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, 2, run(nil, nil, &bytes.Buffer{}, stderr))
+	assert.Equal(t, 2, run([]string{"frobnicate"}, nil, &bytes.Buffer{}, stderr))
+	assert.Contains(t, stderr.String(), `unknown command "frobnicate"`)
+	assert.Contains(t, stderr.String(), "usage: cefevent stats")
+}
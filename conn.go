@@ -0,0 +1,54 @@
+package cefevent
+
+import (
+	"net"
+	"strconv"
+)
+
+// FromConn returns extensions describing conn from the point of view of a server that accepted it: the remote
+// address is the source and the local address is the destination. TCP and UDP connections also set the transport
+// protocol. Swap the source and destination fields for connections dialed by the device.
+func FromConn(conn net.Conn) Extensions {
+	var ext Extensions
+	ext.SourceAddress, ext.SourcePort, ext.TransportProtocol = addrFields(conn.RemoteAddr())
+	var proto string
+	ext.DestinationAddress, ext.DestinationPort, proto = addrFields(conn.LocalAddr())
+	if ext.TransportProtocol == "" {
+		ext.TransportProtocol = proto
+	}
+	return ext
+}
+
+// addrFields returns the IP, port and transport protocol of addr, as far as they are known
+func addrFields(addr net.Addr) (net.IP, *uint, string) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP, ptr(uint(a.Port)), "TCP"
+	case *net.UDPAddr:
+		return a.IP, ptr(uint(a.Port)), "UDP"
+	case *net.IPAddr:
+		return a.IP, nil, ""
+	case nil:
+		return nil, nil, ""
+	}
+	// Other implementations, e.g. from proxies, commonly use host:port strings
+	ip, port := hostPortFields(addr.String())
+	return ip, port, ""
+}
+
+// hostPortFields returns the IP and port of a host:port string, as far as they are known
+func hostPortFields(hostport string) (net.IP, *uint) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, nil
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return ip, nil
+	}
+	return ip, ptr(uint(p))
+}
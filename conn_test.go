@@ -0,0 +1,75 @@
+package cefevent
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromConn_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	server, err := ln.Accept()
+	require.NoError(t, err)
+	defer server.Close()
+
+	ext := FromConn(server)
+	clientPort := uint(client.LocalAddr().(*net.TCPAddr).Port)
+	serverPort := uint(ln.Addr().(*net.TCPAddr).Port)
+	assert.Equal(t, "TCP", ext.TransportProtocol)
+	assert.True(t, ext.SourceAddress.Equal(net.IPv4(127, 0, 0, 1)))
+	assert.Equal(t, &clientPort, ext.SourcePort)
+	assert.True(t, ext.DestinationAddress.Equal(net.IPv4(127, 0, 0, 1)))
+	assert.Equal(t, &serverPort, ext.DestinationPort)
+}
+
+func TestFromConn_udp(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	client, err := net.Dial("udp", server.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	ext := FromConn(client)
+	assert.Equal(t, "UDP", ext.TransportProtocol)
+	assert.Equal(t, ptr(uint(server.LocalAddr().(*net.UDPAddr).Port)), ext.SourcePort)
+	assert.Equal(t, ptr(uint(client.LocalAddr().(*net.UDPAddr).Port)), ext.DestinationPort)
+}
+
+type stringAddr string
+
+func (a stringAddr) Network() string { return "proxy" }
+func (a stringAddr) String() string  { return string(a) }
+
+func TestAddrFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		addr      net.Addr
+		wantIP    net.IP
+		wantPort  *uint
+		wantProto string
+	}{
+		{"tcp", &net.TCPAddr{IP: net.ParseIP("::1"), Port: 443}, net.ParseIP("::1"), ptr(uint(443)), "TCP"},
+		{"udp", &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 53}, net.IPv4(10, 0, 0, 1), ptr(uint(53)), "UDP"},
+		{"ip", &net.IPAddr{IP: net.IPv4(10, 0, 0, 1)}, net.IPv4(10, 0, 0, 1), nil, ""},
+		{"host port string", stringAddr("192.168.1.1:8080"), net.ParseIP("192.168.1.1"), ptr(uint(8080)), ""},
+		{"hostname", stringAddr("example.com:80"), nil, nil, ""},
+		{"unix", &net.UnixAddr{Name: "/tmp/sock", Net: "unix"}, nil, nil, ""},
+		{"nil", nil, nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, port, proto := addrFields(tt.addr)
+			assert.Equal(t, tt.wantIP, ip)
+			assert.Equal(t, tt.wantPort, port)
+			assert.Equal(t, tt.wantProto, proto)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cefevent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WithContentHash attaches a stable SHA-256 hash of each event's content as the custom extension key, so downstream
+// consumers can discard redelivered events. The header fields (vendor, product, version, class ID, name and severity)
+// are always hashed; if fields lists extension keys only those are hashed alongside the header, otherwise every
+// populated extension field is. The syslog header timestamp is never part of the hash.
+func WithContentHash(key string, fields ...string) LoggerConfigOption {
+	return func(l *Logger) {
+		h := &contentHasher{key: key}
+		if len(fields) > 0 {
+			h.fields = make(map[string]bool, len(fields))
+			for _, f := range fields {
+				h.fields[f] = true
+			}
+		}
+		l.hasher = h
+	}
+}
+
+// contentHasher computes content hashes for events
+type contentHasher struct {
+	// key custom extension key the hash is stored under
+	key string
+	// fields extension keys included in the hash. nil for all fields
+	fields map[string]bool
+}
+
+// apply returns extensions with the content hash added. The caller's CustomExtensions map is not modified.
+func (h *contentHasher) apply(dev device, deviceEventClassId, name, severity string, extensions Extensions) Extensions {
+	pairs := make([]string, 0, 8)
+	extensions.walk(func(key string, v fieldValue) bool {
+		if key == h.key || (h.fields != nil && !h.fields[key]) {
+			return true
+		}
+		pairs = append(pairs, escapeExtensionField(key)+"="+escapeExtensionField(v.String()))
+		return true
+	})
+	sort.Strings(pairs) // independent of output order, so reordering fields in a later release keeps hashes stable
+
+	sum := sha256.New()
+	for _, f := range []string{dev.vendor, dev.product, dev.version, deviceEventClassId, name, severity} {
+		writeHashField(sum, f)
+	}
+	for _, p := range pairs {
+		writeHashField(sum, p)
+	}
+
+	custom := make(map[string]string, len(extensions.CustomExtensions)+1)
+	for k, v := range extensions.CustomExtensions {
+		custom[k] = v
+	}
+	custom[h.key] = hex.EncodeToString(sum.Sum(nil))
+	extensions.CustomExtensions = custom
+	return extensions
+}
+
+// writeHashField writes a length prefixed field so adjacent fields can't run together into the same hash input
+func writeHashField(w io.Writer, f string) {
+	_, _ = w.Write([]byte(strconv.Itoa(len(f)) + ":" + f))
+}
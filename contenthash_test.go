@@ -0,0 +1,50 @@
+package cefevent
+
+import (
+	"bytes"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var contentHashRegex = regexp.MustCompile(`eventHash=([0-9a-f]{64})`)
+
+func logContentHash(t *testing.T, l *Logger, buf *bytes.Buffer, name string, extensions Extensions) string {
+	t.Helper()
+	buf.Reset()
+	require.NoError(t, l.LogLow("100", name, extensions))
+	matches := contentHashRegex.FindStringSubmatch(buf.String())
+	require.NotNil(t, matches, "missing content hash in %q", buf.String())
+	return matches[1]
+}
+
+func TestWithContentHash(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", WithContentHash("eventHash"))
+	custom := map[string]string{"a": "1", "b": "2", "c": "3"}
+	ext := Extensions{Message: "login failed", DestinationAddress: net.IP{10, 0, 0, 1}, CustomExtensions: custom}
+
+	first := logContentHash(t, l, buf, "login", ext)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, logContentHash(t, l, buf, "login", ext), "hash should be stable")
+	}
+	assert.Len(t, custom, 3, "caller's map should be unchanged")
+	assert.NotEqual(t, first, logContentHash(t, l, buf, "logout", ext), "header fields are hashed")
+	ext.Message = "login succeeded"
+	assert.NotEqual(t, first, logContentHash(t, l, buf, "login", ext), "extensions are hashed")
+}
+
+func TestWithContentHash_selectedFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", WithContentHash("eventHash", "dst"))
+	ext := Extensions{Message: "login failed", DestinationAddress: net.IP{10, 0, 0, 1}}
+
+	first := logContentHash(t, l, buf, "login", ext)
+	ext.Message = "login succeeded"
+	assert.Equal(t, first, logContentHash(t, l, buf, "login", ext), "unselected fields are not hashed")
+	ext.DestinationAddress = net.IP{10, 0, 0, 2}
+	assert.NotEqual(t, first, logContentHash(t, l, buf, "login", ext), "selected fields are hashed")
+}
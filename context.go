@@ -0,0 +1,111 @@
+package cefevent
+
+import (
+	"context"
+	"io"
+	"slices"
+	"time"
+)
+
+// ContextWriter is implemented by writers whose writes can be abandoned when a context is done, such as SyslogWriter
+// and KafkaWriter. LogContext passes its context to outputs implementing it.
+type ContextWriter interface {
+	WriteContext(ctx context.Context, p []byte) (int, error)
+}
+
+// WithContextExtractor adds a function pulling extensions out of the context passed to LogContext, e.g. a request or
+// trace ID set by middleware. Extracted fields are merged into each event, with fields set on the event winning on
+// conflict, then fields from the most recently added extractor.
+func WithContextExtractor(fn func(ctx context.Context) Extensions) LoggerConfigOption {
+	return func(l *Logger) {
+		l.contextExtractors = append(slices.Clip(l.contextExtractors), fn)
+	}
+}
+
+// ContextField copies a value from the context passed to LogContext into an extension field, see WithContextFields
+type ContextField struct {
+	// Key is the key the value is stored under in the context, as passed to context.WithValue
+	Key any
+	// Field is the extension key the value is logged as: a standard key such as suser, or a custom extension key
+	Field string
+	// Label labels Field if it is a custom field such as cs1, which must be labelled
+	Label string
+}
+
+// WithContextFields adds the values stored in the context passed to LogContext to each event, e.g. a request ID,
+// authenticated user or tenant set by middleware, so handlers don't pass them to every call. Values are formatted as
+// ExtensionsFromMap does; missing values, values of other types and values which aren't valid for their field (e.g.
+// a non-IP for src) are skipped. Returns an error wrapping InvalidExtensionKeyErr for an invalid Field, or
+// MissingLabelErr for a custom field without a Label.
+func WithContextFields(fields ...ContextField) (LoggerConfigOption, error) {
+	for _, f := range fields {
+		if !validExtensionKey(f.Field) {
+			return nil, &fieldError{f.Field, InvalidExtensionKeyErr}
+		}
+		if _, labelled := extensionSetters[f.Field+"Label"]; labelled && f.Label == "" {
+			return nil, &fieldError{f.Field, MissingLabelErr}
+		}
+	}
+	fields = slices.Clone(fields)
+	return WithContextExtractor(func(ctx context.Context) Extensions {
+		var e Extensions
+		for _, f := range fields {
+			v, ok, err := extensionValue(ctx.Value(f.Key))
+			if !ok || err != nil || e.set(f.Field, v) != nil {
+				continue
+			}
+			if setLabel, ok := extensionSetters[f.Field+"Label"]; ok {
+				_ = setLabel(&e, f.Label)
+			}
+		}
+		return e
+	}), nil
+}
+
+// LogContext logs a CEF event like Log, giving up once ctx is done. It returns ctx's error without logging if ctx is
+// already done, and stops waiting on a blocking output: a full WithAsync queue with OverflowBlock, WithByteRateLimit,
+// WithRetry's backoff and outputs implementing ContextWriter. Events already handed to the async queue or held by
+// WithBuffer are still written. Fields from WithContextExtractor are merged into extensions.
+func (l *Logger) LogContext(ctx context.Context, deviceEventClassId, name, severity string, extensions Extensions) (err error) {
+	defer l.recoverPanic(&err)
+	if l.parent != nil {
+		return l.parent.LogContext(ctx, deviceEventClassId, name, severity, l.defaults.Merge(extensions))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for i := len(l.contextExtractors) - 1; i >= 0; i-- {
+		extensions = l.contextExtractors[i](ctx).Merge(extensions)
+	}
+	return l.log(ctx, l.device(), deviceEventClassId, name, severity, extensions)
+}
+
+// writeAllContext is writeAll, passing ctx to writers implementing ContextWriter
+func writeAllContext(ctx context.Context, w io.Writer, p []byte) error {
+	cw, ok := w.(ContextWriter)
+	if !ok || ctx.Done() == nil {
+		return writeAll(w, p)
+	}
+	n, err := cw.WriteContext(ctx, p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// sleepContext calls sleep(d), or waits for d or ctx to be done if ctx can be cancelled, returning ctx's error if it
+// is done first
+func sleepContext(ctx context.Context, d time.Duration, sleep func(time.Duration)) error {
+	if ctx.Done() == nil {
+		sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
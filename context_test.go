@@ -0,0 +1,155 @@
+package cefevent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type requestIdKey struct{}
+
+// contextRecorder is a ContextWriter recording whether it was passed a cancellable context
+type contextRecorder struct {
+	bytes.Buffer
+	withContext int
+}
+
+func (w *contextRecorder) WriteContext(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	w.withContext++
+	return w.Write(p)
+}
+
+func TestLogger_LogContext(t *testing.T) {
+	requestId := func(ctx context.Context) Extensions {
+		id, _ := ctx.Value(requestIdKey{}).(string)
+		return Extensions{ExternalId: id, Message: "from context"}
+	}
+	source := func(context.Context) Extensions {
+		return Extensions{SourceHostName: "host.example", Message: "from source"}
+	}
+	ctx := context.WithValue(context.Background(), requestIdKey{}, "req-1")
+	tests := []struct {
+		name       string
+		extractors []func(context.Context) Extensions
+		extensions Extensions
+		want       string
+	}{
+		{"no extractors", nil, Extensions{Message: "hello"}, "msg=hello"},
+		{"event wins", []func(context.Context) Extensions{requestId}, Extensions{Message: "hello"}, "msg=hello externalId=req-1"},
+		{"later extractor wins", []func(context.Context) Extensions{requestId, source}, Extensions{},
+			"msg=from source externalId=req-1 shost=host.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			var opts []LoggerConfigOption
+			for _, fn := range tt.extractors {
+				opts = append(opts, WithContextExtractor(fn))
+			}
+			l := NewLogger(buf, "v", "p", "1", append(opts, OmitSyslogHeader())...)
+			require.NoError(t, l.LogContext(ctx, "100", "test", LowSeverity, tt.extensions))
+			assert.Equal(t, "CEF:1|v|p|1|100|test|Low|"+tt.want, buf.String())
+		})
+	}
+}
+
+type ctxKey string
+
+func TestWithContextFields(t *testing.T) {
+	opt, err := WithContextFields(
+		ContextField{requestIdKey{}, "externalId", ""},
+		ContextField{ctxKey("user"), "suser", ""},
+		ContextField{ctxKey("tenant"), "cs1", "tenant"},
+		ContextField{ctxKey("client"), "src", ""},
+		ContextField{ctxKey("attempt"), "attempt", ""},
+	)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), opt)
+
+	ctx := context.WithValue(context.Background(), requestIdKey{}, "req-1")
+	ctx = context.WithValue(ctx, ctxKey("tenant"), "acme")
+	ctx = context.WithValue(ctx, ctxKey("client"), "not-an-ip")
+	ctx = context.WithValue(ctx, ctxKey("attempt"), 2)
+	require.NoError(t, l.LogContext(ctx, "100", "test", LowSeverity, Extensions{SourceUserName: "event"}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|externalId=req-1 suser=event cs1=acme cs1Label=tenant attempt=2", buf.String())
+
+	_, err = WithContextFields(ContextField{ctxKey("tenant"), "cs1", ""})
+	assert.ErrorIs(t, err, MissingLabelErr)
+	_, err = WithContextFields(ContextField{ctxKey("tenant"), "a b", ""})
+	assert.ErrorIs(t, err, InvalidExtensionKeyErr)
+}
+
+func TestLogger_LogContext_done(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader()).With(Extensions{SourceHostName: "child"})
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, l.LogContext(ctx, "100", "live", LowSeverity, Extensions{}))
+	cancel()
+	assert.ErrorIs(t, l.LogContext(ctx, "100", "cancelled", LowSeverity, Extensions{}), context.Canceled)
+	assert.Equal(t, "CEF:1|v|p|1|100|live|Low|shost=child", buf.String())
+}
+
+func TestLogger_LogContext_blocking(t *testing.T) {
+	tests := []struct {
+		name   string
+		out    io.Writer
+		opt    LoggerConfigOption
+		logged int
+	}{
+		{"byte rate limit", &bytes.Buffer{}, WithByteRateLimit(1, 1), 1},
+		{"retry backoff", &failingWriter{failures: 10, err: syscall.ECONNREFUSED}, WithRetry(10, time.Hour), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLogger(tt.out, "v", "p", "1", tt.opt, OmitSyslogHeader())
+			for i := 0; i < tt.logged; i++ {
+				require.NoError(t, l.LogLow("100", "first", Extensions{}))
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			assert.ErrorIs(t, l.LogContext(ctx, "100", "second", LowSeverity, Extensions{}), context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestLogger_LogContext_asyncBlock(t *testing.T) {
+	w := newBlockingWriter()
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithAsync(1, OverflowBlock))
+	require.NoError(t, l.LogLow("100", "first", Extensions{}))
+	<-w.started
+	require.NoError(t, l.LogLow("100", "second", Extensions{})) // fills the queue
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.LogContext(ctx, "100", "third", LowSeverity, Extensions{}), context.DeadlineExceeded)
+
+	close(w.release)
+	require.NoError(t, l.Close())
+	assert.NotContains(t, w.String(), "third")
+	assert.Equal(t, uint64(0), l.DroppedCount())
+}
+
+func Test_writeAllContext(t *testing.T) {
+	w := &contextRecorder{}
+	require.NoError(t, writeAllContext(context.Background(), w, []byte("a")))
+	assert.Equal(t, 0, w.withContext, "uncancellable contexts use Write")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, writeAllContext(ctx, w, []byte("b")))
+	assert.Equal(t, 1, w.withContext)
+	cancel()
+	assert.ErrorIs(t, writeAllContext(ctx, w, []byte("c")), context.Canceled)
+	assert.Equal(t, "ab", w.String())
+}
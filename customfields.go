@@ -0,0 +1,165 @@
+package cefevent
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// MissingLabelErr error when a custom field such as cs1 is set without a label
+var MissingLabelErr = errors.New("custom field missing label")
+
+// CustomField is one of the spec's labelled custom fields, e.g. cs1 with cs1Label. SIEM mappers rely on the label to
+// interpret the value, so events with a custom field but no label are rejected.
+type CustomField[T any] struct {
+	Label string
+	Value T
+}
+
+// Labeled is a convenience function for setting custom fields, e.g. DeviceCustomString1: Labeled("Policy", "deny-all")
+func Labeled[T any](label string, value T) *CustomField[T] {
+	return &CustomField[T]{Label: label, Value: value}
+}
+
+// customFieldSpec describes how a custom field is parsed into & copied within Extensions
+type customFieldSpec struct {
+	key      string
+	set      func(e *Extensions, v string) error
+	setLabel func(e *Extensions, label string)
+	clone    func(e *Extensions)
+}
+
+// customFieldSpecs lists the custom fields in emitted order
+var customFieldSpecs = []customFieldSpec{
+	customSpec("cfp1", func(e *Extensions) **CustomField[float64] { return &e.DeviceCustomFloatingPoint1 }, parseFloat),
+	customSpec("cfp2", func(e *Extensions) **CustomField[float64] { return &e.DeviceCustomFloatingPoint2 }, parseFloat),
+	customSpec("cfp3", func(e *Extensions) **CustomField[float64] { return &e.DeviceCustomFloatingPoint3 }, parseFloat),
+	customSpec("cfp4", func(e *Extensions) **CustomField[float64] { return &e.DeviceCustomFloatingPoint4 }, parseFloat),
+	customSpec("cn1", func(e *Extensions) **CustomField[int64] { return &e.DeviceCustomNumber1 }, parseInt),
+	customSpec("cn2", func(e *Extensions) **CustomField[int64] { return &e.DeviceCustomNumber2 }, parseInt),
+	customSpec("cn3", func(e *Extensions) **CustomField[int64] { return &e.DeviceCustomNumber3 }, parseInt),
+	customSpec("cs1", func(e *Extensions) **CustomField[string] { return &e.DeviceCustomString1 }, parseString),
+	customSpec("cs2", func(e *Extensions) **CustomField[string] { return &e.DeviceCustomString2 }, parseString),
+	customSpec("cs3", func(e *Extensions) **CustomField[string] { return &e.DeviceCustomString3 }, parseString),
+	customSpec("cs4", func(e *Extensions) **CustomField[string] { return &e.DeviceCustomString4 }, parseString),
+	customSpec("cs5", func(e *Extensions) **CustomField[string] { return &e.DeviceCustomString5 }, parseString),
+	customSpec("cs6", func(e *Extensions) **CustomField[string] { return &e.DeviceCustomString6 }, parseString),
+	customSpec("deviceCustomDate1", func(e *Extensions) **CustomField[time.Time] { return &e.DeviceCustomDate1 }, parseTimestamp),
+	customSpec("deviceCustomDate2", func(e *Extensions) **CustomField[time.Time] { return &e.DeviceCustomDate2 }, parseTimestamp),
+	customSpec("flexDate1", func(e *Extensions) **CustomField[time.Time] { return &e.FlexDate1 }, parseTimestamp),
+	customSpec("flexString1", func(e *Extensions) **CustomField[string] { return &e.FlexString1 }, parseString),
+	customSpec("flexString2", func(e *Extensions) **CustomField[string] { return &e.FlexString2 }, parseString),
+}
+
+func parseString(v string) (string, error) {
+	return v, nil
+}
+
+func parseInt(v string) (int64, error) {
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func parseFloat(v string) (float64, error) {
+	return strconv.ParseFloat(v, 64)
+}
+
+func customSpec[T any](key string, field func(e *Extensions) **CustomField[T], parse func(string) (T, error)) customFieldSpec {
+	ensure := func(e *Extensions) *CustomField[T] {
+		p := field(e)
+		if *p == nil {
+			*p = &CustomField[T]{}
+		}
+		return *p
+	}
+	return customFieldSpec{
+		key: key,
+		set: func(e *Extensions, v string) error {
+			parsed, err := parse(v)
+			if err != nil {
+				return err
+			}
+			ensure(e).Value = parsed
+			return nil
+		},
+		setLabel: func(e *Extensions, label string) {
+			ensure(e).Label = label
+		},
+		clone: func(e *Extensions) {
+			p := field(e)
+			*p = clonePtr(*p)
+		},
+	}
+}
+
+// eachCustomField calls fn with the key, label key, label & value of each populated custom field, in the order of
+// customFieldSpecs. Written out field by field rather than driven by customFieldSpecs so that logging doesn't move e
+// to the heap.
+func (e *Extensions) eachCustomField(fn func(key, labelKey, label string, v fieldValue)) {
+	customFloatValue(fn, "cfp1", "cfp1Label", e.DeviceCustomFloatingPoint1)
+	customFloatValue(fn, "cfp2", "cfp2Label", e.DeviceCustomFloatingPoint2)
+	customFloatValue(fn, "cfp3", "cfp3Label", e.DeviceCustomFloatingPoint3)
+	customFloatValue(fn, "cfp4", "cfp4Label", e.DeviceCustomFloatingPoint4)
+	customNumberValue(fn, "cn1", "cn1Label", e.DeviceCustomNumber1)
+	customNumberValue(fn, "cn2", "cn2Label", e.DeviceCustomNumber2)
+	customNumberValue(fn, "cn3", "cn3Label", e.DeviceCustomNumber3)
+	customStringValue(fn, "cs1", "cs1Label", e.DeviceCustomString1)
+	customStringValue(fn, "cs2", "cs2Label", e.DeviceCustomString2)
+	customStringValue(fn, "cs3", "cs3Label", e.DeviceCustomString3)
+	customStringValue(fn, "cs4", "cs4Label", e.DeviceCustomString4)
+	customStringValue(fn, "cs5", "cs5Label", e.DeviceCustomString5)
+	customStringValue(fn, "cs6", "cs6Label", e.DeviceCustomString6)
+	customDateValue(fn, "deviceCustomDate1", "deviceCustomDate1Label", e.DeviceCustomDate1)
+	customDateValue(fn, "deviceCustomDate2", "deviceCustomDate2Label", e.DeviceCustomDate2)
+	customDateValue(fn, "flexDate1", "flexDate1Label", e.FlexDate1)
+	customStringValue(fn, "flexString1", "flexString1Label", e.FlexString1)
+	customStringValue(fn, "flexString2", "flexString2Label", e.FlexString2)
+}
+
+func customStringValue(fn func(key, labelKey, label string, v fieldValue), key, labelKey string, f *CustomField[string]) {
+	if f != nil {
+		fn(key, labelKey, f.Label, fieldValue{kind: stringKind, s: f.Value})
+	}
+}
+
+func customNumberValue(fn func(key, labelKey, label string, v fieldValue), key, labelKey string, f *CustomField[int64]) {
+	if f != nil {
+		fn(key, labelKey, f.Label, fieldValue{kind: intKind, i: f.Value})
+	}
+}
+
+func customFloatValue(fn func(key, labelKey, label string, v fieldValue), key, labelKey string, f *CustomField[float64]) {
+	if f != nil {
+		fn(key, labelKey, f.Label, fieldValue{kind: floatKind, f: f.Value})
+	}
+}
+
+func customDateValue(fn func(key, labelKey, label string, v fieldValue), key, labelKey string, f *CustomField[time.Time]) {
+	if f != nil {
+		fn(key, labelKey, f.Label, fieldValue{kind: timeKind, t: f.Value})
+	}
+}
+
+func (e *Extensions) walkCustomFields(w *fieldWalker) {
+	e.eachCustomField(func(key, labelKey, label string, v fieldValue) {
+		switch v.kind {
+		case stringKind:
+			w.str(key, v.s)
+		case timeKind:
+			w.time(key, v.t)
+		default:
+			w.emit(key, v)
+		}
+		w.str(labelKey, label)
+	})
+}
+
+// labelErrors returns a fieldError wrapping MissingLabelErr for each custom field set without a label
+func (e *Extensions) labelErrors() []error {
+	var errs []error
+	e.eachCustomField(func(_, labelKey, label string, _ fieldValue) {
+		if label == "" {
+			errs = append(errs, &fieldError{labelKey, MissingLabelErr})
+		}
+	})
+	return errs
+}
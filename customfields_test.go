@@ -0,0 +1,80 @@
+package cefevent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtensions_customFields(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Extensions
+		want string
+	}{
+		{
+			"labelled",
+			Extensions{
+				DeviceCustomString1:        Labeled("Policy", "deny-all"),
+				DeviceCustomNumber2:        Labeled("Retries", int64(0)),
+				DeviceCustomFloatingPoint1: Labeled("Score", 0.75),
+				DeviceCustomDate1:          Labeled("Expiry", testTime()),
+				FlexString2:                Labeled("Zone", "dmz"),
+			},
+			"cfp1=0.75 cfp1Label=Score cn2=0 cn2Label=Retries cs1=deny-all cs1Label=Policy " +
+				"deviceCustomDate1=1699530320000 deviceCustomDate1Label=Expiry flexString2=dmz flexString2Label=Zone",
+		},
+		{
+			"empty_values",
+			Extensions{DeviceCustomString3: Labeled("Unused", ""), FlexDate1: Labeled("Never", time.Time{})},
+			"cs3Label=Unused flexDate1Label=Never",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.e.String())
+		})
+	}
+}
+
+func TestLogger_Log_missingLabel(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader())
+	err := l.LogLow("100", "test", Extensions{
+		DeviceCustomString1: Labeled("", "value"),
+		DeviceCustomNumber1: &CustomField[int64]{Value: 3},
+	})
+	assert.ErrorIs(t, err, MissingLabelErr)
+	assert.ErrorContains(t, err, `"cs1Label"`)
+	assert.ErrorContains(t, err, `"cn1Label"`)
+	assert.Empty(t, w.Records())
+
+	require.NoError(t, l.LogLow("100", "test", Extensions{DeviceCustomString1: Labeled("Policy", "value")}))
+	assert.Equal(t, []string{"CEF:1|v|p|1|100|test|Low|cs1=value cs1Label=Policy"}, w.Records())
+}
+
+func TestExtensions_Clone_customFields(t *testing.T) {
+	e := Extensions{DeviceCustomString1: Labeled("Policy", "value")}
+	c := e.Clone()
+	c.DeviceCustomString1.Value = "changed"
+	assert.Equal(t, "value", e.DeviceCustomString1.Value)
+}
+
+func TestExtensions_eachCustomField(t *testing.T) {
+	// eachCustomField is written out by hand, so check it covers customFieldSpecs in the same order
+	e := Extensions{}
+	var want []string
+	for _, spec := range customFieldSpecs {
+		spec.setLabel(&e, spec.key+" label")
+		want = append(want, spec.key)
+	}
+	var got []string
+	e.eachCustomField(func(key, labelKey, label string, _ fieldValue) {
+		assert.Equal(t, key+"Label", labelKey)
+		assert.Equal(t, key+" label", label)
+		got = append(got, key)
+	})
+	assert.Equal(t, want, got)
+}
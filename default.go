@@ -0,0 +1,112 @@
+package cefevent
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Environment variables read by LoggerOptionsFromEnv
+const (
+	EnvDeviceVendor  = "CEF_DEVICE_VENDOR"  // DeviceVendor header field
+	EnvDeviceProduct = "CEF_DEVICE_PRODUCT" // DeviceProduct header field
+	EnvDeviceVersion = "CEF_DEVICE_VERSION" // DeviceVersion header field
+	EnvCefVersion    = "CEF_VERSION"        // CEF version, 0 or 1
+	EnvSyslogHeader  = "CEF_SYSLOG_HEADER"  // Set to false to omit the syslog header
+	EnvOutput        = "CEF_OUTPUT"         // "stdout", "stderr" or a file path to append to
+)
+
+// WithDevice sets the DeviceVendor, DeviceProduct and DeviceVersion header fields
+func WithDevice(deviceVendor, deviceProduct, deviceVersion string) LoggerConfigOption {
+	return func(l *Logger) {
+		l.DeviceVendor = deviceVendor
+		l.DeviceProduct = deviceProduct
+		l.DeviceVersion = deviceVersion
+	}
+}
+
+// WithOutput sets the writer events are written to
+func WithOutput(out io.Writer) LoggerConfigOption {
+	return func(l *Logger) {
+		l.out = out
+	}
+}
+
+// SetDefault reconfigures the default logger used by the package level functions, replacing it with a clone that has
+// fns applied. For example, to attribute events logged by package level functions to your application:
+//
+//	cefevent.SetDefault(cefevent.WithDevice("Acme", "Widgets", "1.2.0"))
+func SetDefault(fns ...LoggerConfigOption) {
+	SetDefaultLogger(Default().Clone(fns...))
+}
+
+// LoggerOptionsFromEnv returns options configured by the CEF_* environment variables (see EnvDeviceVendor etc.). Unset
+// variables are ignored, so the options only override what has been explicitly configured.
+func LoggerOptionsFromEnv() ([]LoggerConfigOption, error) {
+	var fns []LoggerConfigOption
+	vendor, hasVendor := os.LookupEnv(EnvDeviceVendor)
+	product, hasProduct := os.LookupEnv(EnvDeviceProduct)
+	version, hasVersion := os.LookupEnv(EnvDeviceVersion)
+	if hasVendor || hasProduct || hasVersion {
+		fns = append(fns, func(l *Logger) {
+			if hasVendor {
+				l.DeviceVendor = vendor
+			}
+			if hasProduct {
+				l.DeviceProduct = product
+			}
+			if hasVersion {
+				l.DeviceVersion = version
+			}
+		})
+	}
+	if v, ok := os.LookupEnv(EnvCefVersion); ok {
+		ver, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvCefVersion, InvalidCefVersionErr)
+		}
+		fn, err := WithCefVersion(byte(ver))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvCefVersion, err)
+		}
+		fns = append(fns, fn)
+	}
+	if v, ok := os.LookupEnv(EnvSyslogHeader); ok {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a boolean", EnvSyslogHeader, v)
+		}
+		fns = append(fns, func(l *Logger) {
+			l.addSyslogHeader = enabled
+		})
+	}
+	if v, ok := os.LookupEnv(EnvOutput); ok {
+		var out io.Writer
+		switch v {
+		case "stdout", "":
+			out = os.Stdout
+		case "stderr":
+			out = os.Stderr
+		default:
+			f, err := os.OpenFile(v, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", EnvOutput, err)
+			}
+			out = f
+		}
+		fns = append(fns, WithOutput(out))
+	}
+	return fns, nil
+}
+
+// InitDefaultFromEnv reconfigures the default logger from the CEF_* environment variables. Opt-in: call it early in
+// main so package level Log calls made by libraries are correctly attributed.
+func InitDefaultFromEnv() error {
+	fns, err := LoggerOptionsFromEnv()
+	if err != nil {
+		return err
+	}
+	SetDefault(fns...)
+	return nil
+}
@@ -0,0 +1,54 @@
+package cefevent
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restoreDefault resets the default logger once the test finishes
+func restoreDefault(t *testing.T) {
+	orig := Default()
+	t.Cleanup(func() {
+		SetDefaultLogger(orig)
+	})
+}
+
+func TestSetDefault(t *testing.T) {
+	restoreDefault(t)
+	buf := &bytes.Buffer{}
+	SetDefault(WithDevice("Acme", "Widgets", "1.2.0"), WithOutput(buf), OmitSyslogHeader())
+	require.NoError(t, LogLow("100", "library event", Extensions{}))
+	assert.Equal(t, "CEF:1|Acme|Widgets|1.2.0|100|library event|Low|", buf.String())
+}
+
+func TestInitDefaultFromEnv(t *testing.T) {
+	restoreDefault(t)
+	path := filepath.Join(t.TempDir(), "events.cef")
+	t.Setenv(EnvDeviceVendor, "Acme")
+	t.Setenv(EnvDeviceProduct, "Widgets")
+	t.Setenv(EnvCefVersion, "0")
+	t.Setenv(EnvSyslogHeader, "false")
+	t.Setenv(EnvOutput, path)
+
+	require.NoError(t, InitDefaultFromEnv())
+	require.NoError(t, LogHigh("100", "env configured", Extensions{}))
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "CEF:0|Acme|Widgets|v0.1|100|env configured|High|", string(got))
+}
+
+func TestLoggerOptionsFromEnv_errors(t *testing.T) {
+	t.Setenv(EnvCefVersion, "7")
+	_, err := LoggerOptionsFromEnv()
+	assert.ErrorIs(t, err, InvalidCefVersionErr)
+
+	t.Setenv(EnvCefVersion, "1")
+	t.Setenv(EnvSyslogHeader, "sometimes")
+	_, err = LoggerOptionsFromEnv()
+	assert.ErrorContains(t, err, EnvSyslogHeader)
+}
@@ -0,0 +1,282 @@
+package cefevent
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ecsFields maps extension keys to Elastic Common Schema (ECS) fields. Keys without an ECS equivalent are kept under
+// cef.extensions.
+var ecsFields = []struct {
+	key  string
+	path string
+}{
+	{"msg", "message"},
+	{"act", "event.action"},
+	{"app", "network.protocol"},
+	{"end", "event.end"},
+	{"externalId", "event.id"},
+	{"in", "source.bytes"},
+	{"out", "destination.bytes"},
+	{"outcome", "event.outcome"},
+	{"proto", "network.transport"},
+	{"reason", "event.reason"},
+	{"start", "event.start"},
+	{"rt", "@timestamp"},
+	{"ahost", "agent.name"},
+	{"aid", "agent.id"},
+	{"at", "agent.type"},
+	{"av", "agent.version"},
+	{"dhost", "destination.domain"},
+	{"dlat", "destination.geo.location.lat"},
+	{"dlong", "destination.geo.location.lon"},
+	{"dmac", "destination.mac"},
+	{"dntdom", "destination.user.domain"},
+	{"dpid", "process.pid"},
+	{"dproc", "process.name"},
+	{"dpt", "destination.port"},
+	{"dst", "destination.ip"},
+	{"duid", "destination.user.id"},
+	{"duser", "destination.user.name"},
+	{"destinationTranslatedAddress", "destination.nat.ip"},
+	{"destinationTranslatedPort", "destination.nat.port"},
+	{"deviceInboundInterface", "observer.ingress.interface.name"},
+	{"deviceOutboundInterface", "observer.egress.interface.name"},
+	{"dvc", "observer.ip"},
+	{"dvchost", "observer.hostname"},
+	{"dvcmac", "observer.mac"},
+	{"fileCreateTime", "file.created"},
+	{"fileModificationTime", "file.mtime"},
+	{"filePath", "file.path"},
+	{"filePermission", "file.mode"},
+	{"fileType", "file.type"},
+	{"fname", "file.name"},
+	{"fsize", "file.size"},
+	{"request", "url.original"},
+	{"requestClientApplication", "user_agent.original"},
+	{"requestContext", "http.request.referrer"},
+	{"requestMethod", "http.request.method"},
+	{"shost", "source.domain"},
+	{"slat", "source.geo.location.lat"},
+	{"slong", "source.geo.location.lon"},
+	{"smac", "source.mac"},
+	{"sntdom", "source.user.domain"},
+	{"spt", "source.port"},
+	{"src", "source.ip"},
+	{"suid", "source.user.id"},
+	{"suser", "source.user.name"},
+	{"sourceTranslatedAddress", "source.nat.ip"},
+	{"sourceTranslatedPort", "source.nat.port"},
+}
+
+// ecsPaths indexes ecsFields by extension key
+var ecsPaths = func() map[string]string {
+	m := make(map[string]string, len(ecsFields))
+	for _, f := range ecsFields {
+		m[f.key] = f.path
+	}
+	return m
+}()
+
+// ToECS converts e to an Elastic Common Schema document, ready to be encoded as JSON and indexed into Elasticsearch.
+// The device vendor, product & version become observer.vendor, observer.product & observer.version, the class ID
+// event.code, and the severity event.severity on the 0-10 scale. Extension fields are mapped to their ECS equivalents
+// (e.g. src to source.ip, dpt to destination.port, rt to @timestamp); the name, severity as given, version and any
+// extension fields without an ECS equivalent, including CustomExtensions, are kept under cef so FromECS can restore
+// them.
+func (e Event) ToECS() map[string]any {
+	doc := map[string]any{}
+	setECSPath(doc, "observer.vendor", e.DeviceVendor)
+	setECSPath(doc, "observer.product", e.DeviceProduct)
+	setECSPath(doc, "observer.version", e.DeviceVersion)
+	setECSPath(doc, "event.code", e.DeviceEventClassId)
+	setECSPath(doc, "cef.version", int(e.Version))
+	setECSPath(doc, "cef.name", e.Name)
+	setECSPath(doc, "cef.severity", e.Severity)
+	if level, err := severityLevel(e.Severity); err == nil && level >= 0 {
+		setECSPath(doc, "event.severity", level)
+	}
+
+	var extensions map[string]any
+	e.Extensions.walk(func(key string, v fieldValue) bool {
+		var value any
+		switch v.kind {
+		case intKind:
+			value = v.i
+		case uintKind:
+			value = v.u
+		case floatKind:
+			value = v.f
+		case timeKind:
+			value = v.t.UTC()
+		default:
+			value = v.String()
+		}
+		if key == "proto" {
+			value = strings.ToLower(v.s)
+		}
+		if path, ok := ecsPaths[key]; ok {
+			setECSPath(doc, path, value)
+			return true
+		}
+		if extensions == nil {
+			extensions = map[string]any{}
+			setECSPath(doc, "cef.extensions", extensions)
+		}
+		extensions[key] = value // keys aren't split on dots
+		return true
+	})
+	return doc
+}
+
+// FromECS converts an Elastic Common Schema document back to an Event, reversing ToECS. Fields may be nested objects
+// or flattened dotted keys. Values may be of the types produced by ToECS or decoded from JSON; times may also be
+// RFC 3339 strings or epoch milliseconds. Returns an error wrapping MalformedEventErr if a field can't be decoded.
+func FromECS(doc map[string]any) (Event, error) {
+	var e Event
+	header := func(path string, dst *string) error {
+		v, ok := getECSPath(doc, path)
+		if !ok {
+			return nil
+		}
+		s, err := ecsString(v)
+		if err != nil {
+			return &fieldError{path, fmt.Errorf("%w: %w", MalformedEventErr, err)}
+		}
+		*dst = s
+		return nil
+	}
+	var version string
+	for _, h := range []struct {
+		path string
+		dst  *string
+	}{
+		{"observer.vendor", &e.DeviceVendor},
+		{"observer.product", &e.DeviceProduct},
+		{"observer.version", &e.DeviceVersion},
+		{"event.code", &e.DeviceEventClassId},
+		{"cef.name", &e.Name},
+		{"event.severity", &e.Severity},
+		{"cef.severity", &e.Severity}, // the severity as given wins over the number
+		{"cef.version", &version},
+	} {
+		if err := header(h.path, h.dst); err != nil {
+			return Event{}, err
+		}
+	}
+	if version != "" {
+		v, err := strconv.ParseUint(version, 10, 8)
+		if err != nil {
+			return Event{}, &fieldError{"cef.version", fmt.Errorf("%w: %w", MalformedEventErr, err)}
+		}
+		e.Version = byte(v)
+	}
+
+	set := func(path, key string, v any) error {
+		s, err := ecsString(v)
+		if err == nil {
+			if key == "proto" {
+				s = strings.ToUpper(s)
+			}
+			err = e.Extensions.set(key, s)
+		}
+		if err != nil {
+			return &fieldError{path, fmt.Errorf("%w: %w", MalformedEventErr, err)}
+		}
+		return nil
+	}
+	for _, f := range ecsFields {
+		if v, ok := getECSPath(doc, f.path); ok {
+			if err := set(f.path, f.key, v); err != nil {
+				return Event{}, err
+			}
+		}
+	}
+	if v, ok := getECSPath(doc, "cef.extensions"); ok {
+		extensions, ok := v.(map[string]any)
+		if !ok {
+			return Event{}, &fieldError{"cef.extensions", fmt.Errorf("%w: expected object, got %T", MalformedEventErr, v)}
+		}
+		for key, v := range extensions {
+			if err := set("cef.extensions."+key, key, v); err != nil {
+				return Event{}, err
+			}
+		}
+	}
+	return e, nil
+}
+
+// setECSPath sets the field at a dotted path, creating objects as needed
+func setECSPath(doc map[string]any, path string, v any) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := doc[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			doc[part] = next
+		}
+		doc = next
+	}
+	doc[parts[len(parts)-1]] = v
+}
+
+// getECSPath returns the field at a dotted path, which may be nested or flattened at any level
+func getECSPath(doc map[string]any, path string) (any, bool) {
+	if v, ok := doc[path]; ok {
+		return v, true
+	}
+	for i := strings.IndexByte(path, '.'); i >= 0; i = nextDot(path, i) {
+		if next, ok := doc[path[:i]].(map[string]any); ok {
+			if v, ok := getECSPath(next, path[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// nextDot returns the index of the next '.' in path after i, or -1
+func nextDot(path string, i int) int {
+	if j := strings.IndexByte(path[i+1:], '.'); j >= 0 {
+		return i + 1 + j
+	}
+	return -1
+}
+
+// ecsString formats an ECS field value as it would appear in a CEF extension
+func ecsString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return strconv.FormatInt(v.UnixMilli(), 10), nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return strconv.FormatInt(int64(v), 10), nil
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return ecsString(float64(v))
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", v)
+}
@@ -0,0 +1,113 @@
+package cefevent
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent_ToECS(t *testing.T) {
+	e := Event{Version: 1, DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100",
+		Name: "test", Severity: HighSeverity, Extensions: Extensions{
+			Message:                 "hi",
+			TransportProtocol:       "TCP",
+			SourceAddress:           net.IPv4(10, 0, 0, 1),
+			DestinationPort:         ptr(uint(443)),
+			BytesIn:                 ptr(uint(10)),
+			DeviceReceiptTime:       testTime(),
+			DeviceCustomString1:     Labeled("policy", "deny"),
+			CustomExtensions:        map[string]string{"a.b": "c"},
+			DeviceOutboundInterface: "eth1",
+		}}
+	assert.Equal(t, map[string]any{
+		"@timestamp": testTime().UTC(),
+		"message":    "hi",
+		"event":      map[string]any{"code": "100", "severity": 7},
+		"observer": map[string]any{"vendor": "v", "product": "p", "version": "1",
+			"egress": map[string]any{"interface": map[string]any{"name": "eth1"}}},
+		"network":     map[string]any{"transport": "tcp"},
+		"source":      map[string]any{"ip": "10.0.0.1", "bytes": uint64(10)},
+		"destination": map[string]any{"port": uint64(443)},
+		"cef": map[string]any{"version": 1, "name": "test", "severity": HighSeverity,
+			"extensions": map[string]any{"cs1": "deny", "cs1Label": "policy", "a.b": "c"}},
+	}, e.ToECS())
+}
+
+func TestFromECS(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    Event
+		wantErr bool
+	}{
+		{
+			"nested",
+			`{"@timestamp":"2023-11-09T11:45:20Z","observer":{"vendor":"v","product":"p","version":"1"},
+				"event":{"code":"100","severity":7},"source":{"ip":"10.0.0.1","port":1234},
+				"network":{"transport":"udp"},"cef":{"extensions":{"k":"v"}}}`,
+			Event{DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100",
+				Severity: "7", Extensions: Extensions{
+					DeviceReceiptTime: time.Date(2023, 11, 9, 11, 45, 20, 0, time.UTC),
+					SourceAddress:     net.IPv4(10, 0, 0, 1),
+					SourcePort:        ptr(uint(1234)),
+					TransportProtocol: "UDP",
+					CustomExtensions:  map[string]string{"k": "v"},
+				}},
+			false,
+		},
+		{
+			"flattened",
+			`{"destination.port":22,"cef.severity":"High","url":{"original":"https://example.com/"}}`,
+			Event{Severity: HighSeverity, Extensions: Extensions{DestinationPort: ptr(uint(22)),
+				RequestUrl: url.URL{Scheme: "https", Host: "example.com", Path: "/"}}},
+			false,
+		},
+		{"bad_value", `{"destination":{"port":"x"}}`, Event{}, true},
+		{"bad_type", `{"message":["a"]}`, Event{}, true},
+		{"bad_extensions", `{"cef":{"extensions":"x"}}`, Event{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc map[string]any
+			require.NoError(t, json.Unmarshal([]byte(tt.doc), &doc))
+			got, err := FromECS(doc)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, MalformedEventErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFromECS_roundTrip(t *testing.T) {
+	e := Event{Version: 1, DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100",
+		Name: "test", Severity: MediumSeverity, Extensions: Extensions{
+			Message:             "hi",
+			TransportProtocol:   "TCP",
+			SourceAddress:       net.IPv4(10, 0, 0, 1),
+			DestinationPort:     ptr(uint(443)),
+			DeviceReceiptTime:   testTime().UTC(),
+			DeviceCustomString1: Labeled("policy", "deny"),
+			DeviceCustomNumber1: Labeled("count", int64(3)),
+			CustomExtensions:    map[string]string{"a.b": "c"},
+		}}
+
+	got, err := FromECS(e.ToECS())
+	require.NoError(t, err)
+	assert.Equal(t, e, got)
+
+	b, err := json.Marshal(e.ToECS())
+	require.NoError(t, err)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(b, &doc))
+	got, err = FromECS(doc)
+	require.NoError(t, err)
+	assert.Equal(t, e, got)
+}
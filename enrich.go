@@ -0,0 +1,134 @@
+package cefevent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// GeoLocation is where an IP address is, as found by a GeoIPLookup
+type GeoLocation struct {
+	Latitude, Longitude float64
+}
+
+// GeoIPLookup finds the location of IP addresses, e.g. a thin adapter over a MaxMind database reader, keeping the
+// database library out of this module. It returns false for addresses it has no location for.
+type GeoIPLookup interface {
+	LookupGeoIP(ctx context.Context, ip net.IP) (GeoLocation, bool, error)
+}
+
+// Asset describes a host in an asset inventory, as found by an AssetLookup
+type Asset struct {
+	HostName   string
+	NtDomain   string
+	MacAddress net.HardwareAddr
+	// Tags are custom extensions describing the asset, e.g. {"owner": "payments"}. They are added to events with the
+	// key prefixed by "source" or "destination" and capitalized, e.g. sourceOwner.
+	Tags map[string]string
+}
+
+// AssetLookup finds hosts in an asset inventory by IP address, e.g. a CMDB client. It returns false for addresses with
+// no asset.
+type AssetLookup interface {
+	LookupAsset(ctx context.Context, ip net.IP) (Asset, bool, error)
+}
+
+// endpoint points at the Extensions fields describing one end of an event, so sources & destinations are enriched alike
+type endpoint struct {
+	name      string
+	addr      net.IP
+	host      *string
+	ntDomain  *string
+	mac       *net.HardwareAddr
+	lat, long **float64
+}
+
+// endpoints returns the source & destination of e
+func endpoints(e *Extensions) [2]endpoint {
+	return [2]endpoint{
+		{"source", e.SourceAddress, &e.SourceHostName, &e.SourceNtDomain, &e.SourceMacAddress,
+			&e.SourceGeoLatitude, &e.SourceGeoLongitude},
+		{"destination", e.DestinationAddress, &e.DestinationHostName, &e.DestinationNtDomain, &e.DestinationMacAddress,
+			&e.DestinationGeoLatitude, &e.DestinationGeoLongitude},
+	}
+}
+
+// EnrichGeoIP is a Stage setting the geo latitude & longitude of the source and destination of events from their
+// addresses, unless already set. A lookup error fails the event.
+func EnrichGeoIP(lookup GeoIPLookup) Stage {
+	return func(ctx context.Context, e *Event) (bool, error) {
+		for _, end := range endpoints(&e.Extensions) {
+			if end.addr == nil || *end.lat != nil || *end.long != nil {
+				continue
+			}
+			loc, ok, err := lookup.LookupGeoIP(ctx, end.addr)
+			if err != nil {
+				return false, fmt.Errorf("geoip lookup %s: %w", end.addr, err)
+			}
+			if ok {
+				*end.lat, *end.long = ptr(loc.Latitude), ptr(loc.Longitude)
+			}
+		}
+		return true, nil
+	}
+}
+
+// EnrichAssets is a Stage describing the source and destination hosts of events from an asset inventory, by their
+// addresses. Host names, NT domains and MAC addresses are only set if the event has none, while tags replace any
+// values they had. A lookup error fails the event.
+func EnrichAssets(lookup AssetLookup) Stage {
+	return func(ctx context.Context, e *Event) (bool, error) {
+		for _, end := range endpoints(&e.Extensions) {
+			if end.addr == nil {
+				continue
+			}
+			asset, ok, err := lookup.LookupAsset(ctx, end.addr)
+			if err != nil {
+				return false, fmt.Errorf("asset lookup %s: %w", end.addr, err)
+			}
+			if !ok {
+				continue
+			}
+			if *end.host == "" {
+				*end.host = asset.HostName
+			}
+			if *end.ntDomain == "" {
+				*end.ntDomain = asset.NtDomain
+			}
+			if *end.mac == nil {
+				*end.mac = asset.MacAddress
+			}
+			for k, v := range asset.Tags {
+				if k == "" {
+					continue
+				}
+				if e.Extensions.CustomExtensions == nil {
+					e.Extensions.CustomExtensions = make(map[string]string, len(asset.Tags))
+				}
+				e.Extensions.CustomExtensions[end.name+strings.ToUpper(k[:1])+k[1:]] = v
+			}
+		}
+		return true, nil
+	}
+}
+
+// AddFields returns a Stage setting fields on every event, replacing any values they had, e.g. to tag events with the
+// relay they passed through. Keys may be standard or custom extension keys, as accepted by Event.Field. Returns an
+// error if a value can't be stored in its field.
+func AddFields(fields map[string]string) (Stage, error) {
+	var check Extensions
+	standard := make(map[string]string, len(fields))
+	for k, v := range fields {
+		if err := check.set(standardKey(k), v); err != nil {
+			return nil, &fieldError{k, err}
+		}
+		standard[standardKey(k)] = v
+	}
+	return func(_ context.Context, e *Event) (bool, error) {
+		for k, v := range standard {
+			_ = e.Extensions.set(k, v) // checked above
+		}
+		return true, nil
+	}, nil
+}
@@ -0,0 +1,95 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticGeoIP is a GeoIPLookup over a map of addresses, failing for 192.0.2.99
+type staticGeoIP map[string]GeoLocation
+
+func (g staticGeoIP) LookupGeoIP(_ context.Context, ip net.IP) (GeoLocation, bool, error) {
+	if ip.String() == "192.0.2.99" {
+		return GeoLocation{}, false, errors.New("lookup failed")
+	}
+	loc, ok := g[ip.String()]
+	return loc, ok, nil
+}
+
+// staticAssets is an AssetLookup over a map of addresses
+type staticAssets map[string]Asset
+
+func (a staticAssets) LookupAsset(_ context.Context, ip net.IP) (Asset, bool, error) {
+	asset, ok := a[ip.String()]
+	return asset, ok, nil
+}
+
+func TestEnrichGeoIP(t *testing.T) {
+	lookup := staticGeoIP{"10.0.0.1": {51.5, -0.12}, "10.0.0.2": {40.7, -74}}
+	tests := []struct {
+		name    string
+		line    string
+		want    string
+		wantErr string
+	}{
+		{"both", "src=10.0.0.1 dst=10.0.0.2", "dlat=40.7 dlong=-74 dst=10.0.0.2 slat=51.5 slong=-0.12 src=10.0.0.1", ""},
+		{"unknown", "src=10.0.0.1 dst=10.0.0.3", "dst=10.0.0.3 slat=51.5 slong=-0.12 src=10.0.0.1", ""},
+		{"already_set", "src=10.0.0.1 slat=1 slong=2", "slat=1 slong=2 src=10.0.0.1", ""},
+		{"no_address", "msg=hello", "msg=hello", ""},
+		{"error", "src=192.0.2.99", "", "geoip lookup 192.0.2.99: lookup failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse("CEF:0|v|p|1|100|n|Low|" + tt.line)
+			require.NoError(t, err)
+			keep, err := EnrichGeoIP(lookup)(context.Background(), &e)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, keep)
+			assert.Equal(t, tt.want, e.Extensions.format(nil))
+		})
+	}
+}
+
+func TestEnrichAssets(t *testing.T) {
+	lookup := staticAssets{
+		"10.0.0.1": {HostName: "web-1.example", NtDomain: "CORP", MacAddress: net.HardwareAddr{0, 0, 0x5e, 0, 0x53, 1},
+			Tags: map[string]string{"owner": "payments", "": "ignored"}},
+		"10.0.0.2": {HostName: "db-1.example", Tags: map[string]string{"owner": "data"}},
+	}
+	e, err := Parse("CEF:0|v|p|1|100|n|Low|src=10.0.0.1 dst=10.0.0.2 dhost=db.example sourceOwner=old")
+	require.NoError(t, err)
+	keep, err := EnrichAssets(lookup)(context.Background(), &e)
+	require.NoError(t, err)
+	assert.True(t, keep)
+	assert.Equal(t, "dhost=db.example dst=10.0.0.2 shost=web-1.example smac=00:00:5e:00:53:01 sntdom=CORP "+
+		"src=10.0.0.1 destinationOwner=data sourceOwner=payments", e.Extensions.format(nil))
+
+	e, err = Parse("CEF:0|v|p|1|100|n|Low|src=10.0.0.3")
+	require.NoError(t, err)
+	_, err = EnrichAssets(lookup)(context.Background(), &e)
+	require.NoError(t, err)
+	assert.Equal(t, "src=10.0.0.3", e.Extensions.format(nil))
+}
+
+func TestAddFields(t *testing.T) {
+	stage, err := AddFields(map[string]string{"deviceHostName": "relay.example", "relay": "r1", "dvc": "192.0.2.1"})
+	require.NoError(t, err)
+	e, err := Parse("CEF:0|v|p|1|100|n|Low|dvchost=old.example relay=r0 msg=hi")
+	require.NoError(t, err)
+	keep, err := stage(context.Background(), &e)
+	require.NoError(t, err)
+	assert.True(t, keep)
+	assert.Equal(t, "msg=hi dvc=192.0.2.1 dvchost=relay.example relay=r1", e.Extensions.format(nil))
+
+	_, err = AddFields(map[string]string{"dpt": "https"})
+	assert.ErrorContains(t, err, `"dpt": `)
+}
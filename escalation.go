@@ -0,0 +1,111 @@
+package cefevent
+
+import (
+	"sync"
+	"time"
+)
+
+// EscalationRule raises the severity of events matching a condition once enough of them have been seen.
+//
+// For example, to escalate failures repeated more than 10 times within a minute to High:
+//
+//	EscalationRule{
+//		Match: func(_, _ string, ext Extensions) bool { return ext.Outcome == "failure" },
+//		Threshold: 11,
+//		Window: time.Minute,
+//		Severity: HighSeverity,
+//	}
+type EscalationRule struct {
+	// Match reports whether an event is counted by this rule
+	Match func(deviceEventClassId, name string, extensions Extensions) bool
+
+	// Threshold is the number of matching occurrences required before escalating. Each event counts as its
+	// BaseEventCount, or 1 if that is unset. Values less than 2 escalate every match.
+	Threshold int
+
+	// Window is the sliding period over which occurrences are counted. If zero, only the current event is considered.
+	Window time.Duration
+
+	// Severity is the severity escalated to. Events already at or above this severity are left unchanged.
+	Severity string
+}
+
+// escalator tracks the matches seen for a single EscalationRule
+type escalator struct {
+	rule  EscalationRule
+	level int
+
+	mu   sync.Mutex
+	hits []escalationHit
+}
+
+type escalationHit struct {
+	at    time.Time
+	count int
+}
+
+// WithEscalationRules configures rules which escalate event severity based on event content. Rules are evaluated in
+// order for every event and the highest resulting severity is used. Returns InvalidSeverityError if a rule has an
+// invalid target severity.
+func WithEscalationRules(rules ...EscalationRule) (LoggerConfigOption, error) {
+	escalators := make([]*escalator, 0, len(rules))
+	for _, r := range rules {
+		level, err := severityLevel(r.Severity)
+		if err != nil {
+			return nil, err
+		}
+		escalators = append(escalators, &escalator{rule: r, level: level})
+	}
+	return func(l *Logger) {
+		l.escalators = escalators
+	}, nil
+}
+
+// observe records a match at now and returns the number of occurrences within the rule's window
+func (e *escalator) observe(now time.Time, count int) int {
+	if e.rule.Window <= 0 {
+		return count
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cutoff := now.Add(-e.rule.Window)
+	i := 0
+	for i < len(e.hits) && !e.hits[i].at.After(cutoff) {
+		i++
+	}
+	e.hits = append(e.hits[i:], escalationHit{at: now, count: count})
+	total := 0
+	for _, h := range e.hits {
+		total += h.count
+	}
+	return total
+}
+
+// escalateSeverity applies the logger's escalation rules to an event, returning the severity it should be logged with
+func (l *Logger) escalateSeverity(deviceEventClassId, name, severity string, extensions Extensions) string {
+	if len(l.escalators) == 0 {
+		return severity
+	}
+	current, err := severityLevel(severity)
+	if err != nil {
+		current = -1 // treat invalid severities as unknown so they can still be escalated
+	}
+	count := extensions.BaseEventCount
+	if count < 1 {
+		count = 1
+	}
+	now := l.getTime()
+	for _, e := range l.escalators {
+		if e.rule.Match == nil || !e.rule.Match(deviceEventClassId, name, extensions) {
+			continue
+		}
+		if e.observe(now, count) < e.rule.Threshold {
+			continue
+		}
+		if e.level > current {
+			current = e.level
+			severity = e.rule.Severity
+		}
+	}
+	return severity
+}
@@ -0,0 +1,64 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func failureRule(threshold int, window time.Duration) EscalationRule {
+	return EscalationRule{
+		Match: func(_, _ string, ext Extensions) bool {
+			return ext.Outcome == "failure"
+		},
+		Threshold: threshold,
+		Window:    window,
+		Severity:  HighSeverity,
+	}
+}
+
+func TestWithEscalationRules_error(t *testing.T) {
+	_, err := WithEscalationRules(EscalationRule{Severity: "catastrophic"})
+	assert.ErrorIs(t, err, InvalidSeverityError)
+}
+
+func TestLogger_escalateSeverity(t *testing.T) {
+	now := testTime()
+	l := NewLogger(&bytes.Buffer{}, "testVendor", "testProduct", "1.0",
+		MustLoggerConfig(WithEscalationRules(failureRule(3, time.Minute))),
+	)
+	l.getTime = func() time.Time {
+		return now
+	}
+	failure := Extensions{Outcome: "failure"}
+
+	assert.Equal(t, LowSeverity, l.escalateSeverity("100", "login", LowSeverity, failure))
+	assert.Equal(t, LowSeverity, l.escalateSeverity("100", "login", LowSeverity, Extensions{Outcome: "success"}))
+	assert.Equal(t, LowSeverity, l.escalateSeverity("100", "login", LowSeverity, failure))
+	assert.Equal(t, HighSeverity, l.escalateSeverity("100", "login", LowSeverity, failure), "third failure in window")
+	assert.Equal(t, VeryHighSeverity, l.escalateSeverity("100", "login", VeryHighSeverity, failure), "never de-escalate")
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, LowSeverity, l.escalateSeverity("100", "login", LowSeverity, failure), "window expired")
+}
+
+func TestLogger_escalateSeverity_eventCount(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0",
+		OmitSyslogHeader(),
+		MustLoggerConfig(WithEscalationRules(failureRule(11, 0))),
+	)
+	require.NoError(t, l.LogLow("100", "login", Extensions{Outcome: "failure", BaseEventCount: 10}))
+	matches := severityRegex.FindStringSubmatch(buf.String())
+	require.NotNil(t, matches, "unmatched severity")
+	assert.Equal(t, LowSeverity, matches[1])
+
+	buf.Reset()
+	require.NoError(t, l.LogLow("100", "login", Extensions{Outcome: "failure", BaseEventCount: 11}))
+	matches = severityRegex.FindStringSubmatch(buf.String())
+	require.NotNil(t, matches, "unmatched severity")
+	assert.Equal(t, HighSeverity, matches[1])
+}
@@ -0,0 +1,94 @@
+package cefevent
+
+import "strconv"
+
+// Event is a complete CEF event: the header fields and its extensions
+type Event struct {
+	// Version is the CEF format version, 0 or 1
+	Version byte
+
+	DeviceVendor       string
+	DeviceProduct      string
+	DeviceVersion      string
+	DeviceEventClassId string
+	Name               string
+	Severity           string
+
+	Extensions Extensions
+}
+
+// String formats e as a CEF record, without a syslog header
+func (e Event) String() string {
+	return e.format(legacyKeys)
+}
+
+// AppendText appends e formatted as a CEF record, without a syslog header, to b. Returns InvalidCefVersionErr if
+// Version isn't 0 or 1.
+func (e Event) AppendText(b []byte) ([]byte, error) {
+	if e.Version != 0 && e.Version != 1 {
+		return b, InvalidCefVersionErr
+	}
+	return e.AppendCEF(b), nil
+}
+
+// AppendCEF appends e formatted as a CEF record, without a syslog header, to dst and returns the extended slice. Unlike
+// AppendText the version isn't checked. See Extensions.AppendCEF for when it allocates.
+func (e Event) AppendCEF(dst []byte) []byte {
+	return e.appendFormat(dst, legacyKeys)
+}
+
+// MarshalText formats e as a CEF record, without a syslog header. Returns InvalidCefVersionErr if Version isn't 0 or 1.
+func (e Event) MarshalText() ([]byte, error) {
+	return e.AppendText(nil)
+}
+
+// UnmarshalText parses a CEF record into e, see Parse
+func (e *Event) UnmarshalText(text []byte) error {
+	ev, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*e = ev
+	return nil
+}
+
+// Field returns the value of the field key in e and whether it is set. Keys are the JSON names of the header fields
+// (version, deviceVendor, deviceProduct, deviceVersion, deviceEventClassId, name and severity), standard extension keys
+// with their legacy spellings and full dictionary names (e.g. "dpt" or "destinationPort"), and CustomExtensions keys.
+// Header fields hide custom extensions of the same name. Extension values are formatted as by Extensions.Fields.
+func (e *Event) Field(key string) (string, bool) {
+	switch key {
+	case "version":
+		return strconv.Itoa(int(e.Version)), true
+	case "deviceVendor":
+		return e.DeviceVendor, e.DeviceVendor != ""
+	case "deviceProduct":
+		return e.DeviceProduct, e.DeviceProduct != ""
+	case "deviceVersion":
+		return e.DeviceVersion, e.DeviceVersion != ""
+	case "deviceEventClassId":
+		return e.DeviceEventClassId, e.DeviceEventClassId != ""
+	case "name":
+		return e.Name, e.Name != ""
+	case "severity":
+		return e.Severity, e.Severity != ""
+	}
+	return e.Extensions.field(key)
+}
+
+// format formats the event, emitting any extension keys found in aliases under their alias instead
+func (e Event) format(aliases map[string]string) string {
+	return string(e.appendFormat(nil, aliases))
+}
+
+// appendFormat appends the formatted event to b, emitting any extension keys found in aliases under their alias instead
+func (e Event) appendFormat(b []byte, aliases map[string]string) []byte {
+	b = append(b, "CEF:"...)
+	b = strconv.AppendUint(b, uint64(e.Version), 10)
+	for _, field := range [...]string{e.DeviceVendor, e.DeviceProduct, e.DeviceVersion, e.DeviceEventClassId, e.Name, e.Severity} {
+		b = append(b, '|')
+		b = appendEscapedHeader(b, field)
+	}
+	b = append(b, '|')
+	return e.Extensions.appendFormat(b, aliases)
+}
@@ -0,0 +1,102 @@
+package cefevent
+
+import (
+	"encoding"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ fmt.Stringer             = Event{}
+	_ encoding.TextMarshaler   = Event{}
+	_ encoding.TextUnmarshaler = &Event{}
+)
+
+func TestEvent_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			"header_only",
+			Event{Version: 1, DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100", Name: "test", Severity: LowSeverity},
+			"CEF:1|v|p|1|100|test|Low|",
+		},
+		{
+			"escaped",
+			Event{DeviceVendor: "a|b", DeviceProduct: `c\d`, DeviceVersion: "1", DeviceEventClassId: "100", Name: "n", Severity: "5",
+				Extensions: Extensions{Message: "x=y", DestinationPort: ptr(uint(443))}},
+			`CEF:0|a\|b|c\\d|1|100|n|5|msg=x\=y dpt=443`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.event.String())
+			text, err := tt.event.MarshalText()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(text))
+			appended, err := tt.event.AppendText([]byte("prefix "))
+			require.NoError(t, err)
+			assert.Equal(t, "prefix "+tt.want, string(appended))
+			assert.Equal(t, "prefix "+tt.want, string(tt.event.AppendCEF([]byte("prefix "))))
+		})
+	}
+}
+
+func TestEvent_MarshalText_invalidVersion(t *testing.T) {
+	_, err := Event{Version: 2}.MarshalText()
+	assert.ErrorIs(t, err, InvalidCefVersionErr)
+}
+
+func TestEvent_UnmarshalText(t *testing.T) {
+	want := Event{Version: 1, DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100",
+		Name: "test", Severity: HighSeverity, Extensions: Extensions{Message: "hi", DestinationHostName: "example.com"}}
+	text, err := want.MarshalText()
+	require.NoError(t, err)
+
+	var got Event
+	require.NoError(t, got.UnmarshalText(text))
+	assert.Equal(t, want, got)
+	assert.ErrorIs(t, got.UnmarshalText([]byte("not cef")), MalformedEventErr)
+}
+
+func TestEvent_Field(t *testing.T) {
+	e, err := Parse("CEF:0|v|p|1|100|login|High|dpt=443 src=10.0.0.1 cs1=x cs1Label=policy name=custom k=v")
+	require.NoError(t, err)
+	tests := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"version", "0", true},
+		{"deviceEventClassId", "100", true},
+		{"name", "login", true},
+		{"severity", "High", true},
+		{"dpt", "443", true},
+		{"destinationPort", "443", true},
+		{"src", "10.0.0.1", true},
+		{"cs1", "x", true},
+		{"deviceCustomString1Label", "policy", true},
+		{"k", "v", true},
+		{"spt", "", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v, ok := e.Field(tt.key)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, v)
+		})
+	}
+
+	legacy, err := Parse("CEF:0|v|p|1|100|n|Low|dcvhost=device.example")
+	require.NoError(t, err)
+	v, _ := legacy.Field("dvchost")
+	assert.Equal(t, "device.example", v)
+	v, _ = legacy.Field("dcvhost")
+	assert.Equal(t, "device.example", v)
+}
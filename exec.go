@@ -0,0 +1,180 @@
+package cefevent
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default class IDs for events logged by AuditedCmd
+const (
+	ExecStartClassId = "exec-start"
+	ExecExitClassId  = "exec-exit"
+)
+
+// AuditedCmd wraps an exec.Cmd, logging a CEF event when the process starts and another when it exits. The
+// destination process fields describe the child process and the device process fields describe the current process.
+type AuditedCmd struct {
+	// Cmd is the wrapped command. Configure it as normal, but start it through AuditedCmd so events are logged
+	Cmd *exec.Cmd
+
+	logger       *Logger
+	startClassId string
+	exitClassId  string
+	severity     string
+	redact       func(args []string) []string
+	started      time.Time
+}
+
+// CmdAuditOption configures an AuditedCmd
+type CmdAuditOption func(c *AuditedCmd)
+
+// WithArgRedactor redacts the command line before it is logged. fn receives a copy of the arguments, including the
+// command name, and returns the arguments to log.
+func WithArgRedactor(fn func(args []string) []string) CmdAuditOption {
+	return func(c *AuditedCmd) {
+		c.redact = fn
+	}
+}
+
+// WithCmdEventClasses overrides the device event class IDs used for the start and exit events
+func WithCmdEventClasses(startClassId, exitClassId string) CmdAuditOption {
+	return func(c *AuditedCmd) {
+		c.startClassId = startClassId
+		c.exitClassId = exitClassId
+	}
+}
+
+// WithCmdSeverity overrides the severity of the start and exit events. Defaults to LowSeverity
+func WithCmdSeverity(severity string) CmdAuditOption {
+	return func(c *AuditedCmd) {
+		c.severity = severity
+	}
+}
+
+// RedactArgsAfter returns an argument redactor which masks the value following any of flags, in both "--flag value" and
+// "--flag=value" forms. For use with WithArgRedactor.
+func RedactArgsAfter(flags ...string) func(args []string) []string {
+	return func(args []string) []string {
+		for i := 0; i < len(args); i++ {
+			for _, f := range flags {
+				if args[i] == f && i+1 < len(args) {
+					i++
+					args[i] = "***"
+					break
+				}
+				if strings.HasPrefix(args[i], f+"=") {
+					args[i] = f + "=***"
+					break
+				}
+			}
+		}
+		return args
+	}
+}
+
+// AuditCommand wraps cmd so that starting it logs CEF events to l
+func AuditCommand(l *Logger, cmd *exec.Cmd, opts ...CmdAuditOption) *AuditedCmd {
+	c := &AuditedCmd{
+		Cmd:          cmd,
+		logger:       l,
+		startClassId: ExecStartClassId,
+		exitClassId:  ExecExitClassId,
+		severity:     LowSeverity,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Start starts the command, logging a start event. If the command fails to start the event records the failure.
+// Failures to log are reported to the Logger's error handler rather than returned, so they can't mask command errors.
+func (c *AuditedCmd) Start() error {
+	err := c.Cmd.Start()
+	c.started = c.logger.getTime()
+	ext := c.extensions()
+	if err != nil {
+		ext.Outcome = "failure"
+		ext.Reason = err.Error()
+	} else {
+		ext.Outcome = "success"
+	}
+	c.log(c.startClassId, "process started", ext)
+	return err
+}
+
+// Wait waits for the command to exit, logging an exit event with an outcome derived from the exit code and the
+// process's run time
+func (c *AuditedCmd) Wait() error {
+	err := c.Cmd.Wait()
+	ended := c.logger.getTime()
+	ext := c.extensions()
+	ext.EndTime = ended
+	ext.CustomExtensions = map[string]string{
+		"durationMs": strconv.FormatInt(ended.Sub(c.started).Milliseconds(), 10),
+	}
+	if err == nil {
+		ext.Outcome = "success"
+		ext.Reason = "exit status 0"
+	} else {
+		ext.Outcome = "failure"
+		ext.Reason = err.Error()
+	}
+	if c.Cmd.ProcessState != nil {
+		ext.CustomExtensions["exitCode"] = strconv.Itoa(c.Cmd.ProcessState.ExitCode())
+	}
+	c.log(c.exitClassId, "process exited", ext)
+	return err
+}
+
+// Run starts the command and waits for it to exit, logging both events
+func (c *AuditedCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output, logging both events
+func (c *AuditedCmd) Output() ([]byte, error) {
+	if c.Cmd.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Cmd.Stdout = &stdout
+	err := c.Run()
+	return stdout.Bytes(), err
+}
+
+// extensions returns the fields common to the start & exit events
+func (c *AuditedCmd) extensions() Extensions {
+	args := append([]string(nil), c.Cmd.Args...)
+	if len(args) == 0 {
+		args = []string{c.Cmd.Path}
+	}
+	if c.redact != nil {
+		args = c.redact(args)
+	}
+	ext := Extensions{
+		Message:                strings.Join(args, " "),
+		DestinationProcessName: filepath.Base(c.Cmd.Path),
+		DeviceProcessName:      filepath.Base(os.Args[0]),
+		DeviceProcessId:        ptr(uint(os.Getpid())),
+	}
+	if c.Cmd.Process != nil {
+		ext.DestinationProcessId = ptr(uint(c.Cmd.Process.Pid))
+	}
+	return ext
+}
+
+func (c *AuditedCmd) log(deviceEventClassId, name string, ext Extensions) {
+	if err := c.logger.Log(deviceEventClassId, name, c.severity, ext); err != nil {
+		c.logger.handleError(err)
+	}
+}
@@ -0,0 +1,52 @@
+package cefevent
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactArgsAfter(t *testing.T) {
+	redact := RedactArgsAfter("--password", "-p")
+	got := redact([]string{"mysql", "-u", "root", "-p", "hunter2", "--password=hunter2", "--password"})
+	assert.Equal(t, []string{"mysql", "-u", "root", "-p", "***", "--password=***", "--password"}, got)
+}
+
+func TestAuditCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	cmd := AuditCommand(l, exec.Command("sh", "-c", "exit 3", "--token", "secret"),
+		WithArgRedactor(RedactArgsAfter("--token")),
+		WithCmdSeverity(MediumSeverity),
+	)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+
+	records := w.Records()
+	require.Len(t, records, 2)
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|exec-start\|process started\|Medium\|`+
+		`msg=sh -c exit 3 --token \*\*\* outcome=success dpid=\d+ dproc=sh`, records[0])
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|exec-exit\|process exited\|Medium\|`+
+		`msg=sh -c exit 3 --token \*\*\* end=\d+ outcome=failure reason=exit status 3 dpid=\d+ dproc=sh`, records[1])
+	assert.Regexp(t, regexp.MustCompile(`exitCode=3`), records[1])
+	assert.Regexp(t, regexp.MustCompile(`durationMs=\d+`), records[1])
+	assert.Contains(t, records[1], "dvcpid=")
+}
+
+func TestAuditCommand_startFailure(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	_, err := AuditCommand(l, exec.Command("/nonexistent/binary")).Output()
+	require.Error(t, err)
+	records := w.Records()
+	require.Len(t, records, 1)
+	assert.Contains(t, records[0], "outcome=failure reason=")
+}
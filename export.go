@@ -0,0 +1,91 @@
+package cefevent
+
+// ExportKind is the type of an ExportColumn's values
+type ExportKind int
+
+const (
+	ExportString ExportKind = iota // Values are strings
+	ExportInt                      // Values are int64s
+	ExportTime                     // Values are int64 milliseconds since the unix epoch
+)
+
+// ExportColumn is a column of the table events are flattened into for export, see ExportValues
+type ExportColumn struct {
+	Name     string // The header field's JSON name, or the extension's key
+	Kind     ExportKind
+	Optional bool // Whether values may be nil, for extensions an event doesn't have
+}
+
+// ExportColumns are the columns events are flattened into for export to SQL, Parquet and other tables: the header
+// fields, the extensions most often queried, and extras, a JSON object of the other extensions encoded as by
+// Extensions.MarshalJSON. SQLExporter writes them to SQL databases, and the separate parquetexport module to Parquet
+// files.
+var ExportColumns = []ExportColumn{
+	{"version", ExportInt, false},
+	{"deviceVendor", ExportString, false},
+	{"deviceProduct", ExportString, false},
+	{"deviceVersion", ExportString, false},
+	{"deviceEventClassId", ExportString, false},
+	{"name", ExportString, false},
+	{"severity", ExportString, false},
+	{"rt", ExportTime, true},
+	{"src", ExportString, true},
+	{"spt", ExportInt, true},
+	{"shost", ExportString, true},
+	{"suser", ExportString, true},
+	{"dst", ExportString, true},
+	{"dpt", ExportInt, true},
+	{"dhost", ExportString, true},
+	{"duser", ExportString, true},
+	{"proto", ExportString, true},
+	{"act", ExportString, true},
+	{"outcome", ExportString, true},
+	{"msg", ExportString, true},
+	{"extras", ExportString, false},
+}
+
+// exportColumnIndex maps the extension columns of ExportColumns to their index
+var exportColumnIndex = func() map[string]int {
+	index := make(map[string]int)
+	for i, c := range ExportColumns {
+		if c.Optional {
+			index[c.Name] = i
+		}
+	}
+	return index
+}()
+
+// ExportValues flattens e into a row of values for ExportColumns: a string or int64 as the column's Kind, or nil for
+// an extension e doesn't have
+func ExportValues(e Event) []any {
+	row := make([]any, len(ExportColumns))
+	row[0] = int64(e.Version)
+	row[1], row[2], row[3] = e.DeviceVendor, e.DeviceProduct, e.DeviceVersion
+	row[4], row[5], row[6] = e.DeviceEventClassId, e.Name, e.Severity
+	extras := []byte{'{'}
+	e.Extensions.walk(func(key string, v fieldValue) bool {
+		i, ok := exportColumnIndex[key]
+		if !ok {
+			if len(extras) > 1 {
+				extras = append(extras, ',')
+			}
+			extras = appendJSONString(extras, key)
+			extras = append(extras, ':')
+			extras = appendJSONValue(extras, v)
+			return true
+		}
+		switch v.kind {
+		case intKind:
+			row[i] = v.i
+		case uintKind:
+			row[i] = int64(v.u)
+		case timeKind:
+			row[i] = v.t.UnixMilli()
+		default:
+			row[i] = v.String()
+		}
+		return true
+	})
+	row[len(row)-1] = string(append(extras, '}'))
+	return row
+}
@@ -0,0 +1,19 @@
+package cefevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportValues(t *testing.T) {
+	e, err := Parse(`CEF:0|v|p|1|100|login|High|rt=1699530300000 src=10.0.0.1 spt=5555 dst=10.0.0.2 dpt=22 ` +
+		`suser=alice act=allow msg=hi "there" cnt=3 cs1=x cs1Label=note`)
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(0), "v", "p", "1", "100", "login", "High", int64(1699530300000), "10.0.0.1",
+		int64(5555), nil, "alice", "10.0.0.2", int64(22), nil, nil, nil, "allow", nil, `hi "there"`,
+		`{"cnt":3,"cs1":"x","cs1Label":"note"}`}, ExportValues(e))
+
+	assert.Equal(t, "{}", ExportValues(Event{})[len(ExportColumns)-1])
+}
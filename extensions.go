@@ -1,11 +1,16 @@
 package cefevent
 
 import (
+	"iter"
+	"maps"
 	"net"
+	"net/netip"
 	"net/url"
+	"reflect"
+	"slices"
 	"strconv"
-	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Event type constants for use in the Type field
@@ -23,7 +28,8 @@ type Extensions struct {
 	// Message is an arbitrary message giving more details about the event
 	Message string
 
-	// BaseEventCount is the number of times this same event was observed. Omitted if count is less than 2.
+	// BaseEventCount is the number of times this same event was observed. Omitted if count is less than 2, unless Type
+	// is AggregatedEventType and count is 1.
 	BaseEventCount int
 
 	// ApplicationProtocol application level protocol, example values are HTTP, HTTPS, SSHv2, Telnet, POP, and so on.
@@ -56,6 +62,71 @@ type Extensions struct {
 	// Reason is the audit event was generated e.g. "bad password"
 	Reason string
 
+	// DeviceEventCategory represents the category assigned by the originating device e.g. "/Monitor/Disk/Read"
+	DeviceEventCategory string
+
+	// EventId is a unique ID assigned to the event, typically by a collector such as ArcSight
+	EventId *int64
+
+	// RawEvent is the original event before it was normalized
+	RawEvent string
+
+	// StartTime is the time when the activity the event refers to started
+	StartTime time.Time
+
+	// CustomerExternalId identifies the customer (tenant) the event belongs to
+	CustomerExternalId string
+
+	// CustomerUri is the URI of the customer resource the event belongs to
+	CustomerUri string
+
+	//// Agent Fields, usually set by a collector forwarding the event rather than the device itself
+
+	// AgentAddress is the IP address of the collecting agent
+	AgentAddress net.IP
+
+	// AgentHostName is the FQDN of the collecting agent
+	AgentHostName string
+
+	// AgentId is the ID of the collecting agent
+	AgentId string
+
+	// AgentMacAddress is the MAC address of the collecting agent
+	AgentMacAddress net.HardwareAddr
+
+	// AgentReceiptTime is the time at which the agent received the event
+	AgentReceiptTime time.Time
+
+	// AgentType is the type of the collecting agent
+	AgentType string
+
+	// AgentTimeZone is the timezone of the collecting agent
+	AgentTimeZone *time.Location
+
+	// AgentVersion is the version of the collecting agent
+	AgentVersion string
+
+	// AgentDnsDomain is the DNS domain name portion of the agent's FQDN
+	AgentDnsDomain string
+
+	// AgentNtDomain is the Windows domain name of the agent
+	AgentNtDomain string
+
+	// AgentTranslatedAddress is the translated IP address of the agent
+	AgentTranslatedAddress net.IP
+
+	// AgentTranslatedZoneExternalId is the external ID of the network zone of the agent's translated address
+	AgentTranslatedZoneExternalId string
+
+	// AgentTranslatedZoneUri is the URI of the network zone of the agent's translated address
+	AgentTranslatedZoneUri string
+
+	// AgentZoneExternalId is the external ID of the agent's network zone
+	AgentZoneExternalId string
+
+	// AgentZoneUri is the URI of the agent's network zone
+	AgentZoneUri string
+
 	//// Source Fields
 
 	// SourceHostName is the FQDN of the source machine
@@ -82,6 +153,42 @@ type Extensions struct {
 	// SourceProcessId is the PID of the originating process for the event.
 	SourceProcessId *int
 
+	// SourceAddress identifies the source that an event refers to in an IP network.
+	SourceAddress net.IP
+
+	// SourcePort is the valid port number of the source. Between 0 & 65535
+	SourcePort *uint
+
+	// SourceUserName identifies the source user by name. Email addresses are also mapped into the UserName fields.
+	SourceUserName string
+
+	// SourceUserId identifies the source user by ID e.g. root is typically "0"
+	SourceUserId string
+
+	// SourceUserPrivileges identify source user's privileges e.g. "Administrator", "User", "Guest"
+	SourceUserPrivileges string
+
+	// SourceProcessName is the name of the event's source process
+	SourceProcessName string
+
+	// SourceGeoLatitude is the latitude of the source, between -90 & 90
+	SourceGeoLatitude *float64
+
+	// SourceGeoLongitude is the longitude of the source, between -180 & 180
+	SourceGeoLongitude *float64
+
+	// SourceZoneExternalId is the external ID of the source's network zone
+	SourceZoneExternalId string
+
+	// SourceZoneUri is the URI of the source's network zone
+	SourceZoneUri string
+
+	// SourceTranslatedZoneExternalId is the external ID of the network zone of the source's translated address
+	SourceTranslatedZoneExternalId string
+
+	// SourceTranslatedZoneUri is the URI of the network zone of the source's translated address
+	SourceTranslatedZoneUri string
+
 	//// Destination Fields
 
 	// DestinationDnsDomain the DNS domain part of the complete fully qualified domain name (FQDN).
@@ -124,6 +231,27 @@ type Extensions struct {
 	// DestinationUserId identifies the destination user by ID e.g. root is typically "0"
 	DestinationUserId string
 
+	// DestinationUserName identifies the destination user by name. Email addresses are also mapped into the UserName fields.
+	DestinationUserName string
+
+	// DestinationGeoLatitude is the latitude of the destination, between -90 & 90
+	DestinationGeoLatitude *float64
+
+	// DestinationGeoLongitude is the longitude of the destination, between -180 & 180
+	DestinationGeoLongitude *float64
+
+	// DestinationZoneExternalId is the external ID of the destination's network zone
+	DestinationZoneExternalId string
+
+	// DestinationZoneUri is the URI of the destination's network zone
+	DestinationZoneUri string
+
+	// DestinationTranslatedZoneExternalId is the external ID of the network zone of the destination's translated address
+	DestinationTranslatedZoneExternalId string
+
+	// DestinationTranslatedZoneUri is the URI of the network zone of the destination's translated address
+	DestinationTranslatedZoneUri string
+
 	//// Device Fields
 
 	// DeviceAction is the action taken by device
@@ -177,6 +305,18 @@ type Extensions struct {
 	// DeviceReceiptTime is the time at which the event was received
 	DeviceReceiptTime time.Time
 
+	// DeviceZoneExternalId is the external ID of the device's network zone
+	DeviceZoneExternalId string
+
+	// DeviceZoneUri is the URI of the device's network zone
+	DeviceZoneUri string
+
+	// DeviceTranslatedZoneExternalId is the external ID of the network zone of the device's translated address
+	DeviceTranslatedZoneExternalId string
+
+	// DeviceTranslatedZoneUri is the URI of the network zone of the device's translated address
+	DeviceTranslatedZoneUri string
+
 	//// File fields
 
 	// FileCreateTime is the time when the file was created
@@ -249,276 +389,593 @@ type Extensions struct {
 
 	// RequestMethod is the HTTP verb for the request (e.g. "GET")
 	RequestMethod string
-	// TODO add all extensions
+
+	//// Custom fields. Each must be set with a label, see CustomField
+
+	// DeviceCustomFloatingPoint1 is emitted as cfp1 & cfp1Label
+	DeviceCustomFloatingPoint1 *CustomField[float64]
+	DeviceCustomFloatingPoint2 *CustomField[float64]
+	DeviceCustomFloatingPoint3 *CustomField[float64]
+	DeviceCustomFloatingPoint4 *CustomField[float64]
+
+	// DeviceCustomNumber1 is emitted as cn1 & cn1Label
+	DeviceCustomNumber1 *CustomField[int64]
+	DeviceCustomNumber2 *CustomField[int64]
+	DeviceCustomNumber3 *CustomField[int64]
+
+	// DeviceCustomString1 is emitted as cs1 & cs1Label
+	DeviceCustomString1 *CustomField[string]
+	DeviceCustomString2 *CustomField[string]
+	DeviceCustomString3 *CustomField[string]
+	DeviceCustomString4 *CustomField[string]
+	DeviceCustomString5 *CustomField[string]
+	DeviceCustomString6 *CustomField[string]
+
+	// DeviceCustomDate1 is emitted as deviceCustomDate1 & deviceCustomDate1Label
+	DeviceCustomDate1 *CustomField[time.Time]
+	DeviceCustomDate2 *CustomField[time.Time]
+
+	// FlexDate1 is emitted as flexDate1 & flexDate1Label
+	FlexDate1 *CustomField[time.Time]
+
+	// FlexString1 is emitted as flexString1 & flexString1Label
+	FlexString1 *CustomField[string]
+	FlexString2 *CustomField[string]
 
 	// CustomExtensions includes non-standard mappings in the extension field. Keys in the map shouldn't overlap with fields in the
-	// CEF spec to avoid duplicate values
+	// CEF spec to avoid duplicate values. They are emitted after the standard fields, sorted by key so output is
+	// deterministic
 	CustomExtensions map[string]string
 }
 
-// String formats extension for including in CEF event
+// String formats extension for including in CEF event, with the legacy key spellings Loggers emit by default
 func (e Extensions) String() string {
-	b := strings.Builder{}
-	if e.Message != "" {
-		b.WriteString("msg=" + escapeExtensionField(e.Message) + " ")
-	}
-	if e.DeviceAction != "" {
-		b.WriteString("act=" + escapeExtensionField(e.DeviceAction) + " ")
-	}
-	if e.ApplicationProtocol != "" {
-		b.WriteString("app=" + escapeExtensionField(e.ApplicationProtocol) + " ")
-	}
-	if e.BaseEventCount > 1 {
-		b.WriteString("cnt=" + strconv.FormatInt(int64(e.BaseEventCount), 10) + " ")
-	}
-	if !e.EndTime.IsZero() {
-		b.WriteString("end=" + strconv.FormatInt(e.EndTime.UnixMilli(), 10) + " ") // Use unix time here
-	}
-	if e.ExternalId != "" {
-		b.WriteString("externalId=" + escapeExtensionField(e.ExternalId) + " ")
-	}
-	if e.Type != 0 {
-		b.WriteString("type=" + strconv.FormatInt(int64(e.Type), 10) + " ")
-	}
-	if e.BytesIn != nil {
-		b.WriteString("in=" + strconv.FormatUint(uint64(*e.BytesIn), 10) + " ")
-	}
-	if e.BytesOut != nil {
-		b.WriteString("out=" + strconv.FormatUint(uint64(*e.BytesOut), 10) + " ")
-	}
-	if e.Outcome != "" {
-		b.WriteString("outcome=" + escapeExtensionField(e.Outcome) + " ")
+	return e.format(legacyKeys)
+}
+
+// format formats the extension, emitting any keys found in aliases under their alias instead
+func (e Extensions) format(aliases map[string]string) string {
+	return string(e.appendFormat(nil, aliases))
+}
+
+// AppendCEF appends the extension, formatted as by String, to dst and returns the extended slice. It doesn't allocate
+// unless dst needs to grow or e has more than 16 CustomExtensions, so it suits use inside other loggers' encoders.
+func (e Extensions) AppendCEF(dst []byte) []byte {
+	return e.appendFormat(dst, legacyKeys)
+}
+
+// appendFormat appends the formatted extension to b, emitting any keys found in aliases under their alias instead
+func (e Extensions) appendFormat(b []byte, aliases map[string]string) []byte {
+	start := len(b)
+	e.walk(func(key string, v fieldValue) bool {
+		if alias, ok := aliases[key]; ok {
+			key = alias
+		}
+		if len(b) > start {
+			b = append(b, ' ')
+		}
+		b = appendEscapedExtension(b, key)
+		b = append(b, '=')
+		b = v.appendTo(b)
+		return true
+	})
+	return b
+}
+
+// Clone returns a deep copy of e. Pointer fields, addresses and CustomExtensions are copied so the clone can be mutated
+// without affecting e. DeviceTimeZone, AgentTimeZone and RequestUrl.User are immutable and shared.
+func (e Extensions) Clone() Extensions {
+	c := e
+	c.BytesIn = clonePtr(e.BytesIn)
+	c.BytesOut = clonePtr(e.BytesOut)
+	c.EventId = clonePtr(e.EventId)
+	c.AgentAddress = slices.Clone(e.AgentAddress)
+	c.AgentMacAddress = slices.Clone(e.AgentMacAddress)
+	c.AgentTranslatedAddress = slices.Clone(e.AgentTranslatedAddress)
+	c.SourceMacAddress = slices.Clone(e.SourceMacAddress)
+	c.SourceTranslatedAddress = slices.Clone(e.SourceTranslatedAddress)
+	c.SourceTranslatedPort = clonePtr(e.SourceTranslatedPort)
+	c.SourceProcessId = clonePtr(e.SourceProcessId)
+	c.SourceAddress = slices.Clone(e.SourceAddress)
+	c.SourcePort = clonePtr(e.SourcePort)
+	c.SourceGeoLatitude = clonePtr(e.SourceGeoLatitude)
+	c.SourceGeoLongitude = clonePtr(e.SourceGeoLongitude)
+	c.DestinationTranslatedAddress = slices.Clone(e.DestinationTranslatedAddress)
+	c.DestinationTranslatedPort = clonePtr(e.DestinationTranslatedPort)
+	c.DestinationMacAddress = slices.Clone(e.DestinationMacAddress)
+	c.DestinationProcessId = clonePtr(e.DestinationProcessId)
+	c.DestinationPort = clonePtr(e.DestinationPort)
+	c.DestinationAddress = slices.Clone(e.DestinationAddress)
+	c.DestinationGeoLatitude = clonePtr(e.DestinationGeoLatitude)
+	c.DestinationGeoLongitude = clonePtr(e.DestinationGeoLongitude)
+	c.DeviceDirection = clonePtr(e.DeviceDirection)
+	c.DeviceTranslatedAddress = slices.Clone(e.DeviceTranslatedAddress)
+	c.DeviceAddress = slices.Clone(e.DeviceAddress)
+	c.DeviceMacAddress = slices.Clone(e.DeviceMacAddress)
+	c.DeviceProcessId = clonePtr(e.DeviceProcessId)
+	c.FileSize = clonePtr(e.FileSize)
+	c.OldFileSize = clonePtr(e.OldFileSize)
+	for _, spec := range customFieldSpecs {
+		spec.clone(&c)
+	}
+	c.CustomExtensions = maps.Clone(e.CustomExtensions)
+	return c
+}
+
+// Merge returns a copy of e with every field set in override replacing e's value. CustomExtensions are merged by key,
+// with override's values winning. Neither e nor override is modified.
+func (e Extensions) Merge(override Extensions) Extensions {
+	m := e.Clone()
+	o := override.Clone()
+	mv := reflect.ValueOf(&m).Elem()
+	ov := reflect.ValueOf(o)
+	for i := 0; i < ov.NumField(); i++ {
+		if f := ov.Field(i); !f.IsZero() && ov.Type().Field(i).Name != "CustomExtensions" {
+			mv.Field(i).Set(f)
+		}
 	}
-	if e.TransportProtocol != "" {
-		b.WriteString("proto=" + escapeExtensionField(e.TransportProtocol) + " ")
+	if len(o.CustomExtensions) > 0 {
+		if m.CustomExtensions == nil {
+			m.CustomExtensions = make(map[string]string, len(o.CustomExtensions))
+		}
+		maps.Copy(m.CustomExtensions, o.CustomExtensions)
 	}
-	if e.Reason != "" {
-		b.WriteString("reason=" + escapeExtensionField(e.Reason) + " ")
+	return m
+}
+
+// ptr is a convenience function to convert literal values to pointers
+func ptr[A any](v A) *A {
+	return &v
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil if p is nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
 	}
-	destinationStr := e.marshalDestinationFields()
-	if len(destinationStr) > 0 {
-		b.WriteString(destinationStr)
+	v := *p
+	return &v
+}
+
+// Fields returns an iterator over the populated fields in the order they are emitted, yielding each field's short key
+// (e.g. "dpt") and its formatted value. Values are formatted as they appear in the extension (times as epoch
+// milliseconds, addresses in their text form) but are not escaped.
+func (e Extensions) Fields() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		e.walk(func(key string, v fieldValue) bool {
+			return yield(key, v.String())
+		})
 	}
-	deviceString := e.marshalDeviceFields()
-	if len(deviceString) > 0 {
-		b.WriteString(deviceString)
+}
+
+// field returns the formatted value of the field key and whether it is set, accepting the same keys as Event.Field
+func (e *Extensions) field(key string) (string, bool) {
+	key = standardKey(key)
+	if _, standard := extensionSetters[key]; !standard {
+		v, ok := e.CustomExtensions[key]
+		return v, ok
+	}
+	var value string
+	found := !e.walk(func(k string, v fieldValue) bool {
+		if k == key {
+			value = v.String()
+			return false
+		}
+		return true
+	})
+	return value, found
+}
+
+// standardKey returns the standard key for a legacy spelling or full dictionary name, or key if it is neither
+func standardKey(key string) string {
+	if standard, ok := legacyKeyNames[key]; ok {
+		return standard
 	}
-	fileString := e.marshalFileFields()
-	if len(fileString) > 0 {
-		b.WriteString(fileString)
+	if standard, ok := dictionaryKeyNames[key]; ok {
+		return standard
 	}
-	// TODO implement
+	return key
+}
+
+// EncodedLen returns the length in bytes of e.String() without building the string, so batching layers and MTU-aware
+// transports can make framing decisions before formatting an event. Like String, it counts the legacy key spellings a
+// Logger emits by default; events from a Logger using WithStandardKeys are a few bytes shorter.
+func (e Extensions) EncodedLen() int {
+	return e.encodedLen(legacyKeys)
+}
+
+// encodedLen returns the length in bytes of e.format(aliases)
+func (e Extensions) encodedLen(aliases map[string]string) int {
+	n := 0
+	e.walk(func(key string, v fieldValue) bool {
+		if alias, ok := aliases[key]; ok {
+			key = alias
+		}
+		if n > 0 {
+			n++ // separating space
+		}
+		n += escapedExtensionLen(key) + 1 + escapedExtensionLen(v.String())
+		return true
+	})
+	return n
+}
 
-	for k, v := range e.CustomExtensions {
-		b.WriteString(escapeExtensionField(k) + "=" + escapeExtensionField(v) + " ")
+// IsEmpty reports whether e has no fields to emit
+func (e Extensions) IsEmpty() bool {
+	return e.walk(func(string, fieldValue) bool {
+		return false
+	})
+}
+
+// PopulatedFields returns the short keys of every field e would emit, in emission order, including CustomExtensions
+// keys. Useful for telemetry on which fields instrumentation actually sets.
+func (e Extensions) PopulatedFields() []string {
+	var keys []string
+	e.walk(func(key string, _ fieldValue) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// walk calls yield for each populated field in the order they are emitted, stopping early if yield returns false.
+// Returns false if stopped early.
+func (e Extensions) walk(yield func(key string, v fieldValue) bool) bool {
+	w := &fieldWalker{yield: yield}
+	w.str("msg", e.Message)
+	w.str("act", e.DeviceAction)
+	w.str("app", e.ApplicationProtocol)
+	if e.BaseEventCount > 1 || (e.BaseEventCount == 1 && e.Type == AggregatedEventType) {
+		w.int("cnt", int64(e.BaseEventCount))
+	}
+	w.time("end", e.EndTime) // Use unix time here
+	w.str("externalId", e.ExternalId)
+	if e.Type != 0 {
+		w.uint("type", uint64(e.Type))
+	}
+	w.uintPtr("in", e.BytesIn)
+	w.uintPtr("out", e.BytesOut)
+	w.str("outcome", e.Outcome)
+	w.str("proto", e.TransportProtocol)
+	w.str("reason", e.Reason)
+	w.str("cat", e.DeviceEventCategory)
+	w.str("customerExternalID", e.CustomerExternalId)
+	w.str("customerURI", e.CustomerUri)
+	w.int64Ptr("eventId", e.EventId)
+	w.str("rawEvent", e.RawEvent)
+	w.time("start", e.StartTime)
+	e.walkAgentFields(w)
+	e.walkDestinationFields(w)
+	e.walkDeviceFields(w)
+	e.walkFileFields(w)
+	e.walkHttpFields(w)
+	e.walkSourceFields(w)
+	e.walkCustomFields(w)
+
+	if len(e.CustomExtensions) > 0 {
+		var buf [16]string // sort typical numbers of keys without allocating
+		keys := buf[:0]
+		for k := range e.CustomExtensions {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			w.custom(k, e.CustomExtensions[k])
+		}
 	}
-	return strings.TrimSpace(b.String())
+	return !w.stopped
 }
 
-func (e Extensions) marshalDeviceFields() string {
-	b := strings.Builder{}
+func (e Extensions) walkAgentFields(w *fieldWalker) {
+	w.str("agentDnsDomain", e.AgentDnsDomain)
+	w.str("agentNtDomain", e.AgentNtDomain)
+	w.ip("agentTranslatedAddress", e.AgentTranslatedAddress)
+	w.str("agentTranslatedZoneExternalID", e.AgentTranslatedZoneExternalId)
+	w.str("agentTranslatedZoneURI", e.AgentTranslatedZoneUri)
+	w.str("agentZoneExternalID", e.AgentZoneExternalId)
+	w.str("agentZoneURI", e.AgentZoneUri)
+	w.ip("agt", e.AgentAddress)
+	w.str("ahost", e.AgentHostName)
+	w.str("aid", e.AgentId)
+	w.mac("amac", e.AgentMacAddress)
+	w.time("art", e.AgentReceiptTime)
+	w.str("at", e.AgentType)
+	if e.AgentTimeZone != nil {
+		w.str("atz", e.AgentTimeZone.String())
+	}
+	w.str("av", e.AgentVersion)
+}
 
+func (e Extensions) walkDeviceFields(w *fieldWalker) {
 	if e.DeviceDirection != nil {
-		b.WriteString("deviceDirection=" + strconv.FormatUint(uint64(*e.DeviceDirection), 10) + " ")
-	}
-	if e.DeviceDnsDomain != "" {
-		b.WriteString("deviceDnsDomain=" + escapeExtensionField(e.DeviceDnsDomain) + " ")
-	}
-	if e.DeviceExternalId != "" {
-		b.WriteString("deviceExternalId=" + escapeExtensionField(e.DeviceExternalId) + " ")
-	}
-	if e.DeviceFacility != "" {
-		b.WriteString("deviceFacility=" + escapeExtensionField(e.DeviceFacility) + " ")
-	}
-	if e.DeviceInboundInterface != "" {
-		b.WriteString("deviceInboundInterface=" + escapeExtensionField(e.DeviceInboundInterface) + " ")
-	}
-	if e.DeviceNtDomain != "" {
-		b.WriteString("deviceNtInterface=" + escapeExtensionField(e.DeviceNtDomain) + " ")
-	}
-	if e.DeviceOutboundInterface != "" {
-		b.WriteString("deviceOutboundInterface=" + escapeExtensionField(e.DeviceOutboundInterface) + " ")
-	}
-	if e.DevicePayloadId != "" {
-		b.WriteString("devicePayloadId=" + escapeExtensionField(e.DevicePayloadId) + " ")
-	}
-	if e.DeviceProcessName != "" {
-		b.WriteString("deviceProcessName=" + escapeExtensionField(e.DeviceProcessName) + " ")
-	}
+		w.uint("deviceDirection", uint64(*e.DeviceDirection))
+	}
+	w.str("deviceDnsDomain", e.DeviceDnsDomain)
+	w.str("deviceExternalId", e.DeviceExternalId)
+	w.str("deviceFacility", e.DeviceFacility)
+	w.str("deviceInboundInterface", e.DeviceInboundInterface)
+	w.str("deviceNtDomain", e.DeviceNtDomain)
+	w.str("deviceOutboundInterface", e.DeviceOutboundInterface)
+	w.str("devicePayloadId", e.DevicePayloadId)
+	w.str("deviceProcessName", e.DeviceProcessName)
+	w.ip("deviceTranslatedAddress", e.DeviceTranslatedAddress)
+	w.str("deviceTranslatedZoneExternalID", e.DeviceTranslatedZoneExternalId)
+	w.str("deviceTranslatedZoneURI", e.DeviceTranslatedZoneUri)
+	w.str("deviceZoneExternalID", e.DeviceZoneExternalId)
+	w.str("deviceZoneURI", e.DeviceZoneUri)
 	if e.DeviceTimeZone != nil {
-		b.WriteString("dtz=" + escapeExtensionField(e.DeviceTimeZone.String()) + " ")
-	}
-	if str := e.DeviceAddress.String(); str != "<nil>" {
-		b.WriteString("dvc=" + str + " ")
+		w.str("dtz", e.DeviceTimeZone.String())
 	}
-	if e.DeviceHostName != "" {
-		b.WriteString("dcvhost=" + escapeExtensionField(e.DeviceHostName) + " ")
-	}
-	if len(e.DeviceMacAddress) != 0 {
-		b.WriteString("dvcmac=" + e.DeviceMacAddress.String() + " ")
-	}
-	if e.DeviceProcessId != nil {
-		b.WriteString("dvcpid=" + strconv.FormatUint(uint64(*e.DeviceProcessId), 10) + " ")
-	}
-	if !e.DeviceReceiptTime.IsZero() {
-		b.WriteString("rt=" + strconv.FormatInt(e.DeviceReceiptTime.UnixMilli(), 10) + " ")
-	}
-	// TODO add custom mapped fields
-	return b.String()
+	w.ip("dvc", e.DeviceAddress)
+	w.str("dvchost", e.DeviceHostName)
+	w.mac("dvcmac", e.DeviceMacAddress)
+	w.uintPtr("dvcpid", e.DeviceProcessId)
+	w.time("rt", e.DeviceReceiptTime)
 }
 
-func (e Extensions) marshalDestinationFields() string {
-	b := strings.Builder{}
-	if e.DestinationDnsDomain != "" {
-		b.WriteString("destinationDnsDomain=" + escapeExtensionField(e.DestinationDnsDomain) + " ")
-	}
-	if e.DestinationServiceName != "" {
-		b.WriteString("destinationServiceName=" + escapeExtensionField(e.DestinationServiceName) + " ")
-	}
-	if str := e.DestinationTranslatedAddress.String(); str != "<nil>" {
-		b.WriteString("destinationTranslatedAddress=" + escapeExtensionField(str) + " ")
-	}
-	if e.DestinationTranslatedPort != nil {
-		b.WriteString("destinationTranslatedPort=" + strconv.FormatUint(uint64(*e.DestinationTranslatedPort), 10) + " ")
-	}
-	if e.DestinationHostName != "" {
-		b.WriteString("dhost=" + escapeExtensionField(e.DestinationHostName) + " ")
-	}
-	if len(e.DestinationMacAddress) != 0 {
-		b.WriteString("dmac=" + e.DestinationMacAddress.String() + " ")
-	}
-	if e.DestinationNtDomain != "" {
-		b.WriteString("dntdom=" + escapeExtensionField(e.DestinationNtDomain) + " ")
-	}
-	if e.DestinationProcessId != nil {
-		b.WriteString("dpid=" + strconv.FormatUint(uint64(*e.DestinationProcessId), 10) + " ")
-	}
-	if e.DestinationUserPrivileges != "" {
-		b.WriteString("dpriv=" + escapeExtensionField(e.DestinationUserPrivileges) + " ")
-	}
-	if e.DestinationProcessName != "" {
-		b.WriteString("dproc=" + escapeExtensionField(e.DestinationProcessName) + " ")
-	}
-	if e.DestinationPort != nil {
-		b.WriteString("dpt=" + strconv.FormatUint(uint64(*e.DestinationPort), 10) + " ")
-	}
-	if str := e.DestinationAddress.String(); str != "<nil>" {
-		b.WriteString("dst=" + str + " ")
-	}
-	if e.DestinationUserId != "" {
-		b.WriteString("duid=" + escapeExtensionField(e.DestinationUserId) + " ")
-	}
-	// TODO add destination marshaling
+func (e Extensions) walkDestinationFields(w *fieldWalker) {
+	w.str("destinationDnsDomain", e.DestinationDnsDomain)
+	w.str("destinationServiceName", e.DestinationServiceName)
+	w.ip("destinationTranslatedAddress", e.DestinationTranslatedAddress)
+	w.uintPtr("destinationTranslatedPort", e.DestinationTranslatedPort)
+	w.str("destinationTranslatedZoneExternalID", e.DestinationTranslatedZoneExternalId)
+	w.str("destinationTranslatedZoneURI", e.DestinationTranslatedZoneUri)
+	w.str("destinationZoneExternalID", e.DestinationZoneExternalId)
+	w.str("destinationZoneURI", e.DestinationZoneUri)
+	w.str("dhost", e.DestinationHostName)
+	w.floatPtr("dlat", e.DestinationGeoLatitude)
+	w.floatPtr("dlong", e.DestinationGeoLongitude)
+	w.mac("dmac", e.DestinationMacAddress)
+	w.str("dntdom", e.DestinationNtDomain)
+	w.uintPtr("dpid", e.DestinationProcessId)
+	w.str("dpriv", e.DestinationUserPrivileges)
+	w.str("dproc", e.DestinationProcessName)
+	w.uintPtr("dpt", e.DestinationPort)
+	w.ip("dst", e.DestinationAddress)
+	w.str("duid", e.DestinationUserId)
+	w.str("duser", e.DestinationUserName)
+}
 
-	// TODO add custom mapped fields
+func (e Extensions) walkFileFields(w *fieldWalker) {
+	w.time("fileCreateTime", e.FileCreateTime)
+	w.str("fileHash", e.FileHash)
+	w.str("fileId", e.FileId)
+	w.time("fileModificationTime", e.FileModificationTime)
+	w.str("filePath", e.FilePath)
+	w.str("filePermission", e.FilePermission)
+	w.str("fileType", e.FileType)
+	w.str("fname", e.FileName)
+	w.uintPtr("fsize", e.FileSize)
+	w.time("oldFileCreateTime", e.OldFileCreateTime)
+	w.str("oldFileHash", e.OldFileHash)
+	w.str("oldFileId", e.OldFileId)
+	w.time("oldFileModificationTime", e.OldFileModificationTime)
+	w.str("oldFileName", e.OldFileName)
+	w.str("oldFilePath", e.OldFilePath)
+	w.str("oldFilePermission", e.OldFilePermission)
+	w.str("oldFileType", e.OldFileType)
+	w.uintPtr("oldFileSize", e.OldFileSize)
+}
 
-	return b.String()
+func (e Extensions) walkHttpFields(w *fieldWalker) {
+	if (url.URL{}) != e.RequestUrl {
+		w.str("request", e.RequestUrl.String())
+	}
+	w.str("requestClientApplication", e.RequestClientApplication)
+	w.str("requestContext", e.RequestContext)
+	w.str("requestCookies", e.RequestCookies)
+	w.str("requestMethod", e.RequestMethod)
 }
 
-func (e Extensions) marshalFileFields() string {
-	b := strings.Builder{}
+func (e Extensions) walkSourceFields(w *fieldWalker) {
+	w.str("shost", e.SourceHostName)
+	w.floatPtr("slat", e.SourceGeoLatitude)
+	w.floatPtr("slong", e.SourceGeoLongitude)
+	w.mac("smac", e.SourceMacAddress)
+	w.str("sntdom", e.SourceNtDomain)
+	w.str("sourceDnsDomain", e.SourceDnsDomain)
+	w.str("sourceServiceName", e.SourceServiceName)
+	w.ip("sourceTranslatedAddress", e.SourceTranslatedAddress)
+	w.uintPtr("sourceTranslatedPort", e.SourceTranslatedPort)
+	w.str("sourceTranslatedZoneExternalID", e.SourceTranslatedZoneExternalId)
+	w.str("sourceTranslatedZoneURI", e.SourceTranslatedZoneUri)
+	w.str("sourceZoneExternalID", e.SourceZoneExternalId)
+	w.str("sourceZoneURI", e.SourceZoneUri)
+	w.intPtr("spid", e.SourceProcessId)
+	w.str("spriv", e.SourceUserPrivileges)
+	w.str("sproc", e.SourceProcessName)
+	w.uintPtr("spt", e.SourcePort)
+	w.ip("src", e.SourceAddress)
+	w.str("suid", e.SourceUserId)
+	w.str("suser", e.SourceUserName)
+}
 
-	if !e.FileCreateTime.IsZero() {
-		b.WriteString("fileCreateTime=" + strconv.FormatInt(e.FileCreateTime.UnixMilli(), 10) + " ")
-	}
-	if e.FileHash != "" {
-		b.WriteString("fileHash=" + escapeExtensionField(e.FileHash) + " ")
-	}
-	if e.FileId != "" {
-		b.WriteString("fileId=" + escapeExtensionField(e.FileId) + " ")
-	}
-	if !e.FileModificationTime.IsZero() {
-		b.WriteString("fileModificationTime=" + strconv.FormatInt(e.FileModificationTime.UnixMilli(), 10) + " ")
-	}
-	if e.FilePath != "" {
-		b.WriteString("filePath=" + escapeExtensionField(e.FilePath) + " ")
-	}
-	if e.FilePermission != "" {
-		b.WriteString("filePermission=" + escapeExtensionField(e.FilePermission) + " ")
-	}
-	if e.FileType != "" {
-		b.WriteString("fileType=" + escapeExtensionField(e.FileType) + " ")
-	}
-	if e.FileName != "" {
-		b.WriteString("fname=" + escapeExtensionField(e.FileName) + " ")
-	}
-	if e.FileSize != nil {
-		b.WriteString("fsize=" + strconv.FormatUint(uint64(*e.FileSize), 10) + " ")
-	}
-	if !e.OldFileCreateTime.IsZero() {
-		b.WriteString("oldFileCreateTime=" + strconv.FormatInt(e.OldFileCreateTime.UnixMilli(), 10) + " ")
-	}
-	if e.OldFileHash != "" {
-		b.WriteString("oldFileHash=" + escapeExtensionField(e.OldFileHash) + " ")
-	}
-	if e.OldFileId != "" {
-		b.WriteString("oldFileId=" + escapeExtensionField(e.OldFileId) + " ")
-	}
-	if !e.OldFileModificationTime.IsZero() {
-		b.WriteString("oldFileModificationTime=" + strconv.FormatInt(e.OldFileModificationTime.UnixMilli(), 10) + " ")
-	}
-	if e.OldFileName != "" {
-		b.WriteString("oldFileName=" + escapeExtensionField(e.OldFileName) + " ")
-	}
-	if e.OldFilePath != "" {
-		b.WriteString("oldFilePath=" + escapeExtensionField(e.OldFilePath) + " ")
+// fieldKind identifies the type of value held by a fieldValue
+type fieldKind uint8
+
+const (
+	stringKind fieldKind = iota
+	intKind
+	uintKind
+	timeKind
+	ipKind
+	macKind
+	floatKind
+)
+
+// fieldValue is a single populated extension value. Values are kept in their native type so each encoder can decide
+// how they are formatted.
+type fieldValue struct {
+	kind fieldKind
+	s    string
+	i    int64
+	u    uint64
+	t    time.Time
+	ip   net.IP
+	mac  net.HardwareAddr
+	f    float64
+}
+
+// String formats the value as it appears in a CEF extension, before escaping. Times are formatted as milliseconds since
+// the unix epoch.
+func (v fieldValue) String() string {
+	switch v.kind {
+	case intKind:
+		return strconv.FormatInt(v.i, 10)
+	case uintKind:
+		return strconv.FormatUint(v.u, 10)
+	case timeKind:
+		return strconv.FormatInt(v.t.UnixMilli(), 10)
+	case ipKind:
+		return v.ip.String()
+	case macKind:
+		return v.mac.String()
+	case floatKind:
+		return strconv.FormatFloat(v.f, 'f', -1, 64)
+	default:
+		return v.s
 	}
-	if e.OldFilePermission != "" {
-		b.WriteString("oldFilePermission=" + escapeExtensionField(e.OldFilePermission) + " ")
+}
+
+// appendTo appends the formatted value to b, escaped for use as an extension value
+func (v fieldValue) appendTo(b []byte) []byte {
+	switch v.kind {
+	case intKind:
+		return strconv.AppendInt(b, v.i, 10)
+	case uintKind:
+		return strconv.AppendUint(b, v.u, 10)
+	case timeKind:
+		return strconv.AppendInt(b, v.t.UnixMilli(), 10)
+	case floatKind:
+		return strconv.AppendFloat(b, v.f, 'f', -1, 64)
+	case stringKind:
+		return appendEscapedExtension(b, v.s)
+	case ipKind:
+		if addr, ok := netip.AddrFromSlice(v.ip); ok {
+			return addr.Unmap().AppendTo(b)
+		}
+	case macKind:
+		const hexDigits = "0123456789abcdef"
+		for i, c := range v.mac {
+			if i > 0 {
+				b = append(b, ':')
+			}
+			b = append(b, hexDigits[c>>4], hexDigits[c&0xf])
+		}
+		return b
 	}
-	if e.OldFileType != "" {
-		b.WriteString("oldFileType=" + escapeExtensionField(e.OldFileType) + " ")
+	return appendEscapedExtension(b, v.String())
+}
+
+// fieldWalker passes populated fields to yield, skipping zero values and ignoring everything once yield has returned
+// false
+type fieldWalker struct {
+	yield   func(key string, v fieldValue) bool
+	stopped bool
+}
+
+func (w *fieldWalker) emit(key string, v fieldValue) {
+	if !w.stopped && !w.yield(key, v) {
+		w.stopped = true
 	}
-	if e.OldFileSize != nil {
-		b.WriteString("oldFileSize=" + strconv.FormatUint(uint64(*e.OldFileSize), 10) + " ")
+}
+
+func (w *fieldWalker) str(key, v string) {
+	if v != "" {
+		w.emit(key, fieldValue{kind: stringKind, s: v})
 	}
+}
 
-	return b.String()
+func (w *fieldWalker) int(key string, v int64) {
+	w.emit(key, fieldValue{kind: intKind, i: v})
 }
 
-func (e Extensions) marshalHttpFields() string {
-	b := strings.Builder{}
-	if (url.URL{}) != e.RequestUrl {
-		b.WriteString("request=" + escapeExtensionField(e.RequestUrl.String()) + " ")
+func (w *fieldWalker) uint(key string, v uint64) {
+	w.emit(key, fieldValue{kind: uintKind, u: v})
+}
+
+func (w *fieldWalker) int64Ptr(key string, v *int64) {
+	if v != nil {
+		w.int(key, *v)
 	}
-	if e.RequestClientApplication != "" {
-		b.WriteString("requestClientApplication=" + escapeExtensionField(e.RequestClientApplication) + " ")
+}
+
+func (w *fieldWalker) floatPtr(key string, v *float64) {
+	if v != nil {
+		w.emit(key, fieldValue{kind: floatKind, f: *v})
 	}
-	if e.RequestContext != "" {
-		b.WriteString("requestContext=" + escapeExtensionField(e.RequestContext) + " ")
+}
+
+func (w *fieldWalker) intPtr(key string, v *int) {
+	if v != nil {
+		w.int(key, int64(*v))
 	}
-	if e.RequestCookies != "" {
-		b.WriteString("requestCookies=" + escapeExtensionField(e.RequestCookies) + " ")
+}
+
+func (w *fieldWalker) uintPtr(key string, v *uint) {
+	if v != nil {
+		w.uint(key, uint64(*v))
 	}
-	if e.RequestMethod != "" {
-		b.WriteString("requestMethod=" + escapeExtensionField(e.RequestMethod) + " ")
+}
+
+func (w *fieldWalker) time(key string, v time.Time) {
+	if !v.IsZero() {
+		w.emit(key, fieldValue{kind: timeKind, t: v})
 	}
+}
 
-	return b.String()
+func (w *fieldWalker) ip(key string, v net.IP) {
+	if len(v) != 0 {
+		w.emit(key, fieldValue{kind: ipKind, ip: v})
+	}
 }
 
-func (e Extensions) marshalSourceFields() string {
-	b := strings.Builder{}
-	// TODO implement
+func (w *fieldWalker) mac(key string, v net.HardwareAddr) {
+	if len(v) != 0 {
+		w.emit(key, fieldValue{kind: macKind, mac: v})
+	}
+}
 
-	return b.String()
+// custom emits a CustomExtensions entry. Unlike standard fields these are included even when empty.
+func (w *fieldWalker) custom(key, v string) {
+	w.emit(key, fieldValue{kind: stringKind, s: v})
+}
+
+// escapedExtensionLen returns len(escapeExtensionField(f)) without allocating
+func escapedExtensionLen(f string) int {
+	n := 0
+	for _, r := range f {
+		switch r {
+		case '\n', '\r', '=', '\\':
+			n += 2
+		default:
+			n += utf8.RuneLen(r) // invalid bytes are re-encoded as utf8.RuneError
+		}
+	}
+	return n
 }
 
 func escapeExtensionField(f string) string {
-	b := strings.Builder{}
-	for _, r := range []rune(f) {
+	return string(appendEscapedExtension(nil, f))
+}
+
+// appendEscapedExtension appends f to b, escaped for use as an extension key or value
+func appendEscapedExtension(b []byte, f string) []byte {
+	for i, r := range f {
 		switch r {
 		case '\n':
-			b.WriteString(`\n`)
+			b = append(b, `\n`...)
 		case '\r':
-			b.WriteString(`\r`)
+			b = append(b, `\r`...)
 		case '=':
-			b.WriteString(`\=`)
+			b = append(b, `\=`...)
 		case '\\':
-			b.WriteString(`\\`)
+			b = append(b, `\\`...)
+		case utf8.RuneError:
+			b = utf8.AppendRune(b, r) // invalid bytes are re-encoded
 		default:
-			b.WriteRune(r)
+			b = append(b, f[i:i+utf8.RuneLen(r)]...)
 		}
 	}
-	return b.String()
+	return b
 }
@@ -2,7 +2,10 @@ package cefevent
 
 import (
 	"net"
+	"net/url"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -86,6 +89,74 @@ func TestExtensions_String(t *testing.T) {
 			},
 			"fileCreateTime=1699530320000 fileId=6452 fileModificationTime=1699530320000 fileType=normal fname=example.txt fsize=2048",
 		},
+		{
+			"source_address",
+			Extensions{
+				TransportProtocol: "TCP",
+				SourceAddress:     net.IP{10, 0, 0, 7},
+				SourcePort:        ptr(uint(51234)),
+				DestinationPort:   ptr(uint(443)),
+			},
+			"proto=TCP dpt=443 spt=51234 src=10.0.0.7",
+		},
+		{
+			"source_fields",
+			Extensions{
+				SourceHostName:          "client.example.com",
+				SourceMacAddress:        net.HardwareAddr{0x00, 0x0d, 0x60, 0xaf, 0x1b, 0x61},
+				SourceNtDomain:          "CORP",
+				SourceDnsDomain:         "example.com",
+				SourceServiceName:       "sshd",
+				SourceTranslatedAddress: net.IP{203, 0, 113, 5},
+				SourceTranslatedPort:    ptr(uint(40000)),
+				SourceProcessId:         ptr(1234),
+				SourceUserPrivileges:    "Administrator",
+				SourceUserId:            "0",
+				SourceUserName:          "root",
+			},
+			"shost=client.example.com smac=00:0d:60:af:1b:61 sntdom=CORP sourceDnsDomain=example.com sourceServiceName=sshd " +
+				"sourceTranslatedAddress=203.0.113.5 sourceTranslatedPort=40000 spid=1234 spriv=Administrator suid=0 suser=root",
+		},
+		{
+			"http_fields",
+			Extensions{
+				RequestUrl:               url.URL{Scheme: "https", Host: "example.com", Path: "/login", RawQuery: "next=/"},
+				RequestClientApplication: "curl/8.0",
+				RequestContext:           "https://example.com/",
+				RequestCookies:           "session=abc",
+				RequestMethod:            "POST",
+			},
+			"request=https://example.com/login?next\\=/ requestClientApplication=curl/8.0 requestContext=https://example.com/ " +
+				"requestCookies=session\\=abc requestMethod=POST",
+		},
+		{
+			"general_fields",
+			Extensions{
+				DeviceEventCategory: "/Monitor/Disk/Read",
+				CustomerExternalId:  "tenant-1",
+				EventId:             ptr(int64(42)),
+				RawEvent:            "raw",
+				StartTime:           testTime(),
+				DestinationUserName: "alice",
+				SourceProcessName:   "bash",
+			},
+			"cat=/Monitor/Disk/Read customerExternalID=tenant-1 eventId=42 rawEvent=raw start=1699530320000 duser=alice sproc=bash",
+		},
+		{
+			"agent_geo_zone_fields",
+			Extensions{
+				AgentAddress:            net.IP{10, 0, 0, 2},
+				AgentHostName:           "collector",
+				AgentTimeZone:           time.UTC,
+				DestinationGeoLatitude:  ptr(51.5072),
+				DestinationGeoLongitude: ptr(-0.1276),
+				DeviceTranslatedAddress: net.IP{192, 0, 2, 1},
+				DeviceZoneUri:           "/All Zones/DMZ",
+				SourceZoneExternalId:    "zone-7",
+			},
+			"agt=10.0.0.2 ahost=collector atz=UTC dlat=51.5072 dlong=-0.1276 deviceTranslatedAddress=192.0.2.1 " +
+				"deviceZoneURI=/All Zones/DMZ sourceZoneExternalID=zone-7",
+		},
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
@@ -95,7 +166,228 @@ func TestExtensions_String(t *testing.T) {
 	}
 }
 
-// ptr is a convenience function to convert literal values to pointers
-func ptr[A any](v A) *A {
-	return &v
+func TestExtensions_walk(t *testing.T) {
+	e := Extensions{
+		Message:           "hello",
+		DestinationPort:   ptr(uint(443)),
+		DeviceReceiptTime: testTime(),
+	}
+	var keys []string
+	completed := e.walk(func(key string, v fieldValue) bool {
+		keys = append(keys, key+"="+v.String())
+		return true
+	})
+	assert.True(t, completed)
+	assert.Equal(t, []string{"msg=hello", "dpt=443", "rt=1699530320000"}, keys)
+
+	keys = nil
+	completed = e.walk(func(key string, v fieldValue) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	assert.False(t, completed)
+	assert.Equal(t, []string{"msg", "dpt"}, keys)
+}
+
+func TestExtensions_Fields(t *testing.T) {
+	e := Extensions{
+		Message:            "a=b",
+		DestinationAddress: net.IP{10, 0, 0, 1},
+		EndTime:            testTime(),
+		CustomExtensions:   map[string]string{"extra": "value"},
+	}
+	got := map[string]string{}
+	for k, v := range e.Fields() {
+		got[k] = v
+	}
+	assert.Equal(t, map[string]string{
+		"msg":   "a=b",
+		"end":   "1699530320000",
+		"dst":   "10.0.0.1",
+		"extra": "value",
+	}, got)
+
+	var keys []string
+	for k := range e.Fields() {
+		keys = append(keys, k)
+		break
+	}
+	assert.Equal(t, []string{"msg"}, keys)
+}
+
+func TestExtensions_Clone(t *testing.T) {
+	// Populate every reference type field so new fields can't be missed by Clone
+	e := Extensions{}
+	ev := reflect.ValueOf(&e).Elem()
+	for i := 0; i < ev.NumField(); i++ {
+		f := ev.Field(i)
+		switch f.Kind() {
+		case reflect.Pointer:
+			f.Set(reflect.New(f.Type().Elem()))
+		case reflect.Slice:
+			f.Set(reflect.MakeSlice(f.Type(), 4, 4))
+		case reflect.Map:
+			f.Set(reflect.MakeMap(f.Type()))
+		}
+	}
+	e.DeviceTimeZone = time.UTC
+	e.AgentTimeZone = time.UTC
+
+	c := e.Clone()
+	assert.Equal(t, e, c)
+	cv := reflect.ValueOf(c)
+	for i := 0; i < ev.NumField(); i++ {
+		name := ev.Type().Field(i).Name
+		if name == "DeviceTimeZone" || name == "AgentTimeZone" {
+			continue // immutable, shared by design
+		}
+		switch ev.Field(i).Kind() {
+		case reflect.Pointer, reflect.Map:
+			assert.NotEqual(t, ev.Field(i).Pointer(), cv.Field(i).Pointer(), "%s is aliased", name)
+		case reflect.Slice:
+			assert.NotEqual(t, ev.Field(i).Index(0).Addr().Pointer(), cv.Field(i).Index(0).Addr().Pointer(), "%s is aliased", name)
+		}
+	}
+
+	assert.Equal(t, Extensions{}, Extensions{}.Clone())
+}
+
+func TestExtensions_String_customOrder(t *testing.T) {
+	e := Extensions{
+		Message:          "hi",
+		CustomExtensions: map[string]string{"zeta": "1", "alpha": "2", "Beta": "3", "mid": "4", "alpha2": "5"},
+	}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "msg=hi Beta=3 alpha=2 alpha2=5 mid=4 zeta=1", e.String())
+	}
+	assert.Equal(t, []string{"msg", "Beta", "alpha", "alpha2", "mid", "zeta"}, e.PopulatedFields())
+}
+
+func TestExtensions_AppendCEF(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Extensions
+		want string
+	}{
+		{"empty", Extensions{}, ""},
+		{"escaped", Extensions{Message: "a=b\\c\n", DeviceCustomString1: Labeled("l", "v")}, `msg=a\=b\\c\n cs1=v cs1Label=l`},
+		{"ipv4", Extensions{SourceAddress: net.IPv4(10, 0, 0, 1)}, "src=10.0.0.1"},
+		{"ipv4_4byte", Extensions{SourceAddress: net.IP{10, 0, 0, 1}}, "src=10.0.0.1"},
+		{"ipv6", Extensions{SourceAddress: net.ParseIP("2001:db8::1")}, "src=2001:db8::1"},
+		{"mac", Extensions{SourceMacAddress: net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0xfe}}, "smac=00:1a:2b:3c:4d:fe"},
+		{"eui64", Extensions{SourceMacAddress: net.HardwareAddr{1, 2, 3, 4, 5, 6, 7, 8}}, "smac=01:02:03:04:05:06:07:08"},
+		{"numbers", Extensions{DestinationPort: ptr(uint(443)), DeviceCustomFloatingPoint1: Labeled("f", 1.5)}, "dpt=443 cfp1=1.5 cfp1Label=f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.e.AppendCEF([]byte("prefix|"))
+			assert.Equal(t, "prefix|"+tt.want, string(got))
+			assert.Equal(t, tt.want, tt.e.String())
+		})
+	}
+}
+
+func TestExtensions_AppendCEF_allocs(t *testing.T) {
+	e := Extensions{
+		Message:             "login failed",
+		SourceAddress:       net.IPv4(10, 0, 0, 1),
+		SourceMacAddress:    net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DestinationPort:     ptr(uint(22)),
+		DeviceReceiptTime:   testTime(),
+		DeviceCustomString1: Labeled("policy", "deny"),
+		CustomExtensions:    map[string]string{"b": "1", "a": "2"},
+	}
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = e.AppendCEF(buf[:0])
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestExtensions_Merge(t *testing.T) {
+	base := Extensions{
+		DeviceHostName:   "edge-1",
+		DeviceFacility:   "auth",
+		DeviceProcessId:  ptr(uint(1)),
+		CustomExtensions: map[string]string{"a": "1", "b": "1"},
+	}
+	override := Extensions{
+		DeviceFacility:   "kern",
+		Message:          "hi",
+		CustomExtensions: map[string]string{"b": "2"},
+	}
+	want := Extensions{
+		DeviceHostName:   "edge-1",
+		DeviceFacility:   "kern",
+		DeviceProcessId:  ptr(uint(1)),
+		Message:          "hi",
+		CustomExtensions: map[string]string{"a": "1", "b": "2"},
+	}
+	m := base.Merge(override)
+	assert.Equal(t, want, m)
+	assert.NotSame(t, base.DeviceProcessId, m.DeviceProcessId)
+	assert.Equal(t, map[string]string{"a": "1", "b": "1"}, base.CustomExtensions)
+	assert.Equal(t, base, base.Merge(Extensions{}))
+	assert.Equal(t, override, Extensions{}.Merge(override))
+}
+
+func TestExtensions_IsEmpty(t *testing.T) {
+	assert.True(t, Extensions{}.IsEmpty())
+	assert.True(t, Extensions{BaseEventCount: 1, CustomExtensions: map[string]string{}}.IsEmpty(), "omitted fields don't count")
+	assert.False(t, Extensions{DeviceDirection: ptr(uint8(0))}.IsEmpty())
+	assert.False(t, Extensions{CustomExtensions: map[string]string{"extra": ""}}.IsEmpty())
+}
+
+func TestExtensions_PopulatedFields(t *testing.T) {
+	assert.Nil(t, Extensions{}.PopulatedFields())
+	e := Extensions{
+		Message:          "hello",
+		FileName:         "example.txt",
+		DeviceAddress:    net.IP{10, 0, 0, 1},
+		CustomExtensions: map[string]string{"extra": "value"},
+	}
+	assert.Equal(t, []string{"msg", "dvc", "fname", "extra"}, e.PopulatedFields())
+}
+
+func TestExtensions_EncodedLen(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Extensions
+	}{
+		{
+			"empty",
+			Extensions{},
+		},
+		{
+			"escaped",
+			Extensions{
+				Message:          "a=b\\c\r\nd",
+				DestinationPort:  ptr(uint(8080)),
+				CustomExtensions: map[string]string{"k=1": "v\n"},
+			},
+		},
+		{
+			"multibyte",
+			Extensions{Message: "héllo wörld", EndTime: testTime(), DeviceMacAddress: net.HardwareAddr{1, 2, 3, 4, 5, 6}},
+		},
+		{
+			"invalid_utf8",
+			Extensions{Message: "bad\xffbyte"},
+		},
+		{
+			"legacy_keys",
+			Extensions{DeviceNtDomain: "CORP", DeviceHostName: "host-1"},
+		},
+		{
+			"legacy_keys_mixed",
+			Extensions{Message: "hi", DeviceNtDomain: "CORP", SourceHostName: "client", DeviceHostName: "host-1",
+				CustomExtensions: map[string]string{"dvchost": "custom"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, len(tt.e.String()), tt.e.EncodedLen())
+			assert.Equal(t, len(tt.e.format(nil)), tt.e.encodedLen(nil), "standard keys")
+		})
+	}
 }
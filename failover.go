@@ -0,0 +1,138 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AllWritersFailedErr error when a FailoverWriter couldn't write a record to any of its writers
+var AllWritersFailedErr = errors.New("all writers failed")
+
+// FailoverWriter writes each record to a primary writer, failing over to the first of its secondaries which accepts the
+// record when the primary fails, e.g. from a SyslogWriter to a local RotatingFileWriter spool. While failed over, the
+// higher priority writers are probed every probe interval by writing the next record to them, switching back as soon as
+// one accepts it. Use it as a Logger's output. It is safe to use from multiple goroutines.
+type FailoverWriter struct {
+	writers       []io.Writer // primary first
+	probeInterval time.Duration
+	replayLimit   int // 0 unless replaying
+
+	// now is overridden in tests
+	now func() time.Time
+
+	mu         sync.Mutex
+	active     int
+	nextProbe  time.Time
+	spool      [][]byte
+	spoolBytes int
+}
+
+// FailoverOption configures a FailoverWriter
+type FailoverOption func(w *FailoverWriter)
+
+// WithProbeInterval sets how often a failed over writer tries the writers ahead of the one in use. Defaults to 30s
+func WithProbeInterval(d time.Duration) FailoverOption {
+	return func(w *FailoverWriter) {
+		w.probeInterval = d
+	}
+}
+
+// WithFailoverReplay keeps up to maxBytes of the records written while failed over, and writes them to the recovered
+// writer before any new records, so it isn't missing the events logged during the outage. The oldest records are
+// discarded once the limit is reached.
+func WithFailoverReplay(maxBytes int) FailoverOption {
+	return func(w *FailoverWriter) {
+		w.replayLimit = maxBytes
+	}
+}
+
+// NewFailoverWriter returns a writer sending records to primary, or to secondaries in order when primary fails
+func NewFailoverWriter(primary io.Writer, secondaries []io.Writer, opts ...FailoverOption) *FailoverWriter {
+	w := &FailoverWriter{
+		writers:       append([]io.Writer{primary}, secondaries...),
+		probeInterval: 30 * time.Second,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Write writes p to the writer in use, failing over to the next writer if that fails. It only returns an error,
+// wrapping AllWritersFailedErr, if no writer accepted p.
+func (w *FailoverWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if w.active > 0 && !now.Before(w.nextProbe) {
+		w.probe()
+		w.nextProbe = now.Add(w.probeInterval)
+	}
+	var errs []error
+	for i := w.active; i < len(w.writers); i++ {
+		if err := writeAll(w.writers[i], p); err != nil {
+			errs = append(errs, fmt.Errorf("writer %d: %w", i, err))
+			continue
+		}
+		if i != w.active {
+			w.active = i
+			w.nextProbe = now.Add(w.probeInterval)
+		}
+		if i > 0 && w.replayLimit > 0 {
+			w.spoolRecord(p)
+		}
+		return len(p), nil
+	}
+	return 0, fmt.Errorf("%w: %w", AllWritersFailedErr, errors.Join(errs...))
+}
+
+// probe switches back to the highest priority writer which accepts the spooled records. Without replay, that's the
+// primary; Write falls through to the next writer if it is still failing.
+func (w *FailoverWriter) probe() {
+	for i := 0; i < w.active; i++ {
+		n := 0
+		for n < len(w.spool) && writeAll(w.writers[i], w.spool[n]) == nil {
+			n++
+		}
+		if n < len(w.spool) {
+			continue
+		}
+		w.active = i
+		w.spool, w.spoolBytes = nil, 0
+		return
+	}
+}
+
+// spoolRecord keeps a copy of p for replay, discarding the oldest records over the limit
+func (w *FailoverWriter) spoolRecord(p []byte) {
+	w.spool = append(w.spool, append([]byte(nil), p...))
+	w.spoolBytes += len(p)
+	for w.spoolBytes > w.replayLimit && len(w.spool) > 0 {
+		w.spoolBytes -= len(w.spool[0])
+		w.spool = w.spool[1:]
+	}
+}
+
+// Active returns the index of the writer in use, 0 for the primary or 1+i for secondaries[i]
+func (w *FailoverWriter) Active() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active
+}
+
+// Close closes each writer which implements io.Closer
+func (w *FailoverWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var errs []error
+	for _, out := range w.writers {
+		if c, ok := out.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
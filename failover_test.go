@@ -0,0 +1,99 @@
+package cefevent
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyWriter records writes, failing while down is set
+type flakyWriter struct {
+	mu      sync.Mutex
+	down    bool
+	records []string
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return 0, errors.New("connection refused")
+	}
+	f.records = append(f.records, string(p))
+	return len(p), nil
+}
+
+func (f *flakyWriter) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func TestFailoverWriter_Write(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	primary, secondary := &flakyWriter{}, &flakyWriter{}
+	w := NewFailoverWriter(primary, []io.Writer{secondary}, WithProbeInterval(time.Minute))
+	w.now = clock.Now
+
+	write := func(record string) {
+		t.Helper()
+		n, err := w.Write([]byte(record))
+		require.NoError(t, err)
+		assert.Equal(t, len(record), n)
+	}
+	write("1")
+	primary.setDown(true)
+	write("2")
+	assert.Equal(t, 1, w.Active())
+	primary.setDown(false)
+	write("3") // not probed until the interval elapses
+	clock.now = clock.now.Add(time.Minute)
+	write("4")
+	assert.Equal(t, 0, w.Active())
+
+	assert.Equal(t, []string{"1", "4"}, primary.records)
+	assert.Equal(t, []string{"2", "3"}, secondary.records)
+}
+
+func TestFailoverWriter_Write_replay(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	primary, secondary := &flakyWriter{down: true}, &flakyWriter{}
+	w := NewFailoverWriter(primary, []io.Writer{secondary}, WithProbeInterval(time.Minute), WithFailoverReplay(2))
+	w.now = clock.Now
+
+	for _, record := range []string{"1", "2", "3"} {
+		_, err := w.Write([]byte(record))
+		require.NoError(t, err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	_, err := w.Write([]byte("4")) // still down, so stays on the secondary
+	require.NoError(t, err)
+	assert.Equal(t, 1, w.Active())
+
+	primary.setDown(false)
+	clock.now = clock.now.Add(time.Minute)
+	_, err = w.Write([]byte("5"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "4", "5"}, primary.records, "oldest records over the replay limit discarded")
+	assert.Equal(t, []string{"1", "2", "3", "4"}, secondary.records)
+}
+
+func TestFailoverWriter_Write_allFail(t *testing.T) {
+	primary, secondary := &flakyWriter{down: true}, &flakyWriter{down: true}
+	w := NewFailoverWriter(primary, []io.Writer{secondary})
+	_, err := w.Write([]byte("1"))
+	assert.ErrorIs(t, err, AllWritersFailedErr)
+	assert.EqualError(t, err, "all writers failed: writer 0: connection refused\nwriter 1: connection refused")
+}
+
+func TestFailoverWriter_Logger(t *testing.T) {
+	primary, secondary := &flakyWriter{down: true}, &flakyWriter{}
+	l := NewLogger(NewFailoverWriter(primary, []io.Writer{secondary}), "v", "p", "1", OmitSyslogHeader())
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Equal(t, []string{"CEF:1|v|p|1|100|test|Low|"}, secondary.records)
+}
@@ -0,0 +1,84 @@
+package cefevent
+
+import (
+	"context"
+	"slices"
+)
+
+// Predicate reports whether an event matches, for filtering or routing the events in a Pipeline
+type Predicate func(e *Event) bool
+
+// Filter is a Stage keeping only the events matching p
+func Filter(p Predicate) Stage {
+	return func(_ context.Context, e *Event) (bool, error) {
+		return p(e), nil
+	}
+}
+
+// Route is a Stage writing the events matching p to to instead of the Pipeline's EventWriter, e.g. to send high
+// severity events to another collector. Later stages only see the events which didn't match. An error writing to to
+// is returned for the event.
+func Route(p Predicate, to EventWriter) Stage {
+	return func(ctx context.Context, e *Event) (bool, error) {
+		if !p(e) {
+			return true, nil
+		}
+		return false, to.WriteEvent(ctx, *e)
+	}
+}
+
+// MatchClassID matches events with any of the given device event class IDs
+func MatchClassID(ids ...string) Predicate {
+	return func(e *Event) bool {
+		return slices.Contains(ids, e.DeviceEventClassId)
+	}
+}
+
+// MatchSeverityAtLeast matches events at least as severe as sev, compared on the spec's 0-10 scale as WithMinSeverity
+// does. Events with an invalid severity never match, and if sev is invalid nothing does.
+func MatchSeverityAtLeast(sev string) Predicate {
+	min, minErr := severityLevel(sev)
+	return func(e *Event) bool {
+		level, err := severityLevel(e.Severity)
+		return minErr == nil && err == nil && level >= min
+	}
+}
+
+// MatchField matches events where the field key, looked up as by Event.Field, is set to value
+func MatchField(key, value string) Predicate {
+	return func(e *Event) bool {
+		v, ok := e.Field(key)
+		return ok && v == value
+	}
+}
+
+// And matches events matching all of ps
+func And(ps ...Predicate) Predicate {
+	return func(e *Event) bool {
+		for _, p := range ps {
+			if !p(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches events matching any of ps
+func Or(ps ...Predicate) Predicate {
+	return func(e *Event) bool {
+		for _, p := range ps {
+			if p(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches events not matching p
+func Not(p Predicate) Predicate {
+	return func(e *Event) bool {
+		return !p(e)
+	}
+}
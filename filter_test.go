@@ -0,0 +1,71 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicates(t *testing.T) {
+	e, err := Parse("CEF:0|v|p|1|100|login|High|src=10.0.0.1 act=deny")
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		p    Predicate
+		want bool
+	}{
+		{"class_id", MatchClassID("200", "100"), true},
+		{"class_id_other", MatchClassID("200"), false},
+		{"severity_equal", MatchSeverityAtLeast(HighSeverity), true},
+		{"severity_numeric", MatchSeverityAtLeast("7"), true},
+		{"severity_higher", MatchSeverityAtLeast(VeryHighSeverity), false},
+		{"severity_invalid", MatchSeverityAtLeast("Critical"), false},
+		{"field", MatchField("src", "10.0.0.1"), true},
+		{"field_full_name", MatchField("deviceAction", "deny"), true},
+		{"field_other", MatchField("src", "10.0.0.2"), false},
+		{"field_unset", MatchField("dst", ""), false},
+		{"and", And(MatchClassID("100"), MatchField("act", "deny")), true},
+		{"and_one", And(MatchClassID("100"), MatchField("act", "allow")), false},
+		{"and_none", And(), true},
+		{"or", Or(MatchClassID("200"), MatchField("act", "deny")), true},
+		{"or_none", Or(), false},
+		{"not", Not(MatchClassID("100")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.p(&e))
+		})
+	}
+
+	invalid := Event{Severity: "Critical"}
+	assert.False(t, MatchSeverityAtLeast(UnknownSeverity)(&invalid))
+}
+
+func TestFilter(t *testing.T) {
+	out := &eventRecorder{}
+	p := NewPipeline(NewScanner(strings.NewReader(pipelineInput)), out,
+		WithStages(Filter(Or(MatchClassID("200"), MatchField("src", "10.0.0.3")))))
+	require.NoError(t, p.Run(context.Background()))
+	assert.Equal(t, []string{"two", "three"}, out.names())
+}
+
+func TestRoute(t *testing.T) {
+	out, high := &eventRecorder{}, &eventRecorder{}
+	p := NewPipeline(NewScanner(strings.NewReader(pipelineInput)), out,
+		WithStages(Route(MatchSeverityAtLeast(HighSeverity), high)))
+	require.NoError(t, p.Run(context.Background()))
+	assert.Equal(t, []string{"one", "three"}, out.names())
+	assert.Equal(t, []string{"two"}, high.names())
+
+	failing := EventWriterFunc(func(context.Context, Event) error {
+		return errors.New("failed")
+	})
+	e := Event{Severity: HighSeverity}
+	keep, err := Route(MatchSeverityAtLeast(HighSeverity), failing)(context.Background(), &e)
+	assert.False(t, keep)
+	assert.EqualError(t, err, "failed")
+}
@@ -0,0 +1,275 @@
+package cefevent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Device event class IDs for events logged by FileMonitor
+const (
+	FileCreatedClassId  = "file-created"
+	FileModifiedClassId = "file-modified"
+	FileDeletedClassId  = "file-deleted"
+)
+
+// FileWatcher detects changes to files. Implementations can wrap OS notification APIs; PollingWatcher is a portable
+// implementation which periodically scans the file system.
+type FileWatcher interface {
+	// Watch calls changed with the path of each file created, modified or deleted beneath paths until ctx is done,
+	// then returns ctx.Err(). changed may be called for paths that turn out to be unchanged, and may be called
+	// concurrently.
+	Watch(ctx context.Context, paths []string, changed func(path string)) error
+}
+
+// FileMonitor watches files for changes, logging a CEF event with the File fields describing the file's new state and
+// the OldFile fields describing its previous state on each change. Directories are watched recursively, but only
+// changes to the files within them are logged.
+type FileMonitor struct {
+	logger   *Logger
+	watcher  FileWatcher
+	paths    []string
+	newHash  func() hash.Hash
+	severity string
+
+	mu    sync.Mutex
+	files map[string]fileState
+}
+
+// FileMonitorOption configures a FileMonitor
+type FileMonitorOption func(m *FileMonitor)
+
+// WithFileHash sets the hash used for the fileHash & oldFileHash fields. Defaults to SHA-256; nil disables hashing.
+func WithFileHash(newHash func() hash.Hash) FileMonitorOption {
+	return func(m *FileMonitor) {
+		m.newHash = newHash
+	}
+}
+
+// WithFileSeverity overrides the severity of file change events. Defaults to MediumSeverity
+func WithFileSeverity(severity string) FileMonitorOption {
+	return func(m *FileMonitor) {
+		m.severity = severity
+	}
+}
+
+// NewFileMonitor creates a FileMonitor logging changes to paths to l, as detected by w
+func NewFileMonitor(l *Logger, w FileWatcher, paths []string, opts ...FileMonitorOption) *FileMonitor {
+	m := &FileMonitor{
+		logger:   l,
+		watcher:  w,
+		paths:    paths,
+		newHash:  sha256.New,
+		severity: MediumSeverity,
+		files:    make(map[string]fileState),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Run records the current state of the watched files then logs changes until ctx is done, returning the watcher's
+// error. Failures to log or to read a file are reported to the Logger's error handler.
+func (m *FileMonitor) Run(ctx context.Context) error {
+	m.mu.Lock()
+	scanFiles(m.paths, func(path string, fi fs.FileInfo) {
+		if st, err := m.state(path, fi); err == nil {
+			m.files[path] = st
+		}
+	})
+	m.mu.Unlock()
+	return m.watcher.Watch(ctx, m.paths, m.changed)
+}
+
+// changed compares path against its recorded state, logging an event if it has changed
+func (m *FileMonitor) changed(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, existed := m.files[path]
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if existed {
+			delete(m.files, path)
+			m.log(FileDeletedClassId, "file deleted", path, nil, &old)
+		}
+		return
+	}
+	if err != nil {
+		m.logger.handleError(err)
+		return
+	}
+	if fi.IsDir() {
+		return
+	}
+	st, err := m.state(path, fi)
+	if err != nil {
+		m.logger.handleError(err)
+		return
+	}
+	m.files[path] = st
+	switch {
+	case !existed:
+		m.log(FileCreatedClassId, "file created", path, &st, nil)
+	case st != old:
+		m.log(FileModifiedClassId, "file modified", path, &st, &old)
+	}
+}
+
+func (m *FileMonitor) log(deviceEventClassId, name, path string, cur, old *fileState) {
+	ext := Extensions{
+		FilePath: path,
+		FileName: filepath.Base(path),
+	}
+	if cur != nil {
+		ext.FileHash = cur.hash
+		ext.FileId = cur.id
+		ext.FileModificationTime = cur.modTime
+		ext.FilePermission = cur.permission()
+		ext.FileType = cur.fileType()
+		ext.FileSize = ptr(uint(cur.size))
+	}
+	if old != nil {
+		ext.OldFilePath = path
+		ext.OldFileName = filepath.Base(path)
+		ext.OldFileHash = old.hash
+		ext.OldFileId = old.id
+		ext.OldFileModificationTime = old.modTime
+		ext.OldFilePermission = old.permission()
+		ext.OldFileType = old.fileType()
+		ext.OldFileSize = ptr(uint(old.size))
+	}
+	if err := m.logger.Log(deviceEventClassId, name, m.severity, ext); err != nil {
+		m.logger.handleError(err)
+	}
+}
+
+// fileState is the recorded state of a file, compared to detect modifications
+type fileState struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	id      string
+	hash    string
+}
+
+func (m *FileMonitor) state(path string, fi fs.FileInfo) (fileState, error) {
+	st := fileState{
+		size:    fi.Size(),
+		mode:    fi.Mode(),
+		modTime: fi.ModTime().UTC(),
+		id:      fileId(fi),
+	}
+	if m.newHash == nil || !fi.Mode().IsRegular() {
+		return st, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return st, err
+	}
+	defer f.Close()
+	h := m.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return st, err
+	}
+	st.hash = hex.EncodeToString(h.Sum(nil))
+	return st, nil
+}
+
+// permission returns the permission bits in octal, e.g. "0644"
+func (s fileState) permission() string {
+	return "0" + strconv.FormatUint(uint64(s.mode.Perm()), 8)
+}
+
+func (s fileState) fileType() string {
+	switch {
+	case s.mode.IsRegular():
+		return "file"
+	case s.mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case s.mode&fs.ModeNamedPipe != 0:
+		return "pipe"
+	case s.mode&fs.ModeSocket != 0:
+		return "socket"
+	case s.mode&fs.ModeDevice != 0:
+		return "device"
+	}
+	return "other"
+}
+
+// PollingWatcher is a FileWatcher which scans the watched paths at a fixed interval, reporting files whose size,
+// mode or modification time have changed
+type PollingWatcher struct {
+	Interval time.Duration
+}
+
+// NewPollingWatcher creates a PollingWatcher scanning every interval
+func NewPollingWatcher(interval time.Duration) *PollingWatcher {
+	return &PollingWatcher{Interval: interval}
+}
+
+// Watch implements FileWatcher
+func (p *PollingWatcher) Watch(ctx context.Context, paths []string, changed func(path string)) error {
+	prev := pollScan(paths)
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		cur := pollScan(paths)
+		for path, st := range cur {
+			if old, ok := prev[path]; !ok || old != st {
+				changed(path)
+			}
+		}
+		for path := range prev {
+			if _, ok := cur[path]; !ok {
+				changed(path)
+			}
+		}
+		prev = cur
+	}
+}
+
+// pollState is the metadata PollingWatcher compares between scans
+type pollState struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func pollScan(paths []string) map[string]pollState {
+	files := make(map[string]pollState)
+	scanFiles(paths, func(path string, fi fs.FileInfo) {
+		files[path] = pollState{size: fi.Size(), mode: fi.Mode(), modTime: fi.ModTime()}
+	})
+	return files
+}
+
+// scanFiles calls fn for each non-directory beneath paths. Unreadable paths are skipped, as they may be created later.
+func scanFiles(paths []string, fn func(path string, fi fs.FileInfo)) {
+	for _, root := range paths {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if fi, err := d.Info(); err == nil {
+				fn(path, fi)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cefevent
+
+import "io/fs"
+
+// fileId isn't available on this platform
+func fileId(fs.FileInfo) string {
+	return ""
+}
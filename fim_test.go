@@ -0,0 +1,115 @@
+package cefevent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanWatcher is a FileWatcher reporting the paths sent on its channel
+type chanWatcher chan string
+
+func (c chanWatcher) Watch(ctx context.Context, _ []string, changed func(path string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case path := <-c:
+			changed(path)
+		}
+	}
+}
+
+func TestFileMonitor(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.conf")
+	created := filepath.Join(dir, "sub", "created.conf")
+	require.NoError(t, os.WriteFile(existing, []byte("a"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	changes := make(chanWatcher)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewFileMonitor(l, changes, []string{dir}).Run(ctx) }()
+
+	changes <- existing // unchanged
+	require.NoError(t, os.WriteFile(existing, []byte("b"), 0o644))
+	require.NoError(t, os.Chmod(existing, 0o600))
+	changes <- existing
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0o644))
+	changes <- created
+	require.NoError(t, os.Remove(existing))
+	changes <- existing
+	changes <- existing // already deleted
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	records := w.Records()
+	require.Len(t, records, 3)
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|file-modified\|file modified\|Medium\|`+
+		`fileHash=3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d fileId=\d* fileModificationTime=\d+ `+
+		`filePath=.*existing.conf filePermission=0600 fileType=file fname=existing.conf fsize=1 `+
+		`oldFileHash=ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb oldFileId=\d* oldFileModificationTime=\d+ `+
+		`oldFileName=existing.conf oldFilePath=.*existing.conf oldFilePermission=0644 oldFileType=file oldFileSize=1$`, records[0])
+	assert.Regexp(t, `\|file-created\|file created\|Medium\|fileHash=\w+ .*filePath=.*created.conf .*fname=created.conf fsize=3$`, records[1])
+	assert.Regexp(t, `\|file-deleted\|file deleted\|Medium\|filePath=.*existing.conf fname=existing.conf oldFileHash=3e23e8`, records[2])
+	assert.NotContains(t, records[2], "fileHash=")
+}
+
+func TestFileMonitor_withoutHash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	changes := make(chanWatcher)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- NewFileMonitor(l, changes, []string{dir}, WithFileHash(nil), WithFileSeverity(HighSeverity)).Run(ctx)
+	}()
+
+	changes <- path // doesn't exist yet, but waits for the initial scan
+	require.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+	changes <- path
+	cancel()
+	<-done
+
+	records := w.Records()
+	require.Len(t, records, 1)
+	assert.Contains(t, records[0], "|file-created|file created|High|")
+	assert.NotContains(t, records[0], "fileHash")
+}
+
+func TestPollingWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	changed := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = NewPollingWatcher(5*time.Millisecond).Watch(ctx, []string{dir}, func(p string) { changed <- p })
+	}()
+
+	time.Sleep(20 * time.Millisecond) // let the initial scan complete
+	require.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+	select {
+	case got := <-changed:
+		assert.Equal(t, path, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("create not detected")
+	}
+	require.NoError(t, os.Remove(path))
+	select {
+	case got := <-changed:
+		assert.Equal(t, path, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("delete not detected")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package cefevent
+
+import (
+	"io/fs"
+	"strconv"
+	"syscall"
+)
+
+// fileId returns the file's inode number
+func fileId(fi fs.FileInfo) string {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return strconv.FormatUint(uint64(st.Ino), 10)
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package cefevent
+
+import (
+	"io"
+	"strconv"
+)
+
+// OctetCountingWriter frames each record written to it with RFC 6587 octet counting, "LEN SP RECORD", for collectors
+// which require it on plain TCP streams. Trailing newlines are removed from records first. Each framed record is passed
+// to the underlying writer with a single Write call, so it is safe to use from multiple goroutines if the underlying
+// writer is.
+type OctetCountingWriter struct {
+	w io.Writer
+}
+
+// NewOctetCountingWriter wraps w to frame each record written with its length
+func NewOctetCountingWriter(w io.Writer) *OctetCountingWriter {
+	return &OctetCountingWriter{w: w}
+}
+
+// Write writes p as a single framed record
+func (o *OctetCountingWriter) Write(p []byte) (int, error) {
+	buf := recordBuffers.Get().(*[]byte)
+	frame := appendOctetCounted((*buf)[:0], trimRecord(p))
+	defer func() {
+		if cap(frame) <= maxPooledRecordSize {
+			*buf = frame
+			recordBuffers.Put(buf)
+		}
+	}()
+	n, err := o.w.Write(frame)
+	if err == nil && n < len(frame) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendOctetCounted appends record to dst, prefixed with its length
+func appendOctetCounted(dst, record []byte) []byte {
+	dst = strconv.AppendInt(dst, int64(len(record)), 10)
+	dst = append(dst, ' ')
+	return append(dst, record...)
+}
+
+// trimRecord removes trailing newlines from a record, which framing makes redundant
+func trimRecord(p []byte) []byte {
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+	return p
+}
@@ -0,0 +1,55 @@
+package cefevent
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOctetCountingWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   string
+	}{
+		{"plain", "CEF:1|v|p|1|100|test|Low|", "25 CEF:1|v|p|1|100|test|Low|"},
+		{"newline", "CEF:1|v|p|1|100|test|Low|\r\n", "25 CEF:1|v|p|1|100|test|Low|"},
+		{"multibyte", "ñ", "2 ñ"},
+		{"empty", "", "0 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			n, err := NewOctetCountingWriter(buf).Write([]byte(tt.record))
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.record), n)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestOctetCountingWriter_shortWrite(t *testing.T) {
+	_, err := NewOctetCountingWriter(shortWriter{}).Write([]byte("x"))
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+}
+
+func TestOctetCountingWriter_logger(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	records := acceptRecords(t, ln, true)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	l := NewLogger(NewOctetCountingWriter(conn), "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator))
+	require.NoError(t, l.LogLow("100", "first", Extensions{Message: "a\nb"}))
+	require.NoError(t, l.LogLow("101", "second", Extensions{}))
+
+	assert.Equal(t, `CEF:1|v|p|1|100|first|Low|msg=a\nb`, <-records)
+	assert.Equal(t, "CEF:1|v|p|1|101|second|Low|", <-records)
+}
@@ -1,6 +1,6 @@
 module github.com/dmtaylor/cefevent
 
-go 1.21.3
+go 1.23
 
 require github.com/stretchr/testify v1.8.4
 
@@ -0,0 +1,115 @@
+// Package grpcaudit provides gRPC interceptors logging a CEF audit event for each call with cefevent.LogRPC. It is a
+// separate module so that only programs using it depend on gRPC.
+//
+//	srv := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpcaudit.UnaryServerInterceptor(l)),
+//		grpc.ChainStreamInterceptor(grpcaudit.StreamServerInterceptor(l)),
+//	)
+package grpcaudit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dmtaylor/cefevent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor logs each unary call served to l once the handler returns, with the client as the source
+func UnaryServerInterceptor(l *cefevent.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(l, ctx, info.FullMethod, false, start, serverPeer(ctx), err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs each streaming call served to l once the handler returns, with the client as the
+// source
+func StreamServerInterceptor(l *cefevent.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(l, ss.Context(), info.FullMethod, false, start, serverPeer(ss.Context()), err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs each unary call made to l once it returns, with the server as the destination
+func UnaryClientInterceptor(l *cefevent.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+		start := time.Now()
+		p := &peer.Peer{}
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(p))...)
+		logCall(l, ctx, method, true, start, p.Addr, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs each streaming call made to l once it finishes, with the server as the destination. A
+// stream finishes when RecvMsg returns an error, io.EOF for success, or its only response for client streaming calls.
+// Streams abandoned before then aren't logged.
+func StreamClientInterceptor(l *cefevent.Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+		opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		p := &peer.Peer{}
+		cs, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(p))...)
+		if err != nil {
+			logCall(l, ctx, method, true, start, p.Addr, err)
+			return nil, err
+		}
+		return &auditedClientStream{ClientStream: cs, serverStreams: desc.ServerStreams, finish: func(err error) {
+			logCall(l, ctx, method, true, start, p.Addr, err)
+		}}, nil
+	}
+}
+
+// auditedClientStream logs the call once the stream finishes
+type auditedClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	finish        func(err error)
+	once          sync.Once
+}
+
+func (s *auditedClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.once.Do(func() {
+			if errors.Is(err, io.EOF) {
+				s.finish(nil)
+			} else {
+				s.finish(err)
+			}
+		})
+	}
+	return err
+}
+
+// serverPeer returns the address of the client calling a server, if known
+func serverPeer(ctx context.Context) net.Addr {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr
+	}
+	return nil
+}
+
+// logCall logs a finished call. Failing to log doesn't fail the call; the Logger's output reports its own errors, e.g.
+// through WithErrorHandler or WithSelfMonitoring.
+func logCall(l *cefevent.Logger, ctx context.Context, method string, client bool, start time.Time, addr net.Addr,
+	err error) {
+	call := cefevent.RPCCall{FullMethod: method, Peer: addr, Start: start, End: time.Now(), Client: client}
+	call.Deadline, _ = ctx.Deadline()
+	s := status.Convert(err)
+	call.Code, call.Message = uint32(s.Code()), s.Message()
+	_ = cefevent.LogRPC(l, call)
+}
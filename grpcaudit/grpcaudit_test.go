@@ -0,0 +1,158 @@
+package grpcaudit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmtaylor/cefevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// records collects the events written by a Logger, one per Write
+type records struct {
+	mu     sync.Mutex
+	events []cefevent.Event
+}
+
+func (r *records) Write(p []byte) (int, error) {
+	ev, err := cefevent.Parse(string(p))
+	if err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return len(p), nil
+}
+
+func (r *records) take() []cefevent.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+// startHealth serves the gRPC health service with audited server & client loggers, returning a client for it
+func startHealth(t *testing.T) (grpc_health_v1.HealthClient, *records, *records) {
+	t.Helper()
+	serverRecs, clientRecs := &records{}, &records{}
+	server := cefevent.NewLogger(serverRecs, "v", "server", "1", cefevent.OmitSyslogHeader())
+	client := cefevent.NewLogger(clientRecs, "v", "client", "1", cefevent.OmitSyslogHeader())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(server)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(server)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(client)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(client)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn), serverRecs, clientRecs
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	client, serverRecs, clientRecs := startHealth(t)
+	const method = "/grpc.health.v1.Health/Check"
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	serverEvents, clientEvents := serverRecs.take(), clientRecs.take()
+	require.Len(t, serverEvents, 1)
+	require.Len(t, clientEvents, 1)
+	sev, cev := serverEvents[0], clientEvents[0]
+	assert.Equal(t, cefevent.RPCServerClassId, sev.DeviceEventClassId)
+	assert.Equal(t, method, sev.Name)
+	assert.Equal(t, "success", sev.Extensions.Outcome)
+	assert.Equal(t, "127.0.0.1", sev.Extensions.SourceAddress.String())
+	assert.Equal(t, cefevent.RPCClientClassId, cev.DeviceEventClassId)
+	assert.Equal(t, method, cev.Name)
+	assert.Equal(t, "grpc.health.v1.Health", cev.Extensions.DestinationServiceName)
+	assert.Equal(t, "127.0.0.1", cev.Extensions.DestinationAddress.String())
+	assert.NotZero(t, cev.Extensions.DestinationPort)
+	assert.Zero(t, cev.Extensions.DeviceCustomDate1)
+
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	for _, ev := range append(serverRecs.take(), clientRecs.take()...) {
+		assert.Equal(t, "failure", ev.Extensions.Outcome)
+		assert.Equal(t, "NotFound: unknown service", ev.Extensions.Reason)
+		assert.Equal(t, cefevent.LowSeverity, ev.Severity)
+	}
+}
+
+func TestUnaryInterceptors_deadline(t *testing.T) {
+	client, serverRecs, clientRecs := startHealth(t)
+
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	events := append(serverRecs.take(), clientRecs.take()...)
+	require.Len(t, events, 2)
+	for _, ev := range events {
+		require.NotNil(t, ev.Extensions.DeviceCustomDate1)
+		assert.Equal(t, cefevent.RPCDeadlineLabel, ev.Extensions.DeviceCustomDate1.Label)
+		// The server's deadline is derived from the timeout sent by the client
+		assert.WithinDuration(t, deadline, ev.Extensions.DeviceCustomDate1.Value, time.Second)
+		assert.False(t, ev.Extensions.EndTime.Before(ev.Extensions.StartTime))
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	client, serverRecs, clientRecs := startHealth(t)
+	const method = "/grpc.health.v1.Health/Watch"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+	assert.Empty(t, clientRecs.take(), "logged before the stream finished")
+
+	// Watch streams only end when the call does
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	_, _ = stream.Recv()
+
+	events := clientRecs.take()
+	require.Len(t, events, 1, "logged once")
+	assert.Equal(t, cefevent.RPCClientClassId, events[0].DeviceEventClassId)
+	assert.Equal(t, method, events[0].Name)
+	assert.Equal(t, "failure", events[0].Extensions.Outcome)
+	assert.Contains(t, events[0].Extensions.Reason, "Canceled")
+
+	require.Eventually(t, func() bool {
+		serverRecs.mu.Lock()
+		defer serverRecs.mu.Unlock()
+		return len(serverRecs.events) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	events = serverRecs.take()
+	assert.Equal(t, cefevent.RPCServerClassId, events[0].DeviceEventClassId)
+	assert.Equal(t, method, events[0].Name)
+	assert.Equal(t, "127.0.0.1", events[0].Extensions.SourceAddress.String())
+}
@@ -0,0 +1,55 @@
+package cefevent
+
+// Hook observes or alters the events a Logger writes, e.g. for redaction, enrichment, metrics or sampling
+type Hook interface {
+	// BeforeWrite is called with each event the Logger is about to format, after severity filtering. It may modify
+	// the event, or return false to drop it. CustomExtensions is a copy of the caller's map, so keys may be added or
+	// removed freely; pointer fields are shared with the caller, so replace rather than modify their values.
+	BeforeWrite(e *Event) bool
+	// AfterWrite is called once an event has been written, with the number of bytes written and any error. For
+	// asynchronous Loggers it is called once the event is queued, with err reporting whether it was accepted. Also
+	// called for the summary events written by WithRepeatSuppression.
+	AfterWrite(e *Event, n int, err error)
+}
+
+// RegisterHook adds a hook to the Logger, run after those already registered. Safe to call while other goroutines are
+// logging. Hooks registered on a Logger created by With apply to its parent and every other child.
+func (l *Logger) RegisterHook(h Hook) {
+	if l.parent != nil {
+		l.parent.RegisterHook(h)
+		return
+	}
+	l.hooksMu.Lock()
+	defer l.hooksMu.Unlock()
+	var hooks []Hook
+	if cur := l.hooks.Load(); cur != nil {
+		hooks = append(hooks, *cur...)
+	}
+	hooks = append(hooks, h)
+	l.hooks.Store(&hooks)
+}
+
+// beforeWrite runs the BeforeWrite hooks, reporting whether the event should be written
+func (l *Logger) beforeWrite(e *Event) bool {
+	hooks := l.hooks.Load()
+	if hooks == nil {
+		return true
+	}
+	for _, h := range *hooks {
+		if !h.BeforeWrite(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// afterWrite runs the AfterWrite hooks
+func (l *Logger) afterWrite(e *Event, n int, err error) {
+	hooks := l.hooks.Load()
+	if hooks == nil {
+		return
+	}
+	for _, h := range *hooks {
+		h.AfterWrite(e, n, err)
+	}
+}
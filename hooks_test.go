@@ -0,0 +1,98 @@
+package cefevent
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook applies before to events and records the results passed to AfterWrite
+type recordingHook struct {
+	before func(e *Event) bool
+
+	mu      sync.Mutex
+	written []string
+	sizes   []int
+	errs    []error
+}
+
+func (h *recordingHook) BeforeWrite(e *Event) bool {
+	if h.before == nil {
+		return true
+	}
+	return h.before(e)
+}
+
+func (h *recordingHook) AfterWrite(e *Event, n int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.written = append(h.written, e.Name)
+	h.sizes = append(h.sizes, n)
+	h.errs = append(h.errs, err)
+}
+
+func TestLogger_RegisterHook(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader())
+	enrich := &recordingHook{before: func(e *Event) bool {
+		e.Extensions.DeviceHostName = "edge-1"
+		e.DeviceProduct = "Proxy"
+		return true
+	}}
+	veto := &recordingHook{before: func(e *Event) bool {
+		return e.DeviceEventClassId != "noise"
+	}}
+	l.RegisterHook(enrich)
+	l.RegisterHook(veto)
+
+	require.NoError(t, l.LogLow("100", "kept", Extensions{Message: "hi"}))
+	require.NoError(t, l.LogLow("noise", "dropped", Extensions{}))
+	assert.Equal(t, "CEF:1|v|Proxy|1|100|kept|Low|msg=hi dcvhost=edge-1", buf.String())
+	assert.Equal(t, []string{"kept"}, enrich.written)
+	assert.Equal(t, []int{buf.Len()}, enrich.sizes)
+	assert.Equal(t, []error{nil}, veto.errs)
+}
+
+func TestLogger_RegisterHook_customExtensions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader())
+	l.RegisterHook(&recordingHook{before: func(e *Event) bool {
+		delete(e.Extensions.CustomExtensions, "token")
+		e.Extensions.CustomExtensions["tenant"] = "acme"
+		return true
+	}})
+	custom := map[string]string{"token": "secret", "user": "bob"}
+	require.NoError(t, l.LogLow("100", "test", Extensions{CustomExtensions: custom}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|tenant=acme user=bob", buf.String())
+	assert.Equal(t, map[string]string{"token": "secret", "user": "bob"}, custom, "caller's map modified")
+}
+
+func TestLogger_RegisterHook_writeError(t *testing.T) {
+	l := NewLogger(errorWriter{}, "v", "p", "1")
+	h := &recordingHook{}
+	l.With(Extensions{}).RegisterHook(h)
+	assert.Error(t, l.LogLow("100", "test", Extensions{}))
+	require.Len(t, h.errs, 1)
+	assert.ErrorIs(t, h.errs[0], stubWriterError)
+	assert.Equal(t, []int{0}, h.sizes)
+}
+
+func TestLogger_RegisterHook_filtered(t *testing.T) {
+	l := NewLogger(&bytes.Buffer{}, "v", "p", "1", MustLoggerConfig(WithMinSeverity(HighSeverity)))
+	called := false
+	l.RegisterHook(&recordingHook{before: func(*Event) bool {
+		called = true
+		return true
+	}})
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.False(t, called, "hooks don't see events below the minimum severity")
+}
+
+func TestLogger_RegisterHook_panic(t *testing.T) {
+	l := NewLogger(&bytes.Buffer{}, "v", "p", "1")
+	l.RegisterHook(&recordingHook{before: func(*Event) bool { panic("boom") }})
+	assert.ErrorIs(t, l.LogLow("100", "test", Extensions{}), LoggerPanicErr)
+}
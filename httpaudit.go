@@ -0,0 +1,200 @@
+package cefevent
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPRequestClassId is the device event class ID of events logged by HTTPMiddleware
+const HTTPRequestClassId = "http-request"
+
+// HTTPAuditOption configures HTTPMiddleware
+type HTTPAuditOption func(a *httpAuditor)
+
+// WithHTTPEventClass sets how the name & severity of a request's event are chosen from the request and the response
+// status. By default the name is "HTTP request", and the severity is MediumSeverity for 401, 403 and 5xx responses and
+// LowSeverity otherwise.
+func WithHTTPEventClass(fn func(r *http.Request, status int) EventClass) HTTPAuditOption {
+	return func(a *httpAuditor) {
+		a.eventClass = fn
+	}
+}
+
+// WithHTTPSkip sets a function selecting requests which aren't logged, e.g. health checks
+func WithHTTPSkip(fn func(r *http.Request) bool) HTTPAuditOption {
+	return func(a *httpAuditor) {
+		a.skip = fn
+	}
+}
+
+// WithHTTPExtensions sets a function adding fields to a request's event once it has been handled, e.g. the
+// authenticated user from the request's context.
+func WithHTTPExtensions(fn func(r *http.Request, ext *Extensions)) HTTPAuditOption {
+	return func(a *httpAuditor) {
+		a.extensions = fn
+	}
+}
+
+// WithHTTPClientIPHeader sets a header such as X-Real-IP holding the client's address, set by a trusted reverse proxy.
+// When the header holds an IP address it is used as the source address instead of the connection's. Only use this
+// behind a proxy which overwrites the header, as clients can otherwise spoof it.
+func WithHTTPClientIPHeader(header string) HTTPAuditOption {
+	return func(a *httpAuditor) {
+		a.clientIPHeader = header
+	}
+}
+
+// HTTPMiddleware returns middleware logging one CEF event to l for each request handled, with the request's URL,
+// method, user agent & referrer, the client as source and the server as destination, the bytes read from the request
+// body and written to the response, the start & end times, and an outcome of "failure" with the status as the reason
+// for 4xx and 5xx responses. Requests whose handler panics are logged with status 500 before the panic continues.
+func HTTPMiddleware(l *Logger, opts ...HTTPAuditOption) func(http.Handler) http.Handler {
+	a := &httpAuditor{logger: l, eventClass: defaultHTTPEventClass}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a.skip != nil && a.skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			a.serve(next, w, r)
+		})
+	}
+}
+
+// httpAuditor logs events for requests handled by HTTPMiddleware
+type httpAuditor struct {
+	logger         *Logger
+	eventClass     func(r *http.Request, status int) EventClass
+	skip           func(r *http.Request) bool
+	extensions     func(r *http.Request, ext *Extensions)
+	clientIPHeader string
+}
+
+func (a *httpAuditor) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &auditResponseWriter{ResponseWriter: w}
+	var body *CountingReader
+	if r.Body != nil && r.Body != http.NoBody {
+		body = NewCountingReader(r.Body)
+		r.Body = readCloser{body, r.Body}
+	}
+	defer func() {
+		v := recover()
+		if v != nil && rec.status == 0 {
+			rec.status = http.StatusInternalServerError
+		}
+		a.log(r, rec, body, start)
+		if v != nil {
+			panic(v)
+		}
+	}()
+	next.ServeHTTP(rec, r)
+}
+
+func (a *httpAuditor) log(r *http.Request, rec *auditResponseWriter, body *CountingReader, start time.Time) {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK // the handler wrote nothing
+	}
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	ext := Extensions{
+		ApplicationProtocol:      "HTTP",
+		RequestUrl:               u,
+		RequestMethod:            r.Method,
+		RequestClientApplication: r.UserAgent(),
+		RequestContext:           r.Referer(),
+		BytesOut:                 ptr(uint(rec.written)),
+		StartTime:                start,
+		EndTime:                  time.Now(),
+		Outcome:                  "success",
+	}
+	if r.TLS != nil {
+		ext.ApplicationProtocol = "HTTPS"
+	}
+	if body != nil {
+		ext.BytesIn = ptr(uint(body.Count()))
+	} else {
+		ext.BytesIn = ptr(uint(0))
+	}
+	if status >= 400 {
+		ext.Outcome = "failure"
+		ext.Reason = strconv.Itoa(status) + " " + http.StatusText(status)
+	}
+	ext.SourceAddress, ext.SourcePort = hostPortFields(r.RemoteAddr)
+	if a.clientIPHeader != "" {
+		if ip := net.ParseIP(r.Header.Get(a.clientIPHeader)); ip != nil {
+			ext.SourceAddress, ext.SourcePort = ip, nil
+		}
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		ext.DestinationAddress, ext.DestinationPort, ext.TransportProtocol = addrFields(addr)
+	}
+	if a.extensions != nil {
+		a.extensions(r, &ext)
+	}
+
+	ec := a.eventClass(r, status)
+	if err := a.logger.Log(ec.Id, ec.Name, ec.Severity, ext); err != nil {
+		a.logger.handleError(err)
+	}
+}
+
+func defaultHTTPEventClass(_ *http.Request, status int) EventClass {
+	ec := EventClass{Id: HTTPRequestClassId, Name: "HTTP request", Severity: LowSeverity}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden || status >= 500 {
+		ec.Severity = MediumSeverity
+	}
+	return ec
+}
+
+// readCloser counts a request body's reads while closing the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// auditResponseWriter records the status & size of a response. Unwrap gives http.ResponseController access to the
+// underlying writer's optional interfaces, and Flush is passed through for handlers which assert http.Flusher.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *auditResponseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
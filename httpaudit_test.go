@@ -0,0 +1,126 @@
+package cefevent
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	handler := HTTPMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/admin" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/echo?q=1", strings.NewReader("hello"))
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "https://example.com/")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	resp, err = http.Get(srv.URL + "/admin")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	records := w.Records()
+	require.Len(t, records, 2)
+	e, err := Parse(records[0])
+	require.NoError(t, err)
+	assert.Equal(t, HTTPRequestClassId, e.DeviceEventClassId)
+	assert.Equal(t, "HTTP request", e.Name)
+	assert.Equal(t, LowSeverity, e.Severity)
+	assert.Equal(t, srv.URL+"/echo?q=1", e.Extensions.RequestUrl.String())
+	assert.Equal(t, "POST", e.Extensions.RequestMethod)
+	assert.Equal(t, "test-agent", e.Extensions.RequestClientApplication)
+	assert.Equal(t, "https://example.com/", e.Extensions.RequestContext)
+	assert.Equal(t, "HTTP", e.Extensions.ApplicationProtocol)
+	assert.Equal(t, "TCP", e.Extensions.TransportProtocol)
+	assert.Equal(t, "success", e.Extensions.Outcome)
+	assert.Equal(t, ptr(uint(5)), e.Extensions.BytesIn)
+	assert.Equal(t, ptr(uint(5)), e.Extensions.BytesOut)
+	assert.True(t, net.IPv4(127, 0, 0, 1).Equal(e.Extensions.SourceAddress))
+	assert.NotNil(t, e.Extensions.SourcePort)
+	assert.True(t, net.IPv4(127, 0, 0, 1).Equal(e.Extensions.DestinationAddress))
+	assert.Equal(t, srv.Listener.Addr().(*net.TCPAddr).Port, int(*e.Extensions.DestinationPort))
+	assert.False(t, e.Extensions.StartTime.IsZero())
+	assert.False(t, e.Extensions.EndTime.Before(e.Extensions.StartTime))
+
+	e, err = Parse(records[1])
+	require.NoError(t, err)
+	assert.Equal(t, MediumSeverity, e.Severity)
+	assert.Equal(t, "failure", e.Extensions.Outcome)
+	assert.Equal(t, "403 Forbidden", e.Extensions.Reason)
+	assert.Equal(t, ptr(uint(0)), e.Extensions.BytesIn)
+}
+
+func TestHTTPMiddleware_options(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	handler := HTTPMiddleware(l,
+		WithHTTPSkip(func(r *http.Request) bool { return r.URL.Path == "/healthz" }),
+		WithHTTPClientIPHeader("X-Real-IP"),
+		WithHTTPExtensions(func(r *http.Request, ext *Extensions) { ext.SourceUserName = r.Header.Get("X-User") }),
+		WithHTTPEventClass(func(r *http.Request, status int) EventClass {
+			return EventClass{Id: "api-call", Name: r.Method + " " + r.URL.Path, Severity: HighSeverity}
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	r := httptest.NewRequest(http.MethodDelete, "https://example.com/users/1", nil)
+	r.Header.Set("X-Real-IP", "203.0.113.7")
+	r.Header.Set("X-User", "alice")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	records := w.Records()
+	require.Len(t, records, 1)
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|api-call\|DELETE /users/1\|High\|`, records[0])
+	assert.Contains(t, records[0], "app=HTTPS")
+	assert.Contains(t, records[0], "request=https://example.com/users/1")
+	assert.Contains(t, records[0], "src=203.0.113.7")
+	assert.NotContains(t, records[0], "spt=")
+	assert.Contains(t, records[0], "suser=alice")
+	assert.Contains(t, records[0], "outcome=success")
+}
+
+func TestHTTPMiddleware_panic(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	handler := HTTPMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	records := w.Records()
+	require.Len(t, records, 1)
+	assert.Contains(t, records[0], "|Medium|")
+	assert.Contains(t, records[0], "reason=500 Internal Server Error")
+}
+
+func TestHTTPMiddleware_flusher(t *testing.T) {
+	l := NewLogger(io.Discard, "testVendor", "testProduct", "1.0")
+	rec := httptest.NewRecorder()
+	HTTPMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		require.True(t, ok)
+		_, _ = w.Write([]byte("x"))
+		f.Flush()
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, rec.Flushed)
+}
@@ -0,0 +1,284 @@
+package cefevent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPCollectorBufferFullErr error when an HTTPCollectorWriter can't accept more events because sending is failing
+var HTTPCollectorBufferFullErr = errors.New("http collector buffer full")
+
+// BatchRejectedErr error when a collector permanently rejects a batch, e.g. with 400 Bad Request. Rejected batches are
+// dropped rather than retried, so they can't block the records behind them.
+var BatchRejectedErr = errors.New("batch rejected")
+
+// HTTPBatchEncoder encodes a batch of records as a request body, returning the body and its content type
+type HTTPBatchEncoder func(records []string, received []time.Time) (body []byte, contentType string, err error)
+
+// NewlineBatch encodes a batch as one record per line, for raw endpoints such as Splunk HEC's /services/collector/raw
+func NewlineBatch(records []string, _ []time.Time) ([]byte, string, error) {
+	return []byte(strings.Join(records, "\n") + "\n"), "text/plain; charset=utf-8", nil
+}
+
+// SplunkHECBatch returns an encoder for Splunk HEC's /services/collector/event endpoint, sending each record as an
+// event with the given sourcetype, e.g. "cef". sourcetype may be empty to use the token's default.
+func SplunkHECBatch(sourcetype string) HTTPBatchEncoder {
+	return func(records []string, received []time.Time) ([]byte, string, error) {
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		for i, record := range records {
+			event := map[string]any{"event": record, "time": float64(received[i].UnixMilli()) / 1000}
+			if sourcetype != "" {
+				event["sourcetype"] = sourcetype
+			}
+			if err := enc.Encode(event); err != nil {
+				return nil, "", err
+			}
+		}
+		return b.Bytes(), "application/json", nil
+	}
+}
+
+// HTTPCollectorWriter sends records in batches to an HTTP event collector, such as Splunk HEC or a CEF-over-HTTP
+// ingestion endpoint. Each Write is one record; records are buffered and POSTed in the background once a batch is
+// full or the flush interval elapses. Batches failing with a network error, 401, 403, 408, 429 or a 5xx status are
+// retried with the next send; other failures drop the batch. Use it as a Logger's output, and Close it on shutdown to
+// send the remaining records.
+type HTTPCollectorWriter struct {
+	url           string
+	header        http.Header
+	client        *http.Client
+	encode        HTTPBatchEncoder
+	gzip          bool
+	batchSize     int
+	maxBuffered   int
+	flushInterval time.Duration
+	errorHandler  func(err error)
+
+	mu       sync.Mutex
+	records  []string
+	received []time.Time
+	flushMu  sync.Mutex
+	kick     chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// HTTPCollectorOption configures an HTTPCollectorWriter
+type HTTPCollectorOption func(w *HTTPCollectorWriter)
+
+// WithHTTPCollectorHeader sets a request header, e.g. WithHTTPCollectorHeader("Authorization", "Splunk "+token)
+func WithHTTPCollectorHeader(key, value string) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.header.Set(key, value)
+	}
+}
+
+// WithHTTPCollectorEncoder sets how batches are encoded. Defaults to NewlineBatch
+func WithHTTPCollectorEncoder(encode HTTPBatchEncoder) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.encode = encode
+	}
+}
+
+// WithHTTPCollectorGzip compresses request bodies with gzip
+func WithHTTPCollectorGzip() HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.gzip = true
+	}
+}
+
+// WithHTTPCollectorBatchSize sets the number of records that triggers a send. Defaults to 100
+func WithHTTPCollectorBatchSize(n int) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.batchSize = n
+	}
+}
+
+// WithHTTPCollectorMaxBuffered sets how many records are kept while sending is failing before Write returns
+// HTTPCollectorBufferFullErr. Defaults to 10 batches
+func WithHTTPCollectorMaxBuffered(n int) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.maxBuffered = n
+	}
+}
+
+// WithHTTPCollectorFlushInterval sets how often buffered records are sent. Defaults to 5s
+func WithHTTPCollectorFlushInterval(d time.Duration) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.flushInterval = d
+	}
+}
+
+// WithHTTPCollectorClient sets the client used for requests, e.g. for mutual TLS. Defaults to http.DefaultClient
+func WithHTTPCollectorClient(c *http.Client) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.client = c
+	}
+}
+
+// WithHTTPCollectorErrorHandler sets a function called with errors from background sends, which are otherwise
+// discarded
+func WithHTTPCollectorErrorHandler(fn func(err error)) HTTPCollectorOption {
+	return func(w *HTTPCollectorWriter) {
+		w.errorHandler = fn
+	}
+}
+
+// NewHTTPCollectorWriter creates an HTTPCollectorWriter POSTing to url
+func NewHTTPCollectorWriter(url string, opts ...HTTPCollectorOption) *HTTPCollectorWriter {
+	w := &HTTPCollectorWriter{
+		url:           url,
+		header:        make(http.Header),
+		encode:        NewlineBatch,
+		batchSize:     100,
+		flushInterval: 5 * time.Second,
+		kick:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.batchSize < 1 {
+		w.batchSize = 1
+	}
+	if w.maxBuffered == 0 {
+		w.maxBuffered = 10 * w.batchSize
+	}
+	go w.run()
+	return w
+}
+
+// Write buffers p as a single record, after removing any trailing newline
+func (w *HTTPCollectorWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if len(w.records) >= w.maxBuffered {
+		w.mu.Unlock()
+		return 0, HTTPCollectorBufferFullErr
+	}
+	w.records = append(w.records, string(trimRecord(p)))
+	w.received = append(w.received, time.Now())
+	full := len(w.records) >= w.batchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends all buffered records. Records failing with a retryable error stay buffered; rejected batches are
+// dropped, and reported in the returned error.
+func (w *HTTPCollectorWriter) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	w.mu.Lock()
+	records, received := w.records, w.received
+	w.records, w.received = nil, nil
+	w.mu.Unlock()
+
+	var errs []error
+	for len(records) > 0 {
+		n := min(len(records), w.batchSize)
+		err := w.send(ctx, records[:n], received[:n])
+		if err != nil && !errors.Is(err, BatchRejectedErr) {
+			w.mu.Lock()
+			w.records = append(records, w.records...)
+			w.received = append(received, w.received...)
+			w.mu.Unlock()
+			return errors.Join(append(errs, err)...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%d records dropped: %w", n, err))
+		}
+		records, received = records[n:], received[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops background sending and flushes the remaining records
+func (w *HTTPCollectorWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+		<-w.stopped
+	})
+	return w.Flush(context.Background())
+}
+
+func (w *HTTPCollectorWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-w.kick:
+		}
+		if err := w.Flush(context.Background()); err != nil && w.errorHandler != nil {
+			w.errorHandler(err)
+		}
+	}
+}
+
+// send POSTs one batch, returning an error wrapping BatchRejectedErr if retrying wouldn't help
+func (w *HTTPCollectorWriter) send(ctx context.Context, records []string, received []time.Time) error {
+	body, contentType, err := w.encode(records, received)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode: %w", BatchRejectedErr, err)
+	}
+	header := w.header.Clone()
+	header.Set("Content-Type", contentType)
+	if w.gzip {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = b.Bytes()
+		header.Set("Content-Encoding", "gzip")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", BatchRejectedErr, err)
+	}
+	req.Header = header
+	resp, err := httpClient(w.client).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send to http collector: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	if retryableStatus(resp.StatusCode) {
+		return fmt.Errorf("failed to send to http collector: %s", responseError(resp))
+	}
+	return fmt.Errorf("%w by http collector: %s", BatchRejectedErr, responseError(resp))
+}
+
+// retryableStatus reports whether a request failing with status may succeed if retried. Authentication failures are
+// retried as they aren't caused by the batch, and dropping every batch until credentials are fixed would lose events.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= 500
+}
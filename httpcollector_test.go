@@ -0,0 +1,168 @@
+package cefevent
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCollector records the request bodies it receives, responding with status
+type fakeCollector struct {
+	mu      sync.Mutex
+	status  int
+	bodies  []string
+	headers []http.Header
+}
+
+func (f *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, _ := io.ReadAll(body)
+	f.headers = append(f.headers, r.Header.Clone())
+	if f.status != 0 {
+		http.Error(w, "failed", f.status)
+		return
+	}
+	f.bodies = append(f.bodies, string(b))
+}
+
+func (f *fakeCollector) setStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func newFakeCollector(t *testing.T) (*fakeCollector, *httptest.Server) {
+	f := &fakeCollector{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func TestHTTPCollectorWriter(t *testing.T) {
+	f, srv := newFakeCollector(t)
+	w := NewHTTPCollectorWriter(srv.URL, WithHTTPCollectorBatchSize(2), WithHTTPCollectorFlushInterval(time.Hour),
+		WithHTTPCollectorHeader("Authorization", "Splunk token"), WithHTTPCollectorGzip())
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	for _, msg := range []string{"one", "two", "three"} {
+		require.NoError(t, l.LogLow("100", "test", Extensions{Message: msg}))
+	}
+	require.NoError(t, w.Close())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|100|test|Low|msg=one\n"+
+		"CEF:1|testVendor|testProduct|1.0|100|test|Low|msg=two\n"+
+		"CEF:1|testVendor|testProduct|1.0|100|test|Low|msg=three\n", strings.Join(f.bodies, ""))
+	for _, h := range f.headers {
+		assert.Equal(t, "Splunk token", h.Get("Authorization"))
+		assert.Equal(t, "gzip", h.Get("Content-Encoding"))
+		assert.Equal(t, "text/plain; charset=utf-8", h.Get("Content-Type"))
+	}
+}
+
+func TestHTTPCollectorWriter_failure(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantErr   error
+		wantKept  bool
+		wantCalls int
+	}{
+		{"retryable", http.StatusServiceUnavailable, nil, true, 2},
+		{"throttled", http.StatusTooManyRequests, nil, true, 2},
+		{"unauthorized", http.StatusUnauthorized, nil, true, 2},
+		{"forbidden", http.StatusForbidden, nil, true, 2},
+		{"rejected", http.StatusBadRequest, BatchRejectedErr, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, srv := newFakeCollector(t)
+			f.status = tt.status
+			w := NewHTTPCollectorWriter(srv.URL, WithHTTPCollectorFlushInterval(time.Hour),
+				WithHTTPCollectorMaxBuffered(2))
+			for i := 0; i < 2; i++ {
+				_, err := w.Write([]byte("record\n"))
+				require.NoError(t, err)
+			}
+			_, err := w.Write([]byte("record\n"))
+			assert.ErrorIs(t, err, HTTPCollectorBufferFullErr)
+
+			err = w.Flush(context.Background())
+			assert.ErrorContains(t, err, http.StatusText(tt.status))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+
+			f.setStatus(0)
+			require.NoError(t, w.Close())
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			assert.Len(t, f.headers, tt.wantCalls)
+			if tt.wantKept {
+				assert.Equal(t, []string{"record\nrecord\n"}, f.bodies)
+			} else {
+				assert.Empty(t, f.bodies)
+			}
+		})
+	}
+}
+
+func TestSplunkHECBatch(t *testing.T) {
+	received := []time.Time{time.UnixMilli(1699530320500), time.UnixMilli(1699530321000)}
+	body, contentType, err := SplunkHECBatch("cef")([]string{"CEF:1|a", "CEF:1|b"}, received)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+
+	dec := json.NewDecoder(strings.NewReader(string(body)))
+	var events []map[string]any
+	for dec.More() {
+		var event map[string]any
+		require.NoError(t, dec.Decode(&event))
+		events = append(events, event)
+	}
+	assert.Equal(t, []map[string]any{
+		{"event": "CEF:1|a", "time": 1699530320.5, "sourcetype": "cef"},
+		{"event": "CEF:1|b", "time": 1699530321.0, "sourcetype": "cef"},
+	}, events)
+}
+
+func Test_retryableStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, true},
+		{http.StatusRequestEntityTooLarge, false},
+		{http.StatusRequestTimeout, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			assert.Equal(t, tt.want, retryableStatus(tt.status))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package cefevent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JournalUnavailableErr error when the systemd journal isn't available, e.g. on platforms other than Linux
+var JournalUnavailableErr = errors.New("systemd journal unavailable")
+
+// journalSocket is where journald listens for its native protocol
+var journalSocket = "/run/systemd/journal/socket"
+
+// JournalWriter sends each record written to it to the systemd journal, with the record as MESSAGE. The header fields
+// and selected extensions are mirrored as journal fields, e.g. CEF_DEVICE_EVENT_CLASS_ID and CEF_SRC, for querying
+// with journalctl, and PRIORITY is derived from the event's severity. Use it as the output of a Logger with
+// OmitSyslogHeader, as the journal adds its own timestamp & hostname. It is safe to use from multiple goroutines.
+type JournalWriter struct {
+	identifier string
+	keys       []string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// JournalOption configures a JournalWriter
+type JournalOption func(w *JournalWriter)
+
+// WithJournalIdentifier sets SYSLOG_IDENTIFIER, shown by journalctl and matched by journalctl -t. Defaults to the
+// program's name
+func WithJournalIdentifier(identifier string) JournalOption {
+	return func(w *JournalWriter) {
+		w.identifier = identifier
+	}
+}
+
+// WithJournalExtensions sets the extension keys mirrored as journal fields, named CEF_ followed by the upper case key,
+// e.g. "suser" as CEF_SUSER. Defaults to src & dst
+func WithJournalExtensions(keys ...string) JournalOption {
+	return func(w *JournalWriter) {
+		w.keys = keys
+	}
+}
+
+// NewJournalWriter connects to the local systemd journal. It returns an error wrapping JournalUnavailableErr on
+// platforms other than Linux.
+func NewJournalWriter(opts ...JournalOption) (*JournalWriter, error) {
+	w := &JournalWriter{identifier: filepath.Base(os.Args[0]), keys: []string{"src", "dst"}}
+	for _, opt := range opts {
+		opt(w)
+	}
+	conn, err := dialJournal(journalSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journal: %w", err)
+	}
+	w.conn = conn
+	return w, nil
+}
+
+// Write sends p as a single journal entry, after removing any trailing newline
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	entry := w.entry(string(trimRecord(p)))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return 0, net.ErrClosed
+	}
+	if err := w.send(entry); err != nil {
+		return 0, fmt.Errorf("failed to write to journal: %w", err)
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the journal, after which Write returns net.ErrClosed
+func (w *JournalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// entry encodes record as a journal entry in the native protocol. Records which can't be parsed are sent with only
+// MESSAGE, PRIORITY & SYSLOG_IDENTIFIER.
+func (w *JournalWriter) entry(record string) []byte {
+	var b []byte
+	b = appendJournalField(b, "MESSAGE", record)
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", w.identifier)
+	e, err := Parse(record)
+	if err != nil {
+		return appendJournalField(b, "PRIORITY", strconv.Itoa(syslogInformational))
+	}
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(syslogSeverity(e.Severity)))
+	b = appendJournalField(b, "CEF_DEVICE_VENDOR", e.DeviceVendor)
+	b = appendJournalField(b, "CEF_DEVICE_PRODUCT", e.DeviceProduct)
+	b = appendJournalField(b, "CEF_DEVICE_EVENT_CLASS_ID", e.DeviceEventClassId)
+	b = appendJournalField(b, "CEF_NAME", e.Name)
+	b = appendJournalField(b, "CEF_SEVERITY", e.Severity)
+	for key, value := range e.Extensions.Fields() {
+		if slices.Contains(w.keys, key) {
+			b = appendJournalField(b, journalFieldName(key), value)
+		}
+	}
+	return b
+}
+
+// journalFieldName returns the journal field mirroring an extension key. Field names may only contain upper case
+// letters, digits & underscores.
+func journalFieldName(key string) string {
+	return "CEF_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// appendJournalField appends a field in the native protocol: NAME=value and a newline, or for values containing a
+// newline, NAME and a newline followed by the value's length as a little endian uint64, the value, and a newline
+func appendJournalField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package cefevent
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// dialJournal connects to journald's datagram socket
+func dialJournal(path string) (*net.UnixConn, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, errors.Join(JournalUnavailableErr, err)
+	}
+	return conn, nil
+}
+
+// send sends an entry to the journal. Entries too large for a datagram are written to an unlinked temporary file and
+// its descriptor passed instead, as sd_journal_send does.
+func (w *JournalWriter) send(entry []byte) error {
+	_, err := w.conn.Write(entry)
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+	f, err := os.CreateTemp("/dev/shm", "cef-journal-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(entry); err != nil {
+		return err
+	}
+	// WriteMsgUnix refuses connected datagram sockets, so send on the raw socket
+	raw, err := w.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	var sendErr error
+	if err := raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
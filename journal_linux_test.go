@@ -0,0 +1,65 @@
+package cefevent
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalWriter_Write(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "socket")
+	defer func(socket string) { journalSocket = socket }(journalSocket)
+	journalSocket = path
+	_, err := NewJournalWriter()
+	assert.ErrorIs(t, err, JournalUnavailableErr)
+
+	addr := &net.UnixAddr{Name: path, Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadBuffer(1<<20))
+	w, err := NewJournalWriter(WithJournalIdentifier("app"), WithJournalExtensions())
+	require.NoError(t, err)
+	defer w.Close()
+
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader())
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	buf := make([]byte, 1<<16)
+	oob := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, _, _, err := conn.ReadMsgUnix(buf, oob)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=CEF:1|v|p|1|100|test|Low|\nSYSLOG_IDENTIFIER=app\nPRIORITY=6\n", string(buf[:n])[:67])
+
+	// Entries too large for a datagram are passed as a file descriptor
+	require.NoError(t, l.LogLow("100", "test", Extensions{Message: strings.Repeat("x", 1<<20)}))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	require.NoError(t, err)
+	require.Len(t, fds, 1)
+	f := os.NewFile(uintptr(fds[0]), "entry")
+	defer f.Close()
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	entry, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(entry, []byte("MESSAGE=CEF:1|v|p|1|100|test|Low|msg=xxx")))
+
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte("CEF:1|v|p|1|100|test|Low|"))
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package cefevent
+
+import "net"
+
+// dialJournal fails as the journal is only available on Linux
+func dialJournal(string) (*net.UnixConn, error) {
+	return nil, JournalUnavailableErr
+}
+
+// send fails as the journal is only available on Linux
+func (w *JournalWriter) send([]byte) error {
+	return JournalUnavailableErr
+}
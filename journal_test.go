@@ -0,0 +1,35 @@
+package cefevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_appendJournalField(t *testing.T) {
+	assert.Equal(t, "MESSAGE=hello\n", string(appendJournalField(nil, "MESSAGE", "hello")))
+	assert.Equal(t, "MESSAGE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n", string(appendJournalField(nil, "MESSAGE", "a\nb")))
+}
+
+func Test_journalFieldName(t *testing.T) {
+	assert.Equal(t, "CEF_SRC", journalFieldName("src"))
+	assert.Equal(t, "CEF_DEVICECUSTOMDATE1", journalFieldName("deviceCustomDate1"))
+	assert.Equal(t, "CEF_ACME_USER", journalFieldName("acme.user"))
+}
+
+func TestJournalWriter_entry(t *testing.T) {
+	w := &JournalWriter{identifier: "app", keys: []string{"src", "suser"}}
+	assert.Equal(t, "MESSAGE=CEF:1|v|p|1|100|login|High|src=192.0.2.1 dst=192.0.2.2 suser=alice\n"+
+		"SYSLOG_IDENTIFIER=app\n"+
+		"PRIORITY=3\n"+
+		"CEF_DEVICE_VENDOR=v\n"+
+		"CEF_DEVICE_PRODUCT=p\n"+
+		"CEF_DEVICE_EVENT_CLASS_ID=100\n"+
+		"CEF_NAME=login\n"+
+		"CEF_SEVERITY=High\n"+
+		"CEF_SRC=192.0.2.1\n"+
+		"CEF_SUSER=alice\n",
+		string(w.entry("CEF:1|v|p|1|100|login|High|src=192.0.2.1 dst=192.0.2.2 suser=alice")))
+
+	assert.Equal(t, "MESSAGE=not cef\nSYSLOG_IDENTIFIER=app\nPRIORITY=6\n", string(w.entry("not cef")))
+}
@@ -0,0 +1,117 @@
+package cefevent
+
+import (
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// MarshalJSON encodes e as a JSON object keyed by the extension's short keys (e.g. "dpt"), as accepted by SIEM HTTP
+// collectors that take CEF fields as JSON. Counts, ports and other numeric fields are JSON numbers, times are epoch
+// milliseconds, and everything else, including addresses and CustomExtensions, is a string.
+func (e Extensions) MarshalJSON() ([]byte, error) {
+	return e.appendJSON(nil), nil
+}
+
+// MarshalJSON encodes e as a JSON object holding the header fields (version, deviceVendor, deviceProduct,
+// deviceVersion, deviceEventClassId, name and severity) alongside the extension fields, encoded as by
+// Extensions.MarshalJSON.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return e.appendJSON(nil), nil
+}
+
+func (e Event) appendJSON(b []byte) []byte {
+	b = append(b, `{"version":`...)
+	b = strconv.AppendUint(b, uint64(e.Version), 10)
+	for _, f := range [...]struct{ key, value string }{
+		{"deviceVendor", e.DeviceVendor},
+		{"deviceProduct", e.DeviceProduct},
+		{"deviceVersion", e.DeviceVersion},
+		{"deviceEventClassId", e.DeviceEventClassId},
+		{"name", e.Name},
+		{"severity", e.Severity},
+	} {
+		b = append(b, ',')
+		b = appendJSONString(b, f.key)
+		b = append(b, ':')
+		b = appendJSONString(b, f.value)
+	}
+	return e.Extensions.appendJSONFields(b, true)
+}
+
+func (e Extensions) appendJSON(b []byte) []byte {
+	b = append(b, '{')
+	return e.appendJSONFields(b, false)
+}
+
+// appendJSONFields appends each field as a JSON property then closes the object. comma is set if properties have
+// already been written.
+func (e Extensions) appendJSONFields(b []byte, comma bool) []byte {
+	e.walk(func(key string, v fieldValue) bool {
+		if comma {
+			b = append(b, ',')
+		}
+		comma = true
+		b = appendJSONString(b, key)
+		b = append(b, ':')
+		b = appendJSONValue(b, v)
+		return true
+	})
+	return append(b, '}')
+}
+
+// appendJSONValue appends v to b as JSON
+func appendJSONValue(b []byte, v fieldValue) []byte {
+	switch v.kind {
+	case intKind:
+		return strconv.AppendInt(b, v.i, 10)
+	case uintKind:
+		return strconv.AppendUint(b, v.u, 10)
+	case timeKind:
+		return strconv.AppendInt(b, v.t.UnixMilli(), 10)
+	case floatKind:
+		if math.IsNaN(v.f) || math.IsInf(v.f, 0) {
+			return append(b, "null"...)
+		}
+		return strconv.AppendFloat(b, v.f, 'f', -1, 64)
+	case stringKind:
+		return appendJSONString(b, v.s)
+	}
+	return appendJSONString(b, v.String())
+}
+
+// appendJSONString appends s to b as a quoted JSON string. Invalid UTF-8 is replaced with U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, `\n`...)
+			case c == '\r':
+				b = append(b, `\r`...)
+			case c == '\t':
+				b = append(b, `\t`...)
+			case c < 0x20:
+				b = append(b, `\u00`...)
+				b = append(b, hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "\ufffd"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
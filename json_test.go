@@ -0,0 +1,69 @@
+package cefevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ json.Marshaler = Extensions{}
+	_ json.Marshaler = Event{}
+)
+
+func TestExtensions_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Extensions
+		want string
+	}{
+		{"empty", Extensions{}, `{}`},
+		{
+			"types",
+			Extensions{
+				Message:                    "a \"quoted\"\n\tline\x01",
+				BaseEventCount:             3,
+				DestinationPort:            ptr(uint(443)),
+				DestinationAddress:         net.IPv4(10, 0, 0, 2),
+				DeviceReceiptTime:          testTime(),
+				DeviceCustomFloatingPoint1: Labeled("ratio", 0.5),
+				CustomExtensions:           map[string]string{"k": "v"},
+			},
+			`{"msg":"a \"quoted\"\n\tline\u0001","cnt":3,"dpt":443,"dst":"10.0.0.2","rt":1699530320000,` +
+				`"cfp1":0.5,"cfp1Label":"ratio","k":"v"}`,
+		},
+		{"invalid_utf8", Extensions{Message: "a\xffb"}, "{\"msg\":\"a\ufffdb\"}"},
+		{"nan", Extensions{DeviceCustomFloatingPoint1: Labeled("x", math.NaN())}, `{"cfp1":null,"cfp1Label":"x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.e)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestEvent_MarshalJSON(t *testing.T) {
+	e := Event{Version: 1, DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100",
+		Name: "test", Severity: LowSeverity, Extensions: Extensions{Message: "hi"}}
+	got, err := json.Marshal(e)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":1,"deviceVendor":"v","deviceProduct":"p","deviceVersion":"1","deviceEventClassId":"100",`+
+		`"name":"test","severity":"Low","msg":"hi"}`, string(got))
+}
+
+func TestWithFormat_json(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", WithStaticHostname("host"), WithTimeFunc(testTime), WithFormat(JSON))
+	require.NoError(t, l.LogLow("100", "first", Extensions{}))
+	require.NoError(t, l.LogHigh("101", "second", Extensions{DestinationPort: ptr(uint(22))}))
+	assert.Equal(t, `{"version":1,"deviceVendor":"v","deviceProduct":"p","deviceVersion":"1","deviceEventClassId":"100","name":"first","severity":"Low"}`+"\n"+
+		`{"version":1,"deviceVendor":"v","deviceProduct":"p","deviceVersion":"1","deviceEventClassId":"101","name":"second","severity":"High","dpt":22}`+"\n",
+		buf.String())
+}
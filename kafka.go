@@ -0,0 +1,119 @@
+package cefevent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KafkaMessage is a record to publish to Kafka
+type KafkaMessage struct {
+	Topic string
+	Key   []byte // nil for the producer's default partitioning
+	Value []byte // the record, without any trailing newline
+
+	// Failed reports a delivery failure detected after Produce returned, for asynchronous producers
+	Failed func(err error)
+}
+
+// KafkaProducer publishes messages with a Kafka client, e.g. a thin adapter over franz-go's Client.Produce or sarama's
+// AsyncProducer, keeping the client library out of this module. Produce may return before the message is delivered, in
+// which case later delivery failures must be reported with msg.Failed. Produce must not retain ctx.
+type KafkaProducer interface {
+	Produce(ctx context.Context, msg KafkaMessage) error
+}
+
+// KafkaKeyFunc selects the partitioning key for an event. Returning nil leaves partitioning to the producer
+type KafkaKeyFunc func(e Event) []byte
+
+// KafkaKeyByClassId keys messages by device event class ID, so each class of event stays in order
+func KafkaKeyByClassId(e Event) []byte {
+	return []byte(e.DeviceEventClassId)
+}
+
+// KafkaKeyBySourceHost keys messages by source host name, or source address if there is no name, so each source's
+// events stay in order
+func KafkaKeyBySourceHost(e Event) []byte {
+	if e.Extensions.SourceHostName != "" {
+		return []byte(e.Extensions.SourceHostName)
+	}
+	if e.Extensions.SourceAddress != nil {
+		return []byte(e.Extensions.SourceAddress.String())
+	}
+	return nil
+}
+
+// KafkaWriter publishes each record written to it to a Kafka topic, for SIEMs ingesting from a Kafka bus. Use it as a
+// Logger's output.
+type KafkaWriter struct {
+	producer KafkaProducer
+	topic    string
+	key      KafkaKeyFunc
+	onError  func(msg KafkaMessage, err error)
+	timeout  time.Duration
+}
+
+// KafkaOption configures a KafkaWriter
+type KafkaOption func(w *KafkaWriter)
+
+// WithKafkaKey sets how messages are keyed. Records are parsed to select the key, so this costs a Parse per record.
+// Defaults to no key
+func WithKafkaKey(key KafkaKeyFunc) KafkaOption {
+	return func(w *KafkaWriter) {
+		w.key = key
+	}
+}
+
+// WithKafkaDeliveryErrors sets a function called with each message that couldn't be published, whether Produce failed
+// or the producer reported the failure later through KafkaMessage.Failed
+func WithKafkaDeliveryErrors(fn func(msg KafkaMessage, err error)) KafkaOption {
+	return func(w *KafkaWriter) {
+		w.onError = fn
+	}
+}
+
+// WithKafkaTimeout limits how long each Produce call may take. Defaults to 10s
+func WithKafkaTimeout(d time.Duration) KafkaOption {
+	return func(w *KafkaWriter) {
+		w.timeout = d
+	}
+}
+
+// NewKafkaWriter returns a writer publishing records to topic with producer
+func NewKafkaWriter(producer KafkaProducer, topic string, opts ...KafkaOption) *KafkaWriter {
+	w := &KafkaWriter{producer: producer, topic: topic, timeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Write publishes p as a single message, after removing any trailing newline. p is copied, as producers may send it
+// after Write returns.
+func (w *KafkaWriter) Write(p []byte) (int, error) {
+	return w.WriteContext(context.Background(), p)
+}
+
+// WriteContext is Write, giving up once ctx is done or the producer timeout elapses
+func (w *KafkaWriter) WriteContext(ctx context.Context, p []byte) (int, error) {
+	msg := KafkaMessage{Topic: w.topic, Value: append([]byte(nil), trimRecord(p)...)}
+	if w.key != nil {
+		if e, err := Parse(string(msg.Value)); err == nil {
+			msg.Key = w.key(e)
+		}
+	}
+	if w.onError != nil {
+		msg.Failed = func(err error) {
+			w.onError(msg, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	if err := w.producer.Produce(ctx, msg); err != nil {
+		if w.onError != nil {
+			w.onError(msg, err)
+		}
+		return 0, fmt.Errorf("failed to publish to kafka: %w", err)
+	}
+	return len(p), nil
+}
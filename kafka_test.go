@@ -0,0 +1,92 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProducer records messages, failing with err if set
+type fakeProducer struct {
+	msgs []KafkaMessage
+	err  error
+}
+
+func (f *fakeProducer) Produce(ctx context.Context, msg KafkaMessage) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("no deadline")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.err != nil {
+		return f.err
+	}
+	f.msgs = append(f.msgs, msg)
+	return nil
+}
+
+func TestKafkaWriter_Write(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     KafkaKeyFunc
+		ext     Extensions
+		wantKey []byte
+	}{
+		{"no_key", nil, Extensions{}, nil},
+		{"class_id", KafkaKeyByClassId, Extensions{}, []byte("100")},
+		{"source_host", KafkaKeyBySourceHost, Extensions{SourceHostName: "web1", SourceAddress: net.IPv4(192, 0, 2, 1)}, []byte("web1")},
+		{"source_address", KafkaKeyBySourceHost, Extensions{SourceAddress: net.IPv4(192, 0, 2, 1)}, []byte("192.0.2.1")},
+		{"no_source", KafkaKeyBySourceHost, Extensions{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProducer{}
+			var opts []KafkaOption
+			if tt.key != nil {
+				opts = append(opts, WithKafkaKey(tt.key))
+			}
+			l := NewLogger(NewKafkaWriter(p, "cef", opts...), "v", "p", "1", OmitSyslogHeader(),
+				WithRecordTerminator(NewlineTerminator))
+			require.NoError(t, l.LogLow("100", "test", tt.ext))
+			require.Len(t, p.msgs, 1)
+			assert.Equal(t, "cef", p.msgs[0].Topic)
+			assert.Equal(t, tt.wantKey, p.msgs[0].Key)
+			assert.NotContains(t, string(p.msgs[0].Value), "\n")
+		})
+	}
+}
+
+func TestKafkaWriter_Write_errors(t *testing.T) {
+	p := &fakeProducer{err: errors.New("broker unavailable")}
+	var failed []string
+	w := NewKafkaWriter(p, "cef", WithKafkaDeliveryErrors(func(msg KafkaMessage, err error) {
+		failed = append(failed, string(msg.Value)+": "+err.Error())
+	}))
+	_, err := w.Write([]byte("CEF:1|v|p|1|100|test|Low|"))
+	assert.EqualError(t, err, "failed to publish to kafka: broker unavailable")
+
+	// Asynchronous producers report failures after Produce returns
+	p.err = nil
+	_, err = w.Write([]byte("CEF:1|v|p|1|101|test|Low|"))
+	require.NoError(t, err)
+	p.msgs[0].Failed(errors.New("message too large"))
+	assert.Equal(t, []string{
+		"CEF:1|v|p|1|100|test|Low|: broker unavailable",
+		"CEF:1|v|p|1|101|test|Low|: message too large",
+	}, failed)
+}
+
+func TestKafkaWriter_WriteContext(t *testing.T) {
+	p := &fakeProducer{}
+	w := NewKafkaWriter(p, "cef")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := w.WriteContext(ctx, []byte("CEF:1|v|p|1|100|test|Low|\n"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, p.msgs)
+}
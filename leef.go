@@ -0,0 +1,132 @@
+package cefevent
+
+import "strconv"
+
+// Format is the record format a Logger writes
+type Format int
+
+const (
+	CEF   Format = iota // ArcSight Common Event Format, the default
+	LEEF1               // IBM QRadar Log Event Extended Format 1.0, with tab separated attributes
+	LEEF2               // IBM QRadar Log Event Extended Format 2.0, with a configurable attribute delimiter
+	JSON                // Newline delimited JSON objects, as encoded by Event.MarshalJSON, without the syslog header
+)
+
+// leefKeys maps CEF extension keys to their LEEF predefined attribute equivalents. Other keys are emitted unchanged.
+var leefKeys = map[string]string{
+	"dpt":                          "dstPort",
+	"dmac":                         "dstMAC",
+	"destinationTranslatedAddress": "dstPostNAT",
+	"destinationTranslatedPort":    "dstPostNATPort",
+	"in":                           "srcBytes",
+	"out":                          "dstBytes",
+	"request":                      "url",
+	"smac":                         "srcMAC",
+	"sourceTranslatedAddress":      "srcPostNAT",
+	"sourceTranslatedPort":         "srcPostNATPort",
+	"spt":                          "srcPort",
+	"suser":                        "usrName",
+}
+
+// Layout of devTime, and its declaration in each record's devTimeFormat attribute
+const (
+	leefTimeLayout = "Jan 02 2006 15:04:05.000 MST"
+	leefTimeFormat = "MMM dd yyyy HH:mm:ss.SSS z"
+)
+
+// WithFormat sets the record format. Defaults to CEF. LEEF records use the Logger's vendor, product & version and the
+// event's class ID as the event ID, along with the same extension fields: keys with a LEEF equivalent are renamed
+// (e.g. spt is emitted as srcPort, rt as devTime), the severity is emitted as sev, and other keys are unchanged. LEEF
+// has no event name, as QRadar names events by mapping their event ID.
+func WithFormat(f Format) LoggerConfigOption {
+	return func(l *Logger) {
+		l.format = f
+	}
+}
+
+// WithLEEFDelimiter sets the character separating LEEF 2.0 attributes. Defaults to tab
+func WithLEEFDelimiter(d byte) LoggerConfigOption {
+	return func(l *Logger) {
+		l.leefDelimiter = d
+	}
+}
+
+// appendLEEF appends e formatted as a LEEF record to b. delimiter is only used for LEEF 2.0
+func (e Event) appendLEEF(b []byte, format Format, delimiter byte) []byte {
+	if format == LEEF1 || delimiter == 0 {
+		delimiter = '\t'
+	}
+	if format == LEEF1 {
+		b = append(b, "LEEF:1.0"...)
+	} else {
+		b = append(b, "LEEF:2.0"...)
+	}
+	for _, field := range [...]string{e.DeviceVendor, e.DeviceProduct, e.DeviceVersion, e.DeviceEventClassId} {
+		b = append(b, '|')
+		b = appendEscapedHeader(b, field)
+	}
+	b = append(b, '|')
+	if format == LEEF2 {
+		if delimiter > ' ' && delimiter < 0x7f && delimiter != '|' {
+			b = append(b, delimiter)
+		} else {
+			b = append(b, 'x')
+			b = append(b, "0123456789abcdef"[delimiter>>4], "0123456789abcdef"[delimiter&0xf])
+		}
+		b = append(b, '|')
+	}
+
+	start := len(b)
+	attr := func(key string) {
+		if len(b) > start {
+			b = append(b, delimiter)
+		}
+		b = append(b, key...)
+		b = append(b, '=')
+	}
+	if level, err := severityLevel(e.Severity); err == nil && level >= 0 {
+		attr("sev")
+		b = strconv.AppendInt(b, int64(max(level, 1)), 10)
+	}
+	e.Extensions.walk(func(key string, v fieldValue) bool {
+		if key == "rt" {
+			attr("devTime")
+			b = v.t.UTC().AppendFormat(b, leefTimeLayout)
+			attr("devTimeFormat")
+			b = append(b, leefTimeFormat...)
+			return true
+		}
+		if leefKey, ok := leefKeys[key]; ok {
+			key = leefKey
+		}
+		attr(key)
+		switch v.kind {
+		case stringKind:
+			b = appendEscapedLEEF(b, v.s, delimiter)
+		case timeKind:
+			b = strconv.AppendInt(b, v.t.UnixMilli(), 10)
+		default:
+			b = appendEscapedLEEF(b, v.String(), delimiter)
+		}
+		return true
+	})
+	return b
+}
+
+// appendEscapedLEEF appends a LEEF attribute value to b, escaping the delimiter and backslashes with a backslash and
+// line breaks as \n and \r, so records stay on a single line
+func appendEscapedLEEF(b []byte, v string, delimiter byte) []byte {
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\\', delimiter:
+			b = append(b, '\\', c)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
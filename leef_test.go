@@ -0,0 +1,90 @@
+package cefevent
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvent_appendLEEF(t *testing.T) {
+	event := Event{
+		DeviceVendor:       "Acme",
+		DeviceProduct:      "Fire|wall",
+		DeviceVersion:      "1.0",
+		DeviceEventClassId: "100",
+		Name:               "Login failed",
+		Severity:           HighSeverity,
+		Extensions: Extensions{
+			Message:           "bad\tpassword\n",
+			SourceAddress:     net.IPv4(10, 0, 0, 1),
+			SourcePort:        ptr(uint(51234)),
+			SourceUserName:    "alice",
+			DeviceReceiptTime: testTime(),
+			CustomExtensions:  map[string]string{"k": `a^b\c`},
+		},
+	}
+	tests := []struct {
+		name      string
+		format    Format
+		delimiter byte
+		want      string
+	}{
+		{
+			"leef1",
+			LEEF1,
+			'^',
+			"LEEF:1.0|Acme|Fire\\|wall|1.0|100|sev=7\tmsg=bad\\\tpassword\\n\tdevTime=Nov 09 2023 11:45:20.000 UTC\t" +
+				"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\tsrcPort=51234\tsrc=10.0.0.1\tusrName=alice\tk=a^b\\\\c",
+		},
+		{
+			"leef2_default_delimiter",
+			LEEF2,
+			0,
+			"LEEF:2.0|Acme|Fire\\|wall|1.0|100|x09|sev=7\tmsg=bad\\\tpassword\\n\tdevTime=Nov 09 2023 11:45:20.000 UTC\t" +
+				"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\tsrcPort=51234\tsrc=10.0.0.1\tusrName=alice\tk=a^b\\\\c",
+		},
+		{
+			"leef2_caret",
+			LEEF2,
+			'^',
+			"LEEF:2.0|Acme|Fire\\|wall|1.0|100|^|sev=7^msg=bad\tpassword\\n^devTime=Nov 09 2023 11:45:20.000 UTC^" +
+				"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z^srcPort=51234^src=10.0.0.1^usrName=alice^k=a\\^b\\\\c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(event.appendLEEF(nil, tt.format, tt.delimiter)))
+		})
+	}
+}
+
+func TestEvent_appendLEEF_severity(t *testing.T) {
+	tests := []struct {
+		name     string
+		severity string
+		want     string
+	}{
+		{"unknown", UnknownSeverity, "LEEF:2.0|v|p|1|100|x09|"},
+		{"low", LowSeverity, "LEEF:2.0|v|p|1|100|x09|sev=1"},
+		{"zero", "0", "LEEF:2.0|v|p|1|100|x09|sev=1"},
+		{"numeric", "5", "LEEF:2.0|v|p|1|100|x09|sev=5"},
+		{"very_high", VeryHighSeverity, "LEEF:2.0|v|p|1|100|x09|sev=9"},
+		{"invalid", "Severe", "LEEF:2.0|v|p|1|100|x09|"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Event{DeviceVendor: "v", DeviceProduct: "p", DeviceVersion: "1", DeviceEventClassId: "100", Severity: tt.severity}
+			assert.Equal(t, tt.want, string(e.appendLEEF(nil, LEEF2, 0)))
+		})
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", WithStaticHostname("host"), WithTimeFunc(testTime),
+		WithFormat(LEEF2), WithLEEFDelimiter('|'))
+	assert.NoError(t, l.LogMedium("100", "test", Extensions{DestinationPort: ptr(uint(443))}))
+	assert.Equal(t, "Nov 9 11:45:20 host LEEF:2.0|v|p|1|100|x7c|sev=4|dstPort=443", buf.String())
+}
@@ -0,0 +1,24 @@
+package cefevent
+
+// legacyKeys maps standard extension keys to the misspellings emitted by earlier versions of this package
+var legacyKeys = map[string]string{
+	"deviceNtDomain": "deviceNtInterface",
+	"dvchost":        "dcvhost",
+}
+
+// WithStandardKeys emits the dictionary keys dvchost and deviceNtDomain instead of the spellings dcvhost and
+// deviceNtInterface, which Loggers emit by default for compatibility with SIEM parsers built against earlier versions of
+// this package. Opt in once those parsers accept the standard keys.
+func WithStandardKeys() LoggerConfigOption {
+	return func(l *Logger) {
+		l.keyAliases = nil
+	}
+}
+
+// WithLegacyKeys emits the key spellings used by earlier versions of this package (dcvhost instead of dvchost and
+// deviceNtInterface instead of deviceNtDomain). This is the default; the option undoes WithStandardKeys, e.g. in a Clone.
+func WithLegacyKeys() LoggerConfigOption {
+	return func(l *Logger) {
+		l.keyAliases = legacyKeys
+	}
+}
@@ -0,0 +1,34 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithStandardKeys(t *testing.T) {
+	ext := Extensions{DeviceHostName: "evt.example.com", DeviceNtDomain: "CORP", Message: "hello"}
+	tests := []struct {
+		name string
+		fns  []LoggerConfigOption
+		want string
+	}{
+		{"default", nil, "msg=hello deviceNtInterface=CORP dcvhost=evt.example.com"},
+		{"standard", []LoggerConfigOption{WithStandardKeys()}, "msg=hello deviceNtDomain=CORP dvchost=evt.example.com"},
+		{"legacy", []LoggerConfigOption{WithStandardKeys(), WithLegacyKeys()}, "msg=hello deviceNtInterface=CORP dcvhost=evt.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			l := NewLogger(buf, "testVendor", "testProduct", "1.0", append(tt.fns, OmitSyslogHeader())...)
+			if assert.NoError(t, l.LogLow("100", "test", ext)) {
+				assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|100|test|Low|"+tt.want, buf.String())
+			}
+		})
+	}
+	assert.Equal(t, "msg=hello deviceNtInterface=CORP dcvhost=evt.example.com", ext.String())
+	assert.Equal(t, "msg=hello deviceNtInterface=CORP dcvhost=evt.example.com", string(ext.AppendCEF(nil)))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|msg=hello deviceNtInterface=CORP dcvhost=evt.example.com",
+		Event{1, "v", "p", "1", "100", "test", LowSeverity, ext}.String())
+}
@@ -0,0 +1,73 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the longest msg value ArcSight stores without truncating
+const maxMessageLength = 1023
+
+// LintLevel is the seriousness of a LintFinding
+type LintLevel int
+
+const (
+	LintWarning LintLevel = iota // Legal but suspicious, e.g. an empty name
+	LintError                    // Violates the CEF spec
+)
+
+func (l LintLevel) String() string {
+	if l == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// LintFinding is a single problem found by Lint
+type LintFinding struct {
+	// Level is LintError for spec violations and LintWarning for suspicious but legal events
+	Level LintLevel
+	// Field is the header field name or extension key the finding refers to
+	Field string
+	// Err describes the problem. Spec violations wrap the matching sentinel error, e.g. InvalidPortErr
+	Err error
+}
+
+func (f LintFinding) String() string {
+	return f.Level.String() + ": " + f.Err.Error()
+}
+
+// Lint checks an event, returning errors for spec violations and warnings for events which are legal but likely to be
+// mistakes (empty class ID or name, missing rt, oversized msg). Returns nil if nothing was found.
+func Lint(deviceEventClassId, name, severity string, extensions Extensions) []LintFinding {
+	var findings []LintFinding
+	for _, err := range checkEvent(severity, extensions) {
+		f := LintFinding{Level: LintError, Err: err}
+		var fe *fieldError
+		if errors.As(err, &fe) {
+			f.Field = fe.field
+		}
+		findings = append(findings, f)
+	}
+	warn := func(field, format string, args ...any) {
+		findings = append(findings, LintFinding{
+			Level: LintWarning,
+			Field: field,
+			Err:   &fieldError{field, fmt.Errorf(format, args...)},
+		})
+	}
+	if deviceEventClassId == "" {
+		warn("deviceEventClassId", "empty")
+	}
+	if name == "" {
+		warn("name", "empty")
+	}
+	if extensions.DeviceReceiptTime.IsZero() {
+		warn("rt", "missing receipt time")
+	}
+	if n := utf8.RuneCountInString(extensions.Message); n > maxMessageLength {
+		warn("msg", "%d characters is longer than %d and may be truncated", n, maxMessageLength)
+	}
+	return findings
+}
@@ -0,0 +1,70 @@
+package cefevent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name               string
+		deviceEventClassId string
+		eventName          string
+		severity           string
+		extensions         Extensions
+		want               []string
+	}{
+		{
+			"clean",
+			"100",
+			"login",
+			LowSeverity,
+			Extensions{DeviceReceiptTime: testTime()},
+			nil,
+		},
+		{
+			"warnings",
+			"",
+			"",
+			LowSeverity,
+			Extensions{Message: strings.Repeat("x", 1024)},
+			[]string{
+				`warning: "deviceEventClassId": empty`,
+				`warning: "name": empty`,
+				`warning: "rt": missing receipt time`,
+				`warning: "msg": 1024 characters is longer than 1023 and may be truncated`,
+			},
+		},
+		{
+			"errors",
+			"100",
+			"login",
+			"Severe",
+			Extensions{DeviceReceiptTime: testTime(), DestinationPort: ptr(uint(65536))},
+			[]string{
+				`error: "severity": "Severe": invalid severity`,
+				`error: "dpt": 65536: invalid port`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range Lint(tt.deviceEventClassId, tt.eventName, tt.severity, tt.extensions) {
+				got = append(got, f.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLint_fields(t *testing.T) {
+	findings := Lint("100", "login", LowSeverity, Extensions{DeviceDirection: ptr(uint8(3)), DeviceReceiptTime: testTime()})
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, LintError, findings[0].Level)
+		assert.Equal(t, "deviceDirection", findings[0].Field)
+		assert.ErrorIs(t, findings[0].Err, InvalidDeviceDirectionErr)
+	}
+}
@@ -2,26 +2,31 @@
 package cefevent
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
-	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var defaultLogger *Logger
+var defaultLogger atomic.Pointer[Logger]
 
 func init() {
-	defaultLogger = NewLogger(os.Stdout, "go", "cefevent", "v0.1")
+	defaultLogger.Store(NewLogger(os.Stdout, "go", "cefevent", "v0.1"))
 }
 
-var headerEscapeRegex = regexp.MustCompile(`([|\\])`)
-
 // InvalidCefVersionErr error when provided an invalid CEF version. Value should be 0 or 1
 var InvalidCefVersionErr = errors.New("invalid cef version")
 
+// LoggerPanicErr error when a panic is recovered while logging an event
+var LoggerPanicErr = errors.New("recovered panic while logging")
+
 // LoggerConfigOption is a configuring function for a Logger
 type LoggerConfigOption func(l *Logger)
 
@@ -53,18 +58,172 @@ func OmitSyslogHeader() LoggerConfigOption {
 	}
 }
 
-// Logger is a logger for cef events
+// WithCustomExtensionPrefix prefixes every CustomExtensions key with namespace (e.g. "acmeapp_") when logging, to avoid
+// collisions with standard keys and other producers. Keys which already carry the prefix are left as they are.
+func WithCustomExtensionPrefix(namespace string) LoggerConfigOption {
+	return func(l *Logger) {
+		l.customPrefix = namespace
+	}
+}
+
+// WithErrorHandler sets a callback for errors which can't be returned to a caller, such as failed background writes,
+// and for panics recovered while logging. Recovered panics are also returned from Log wrapped in LoggerPanicErr.
+func WithErrorHandler(fn func(err error)) LoggerConfigOption {
+	return func(l *Logger) {
+		l.errorHandler = fn
+	}
+}
+
+// WithTimeFunc sets the function used for syslog header timestamps, e.g. to pin timestamps for deterministic output.
+// Defaults to time.Now
+func WithTimeFunc(fn func() time.Time) LoggerConfigOption {
+	return func(l *Logger) {
+		l.getTime = fn
+	}
+}
+
+// WithHostnameFunc sets the function used for the syslog header hostname. Defaults to os.Hostname
+func WithHostnameFunc(fn func() (string, error)) LoggerConfigOption {
+	return func(l *Logger) {
+		l.getHostname = fn
+	}
+}
+
+// WithStaticHostname sets the syslog header hostname, for environments such as containers where os.Hostname isn't
+// meaningful
+func WithStaticHostname(hostname string) LoggerConfigOption {
+	return WithHostnameFunc(func() (string, error) {
+		return hostname, nil
+	})
+}
+
+// WithConcurrentWrites lets events be written to the output concurrently rather than one at a time. Only use it with
+// writers that are safe for concurrent use and write each call atomically, e.g. UDP or unix datagram sockets, where
+// serializing writes only adds contention. SetOutput still waits for in progress writes.
+func WithConcurrentWrites() LoggerConfigOption {
+	return func(l *Logger) {
+		l.concurrentWrites = true
+	}
+}
+
+// Logger is a logger for cef events.
+//
+// A Logger is safe for concurrent use by multiple goroutines, so a single Logger can be shared across an application
+// like log.Logger. Configuration set through options is fixed once the Logger is created; SetOutput, SetMinSeverity
+// and RegisterHook are the only mutators, and they may be called while logging.
+//
+// Each event is written to the underlying writer with exactly one Write call, so events written to shared pipes or
+// datagram sockets are never interleaved or split across packets. WithBuffer instead writes several whole events per
+// call. A short write is reported as io.ErrShortWrite rather than retried, as writing the remainder separately would
+// break that guarantee. Records are formatted into pooled buffers, so writers must not retain the slice passed to
+// Write, as io.Writer requires.
 type Logger struct {
 	// addSyslogHeader add syslog style header as per spec. Configurable to allow outputting to file, where that header is omitted
 	addSyslogHeader bool
+	// facility syslog facility for the header's PRI prefix. nil if the prefix is omitted
+	facility *Facility
 	// cefVersion should be 0 or 1
 	cefVersion byte
+	// format of written records, CEF unless set with WithFormat
+	format Format
+	// leefDelimiter separates LEEF 2.0 attributes. 0 for the default, tab
+	leefDelimiter byte
+	// terminator written after each record
+	terminator RecordTerminator
 	// out writer for output
 	out io.Writer
 
-	// Manually set time & hostname functions here. This is cursed for testing.
-	getTime     func() time.Time       // You basically always want time.Now() for this
-	getHostname func() (string, error) // use os.Hostname()
+	// Time & hostname functions for the syslog header, set with WithTimeFunc & WithHostnameFunc
+	getTime     func() time.Time       // defaults to time.Now
+	getHostname func() (string, error) // defaults to os.Hostname
+
+	// writeMu serializes writes to out and guards replacing it, as summary events may be written from a background timer
+	writeMu sync.RWMutex
+
+	// concurrentWrites only hold writeMu's read lock while writing, so writes can proceed concurrently
+	concurrentWrites bool
+
+	// minLevel severityLevel of the minimum severity logged, plus one so the zero value logs everything
+	minLevel atomic.Int32
+
+	// escalators rules used to raise event severity based on content
+	escalators []*escalator
+
+	// suppressor collapses rapidly repeated events. nil if disabled
+	suppressor *repeatSuppressor
+
+	// aggregator collects identical events into aggregated events. nil if disabled
+	aggregator *aggregator
+
+	// schema registry custom extensions are checked against. nil if disabled
+	schema *Schema
+
+	// catalog event classes available to LogClass. May be nil
+	catalog *Catalog
+
+	// keyAliases alternative spellings emitted in place of standard keys
+	keyAliases map[string]string
+
+	// customPrefix namespace prepended to CustomExtensions keys
+	customPrefix string
+
+	// hasher attaches content hashes to events. nil if disabled
+	hasher *contentHasher
+
+	// clockOffset returns the skew correction added to emitted timestamps. nil if disabled
+	clockOffset func() time.Duration
+
+	// monotonic clamps emitted timestamps so they never go backwards. nil if disabled
+	monotonic *monotonicClock
+
+	// dryRun format & validate events without writing them
+	dryRun bool
+
+	// strict reject events which violate the spec
+	strict bool
+
+	// truncation enforces field length limits. nil if disabled
+	truncation *TruncationPolicy
+
+	// contextExtractors pull extensions out of the context passed to LogContext
+	contextExtractors []func(ctx context.Context) Extensions
+	// redactions functions rewriting the values of each key, in order. nil if disabled
+	redactions map[string][]func(string) string
+
+	// hooks registered with RegisterHook, replaced as a whole under hooksMu so logging needn't lock
+	hooks   atomic.Pointer[[]Hook]
+	hooksMu sync.Mutex
+
+	// buffer batches records before writing them. nil if disabled
+	buffer *recordBuffer
+
+	// throttle limits the bytes per second written. nil if disabled
+	throttle *tokenBucket
+
+	// retry retries failed writes. nil if disabled
+	retry *retryPolicy
+
+	// limiter rate limits & samples events. nil if disabled
+	limiter *eventLimiter
+
+	// monitor reports drops & output failures as events. nil if disabled
+	monitor *selfMonitor
+
+	// routes alternative writers selected by device event class ID
+	routes []ClassRoute
+
+	// metrics receives counters for events logged, dropped & failed. May be nil
+	metrics MetricsCollector
+
+	// errorHandler receives background errors & recovered panics. May be nil
+	errorHandler func(err error)
+
+	// async queues formatted events for a background writer. nil if writing synchronously
+	async *asyncQueue
+
+	// parent Logger events are passed to after merging defaults. nil unless created by With
+	parent   *Logger
+	defaults Extensions
 
 	// DeviceVendor device vendor in CEF header.
 	DeviceVendor string
@@ -87,44 +246,408 @@ func NewLogger(out io.Writer, deviceVendor, deviceProduct, deviceVersion string,
 		DeviceVendor:    deviceVendor,
 		DeviceProduct:   deviceProduct,
 		DeviceVersion:   deviceVersion,
+		keyAliases:      legacyKeys,
 	}
 	for _, fn := range fns {
 		fn(l)
 	}
+	if l.async != nil {
+		l.async.start(l)
+	}
 	return l
 }
 
-// Log logs CEF event to configured writer
-func (l *Logger) Log(deviceEventClassId, name, severity string, extensions Extensions) error {
-	b := strings.Builder{}
-	if l.addSyslogHeader {
-		b.WriteString(l.getTime().Format(`Jan 2 15:04:05`))
+// Clone creates an independent Logger inheriting l's configuration, with fns applied on top to override e.g. the writer
+// or header fields. Stateful features (escalation windows, repeat suppression, monotonic timestamps, throttling) start
+// afresh in the clone rather than sharing state with l.
+func (l *Logger) Clone(fns ...LoggerConfigOption) *Logger {
+	if l.parent != nil {
+		return l.parent.Clone(fns...).With(l.defaults)
+	}
+	l.writeMu.RLock()
+	out := l.out
+	l.writeMu.RUnlock()
+	c := &Logger{
+		addSyslogHeader:   l.addSyslogHeader,
+		facility:          l.facility,
+		cefVersion:        l.cefVersion,
+		format:            l.format,
+		leefDelimiter:     l.leefDelimiter,
+		terminator:        l.terminator,
+		out:               out,
+		getTime:           l.getTime,
+		getHostname:       l.getHostname,
+		schema:            l.schema,
+		catalog:           l.catalog,
+		keyAliases:        l.keyAliases,
+		customPrefix:      l.customPrefix,
+		hasher:            l.hasher,
+		clockOffset:       l.clockOffset,
+		dryRun:            l.dryRun,
+		strict:            l.strict,
+		truncation:        l.truncation,
+		retry:             l.retry,
+		redactions:        l.redactions,
+		contextExtractors: l.contextExtractors,
+		concurrentWrites:  l.concurrentWrites,
+		routes:            slices.Clone(l.routes),
+		metrics:           l.metrics,
+		errorHandler:      l.errorHandler,
+		DeviceVendor:      l.DeviceVendor,
+		DeviceProduct:     l.DeviceProduct,
+		DeviceVersion:     l.DeviceVersion,
+	}
+	c.minLevel.Store(l.minLevel.Load())
+	c.hooks.Store(l.hooks.Load())
+	for _, e := range l.escalators {
+		c.escalators = append(c.escalators, &escalator{rule: e.rule, level: e.level})
+	}
+	if l.suppressor != nil {
+		c.suppressor = &repeatSuppressor{window: l.suppressor.window}
+	}
+	if l.aggregator != nil {
+		c.aggregator = l.aggregator.clone()
+	}
+	if l.monotonic != nil {
+		c.monotonic = &monotonicClock{}
+	}
+	if l.limiter != nil {
+		c.limiter = l.limiter.cloneOrNew()
+	}
+	if l.monitor != nil {
+		c.monitor = &selfMonitor{interval: l.monitor.interval}
+	}
+	if l.throttle != nil {
+		c.throttle = l.throttle.clone()
+	}
+	if l.async != nil {
+		c.async = &asyncQueue{size: l.async.size, policy: l.async.policy, priority: l.async.priority}
+	}
+	if l.buffer != nil {
+		c.buffer = &recordBuffer{size: l.buffer.size, interval: l.buffer.interval}
+	}
+	for _, fn := range fns {
+		fn(c)
+	}
+	if c.async != nil {
+		c.async.start(c)
+	}
+	return c
+}
+
+// With returns a child Logger which merges extensions into every event it logs, with fields set on the event winning
+// on conflict. The child shares l's output, configuration and state, so e.g. SetOutput or Close on either affects
+// both. Calling With on a child merges the defaults of both.
+func (l *Logger) With(extensions Extensions) *Logger {
+	parent := l
+	if l.parent != nil {
+		parent = l.parent
+		extensions = l.defaults.Merge(extensions)
+	}
+	return &Logger{
+		parent:        parent,
+		defaults:      extensions.Clone(),
+		catalog:       parent.catalog,
+		DeviceVendor:  parent.DeviceVendor,
+		DeviceProduct: parent.DeviceProduct,
+		DeviceVersion: parent.DeviceVersion,
+	}
+}
+
+// Log logs CEF event to configured writer. Log never panics: panics from malformed input or user supplied callbacks are
+// recovered and returned as an error wrapping LoggerPanicErr.
+func (l *Logger) Log(deviceEventClassId, name, severity string, extensions Extensions) (err error) {
+	defer l.recoverPanic(&err)
+	if l.parent != nil {
+		return l.parent.Log(deviceEventClassId, name, severity, l.defaults.Merge(extensions))
+	}
+	return l.log(context.Background(), l.device(), deviceEventClassId, name, severity, extensions)
+}
+
+// LogEvent logs e like Log, with e's DeviceVendor, DeviceProduct and DeviceVersion in place of the Logger's, e.g. for
+// gateways relaying events from several upstream products. Empty device fields default to the Logger's. e.Version is
+// ignored: records use the Logger's CEF version.
+func (l *Logger) LogEvent(e Event) (err error) {
+	defer l.recoverPanic(&err)
+	if l.parent != nil {
+		e.Extensions = l.defaults.Merge(e.Extensions)
+		return l.parent.LogEvent(e)
+	}
+	return l.log(context.Background(), l.eventDevice(e), e.DeviceEventClassId, e.Name, e.Severity, e.Extensions)
+}
+
+// eventDevice returns the header device fields of e, defaulting to the Logger's
+func (l *Logger) eventDevice(e Event) device {
+	dev := l.device()
+	if e.DeviceVendor != "" {
+		dev.vendor = e.DeviceVendor
+	}
+	if e.DeviceProduct != "" {
+		dev.product = e.DeviceProduct
+	}
+	if e.DeviceVersion != "" {
+		dev.version = e.DeviceVersion
+	}
+	return dev
+}
+
+// device identifies the product an event is logged for in the CEF header
+type device struct {
+	vendor, product, version string
+}
+
+// device returns the Logger's device header fields
+func (l *Logger) device() device {
+	return device{l.DeviceVendor, l.DeviceProduct, l.DeviceVersion}
+}
+
+// log runs an event through the Logger's pipeline and writes it with dev in the header. Blocking writes give up once
+// ctx is done.
+func (l *Logger) log(ctx context.Context, dev device, deviceEventClassId, name, severity string, extensions Extensions) (err error) {
+	if to := l.routeLogger(deviceEventClassId); to != nil {
+		return l.forward(ctx, to, dev, deviceEventClassId, name, severity, extensions)
+	}
+	if errs := extensions.labelErrors(); len(errs) > 0 {
+		return l.rejected(errors.Join(errs...))
+	}
+	severity = l.escalateSeverity(deviceEventClassId, name, severity, extensions)
+	if !l.Enabled(severity) {
+		return nil
+	}
+	if l.limiter != nil && !l.limiter.allow(l) {
+		return nil
+	}
+	if l.hooks.Load() != nil {
+		extensions.CustomExtensions = maps.Clone(extensions.CustomExtensions) // hooks mustn't modify the caller's map
+		e := Event{Version: l.cefVersion, DeviceVendor: dev.vendor, DeviceProduct: dev.product, DeviceVersion: dev.version,
+			DeviceEventClassId: deviceEventClassId, Name: name, Severity: severity, Extensions: extensions}
+		if !l.beforeWrite(&e) {
+			return nil
+		}
+		dev = device{e.DeviceVendor, e.DeviceProduct, e.DeviceVersion}
+		deviceEventClassId, name, severity, extensions = e.DeviceEventClassId, e.Name, e.Severity, e.Extensions
+	}
+	if l.redactions != nil {
+		if extensions, err = l.redact(extensions); err != nil {
+			return l.rejected(err)
+		}
+	}
+	if l.schema != nil {
+		if extensions.CustomExtensions, err = l.schema.Apply(extensions.CustomExtensions); err != nil {
+			return l.rejected(err)
+		}
+	}
+	extensions.CustomExtensions = l.prefixCustomExtensions(extensions.CustomExtensions)
+	if l.truncation != nil {
+		if name, extensions, err = l.truncation.truncate(deviceEventClassId, name, extensions); err != nil {
+			return l.rejected(err)
+		}
+	}
+	if l.strict {
+		errs := checkHeader(dev.vendor, dev.product, dev.version, deviceEventClassId, name)
+		if err := errors.Join(append(errs, checkEvent(severity, extensions)...)...); err != nil {
+			return l.rejected(err)
+		}
+	}
+	if l.aggregator != nil && !l.dryRun {
+		l.aggregator.add(l, dev, deviceEventClassId, name, severity, extensions)
+		return nil
+	}
+	if l.hasher != nil {
+		extensions = l.hasher.apply(dev, deviceEventClassId, name, severity, extensions)
+	}
+	if l.suppressor != nil && !l.dryRun && l.suppressor.suppress(l, dev, deviceEventClassId, name, severity, extensions) {
+		l.dropped(DropSuppressed)
+		return nil
+	}
+	return l.write(ctx, dev, deviceEventClassId, name, severity, extensions)
+}
+
+// write formats a single event and writes it to the configured writer. The complete record must be passed to a single
+// Write call, see Logger.
+func (l *Logger) write(ctx context.Context, dev device, deviceEventClassId, name, severity string, extensions Extensions) error {
+	var offset time.Duration
+	if l.clockOffset != nil {
+		offset = l.clockOffset()
+		extensions = offsetExtensions(extensions, offset)
+	}
+	if l.monotonic != nil {
+		extensions = l.monotonic.clampExtensions(extensions)
+	}
+	buf := recordBuffers.Get().(*[]byte)
+	record := (*buf)[:0]
+	defer func() {
+		if cap(record) <= maxPooledRecordSize {
+			*buf = record
+			recordBuffers.Put(buf)
+		}
+	}()
+	if l.addSyslogHeader && l.format != JSON {
+		now := l.getTime().Add(offset)
+		if l.monotonic != nil {
+			now = l.monotonic.clampHeader(now)
+		}
+		if l.facility != nil {
+			record = append(record, syslogPriority(*l.facility, severity)...)
+		}
+		record = now.AppendFormat(record, `Jan 2 15:04:05`)
 		hostname, err := l.getHostname()
 		if err != nil {
 			return fmt.Errorf("failed to get hostname: %w", err)
 		}
-		b.WriteString(" " + hostname + " ")
-	}
-	b.WriteString(fmt.Sprintf("CEF:%d|%s|%s|%s|%s|%s|%s|",
-		l.cefVersion,
-		escapeHeaderField(l.DeviceVendor),
-		escapeHeaderField(l.DeviceProduct),
-		escapeHeaderField(l.DeviceVersion),
-		escapeHeaderField(deviceEventClassId),
-		escapeHeaderField(name),
-		escapeHeaderField(severity),
-	))
-	b.WriteString(extensions.String())
-	_, err := l.out.Write([]byte(b.String()))
-	if err != nil {
+		record = append(record, ' ')
+		record = append(record, hostname...)
+		record = append(record, ' ')
+	}
+	event := Event{
+		Version:            l.cefVersion,
+		DeviceVendor:       dev.vendor,
+		DeviceProduct:      dev.product,
+		DeviceVersion:      dev.version,
+		DeviceEventClassId: deviceEventClassId,
+		Name:               name,
+		Severity:           severity,
+		Extensions:         extensions,
+	}
+	switch l.format {
+	case LEEF1, LEEF2:
+		record = event.appendLEEF(record, l.format, l.leefDelimiter)
+	case JSON:
+		record = append(event.appendJSON(record), '\n')
+	default:
+		record = event.appendFormat(record, l.keyAliases)
+	}
+	if l.dryRun {
+		return validateEvent(severity, extensions)
+	}
+	var err error
+	if l.async != nil {
+		// The queued record outlives the pooled buffer
+		level, _ := severityLevel(severity)
+		err = l.async.enqueue(ctx, l, queuedRecord{deviceEventClassId, slices.Clone(record), level, 0})
+	} else {
+		err = l.output(ctx, deviceEventClassId, record)
+	}
+	if err == nil && l.metrics != nil {
+		l.metrics.EventLogged(severity)
+	}
+	if l.hooks.Load() != nil {
+		e, n := event, len(record)
+		if err != nil {
+			n = 0
+		}
+		l.afterWrite(&e, n, err)
+	}
+	return err
+}
+
+// output writes a formatted record, subject to throttling
+func (l *Logger) output(ctx context.Context, deviceEventClassId string, record []byte) error {
+	if l.throttle != nil {
+		if err := l.throttle.waitContext(ctx, len(record)); err != nil {
+			return err
+		}
+	}
+	write := func() error {
+		return l.writeRecord(ctx, deviceEventClassId, record)
+	}
+	if err := l.retryWrite(ctx, write); err != nil {
+		if l.metrics != nil {
+			l.metrics.WriteError()
+		}
+		if l.monitor != nil {
+			l.monitor.writeFailed(l, err)
+		}
 		return fmt.Errorf("failed to write log: %w", err)
 	}
+	if l.monitor != nil {
+		l.monitor.written(l)
+	}
 	return nil
 }
 
+// recoverPanic converts a panic into an error wrapping LoggerPanicErr and reports it to the error handler. Must be
+// called directly by defer.
+func (l *Logger) recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", LoggerPanicErr, r)
+		l.handleError(*err)
+	}
+}
+
+// handleError passes err to the configured error handler, if any. A panicking handler is ignored.
+func (l *Logger) handleError(err error) {
+	if l == nil || l.errorHandler == nil {
+		return
+	}
+	defer func() {
+		_ = recover()
+	}()
+	l.errorHandler(err)
+}
+
+// writeRecord writes a formatted record and its terminator with a single Write call, holding writeMu so the output
+// can't be replaced mid-write. Writes are serialized unless concurrentWrites is set. Writers implementing
+// io.StringWriter are still given the []byte: the record is already in a pooled buffer, so WriteString would need a
+// copy, or a string aliasing bytes which are reused once the call returns.
+func (l *Logger) writeRecord(ctx context.Context, deviceEventClassId string, record []byte) error {
+	if l.concurrentWrites {
+		l.writeMu.RLock()
+		defer l.writeMu.RUnlock()
+	} else {
+		l.writeMu.Lock()
+		defer l.writeMu.Unlock()
+	}
+	i := l.routeIndex(deviceEventClassId)
+	record = append(record, l.recordTerminator(l.outAt(i))...)
+	var err error
+	if l.buffer != nil {
+		err = l.buffer.add(l, i, record)
+	} else {
+		err = writeAllContext(ctx, l.outAt(i), record)
+	}
+	if err == nil && l.metrics != nil {
+		l.metrics.BytesWritten(len(record))
+	}
+	return err
+}
+
+// SetOutput replaces the Logger's default writer. Safe to call while other goroutines are logging: events already being
+// written finish on the old writer and later events go to out. Writers configured with WithClassRoutes are unaffected.
+func (l *Logger) SetOutput(out io.Writer) {
+	if l.parent != nil {
+		l.parent.SetOutput(out)
+		return
+	}
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	if l.buffer != nil {
+		if err := l.buffer.flushRoute(l, -1); err != nil {
+			l.handleError(fmt.Errorf("failed to write log: %w", err))
+		}
+	}
+	l.out = out
+}
+
+// prefixCustomExtensions returns a copy of custom with the logger's namespace applied to each key. The caller's map is
+// never modified.
+func (l *Logger) prefixCustomExtensions(custom map[string]string) map[string]string {
+	if l.customPrefix == "" || len(custom) == 0 {
+		return custom
+	}
+	prefixed := make(map[string]string, len(custom))
+	for k, v := range custom {
+		if !strings.HasPrefix(k, l.customPrefix) {
+			k = l.customPrefix + k
+		}
+		prefixed[k] = v
+	}
+	return prefixed
+}
+
 // Log logs CEF event with default logger
 func Log(deviceEventClassId, name, severity string, extensions Extensions) error {
-	return defaultLogger.Log(deviceEventClassId, name, severity, extensions)
+	return defaultLogger.Load().Log(deviceEventClassId, name, severity, extensions)
 }
 
 // LogUnknown log CEF event with unknown severity
@@ -134,7 +657,7 @@ func (l *Logger) LogUnknown(deviceEventClassId, name string, extensions Extensio
 
 // LogUnknown log CEF event with unknown severity to default logger
 func LogUnknown(deviceEventClassId, name string, extensions Extensions) error {
-	return defaultLogger.LogUnknown(deviceEventClassId, name, extensions)
+	return defaultLogger.Load().LogUnknown(deviceEventClassId, name, extensions)
 }
 
 // LogLow log CEF event with low severity
@@ -144,7 +667,7 @@ func (l *Logger) LogLow(deviceEventClassId, name string, extensions Extensions)
 
 // LogLow log CEF event with low severity to default logger
 func LogLow(deviceEventClassId, name string, extensions Extensions) error {
-	return defaultLogger.LogLow(deviceEventClassId, name, extensions)
+	return defaultLogger.Load().LogLow(deviceEventClassId, name, extensions)
 }
 
 // LogMedium log CEF event with medium severity
@@ -154,7 +677,7 @@ func (l *Logger) LogMedium(deviceEventClassId, name string, extensions Extension
 
 // LogMedium log CEF event with medium severity to default logger
 func LogMedium(deviceEventClassId, name string, extensions Extensions) error {
-	return defaultLogger.LogMedium(deviceEventClassId, name, extensions)
+	return defaultLogger.Load().LogMedium(deviceEventClassId, name, extensions)
 }
 
 // LogHigh log CEF event with high severity
@@ -164,7 +687,7 @@ func (l *Logger) LogHigh(deviceEventClassId, name string, extensions Extensions)
 
 // LogHigh log CEF event with high severity to default logger
 func LogHigh(deviceEventClassId, name string, extensions Extensions) error {
-	return defaultLogger.LogHigh(deviceEventClassId, name, extensions)
+	return defaultLogger.Load().LogHigh(deviceEventClassId, name, extensions)
 }
 
 // LogVeryHigh log CEF event with very-high severity
@@ -174,14 +697,42 @@ func (l *Logger) LogVeryHigh(deviceEventClassId, name string, extensions Extensi
 
 // LogVeryHigh log CEF event with very-high severity to default logger
 func LogVeryHigh(deviceEventClassId, name string, extensions Extensions) error {
-	return defaultLogger.LogVeryHigh(deviceEventClassId, name, extensions)
+	return defaultLogger.Load().LogVeryHigh(deviceEventClassId, name, extensions)
 }
 
 // SetDefaultLogger sets the default logger to a created one. Useful for using package level functions
 func SetDefaultLogger(log *Logger) {
-	defaultLogger = log
+	defaultLogger.Store(log)
+}
+
+// Default returns the default logger used by the package level functions
+func Default() *Logger {
+	return defaultLogger.Load()
 }
 
 func escapeHeaderField(field string) string {
-	return headerEscapeRegex.ReplaceAllString(field, "\\${1}")
+	return string(appendEscapedHeader(nil, field))
+}
+
+// appendEscapedHeader appends field to b, escaped for use in the CEF header
+func appendEscapedHeader(b []byte, field string) []byte {
+	for i := 0; i < len(field); i++ {
+		if c := field[i]; c == '|' || c == '\\' {
+			b = append(b, '\\')
+		}
+		b = append(b, field[i])
+	}
+	return b
 }
+
+// recordBuffers pools the buffers records are formatted into
+var recordBuffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// maxPooledRecordSize is the capacity above which buffers aren't returned to recordBuffers, so one huge event doesn't
+// pin its buffer in memory
+const maxPooledRecordSize = 64 << 10
@@ -2,10 +2,17 @@ package cefevent
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"reflect"
 	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,6 +38,13 @@ func (e errorWriter) Write(_ []byte) (int, error) {
 	return 0, stubWriterError
 }
 
+// shortWriter accepts one byte less than it is given
+type shortWriter struct{}
+
+func (s shortWriter) Write(p []byte) (int, error) {
+	return len(p) - 1, nil
+}
+
 func TestLogger_Log(t *testing.T) {
 	type fields struct {
 		addSyslogHeader bool
@@ -125,6 +139,39 @@ func TestLogger_LogError(t *testing.T) {
 	assert.EqualError(t, err, "failed to write log: underlying writer error")
 }
 
+func TestLogger_LogShortWrite(t *testing.T) {
+	l := NewLogger(shortWriter{}, "not", "relevant", "1")
+	err := l.LogLow("9001", "scanner", Extensions{})
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+}
+
+func TestLogger_LogSingleWrite(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "not", "relevant", "1")
+	l.getTime = testTime
+	l.getHostname = testHostname
+	err := l.LogHigh("9001", "scanner", Extensions{Message: "port scan", CustomExtensions: map[string]string{"extra": "value"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{
+			"Nov 9 11:45:20 testhost CEF:1|not|relevant|1|9001|scanner|High|msg=port scan extra=value",
+		}, w.Records())
+	}
+}
+
+func TestWithTimeFunc_WithStaticHostname(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "not", "relevant", "1", WithTimeFunc(testTime), WithStaticHostname("container-host"))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Equal(t, []string{"Nov 9 11:45:20 container-host CEF:1|not|relevant|1|100|test|Low|"}, w.Records())
+}
+
+func TestWithHostnameFunc(t *testing.T) {
+	l := NewLogger(&recordingWriter{}, "not", "relevant", "1", WithHostnameFunc(func() (string, error) {
+		return "", stubWriterError
+	}))
+	assert.ErrorIs(t, l.LogLow("100", "test", Extensions{}), stubWriterError)
+}
+
 func TestNewLogger(t *testing.T) {
 	cef0, _ := WithCefVersion(0)
 	type args struct {
@@ -318,3 +365,302 @@ func Test_escapeHeaderField(t *testing.T) {
 		})
 	}
 }
+
+func TestWithCustomExtensionPrefix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithCustomExtensionPrefix("acmeapp_"))
+	custom := map[string]string{"tenant": "globex"}
+	err := l.LogLow("tevt1", "test event 1", Extensions{CustomExtensions: custom})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|tevt1|test event 1|Low|acmeapp_tenant=globex", buf.String())
+		assert.Equal(t, map[string]string{"tenant": "globex"}, custom, "caller's map should be unchanged")
+	}
+
+	buf.Reset()
+	err = l.LogLow("tevt1", "test event 1", Extensions{CustomExtensions: map[string]string{"acmeapp_tenant": "initech"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|tevt1|test event 1|Low|acmeapp_tenant=initech", buf.String())
+	}
+}
+
+func TestLogger_Log_recoversPanic(t *testing.T) {
+	var handled []error
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0",
+		WithErrorHandler(func(err error) {
+			handled = append(handled, err)
+		}),
+		MustLoggerConfig(WithEscalationRules(EscalationRule{
+			Match: func(_, _ string, _ Extensions) bool {
+				panic("bad rule")
+			},
+			Severity: HighSeverity,
+		})),
+	)
+	err := l.LogLow("tevt1", "test event 1", Extensions{})
+	assert.ErrorIs(t, err, LoggerPanicErr)
+	assert.ErrorContains(t, err, "bad rule")
+	assert.Equal(t, []error{err}, handled)
+	assert.Empty(t, buf.String())
+}
+
+func TestLogger_Log_malformedLogger(t *testing.T) {
+	var nilLogger *Logger
+	assert.ErrorIs(t, nilLogger.LogLow("tevt1", "test event 1", Extensions{}), LoggerPanicErr)
+	assert.ErrorIs(t, (&Logger{}).LogLow("tevt1", "test event 1", Extensions{}), LoggerPanicErr)
+
+	l := NewLogger(&bytes.Buffer{}, "testVendor", "testProduct", "1.0", WithErrorHandler(func(err error) {
+		panic("bad handler")
+	}))
+	l.out = nil
+	assert.ErrorIs(t, l.LogLow("tevt1", "test event 1", Extensions{}), LoggerPanicErr)
+}
+
+func TestLogger_SetOutput(t *testing.T) {
+	first := &bytes.Buffer{}
+	second := &recordingWriter{}
+	l := NewLogger(first, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	require.NoError(t, l.LogLow("100", "bootstrap", Extensions{}))
+	l.SetOutput(second)
+	require.NoError(t, l.LogLow("100", "configured", Extensions{}))
+
+	assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|100|bootstrap|Low|", first.String())
+	assert.Equal(t, []string{"CEF:1|testVendor|testProduct|1.0|100|configured|Low|"}, second.Records())
+
+	// Swapping concurrently with logging must be race free
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = l.LogLow("100", "concurrent", Extensions{})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		l.SetOutput(&recordingWriter{})
+	}
+	<-done
+}
+
+func TestLogger_Clone(t *testing.T) {
+	base := NewLogger(&bytes.Buffer{}, "testVendor", "testProduct", "1.0",
+		OmitSyslogHeader(),
+		MustLoggerConfig(WithEscalationRules(EscalationRule{Severity: HighSeverity})),
+		WithRepeatSuppression(time.Minute),
+		WithAggregation(time.Minute, "src"),
+		WithSchema(NewSchema()),
+		WithCatalog(NewCatalog()),
+		WithLegacyKeys(),
+		WithCustomExtensionPrefix("acme_"),
+		WithContentHash("hash"),
+		WithClockOffset(time.Second),
+		WithMonotonicTimestamps(),
+		WithDryRun(),
+		WithStrictValidation(),
+		WithTruncation(TruncationPolicy{}),
+		WithRedaction(DropField("suser")),
+		WithFormat(LEEF2),
+		WithLEEFDelimiter('^'),
+		WithRecordTerminator(CRLFTerminator),
+		WithBuffer(1<<10, time.Second),
+		WithByteRateLimit(100, 100),
+		WithRateLimit(100, 100),
+		WithSelfMonitoring(time.Minute),
+		WithRetry(3, time.Millisecond),
+		WithClassRoutes(RouteClassPrefix("1", &bytes.Buffer{})),
+		WithMetrics(NewPrometheusMetrics("")),
+		WithContextExtractor(func(context.Context) Extensions { return Extensions{} }),
+		WithErrorHandler(func(error) {}),
+		WithConcurrentWrites(),
+		WithAsync(10, OverflowDropOldest, WithSeverityPriority()),
+		WithSyslogPriority(FacilityLocal0),
+		MustLoggerConfig(WithMinSeverity(LowSeverity)),
+	)
+	base.addSyslogHeader = true // make sure every field is non-zero
+	base.RegisterHook(&recordingHook{})
+
+	clone := base.Clone()
+	bv := reflect.ValueOf(base).Elem()
+	cv := reflect.ValueOf(clone).Elem()
+	for i := 0; i < bv.NumField(); i++ {
+		name := bv.Type().Field(i).Name
+		if name == "writeMu" || name == "hooksMu" || name == "parent" || name == "defaults" { // parent & defaults are only set by With
+			continue
+		}
+		require.False(t, bv.Field(i).IsZero(), "test doesn't set %s", name)
+		assert.False(t, cv.Field(i).IsZero(), "%s not copied by Clone", name)
+	}
+	assert.NotSame(t, base.suppressor, clone.suppressor)
+	assert.NotSame(t, base.buffer, clone.buffer)
+	assert.NotSame(t, base.monitor, clone.monitor)
+	assert.NotSame(t, base.escalators[0], clone.escalators[0])
+	assert.Equal(t, base.async.priority, clone.async.priority)
+
+	out := &bytes.Buffer{}
+	override := base.Clone(func(l *Logger) {
+		l.out = out
+		l.DeviceProduct = "otherProduct"
+	}, WithClassRoutes(RouteClassPrefix("2", &bytes.Buffer{})))
+	assert.Equal(t, "otherProduct", override.DeviceProduct)
+	assert.Equal(t, "testProduct", base.DeviceProduct)
+	assert.Same(t, out, override.out)
+	assert.Len(t, override.routes, 2)
+	assert.Len(t, base.routes, 1)
+}
+
+func TestLogger_With(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader())
+	child := l.With(Extensions{DeviceHostName: "edge-1", DeviceFacility: "auth", CustomExtensions: map[string]string{"a": "1"}})
+	grandchild := child.With(Extensions{DeviceFacility: "kern", CustomExtensions: map[string]string{"a": "2"}})
+
+	tests := []struct {
+		name       string
+		l          *Logger
+		extensions Extensions
+		want       string
+	}{
+		{"parent", l, Extensions{Message: "hi"}, "CEF:1|v|p|1|100|test|Low|msg=hi"},
+		{"child", child, Extensions{Message: "hi"}, "CEF:1|v|p|1|100|test|Low|msg=hi deviceFacility=auth dcvhost=edge-1 a=1"},
+		{"event_wins", child, Extensions{DeviceFacility: "cron", CustomExtensions: map[string]string{"a": "3"}},
+			"CEF:1|v|p|1|100|test|Low|deviceFacility=cron dcvhost=edge-1 a=3"},
+		{"grandchild", grandchild, Extensions{}, "CEF:1|v|p|1|100|test|Low|deviceFacility=kern dcvhost=edge-1 a=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			assert.NoError(t, tt.l.LogLow("100", "test", tt.extensions))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	out := &bytes.Buffer{}
+	child.SetOutput(out)
+	assert.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", out.String())
+
+	clone := child.Clone()
+	assert.NotSame(t, l, clone.parent)
+	assert.Equal(t, "edge-1", clone.defaults.DeviceHostName)
+}
+
+func TestLogger_LogEvent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader())
+	child := l.With(Extensions{DeviceHostName: "edge-1"})
+
+	tests := []struct {
+		name string
+		l    *Logger
+		e    Event
+		want string
+	}{
+		{"override", l, Event{DeviceVendor: "Acme", DeviceProduct: "Firewall", DeviceVersion: "2.0", DeviceEventClassId: "100",
+			Name: "test", Severity: LowSeverity, Extensions: Extensions{Message: "hi"}}, "CEF:1|Acme|Firewall|2.0|100|test|Low|msg=hi"},
+		{"defaults", l, Event{DeviceProduct: "Proxy", DeviceEventClassId: "100", Name: "test", Severity: HighSeverity},
+			"CEF:1|v|Proxy|1|100|test|High|"},
+		{"version_ignored", l, Event{Version: 0, DeviceEventClassId: "100", Name: "test", Severity: LowSeverity},
+			"CEF:1|v|p|1|100|test|Low|"},
+		{"child", child, Event{DeviceVendor: "Acme", DeviceEventClassId: "100", Name: "test", Severity: LowSeverity},
+			"CEF:1|Acme|p|1|100|test|Low|dcvhost=edge-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			assert.NoError(t, tt.l.LogEvent(tt.e))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	strict := NewLogger(io.Discard, "v", "p", "1", WithStrictValidation())
+	assert.ErrorIs(t, strict.LogEvent(Event{DeviceVendor: strings.Repeat("v", 64), Severity: LowSeverity}), HeaderFieldTooLongErr)
+
+	w := &recordingWriter{}
+	suppressing := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithRepeatSuppression(time.Hour))
+	for _, vendor := range []string{"Acme", "Acme", "Acme", "Initech"} {
+		assert.NoError(t, suppressing.LogEvent(Event{DeviceVendor: vendor, DeviceEventClassId: "100", Name: "test", Severity: LowSeverity}))
+	}
+	assert.Equal(t, []string{"CEF:1|Acme|p|1|100|test|Low|", "CEF:1|Acme|p|1|100|test|Low|cnt=2 type=1", "CEF:1|Initech|p|1|100|test|Low|"},
+		w.Records())
+}
+
+// overlapWriter records the most Write calls it saw in progress at once
+type overlapWriter struct {
+	inFlight atomic.Int32
+	max      atomic.Int32
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	n := w.inFlight.Add(1)
+	for m := w.max.Load(); n > m && !w.max.CompareAndSwap(m, n); m = w.max.Load() {
+	}
+	time.Sleep(time.Millisecond)
+	w.inFlight.Add(-1)
+	return len(p), nil
+}
+
+func TestLogger_Log_concurrent(t *testing.T) {
+	tests := []struct {
+		name       string
+		fns        []LoggerConfigOption
+		serialized bool
+	}{
+		{"serialized", nil, true},
+		{"concurrent_writes", []LoggerConfigOption{WithConcurrentWrites()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &overlapWriter{}
+			l := NewLogger(w, "v", "p", "1", tt.fns...)
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 5; j++ {
+						assert.NoError(t, l.LogLow("100", "test", Extensions{}))
+					}
+				}()
+			}
+			wg.Wait()
+			if tt.serialized {
+				assert.Equal(t, int32(1), w.max.Load())
+			} else {
+				assert.Greater(t, w.max.Load(), int32(1))
+			}
+		})
+	}
+}
+
+func BenchmarkLogger_Log(b *testing.B) {
+	l := NewLogger(io.Discard, "testVendor", "testProduct", "1.0", WithStaticHostname("host"))
+	extensions := Extensions{
+		Message:             "user login failed",
+		DeviceAction:        "blocked",
+		SourceAddress:       net.IPv4(10, 0, 0, 1),
+		SourcePort:          ptr(uint(51234)),
+		DestinationAddress:  net.IPv4(10, 0, 0, 2),
+		DestinationPort:     ptr(uint(443)),
+		DestinationUserName: "alice",
+		DeviceReceiptTime:   testTime(),
+		CustomExtensions:    map[string]string{"requestId": "abc123", "path": `C:\Windows`},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := l.LogMedium("100", "Login|failed", extensions); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLogger_Log_parallel(b *testing.B) {
+	l := NewLogger(io.Discard, "testVendor", "testProduct", "1.0", WithStaticHostname("host"))
+	extensions := Extensions{Message: "user login failed", DestinationPort: ptr(uint(443))}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := l.LogMedium("100", "Login failed", extensions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
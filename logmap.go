@@ -0,0 +1,110 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// UnsupportedValueErr error when a LogMap value has a type that can't be converted to an extension value
+var UnsupportedValueErr = errors.New("unsupported extension value type")
+
+// LogMap logs a CEF event like Log, with extensions built from fields by ExtensionsFromMap
+func (l *Logger) LogMap(deviceEventClassId, name, severity string, fields map[string]any) error {
+	ext, err := ExtensionsFromMap(fields)
+	if err != nil {
+		if l.parent != nil {
+			return l.parent.rejected(err)
+		}
+		return l.rejected(err)
+	}
+	return l.Log(deviceEventClassId, name, severity, ext)
+}
+
+// ExtensionsFromMap converts fields keyed by dictionary key, e.g. {"src": net.IP, "dpt": 22}, into Extensions.
+// Standard keys are decoded and validated as Parse does; other keys are added to CustomExtensions. Values may be
+// strings, bools, integers, floats, time.Time, net.IP, net.HardwareAddr, url.URL or any fmt.Stringer, and nil values
+// are skipped. Times are written as milliseconds since the epoch. Every invalid field is reported in the returned
+// error, wrapping UnsupportedValueErr, InvalidExtensionKeyErr or the field's own validation error. Useful for adapting
+// other logging libraries' key/value fields.
+func ExtensionsFromMap(fields map[string]any) (Extensions, error) {
+	b := NewExtensions()
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		v, ok, err := extensionValue(fields[key])
+		switch {
+		case err != nil:
+			errs = append(errs, &fieldError{key, err})
+		case !ok:
+		case isStandardKey(key):
+			b.Set(key, v)
+		default:
+			b.WithCustom(key, v)
+		}
+	}
+	ext, err := b.Build()
+	return ext, errors.Join(append(errs, err)...)
+}
+
+// extensionValue formats v as an extension value, reporting false for nil values
+func extensionValue(v any) (string, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, true, nil
+	case bool:
+		return strconv.FormatBool(v), true, nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), true, nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), true, nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), true, nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), true, nil
+	case int64:
+		return strconv.FormatInt(v, 10), true, nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true, nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), true, nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), true, nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), true, nil
+	case uint64:
+		return strconv.FormatUint(v, 10), true, nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	case time.Time:
+		return strconv.FormatInt(v.UnixMilli(), 10), true, nil
+	case net.IP:
+		if v == nil {
+			return "", false, nil
+		}
+		return v.String(), true, nil
+	case net.HardwareAddr:
+		if v == nil {
+			return "", false, nil
+		}
+		return v.String(), true, nil
+	case url.URL:
+		return v.String(), true, nil
+	case *url.URL:
+		if v == nil {
+			return "", false, nil
+		}
+		return v.String(), true, nil
+	case fmt.Stringer:
+		return v.String(), true, nil
+	}
+	return "", false, fmt.Errorf("%w: %T", UnsupportedValueErr, v)
+}
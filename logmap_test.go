@@ -0,0 +1,78 @@
+package cefevent
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtensionsFromMap(t *testing.T) {
+	mac, _ := net.ParseMAC("00:00:5e:00:53:01")
+	u, _ := url.Parse("https://example.com/login?next=%2F")
+	ext, err := ExtensionsFromMap(map[string]any{
+		"src":      net.ParseIP("192.0.2.1"),
+		"smac":     mac,
+		"dpt":      22,
+		"in":       uint64(512),
+		"dlat":     51.5,
+		"end":      testTime(),
+		"request":  u,
+		"msg":      "login",
+		"cs1":      "deny",
+		"cs1Label": "policy",
+		"tenant":   "acme",
+		"admin":    true,
+		"elapsed":  1500 * time.Millisecond,
+		"ignored":  nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Extensions{
+		SourceAddress:          net.ParseIP("192.0.2.1"),
+		SourceMacAddress:       mac,
+		DestinationPort:        ptr(uint(22)),
+		BytesIn:                ptr(uint(512)),
+		DestinationGeoLatitude: ptr(51.5),
+		EndTime:                testTime().UTC(),
+		RequestUrl:             *u,
+		Message:                "login",
+		DeviceCustomString1:    Labeled("policy", "deny"),
+		CustomExtensions:       map[string]string{"tenant": "acme", "admin": "true", "elapsed": "1.5s"},
+	}, ext)
+}
+
+func TestExtensionsFromMap_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]any
+		want   error
+	}{
+		{"unsupported type", map[string]any{"tenant": []string{"a"}}, UnsupportedValueErr},
+		{"invalid port", map[string]any{"dpt": 70000}, InvalidPortErr},
+		{"invalid custom key", map[string]any{"a b": "v"}, InvalidExtensionKeyErr},
+		{"missing label", map[string]any{"cn1": 3}, MissingLabelErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtensionsFromMap(tt.fields)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+	_, err := ExtensionsFromMap(map[string]any{"src": "not-an-ip"})
+	assert.ErrorContains(t, err, `"src": "not-an-ip" is not an IP address`)
+}
+
+func TestLogger_LogMap(t *testing.T) {
+	buf := &bytes.Buffer{}
+	metrics := NewPrometheusMetrics("")
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithMetrics(metrics)).With(Extensions{SourceHostName: "child"})
+	require.NoError(t, l.LogMap("100", "login", LowSeverity, map[string]any{"dpt": 22, "tenant": "acme"}))
+	assert.Equal(t, "CEF:1|v|p|1|100|login|Low|dpt=22 shost=child tenant=acme", buf.String())
+
+	assert.ErrorIs(t, l.LogMap("100", "login", LowSeverity, map[string]any{"dpt": 70000}), InvalidPortErr)
+	assert.Equal(t, uint64(1), metrics.counters.rejected.Load())
+}
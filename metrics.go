@@ -0,0 +1,224 @@
+package cefevent
+
+import (
+	"expvar"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Reasons passed to MetricsCollector.EventDropped
+const (
+	DropRateLimited = "rate_limited" // Dropped by WithRateLimit
+	DropSampledOut  = "sampled_out"  // Not sampled by WithSampling or WithSampleEvery
+	DropQueueFull   = "queue_full"   // Dropped by WithAsync's overflow policy
+	DropSuppressed  = "suppressed"   // Held back by WithRepeatSuppression, and counted in the summary event
+)
+
+// MetricsCollector receives the Logger's metrics, so operators can alert on a broken audit pipeline. Methods are called
+// from the goroutine logging or writing the event, so must be safe for concurrent use and shouldn't block.
+// ExpvarMetrics and PrometheusMetrics are provided.
+type MetricsCollector interface {
+	// EventLogged is called for each event formatted & handed to the output, or to the async queue
+	EventLogged(severity string)
+	// BytesWritten is called with the size of each record written, including any record terminator
+	BytesWritten(n int)
+	// EventRejected is called for each event that couldn't be formatted, e.g. failing validation or redaction
+	EventRejected()
+	// WriteError is called for each record the output failed to write
+	WriteError()
+	// EventDropped is called for each event dropped on purpose, with one of the Drop reasons
+	EventDropped(reason string)
+	// QueueDepth is called with the number of records waiting in the WithAsync queue, as it changes
+	QueueDepth(n int)
+}
+
+// WithMetrics reports the Logger's metrics to c
+func WithMetrics(c MetricsCollector) LoggerConfigOption {
+	return func(l *Logger) {
+		l.metrics = c
+	}
+}
+
+// rejected counts an event which couldn't be formatted, returning err
+func (l *Logger) rejected(err error) error {
+	if l.metrics != nil {
+		l.metrics.EventRejected()
+	}
+	return err
+}
+
+// dropped counts an event dropped for reason
+func (l *Logger) dropped(reason string) {
+	if l.metrics != nil {
+		l.metrics.EventDropped(reason)
+	}
+	if l.monitor != nil {
+		l.monitor.dropped(l, reason)
+	}
+}
+
+// metricCounters is a concurrency safe set of counters shared by the built-in collectors
+type metricCounters struct {
+	bytes      atomic.Uint64
+	rejected   atomic.Uint64
+	writeErrs  atomic.Uint64
+	queueDepth atomic.Int64
+
+	mu         sync.RWMutex
+	bySeverity map[string]*atomic.Uint64
+	byReason   map[string]*atomic.Uint64
+}
+
+// counter returns the counter for key in m, creating it if needed
+func (c *metricCounters) counter(m *map[string]*atomic.Uint64, key string) *atomic.Uint64 {
+	c.mu.RLock()
+	n, ok := (*m)[key]
+	c.mu.RUnlock()
+	if ok {
+		return n
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *m == nil {
+		*m = make(map[string]*atomic.Uint64)
+	}
+	if n, ok = (*m)[key]; !ok {
+		n = &atomic.Uint64{}
+		(*m)[key] = n
+	}
+	return n
+}
+
+// snapshot returns the current value of each counter in m, sorted by key
+func (c *metricCounters) snapshot(m map[string]*atomic.Uint64) (keys []string, values []uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys = slices.Sorted(maps.Keys(m))
+	for _, k := range keys {
+		values = append(values, m[k].Load())
+	}
+	return keys, values
+}
+
+// PrometheusMetrics is a MetricsCollector serving its metrics in the Prometheus text exposition format, without
+// depending on the Prometheus client library. Register it as an http.Handler, e.g. http.Handle("/metrics", m), or
+// serve it alongside an existing registry on its own path.
+type PrometheusMetrics struct {
+	namespace string
+	counters  metricCounters
+}
+
+// NewPrometheusMetrics returns a collector whose metric names are prefixed with namespace, e.g. "myapp" for
+// myapp_cef_events_logged_total. namespace may be empty.
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	if namespace != "" {
+		namespace += "_"
+	}
+	return &PrometheusMetrics{namespace: namespace + "cef_"}
+}
+
+func (m *PrometheusMetrics) EventLogged(severity string) {
+	m.counters.counter(&m.counters.bySeverity, severity).Add(1)
+}
+
+func (m *PrometheusMetrics) BytesWritten(n int) {
+	m.counters.bytes.Add(uint64(n))
+}
+
+func (m *PrometheusMetrics) EventRejected() {
+	m.counters.rejected.Add(1)
+}
+
+func (m *PrometheusMetrics) WriteError() {
+	m.counters.writeErrs.Add(1)
+}
+
+func (m *PrometheusMetrics) EventDropped(reason string) {
+	m.counters.counter(&m.counters.byReason, reason).Add(1)
+}
+
+func (m *PrometheusMetrics) QueueDepth(n int) {
+	m.counters.queueDepth.Store(int64(n))
+}
+
+// ServeHTTP writes the current metrics
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var b strings.Builder
+	labelled := func(name, help, label string, counters map[string]*atomic.Uint64) {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n# TYPE %s%s counter\n", m.namespace, name, help, m.namespace, name)
+		keys, values := m.counters.snapshot(counters)
+		for i, k := range keys {
+			fmt.Fprintf(&b, "%s%s{%s=\"%s\"} %d\n", m.namespace, name, label, labelValueEscaper.Replace(k), values[i])
+		}
+	}
+	metric := func(name, help, typ string, value any) {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n# TYPE %s%s %s\n%s%s %d\n", m.namespace, name, help, m.namespace, name, typ,
+			m.namespace, name, value)
+	}
+	labelled("events_logged_total", "Events logged by severity.", "severity", m.counters.bySeverity)
+	labelled("events_dropped_total", "Events dropped by reason.", "reason", m.counters.byReason)
+	metric("bytes_written_total", "Bytes of records written.", "counter", m.counters.bytes.Load())
+	metric("events_rejected_total", "Events which couldn't be formatted.", "counter", m.counters.rejected.Load())
+	metric("write_errors_total", "Records the output failed to write.", "counter", m.counters.writeErrs.Load())
+	metric("queue_depth", "Records waiting in the async queue.", "gauge", m.counters.queueDepth.Load())
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// labelValueEscaper escapes label values as the exposition format requires, which differs from Go string escaping
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ExpvarMetrics is a MetricsCollector publishing its metrics with expvar, served as JSON on /debug/vars
+type ExpvarMetrics struct {
+	vars       *expvar.Map
+	bySeverity *expvar.Map
+	byReason   *expvar.Map
+	queueDepth *expvar.Int
+}
+
+// NewExpvarMetrics publishes the metrics as the expvar map name, e.g. "cef". Like expvar.Publish, it panics if name is
+// already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		vars:       expvar.NewMap(name),
+		bySeverity: new(expvar.Map),
+		byReason:   new(expvar.Map),
+		queueDepth: new(expvar.Int),
+	}
+	m.vars.Set("events_logged", m.bySeverity)
+	m.vars.Set("events_dropped", m.byReason)
+	m.vars.Set("queue_depth", m.queueDepth)
+	m.vars.Add("bytes_written", 0)
+	m.vars.Add("events_rejected", 0)
+	m.vars.Add("write_errors", 0)
+	return m
+}
+
+func (m *ExpvarMetrics) EventLogged(severity string) {
+	m.bySeverity.Add(severity, 1)
+}
+
+func (m *ExpvarMetrics) BytesWritten(n int) {
+	m.vars.Add("bytes_written", int64(n))
+}
+
+func (m *ExpvarMetrics) EventRejected() {
+	m.vars.Add("events_rejected", 1)
+}
+
+func (m *ExpvarMetrics) WriteError() {
+	m.vars.Add("write_errors", 1)
+}
+
+func (m *ExpvarMetrics) EventDropped(reason string) {
+	m.byReason.Add(reason, 1)
+}
+
+func (m *ExpvarMetrics) QueueDepth(n int) {
+	m.queueDepth.Set(int64(n))
+}
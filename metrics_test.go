@@ -0,0 +1,77 @@
+package cefevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics("test")
+	l := NewLogger(&bytes.Buffer{}, "v", "p", "1", OmitSyslogHeader(), WithMetrics(m), WithRateLimit(1, 3))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	require.NoError(t, l.LogHigh("100", "test", Extensions{}))
+	l.SetOutput(errorWriter{})
+	assert.Error(t, l.LogHigh("100", "test", Extensions{}))
+	assert.Error(t, l.LogHigh("100", "test", Extensions{DeviceCustomString1: Labeled("", "v")}))
+	require.NoError(t, l.LogHigh("100", "test", Extensions{})) // rate limited
+	// Only \, " & newline are escaped in label values, unlike with %q
+	m.EventDropped("a\"b\\c\nd\t")
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP test_cef_events_logged_total Events logged by severity.
+# TYPE test_cef_events_logged_total counter
+test_cef_events_logged_total{severity="High"} 1
+test_cef_events_logged_total{severity="Low"} 1
+# HELP test_cef_events_dropped_total Events dropped by reason.
+# TYPE test_cef_events_dropped_total counter
+`+"test_cef_events_dropped_total{reason=\"a\\\"b\\\\c\\nd\t\"} 1\n"+
+		`test_cef_events_dropped_total{reason="rate_limited"} 1
+# HELP test_cef_bytes_written_total Bytes of records written.
+# TYPE test_cef_bytes_written_total counter
+test_cef_bytes_written_total 51
+# HELP test_cef_events_rejected_total Events which couldn't be formatted.
+# TYPE test_cef_events_rejected_total counter
+test_cef_events_rejected_total 1
+# HELP test_cef_write_errors_total Records the output failed to write.
+# TYPE test_cef_write_errors_total counter
+test_cef_write_errors_total 1
+# HELP test_cef_queue_depth Records waiting in the async queue.
+# TYPE test_cef_queue_depth gauge
+test_cef_queue_depth 0
+`, rec.Body.String())
+}
+
+// expvarTestRuns numbers TestExpvarMetrics runs, as expvar names can't be published twice
+var expvarTestRuns atomic.Int64
+
+func TestExpvarMetrics(t *testing.T) {
+	name := fmt.Sprintf("cefevent_test_%d", expvarTestRuns.Add(1))
+	m := NewExpvarMetrics(name)
+	l := NewLogger(&bytes.Buffer{}, "v", "p", "1", OmitSyslogHeader(), WithMetrics(m),
+		WithAsync(10, OverflowBlock), WithRepeatSuppression(time.Hour))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	require.NoError(t, l.LogLow("100", "test", Extensions{})) // suppressed, then summarised by Close
+	require.NoError(t, l.Close())
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &got))
+	assert.Equal(t, map[string]any{
+		"bytes_written":   float64(62),
+		"events_dropped":  map[string]any{"suppressed": float64(1)},
+		"events_logged":   map[string]any{"Low": float64(2)},
+		"events_rejected": float64(0),
+		"queue_depth":     float64(0),
+		"write_errors":    float64(0),
+	}, got)
+}
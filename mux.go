@@ -0,0 +1,94 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// UnknownTenantErr error when a Mux has no Logger registered for an event's tenant and no fallback
+var UnknownTenantErr = errors.New("no logger for tenant")
+
+// TenantFunc identifies the tenant an event belongs to
+type TenantFunc func(deviceEventClassId, name string, extensions Extensions) string
+
+// TenantFromCustomExtension returns a TenantFunc reading the tenant from the given CustomExtensions key
+func TenantFromCustomExtension(key string) TenantFunc {
+	return func(_, _ string, extensions Extensions) string {
+		return extensions.CustomExtensions[key]
+	}
+}
+
+// Mux routes events to per-tenant Loggers, allowing each tenant to have its own writer and header fields. A Mux is
+// safe to use from multiple goroutines.
+type Mux struct {
+	tenantOf TenantFunc
+	fallback *Logger
+
+	mu      sync.RWMutex
+	loggers map[string]*Logger
+}
+
+// NewMux creates a Mux identifying tenants with tenantOf. Events for unregistered tenants are sent to fallback, which
+// may be nil to reject them with UnknownTenantErr.
+func NewMux(tenantOf TenantFunc, fallback *Logger) *Mux {
+	return &Mux{
+		tenantOf: tenantOf,
+		fallback: fallback,
+		loggers:  make(map[string]*Logger),
+	}
+}
+
+// Handle registers the Logger used for tenant, replacing any existing one
+func (m *Mux) Handle(tenant string, l *Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loggers[tenant] = l
+}
+
+// Remove unregisters the Logger for tenant
+func (m *Mux) Remove(tenant string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.loggers, tenant)
+}
+
+// Logger returns the Logger events for tenant are routed to, or nil if there is none
+func (m *Mux) Logger(tenant string) *Logger {
+	m.mu.RLock()
+	l, ok := m.loggers[tenant]
+	m.mu.RUnlock()
+	if !ok {
+		return m.fallback
+	}
+	return l
+}
+
+// Log logs CEF event to the Logger for the event's tenant. A panic in the TenantFunc is recovered and returned as an
+// error wrapping LoggerPanicErr.
+func (m *Mux) Log(deviceEventClassId, name, severity string, extensions Extensions) (err error) {
+	var tenant string
+	if m.tenantOf != nil {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%w: %v", LoggerPanicErr, r)
+				}
+			}()
+			tenant = m.tenantOf(deviceEventClassId, name, extensions)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return m.LogTenant(tenant, deviceEventClassId, name, severity, extensions)
+}
+
+// LogTenant logs CEF event to the Logger for an explicit tenant, e.g. one taken from a request context
+func (m *Mux) LogTenant(tenant, deviceEventClassId, name, severity string, extensions Extensions) error {
+	l := m.Logger(tenant)
+	if l == nil {
+		return UnknownTenantErr
+	}
+	return l.Log(deviceEventClassId, name, severity, extensions)
+}
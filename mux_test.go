@@ -0,0 +1,43 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMux_Log(t *testing.T) {
+	acme := &bytes.Buffer{}
+	globex := &bytes.Buffer{}
+	fallback := &bytes.Buffer{}
+	m := NewMux(TenantFromCustomExtension("tenant"), NewLogger(fallback, "saas", "platform", "1.0", OmitSyslogHeader()))
+	m.Handle("acme", NewLogger(acme, "acme", "platform", "1.0", OmitSyslogHeader()))
+	m.Handle("globex", NewLogger(globex, "globex", "platform", "1.0", OmitSyslogHeader()))
+
+	assert.NoError(t, m.Log("100", "login", LowSeverity, Extensions{CustomExtensions: map[string]string{"tenant": "acme"}}))
+	assert.NoError(t, m.LogTenant("globex", "100", "login", LowSeverity, Extensions{}))
+	assert.NoError(t, m.Log("100", "login", LowSeverity, Extensions{}))
+
+	assert.Equal(t, "CEF:1|acme|platform|1.0|100|login|Low|tenant=acme", acme.String())
+	assert.Equal(t, "CEF:1|globex|platform|1.0|100|login|Low|", globex.String())
+	assert.Equal(t, "CEF:1|saas|platform|1.0|100|login|Low|", fallback.String())
+}
+
+func TestMux_Log_unknownTenant(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := NewMux(TenantFromCustomExtension("tenant"), nil)
+	m.Handle("acme", NewLogger(buf, "acme", "platform", "1.0"))
+	m.Remove("acme")
+
+	err := m.Log("100", "login", LowSeverity, Extensions{CustomExtensions: map[string]string{"tenant": "acme"}})
+	assert.ErrorIs(t, err, UnknownTenantErr)
+	assert.Empty(t, buf.String())
+}
+
+func TestMux_Log_recoversPanic(t *testing.T) {
+	m := NewMux(func(_, _ string, _ Extensions) string {
+		panic("bad tenant func")
+	}, NewLogger(&bytes.Buffer{}, "saas", "platform", "1.0"))
+	assert.ErrorIs(t, m.Log("100", "login", LowSeverity, Extensions{}), LoggerPanicErr)
+}
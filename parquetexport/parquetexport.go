@@ -0,0 +1,103 @@
+// Package parquetexport writes events to Parquet files with the columns of cefevent.ExportColumns, so archived CEF can
+// be queried efficiently from Spark, DuckDB or Athena. It is a separate module so that only programs using it depend on
+// a Parquet library.
+//
+//	w := parquetexport.NewWriter(f)
+//	err := cefevent.NewPipeline(cefevent.NewScanner(os.Stdin), w).Run(ctx)
+//	err = w.Close()
+package parquetexport
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/dmtaylor/cefevent"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Schema is the schema of the files written: a column per cefevent.ExportColumns, with strings as UTF-8, extras as
+// JSON and rt as a timestamp in milliseconds. Columns for extensions are optional.
+var Schema = newSchema()
+
+func newSchema() *parquet.Schema {
+	group := make(parquet.Group, len(cefevent.ExportColumns))
+	for _, c := range cefevent.ExportColumns {
+		var node parquet.Node
+		switch {
+		case c.Name == "extras":
+			node = parquet.JSON()
+		case c.Kind == cefevent.ExportString:
+			node = parquet.String()
+		case c.Kind == cefevent.ExportTime:
+			node = parquet.Timestamp(parquet.Millisecond)
+		default:
+			node = parquet.Int(64)
+		}
+		if c.Optional {
+			node = parquet.Optional(node)
+		}
+		group[c.Name] = node
+	}
+	return parquet.NewSchema("event", group)
+}
+
+// columns maps each of cefevent.ExportColumns to its leaf column in Schema
+var columns = func() []parquet.LeafColumn {
+	leaves := make([]parquet.LeafColumn, len(cefevent.ExportColumns))
+	for i, c := range cefevent.ExportColumns {
+		leaves[i], _ = Schema.Lookup(c.Name)
+	}
+	return leaves
+}()
+
+// Writer writes events to a Parquet file. It's a cefevent.EventWriter, so it can be the output of a Pipeline. Events
+// are buffered into row groups, and Close must be called to write the last and the file's footer. Safe for concurrent
+// use.
+type Writer struct {
+	mu sync.Mutex
+	w  *parquet.Writer
+}
+
+// NewWriter creates a Writer writing a Parquet file to out, configured by opts, e.g. parquet.Compression
+func NewWriter(out io.Writer, opts ...parquet.WriterOption) *Writer {
+	return &Writer{w: parquet.NewWriter(out, append([]parquet.WriterOption{Schema}, opts...)...)}
+}
+
+// WriteEvent adds e to the current row group
+func (w *Writer) WriteEvent(_ context.Context, e cefevent.Event) error {
+	row := make(parquet.Row, len(columns))
+	for i, v := range cefevent.ExportValues(e) {
+		c := columns[i]
+		var value parquet.Value
+		switch v := v.(type) {
+		case string:
+			value = parquet.ByteArrayValue([]byte(v))
+		case int64:
+			value = parquet.Int64Value(v)
+		}
+		definition := c.MaxDefinitionLevel
+		if value.IsNull() {
+			definition = 0
+		}
+		row[c.ColumnIndex] = value.Level(0, definition, c.ColumnIndex)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.w.WriteRows([]parquet.Row{row})
+	return err
+}
+
+// Flush writes the current row group
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Flush()
+}
+
+// Close writes the last row group and the file's footer. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Close()
+}
@@ -0,0 +1,88 @@
+package parquetexport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dmtaylor/cefevent"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ cefevent.EventWriter = (*Writer)(nil)
+
+// exported are some of the columns of a row, as read back
+type exported struct {
+	Version int64   `parquet:"version"`
+	ClassId string  `parquet:"deviceEventClassId"`
+	Name    string  `parquet:"name"`
+	Rt      *int64  `parquet:"rt,optional"`
+	Src     *string `parquet:"src,optional"`
+	Dpt     *int64  `parquet:"dpt,optional"`
+	Extras  string  `parquet:"extras"`
+}
+
+func readExported(t *testing.T, b []byte) []exported {
+	t.Helper()
+	r := parquet.NewGenericReader[exported](bytes.NewReader(b))
+	defer r.Close()
+	rows := make([]exported, r.NumRows())
+	n, err := r.Read(rows)
+	if !errors.Is(err, io.EOF) {
+		require.NoError(t, err)
+	}
+	return rows[:n]
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestWriter(t *testing.T) {
+	input := `CEF:0|v|p|1|100|login|High|rt=1699530300000 src=10.0.0.1 dpt=22 cs1=x cs1Label=note
+not cef
+CEF:1|v|p|1|200|logout|Low|
+`
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, parquet.Compression(&parquet.Zstd))
+	p := cefevent.NewPipeline(cefevent.NewScanner(strings.NewReader(input)), w)
+	require.NoError(t, p.Run(context.Background()))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []exported{
+		{0, "100", "login", ptr(int64(1699530300000)), ptr("10.0.0.1"), ptr(int64(22)), `{"cs1":"x","cs1Label":"note"}`},
+		{1, "200", "logout", nil, nil, nil, "{}"},
+	}, readExported(t, buf.Bytes()))
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Len(t, f.Schema().Fields(), len(cefevent.ExportColumns))
+	rt, ok := f.Schema().Lookup("rt")
+	require.True(t, ok)
+	assert.Equal(t, "TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)", rt.Node.Type().LogicalType().String())
+}
+
+func TestWriter_concurrent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				assert.NoError(t, w.WriteEvent(context.Background(), cefevent.Event{Name: "n"}))
+			}
+			assert.NoError(t, w.Flush())
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, w.Close())
+	assert.Len(t, readExported(t, buf.Bytes()), 400)
+}
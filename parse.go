@@ -0,0 +1,622 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MalformedEventErr error when a line can't be parsed as a CEF event
+var MalformedEventErr = errors.New("malformed cef event")
+
+// DuplicateKeyErr error when an extension key appears more than once in an event parsed with DuplicateReject
+var DuplicateKeyErr = errors.New("duplicate extension key")
+
+// DuplicatePolicy decides what Parse does when an extension key appears more than once in an event
+type DuplicatePolicy int
+
+const (
+	DuplicateKeepLast  DuplicatePolicy = iota // The last value is kept
+	DuplicateKeepFirst                        // The first value is kept
+	DuplicateCollect                          // Custom extension values are comma joined; standard fields keep the first
+	DuplicateReject                           // The event is malformed, with an error wrapping DuplicateKeyErr
+)
+
+// Parse decodes a CEF line, optionally preceded by a syslog header, into an Event. Standard extension keys are
+// decoded into their Extensions fields, including the legacy spellings Loggers emit by default; any other keys are
+// returned in CustomExtensions. Errors wrap MalformedEventErr. Values which can't be decoded, e.g. a bad IP address, are
+// skipped and the rest of the event is still decoded; FieldErrors lists them from the returned error.
+func Parse(line string, opts ...ParseOption) (Event, error) {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	start := strings.Index(line, "CEF:")
+	if start < 0 {
+		return Event{}, fmt.Errorf("%w: missing CEF: prefix", MalformedEventErr)
+	}
+	header, rest, err := splitHeader(line[start+len("CEF:"):])
+	if err != nil {
+		return Event{}, err
+	}
+	ver, err := strconv.ParseUint(strings.TrimSpace(header[0]), 10, 8)
+	if err != nil {
+		return Event{}, fmt.Errorf("%w: version %q: %w", MalformedEventErr, header[0], InvalidCefVersionErr)
+	}
+	event := Event{
+		Version:            byte(ver),
+		DeviceVendor:       header[1],
+		DeviceProduct:      header[2],
+		DeviceVersion:      header[3],
+		DeviceEventClassId: header[4],
+		Name:               header[5],
+		Severity:           header[6],
+	}
+	event.Extensions, err = parseExtensions(rest, &o)
+	if err != nil || o.schema == nil {
+		return event, err
+	}
+	custom, err := o.schema.Apply(event.Extensions.CustomExtensions)
+	if err != nil {
+		return event, err
+	}
+	if len(custom) > 0 {
+		event.Extensions.CustomExtensions = custom
+	}
+	return event, nil
+}
+
+// ParseBytes decodes a CEF record like Parse. Decoded values share a single copy of b, so b can be reused once it
+// returns, e.g. for the next token of a bufio.Scanner split with ScanRecords.
+func ParseBytes(b []byte, opts ...ParseOption) (Event, error) {
+	return Parse(string(b), opts...)
+}
+
+// ParseOption configures Parse
+type ParseOption func(o *parseOptions)
+
+type parseOptions struct {
+	schema          *Schema
+	caseInsensitive bool
+	duplicates      DuplicatePolicy
+}
+
+// WithParseSchema validates and canonicalizes the CustomExtensions of parsed events against s, as WithSchema does
+// when logging, so producers and consumers sharing a Schema agree on custom fields. Events violating the schema are
+// returned as decoded with an error wrapping SchemaViolationErr.
+func WithParseSchema(s *Schema) ParseOption {
+	return func(o *parseOptions) {
+		o.schema = s
+	}
+}
+
+// WithCaseInsensitiveKeys matches standard extension keys regardless of case, e.g. decoding "DPT" or "Cs1Label" as
+// dpt and cs1Label, for producers which don't follow the dictionary's casing. The dictionary's full names are matched
+// too, e.g. "DeviceCustomString1" as cs1. Formatting the decoded Event emits the canonical keys. Custom extension keys
+// are kept as they are.
+func WithCaseInsensitiveKeys() ParseOption {
+	return func(o *parseOptions) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithDuplicateKeys sets what happens when an extension key appears more than once in an event. Keys are compared after
+// mapping legacy and, with WithCaseInsensitiveKeys, differently cased spellings to the standard key. A value which
+// fails to parse doesn't count as an occurrence. Defaults to DuplicateKeepLast.
+func WithDuplicateKeys(policy DuplicatePolicy) ParseOption {
+	return func(o *parseOptions) {
+		o.duplicates = policy
+	}
+}
+
+// duplicate applies the duplicate policy to a repeated key
+func (o *parseOptions) duplicate(e *Extensions, key, v string) error {
+	switch o.duplicates {
+	case DuplicateCollect:
+		if _, standard := extensionSetters[key]; !standard {
+			e.CustomExtensions[key] += "," + v
+		}
+	case DuplicateReject:
+		return DuplicateKeyErr
+	}
+	return nil
+}
+
+// key returns the standard spelling of key if it matches a standard key under the options, or key otherwise
+func (o *parseOptions) key(key string) string {
+	if canonical, ok := legacyKeyNames[key]; ok {
+		return canonical
+	}
+	if _, ok := extensionSetters[key]; ok || !o.caseInsensitive {
+		return key
+	}
+	if canonical, ok := foldedKeyNames()[strings.ToLower(key)]; ok {
+		return canonical
+	}
+	return key
+}
+
+// splitHeader splits the seven header fields after the CEF: prefix from the extensions, unescaping them
+func splitHeader(s string) ([]string, string, error) {
+	fields := make([]string, 0, 7)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			i++
+			b.WriteByte(s[i])
+		case c == '|':
+			fields = append(fields, b.String())
+			b.Reset()
+			if len(fields) == 7 {
+				return fields, s[i+1:], nil
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("%w: expected 7 header fields, found %d", MalformedEventErr, len(fields))
+}
+
+// parseExtensions decodes the extension key=value pairs. A value runs until the last space before the next key, so
+// values may contain unescaped spaces, and unescaped = signs not preceded by a space are kept as part of the value.
+func parseExtensions(s string, o *parseOptions) (Extensions, error) {
+	var ext Extensions
+	s = strings.TrimLeft(s, " ")
+	if s == "" {
+		return ext, nil
+	}
+
+	var equals []int
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		} else if s[i] == '=' {
+			equals = append(equals, i)
+		}
+	}
+	if len(equals) == 0 || strings.ContainsAny(s[:equals[0]], " ") {
+		return ext, fmt.Errorf("%w: extensions don't start with a key", MalformedEventErr)
+	}
+
+	var errs []error
+	var seen map[string]bool
+	if o.duplicates != DuplicateKeepLast {
+		seen = make(map[string]bool)
+	}
+	keyStart, eq := 0, equals[0]
+	for j := 1; j <= len(equals); j++ {
+		valueEnd, nextKey := len(s), len(s)
+		if j < len(equals) {
+			sp := strings.LastIndexByte(s[eq+1:equals[j]], ' ')
+			if sp < 0 {
+				continue // unescaped = within the value
+			}
+			valueEnd, nextKey = eq+1+sp, eq+2+sp
+		}
+		key, value := o.key(unescapeExtensionField(s[keyStart:eq])), unescapeExtensionField(s[eq+1:valueEnd])
+		var err error
+		if seen[key] {
+			err = o.duplicate(&ext, key, value)
+		} else {
+			err = ext.set(key, value)
+		}
+		if err != nil {
+			errs = append(errs, &fieldError{key, fmt.Errorf("%w: %w", MalformedEventErr, err)})
+		} else if seen != nil {
+			seen[key] = true // only once set, so a valid repeat of a malformed value is kept
+		}
+		if j < len(equals) {
+			keyStart, eq = nextKey, equals[j]
+		}
+	}
+	return ext, errors.Join(errs...)
+}
+
+// unescapeExtensionField reverses escapeExtensionField, also accepting a backslash escaped newline as a newline. Unknown
+// escape sequences are kept as is.
+func unescapeExtensionField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '\\', '=', '\n':
+			b.WriteByte(s[i+1])
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i+1])
+		}
+		i++
+	}
+	return b.String()
+}
+
+// set decodes v into the field for key, or adds it to CustomExtensions if key isn't a standard key
+func (e *Extensions) set(key, v string) error {
+	if canonical, ok := legacyKeyNames[key]; ok {
+		key = canonical
+	}
+	if setter, ok := extensionSetters[key]; ok {
+		return setter(e, v)
+	}
+	if e.CustomExtensions == nil {
+		e.CustomExtensions = make(map[string]string)
+	}
+	e.CustomExtensions[key] = v
+	return nil
+}
+
+// legacyKeyNames maps the legacy key spellings back to the standard keys
+var legacyKeyNames = func() map[string]string {
+	m := make(map[string]string, len(legacyKeys))
+	for standard, legacy := range legacyKeys {
+		m[legacy] = standard
+	}
+	return m
+}()
+
+// dictionaryKeyNames maps the full names in the ArcSight extension dictionary to the keys which abbreviate them
+var dictionaryKeyNames = func() map[string]string {
+	m := map[string]string{
+		"deviceAction":        "act",
+		"applicationProtocol": "app",
+		"baseEventCount":      "cnt",
+		"endTime":             "end",
+		"bytesIn":             "in",
+		"bytesOut":            "out",
+		"eventOutcome":        "outcome",
+		"transportProtocol":   "proto",
+		"message":             "msg",
+		"deviceEventCategory": "cat",
+		"startTime":           "start",
+
+		"agentAddress":     "agt",
+		"agentHostName":    "ahost",
+		"agentId":          "aid",
+		"agentMacAddress":  "amac",
+		"agentReceiptTime": "art",
+		"agentType":        "at",
+		"agentTimeZone":    "atz",
+		"agentVersion":     "av",
+
+		"destinationHostName":       "dhost",
+		"destinationGeoLatitude":    "dlat",
+		"destinationGeoLongitude":   "dlong",
+		"destinationMacAddress":     "dmac",
+		"destinationNtDomain":       "dntdom",
+		"destinationProcessId":      "dpid",
+		"destinationUserPrivileges": "dpriv",
+		"destinationProcessName":    "dproc",
+		"destinationPort":           "dpt",
+		"destinationAddress":        "dst",
+		"destinationUserId":         "duid",
+		"destinationUserName":       "duser",
+
+		"deviceTimeZone":    "dtz",
+		"deviceAddress":     "dvc",
+		"deviceHostName":    "dvchost",
+		"deviceMacAddress":  "dvcmac",
+		"deviceProcessId":   "dvcpid",
+		"deviceReceiptTime": "rt",
+
+		"fileName":   "fname",
+		"fileSize":   "fsize",
+		"requestUrl": "request",
+
+		"sourceHostName":       "shost",
+		"sourceGeoLatitude":    "slat",
+		"sourceGeoLongitude":   "slong",
+		"sourceMacAddress":     "smac",
+		"sourceNtDomain":       "sntdom",
+		"sourceProcessId":      "spid",
+		"sourceUserPrivileges": "spriv",
+		"sourceProcessName":    "sproc",
+		"sourcePort":           "spt",
+		"sourceAddress":        "src",
+		"sourceUserId":         "suid",
+		"sourceUserName":       "suser",
+	}
+	prefixes := map[string]string{"cfp": "deviceCustomFloatingPoint", "cn": "deviceCustomNumber", "cs": "deviceCustomString"}
+	for _, spec := range customFieldSpecs {
+		for prefix, name := range prefixes {
+			if n, ok := strings.CutPrefix(spec.key, prefix); ok && len(n) == 1 {
+				m[name+n] = spec.key
+				m[name+n+"Label"] = spec.key + "Label"
+			}
+		}
+	}
+	return m
+}()
+
+// foldedKeyNames maps the lower case form of each standard and legacy key and dictionary name to its standard spelling
+var foldedKeyNames = sync.OnceValue(func() map[string]string {
+	m := make(map[string]string, len(extensionSetters)+len(legacyKeyNames)+len(dictionaryKeyNames))
+	for key := range extensionSetters {
+		m[strings.ToLower(key)] = key
+	}
+	for legacy, standard := range legacyKeyNames {
+		m[strings.ToLower(legacy)] = standard
+	}
+	for name, key := range dictionaryKeyNames {
+		m[strings.ToLower(name)] = key
+	}
+	return m
+})
+
+// fieldSetter decodes a value into an Extensions field
+type fieldSetter func(e *Extensions, v string) error
+
+// extensionSetters decode each standard key, mirroring Extensions.walk
+var extensionSetters = map[string]fieldSetter{
+	"msg":     strField(func(e *Extensions) *string { return &e.Message }),
+	"act":     strField(func(e *Extensions) *string { return &e.DeviceAction }),
+	"app":     strField(func(e *Extensions) *string { return &e.ApplicationProtocol }),
+	"cnt":     func(e *Extensions, v string) (err error) { e.BaseEventCount, err = strconv.Atoi(v); return err },
+	"end":     timeField(func(e *Extensions) *time.Time { return &e.EndTime }),
+	"type":    func(e *Extensions, v string) (err error) { e.Type, err = parseUint[byte](v, 8); return err },
+	"in":      uintPtrField(func(e *Extensions) **uint { return &e.BytesIn }),
+	"out":     uintPtrField(func(e *Extensions) **uint { return &e.BytesOut }),
+	"outcome": strField(func(e *Extensions) *string { return &e.Outcome }),
+	"proto":   strField(func(e *Extensions) *string { return &e.TransportProtocol }),
+	"reason":  strField(func(e *Extensions) *string { return &e.Reason }),
+
+	"externalId":         strField(func(e *Extensions) *string { return &e.ExternalId }),
+	"cat":                strField(func(e *Extensions) *string { return &e.DeviceEventCategory }),
+	"customerExternalID": strField(func(e *Extensions) *string { return &e.CustomerExternalId }),
+	"customerURI":        strField(func(e *Extensions) *string { return &e.CustomerUri }),
+	"eventId":            int64PtrField(func(e *Extensions) **int64 { return &e.EventId }),
+	"rawEvent":           strField(func(e *Extensions) *string { return &e.RawEvent }),
+	"start":              timeField(func(e *Extensions) *time.Time { return &e.StartTime }),
+
+	"agentDnsDomain":                strField(func(e *Extensions) *string { return &e.AgentDnsDomain }),
+	"agentNtDomain":                 strField(func(e *Extensions) *string { return &e.AgentNtDomain }),
+	"agentTranslatedAddress":        ipField(func(e *Extensions) *net.IP { return &e.AgentTranslatedAddress }),
+	"agentTranslatedZoneExternalID": strField(func(e *Extensions) *string { return &e.AgentTranslatedZoneExternalId }),
+	"agentTranslatedZoneURI":        strField(func(e *Extensions) *string { return &e.AgentTranslatedZoneUri }),
+	"agentZoneExternalID":           strField(func(e *Extensions) *string { return &e.AgentZoneExternalId }),
+	"agentZoneURI":                  strField(func(e *Extensions) *string { return &e.AgentZoneUri }),
+	"agt":                           ipField(func(e *Extensions) *net.IP { return &e.AgentAddress }),
+	"ahost":                         strField(func(e *Extensions) *string { return &e.AgentHostName }),
+	"aid":                           strField(func(e *Extensions) *string { return &e.AgentId }),
+	"amac":                          macField(func(e *Extensions) *net.HardwareAddr { return &e.AgentMacAddress }),
+	"art":                           timeField(func(e *Extensions) *time.Time { return &e.AgentReceiptTime }),
+	"at":                            strField(func(e *Extensions) *string { return &e.AgentType }),
+	"atz":                           locationField(func(e *Extensions) **time.Location { return &e.AgentTimeZone }),
+	"av":                            strField(func(e *Extensions) *string { return &e.AgentVersion }),
+
+	"destinationDnsDomain":                strField(func(e *Extensions) *string { return &e.DestinationDnsDomain }),
+	"destinationServiceName":              strField(func(e *Extensions) *string { return &e.DestinationServiceName }),
+	"destinationTranslatedAddress":        ipField(func(e *Extensions) *net.IP { return &e.DestinationTranslatedAddress }),
+	"destinationTranslatedPort":           uintPtrField(func(e *Extensions) **uint { return &e.DestinationTranslatedPort }),
+	"destinationTranslatedZoneExternalID": strField(func(e *Extensions) *string { return &e.DestinationTranslatedZoneExternalId }),
+	"destinationTranslatedZoneURI":        strField(func(e *Extensions) *string { return &e.DestinationTranslatedZoneUri }),
+	"destinationZoneExternalID":           strField(func(e *Extensions) *string { return &e.DestinationZoneExternalId }),
+	"destinationZoneURI":                  strField(func(e *Extensions) *string { return &e.DestinationZoneUri }),
+	"dhost":                               strField(func(e *Extensions) *string { return &e.DestinationHostName }),
+	"dlat":                                floatPtrField(func(e *Extensions) **float64 { return &e.DestinationGeoLatitude }),
+	"dlong":                               floatPtrField(func(e *Extensions) **float64 { return &e.DestinationGeoLongitude }),
+	"dmac":                                macField(func(e *Extensions) *net.HardwareAddr { return &e.DestinationMacAddress }),
+	"dntdom":                              strField(func(e *Extensions) *string { return &e.DestinationNtDomain }),
+	"dpid":                                uintPtrField(func(e *Extensions) **uint { return &e.DestinationProcessId }),
+	"dpriv":                               strField(func(e *Extensions) *string { return &e.DestinationUserPrivileges }),
+	"dproc":                               strField(func(e *Extensions) *string { return &e.DestinationProcessName }),
+	"dpt":                                 uintPtrField(func(e *Extensions) **uint { return &e.DestinationPort }),
+	"dst":                                 ipField(func(e *Extensions) *net.IP { return &e.DestinationAddress }),
+	"duid":                                strField(func(e *Extensions) *string { return &e.DestinationUserId }),
+	"duser":                               strField(func(e *Extensions) *string { return &e.DestinationUserName }),
+
+	"deviceDirection": func(e *Extensions, v string) error {
+		d, err := parseUint[uint8](v, 8)
+		if err != nil {
+			return err
+		}
+		e.DeviceDirection = &d
+		return nil
+	},
+	"deviceDnsDomain":                strField(func(e *Extensions) *string { return &e.DeviceDnsDomain }),
+	"deviceExternalId":               strField(func(e *Extensions) *string { return &e.DeviceExternalId }),
+	"deviceFacility":                 strField(func(e *Extensions) *string { return &e.DeviceFacility }),
+	"deviceInboundInterface":         strField(func(e *Extensions) *string { return &e.DeviceInboundInterface }),
+	"deviceNtDomain":                 strField(func(e *Extensions) *string { return &e.DeviceNtDomain }),
+	"deviceOutboundInterface":        strField(func(e *Extensions) *string { return &e.DeviceOutboundInterface }),
+	"devicePayloadId":                strField(func(e *Extensions) *string { return &e.DevicePayloadId }),
+	"deviceProcessName":              strField(func(e *Extensions) *string { return &e.DeviceProcessName }),
+	"deviceTranslatedAddress":        ipField(func(e *Extensions) *net.IP { return &e.DeviceTranslatedAddress }),
+	"deviceTranslatedZoneExternalID": strField(func(e *Extensions) *string { return &e.DeviceTranslatedZoneExternalId }),
+	"deviceTranslatedZoneURI":        strField(func(e *Extensions) *string { return &e.DeviceTranslatedZoneUri }),
+	"deviceZoneExternalID":           strField(func(e *Extensions) *string { return &e.DeviceZoneExternalId }),
+	"deviceZoneURI":                  strField(func(e *Extensions) *string { return &e.DeviceZoneUri }),
+	"dtz":                            locationField(func(e *Extensions) **time.Location { return &e.DeviceTimeZone }),
+	"dvc":                            ipField(func(e *Extensions) *net.IP { return &e.DeviceAddress }),
+	"dvchost":                        strField(func(e *Extensions) *string { return &e.DeviceHostName }),
+	"dvcmac":                         macField(func(e *Extensions) *net.HardwareAddr { return &e.DeviceMacAddress }),
+	"dvcpid":                         uintPtrField(func(e *Extensions) **uint { return &e.DeviceProcessId }),
+	"rt":                             timeField(func(e *Extensions) *time.Time { return &e.DeviceReceiptTime }),
+
+	"fileCreateTime":          timeField(func(e *Extensions) *time.Time { return &e.FileCreateTime }),
+	"fileHash":                strField(func(e *Extensions) *string { return &e.FileHash }),
+	"fileId":                  strField(func(e *Extensions) *string { return &e.FileId }),
+	"fileModificationTime":    timeField(func(e *Extensions) *time.Time { return &e.FileModificationTime }),
+	"filePath":                strField(func(e *Extensions) *string { return &e.FilePath }),
+	"filePermission":          strField(func(e *Extensions) *string { return &e.FilePermission }),
+	"fileType":                strField(func(e *Extensions) *string { return &e.FileType }),
+	"fname":                   strField(func(e *Extensions) *string { return &e.FileName }),
+	"fsize":                   uintPtrField(func(e *Extensions) **uint { return &e.FileSize }),
+	"oldFileCreateTime":       timeField(func(e *Extensions) *time.Time { return &e.OldFileCreateTime }),
+	"oldFileHash":             strField(func(e *Extensions) *string { return &e.OldFileHash }),
+	"oldFileId":               strField(func(e *Extensions) *string { return &e.OldFileId }),
+	"oldFileModificationTime": timeField(func(e *Extensions) *time.Time { return &e.OldFileModificationTime }),
+	"oldFileName":             strField(func(e *Extensions) *string { return &e.OldFileName }),
+	"oldFilePath":             strField(func(e *Extensions) *string { return &e.OldFilePath }),
+	"oldFilePermission":       strField(func(e *Extensions) *string { return &e.OldFilePermission }),
+	"oldFileType":             strField(func(e *Extensions) *string { return &e.OldFileType }),
+	"oldFileSize":             uintPtrField(func(e *Extensions) **uint { return &e.OldFileSize }),
+
+	"request": func(e *Extensions, v string) error {
+		u, err := url.Parse(v)
+		if err != nil {
+			return err
+		}
+		e.RequestUrl = *u
+		return nil
+	},
+	"requestClientApplication": strField(func(e *Extensions) *string { return &e.RequestClientApplication }),
+	"requestContext":           strField(func(e *Extensions) *string { return &e.RequestContext }),
+	"requestCookies":           strField(func(e *Extensions) *string { return &e.RequestCookies }),
+	"requestMethod":            strField(func(e *Extensions) *string { return &e.RequestMethod }),
+
+	"shost":                          strField(func(e *Extensions) *string { return &e.SourceHostName }),
+	"slat":                           floatPtrField(func(e *Extensions) **float64 { return &e.SourceGeoLatitude }),
+	"slong":                          floatPtrField(func(e *Extensions) **float64 { return &e.SourceGeoLongitude }),
+	"smac":                           macField(func(e *Extensions) *net.HardwareAddr { return &e.SourceMacAddress }),
+	"sntdom":                         strField(func(e *Extensions) *string { return &e.SourceNtDomain }),
+	"sourceDnsDomain":                strField(func(e *Extensions) *string { return &e.SourceDnsDomain }),
+	"sourceServiceName":              strField(func(e *Extensions) *string { return &e.SourceServiceName }),
+	"sourceTranslatedAddress":        ipField(func(e *Extensions) *net.IP { return &e.SourceTranslatedAddress }),
+	"sourceTranslatedPort":           uintPtrField(func(e *Extensions) **uint { return &e.SourceTranslatedPort }),
+	"sourceTranslatedZoneExternalID": strField(func(e *Extensions) *string { return &e.SourceTranslatedZoneExternalId }),
+	"sourceTranslatedZoneURI":        strField(func(e *Extensions) *string { return &e.SourceTranslatedZoneUri }),
+	"sourceZoneExternalID":           strField(func(e *Extensions) *string { return &e.SourceZoneExternalId }),
+	"sourceZoneURI":                  strField(func(e *Extensions) *string { return &e.SourceZoneUri }),
+	"spid": func(e *Extensions, v string) error {
+		pid, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		e.SourceProcessId = &pid
+		return nil
+	},
+	"spriv": strField(func(e *Extensions) *string { return &e.SourceUserPrivileges }),
+	"sproc": strField(func(e *Extensions) *string { return &e.SourceProcessName }),
+	"spt":   uintPtrField(func(e *Extensions) **uint { return &e.SourcePort }),
+	"src":   ipField(func(e *Extensions) *net.IP { return &e.SourceAddress }),
+	"suid":  strField(func(e *Extensions) *string { return &e.SourceUserId }),
+	"suser": strField(func(e *Extensions) *string { return &e.SourceUserName }),
+}
+
+func init() {
+	for _, spec := range customFieldSpecs {
+		extensionSetters[spec.key] = spec.set
+		setLabel := spec.setLabel
+		extensionSetters[spec.key+"Label"] = func(e *Extensions, v string) error {
+			setLabel(e, v)
+			return nil
+		}
+	}
+}
+
+func strField(field func(e *Extensions) *string) fieldSetter {
+	return func(e *Extensions, v string) error {
+		*field(e) = v
+		return nil
+	}
+}
+
+func uintPtrField(field func(e *Extensions) **uint) fieldSetter {
+	return func(e *Extensions, v string) error {
+		u, err := parseUint[uint](v, strconv.IntSize)
+		if err != nil {
+			return err
+		}
+		*field(e) = &u
+		return nil
+	}
+}
+
+func int64PtrField(field func(e *Extensions) **int64) fieldSetter {
+	return func(e *Extensions, v string) error {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(e) = &i
+		return nil
+	}
+}
+
+func floatPtrField(field func(e *Extensions) **float64) fieldSetter {
+	return func(e *Extensions, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*field(e) = &f
+		return nil
+	}
+}
+
+func timeField(field func(e *Extensions) *time.Time) fieldSetter {
+	return func(e *Extensions, v string) (err error) {
+		*field(e), err = parseTimestamp(v)
+		return err
+	}
+}
+
+func ipField(field func(e *Extensions) *net.IP) fieldSetter {
+	return func(e *Extensions, v string) error {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return fmt.Errorf("%q is not an IP address", v)
+		}
+		*field(e) = ip
+		return nil
+	}
+}
+
+func macField(field func(e *Extensions) *net.HardwareAddr) fieldSetter {
+	return func(e *Extensions, v string) (err error) {
+		*field(e), err = net.ParseMAC(v)
+		return err
+	}
+}
+
+func locationField(field func(e *Extensions) **time.Location) fieldSetter {
+	return func(e *Extensions, v string) (err error) {
+		*field(e), err = time.LoadLocation(v)
+		return err
+	}
+}
+
+func parseUint[T ~uint | ~uint8](v string, bits int) (T, error) {
+	u, err := strconv.ParseUint(v, 10, bits)
+	return T(u), err
+}
+
+// timestampLayouts are the date formats allowed by the CEF spec, besides milliseconds since the epoch
+var timestampLayouts = []string{
+	"Jan 2 2006 15:04:05.000 MST",
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 2006 15:04:05.000",
+	"Jan 2 2006 15:04:05",
+	time.RFC3339Nano,
+}
+
+// parseTimestamp parses a CEF timestamp. Timestamps without a time zone are taken to be UTC.
+func parseTimestamp(v string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a timestamp", v)
+}
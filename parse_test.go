@@ -0,0 +1,284 @@
+package cefevent
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Event
+		wantErr error
+	}{
+		{
+			"no_extensions",
+			"CEF:0|vendor|product|1.0|100|name|Low|",
+			Event{0, "vendor", "product", "1.0", "100", "name", "Low", Extensions{}},
+			nil,
+		},
+		{
+			"syslog_header",
+			"Nov 9 11:45:20 host.example.com CEF:1|vendor|product|1.0|100|name|7|msg=hello world rt=1699530320000\n",
+			Event{1, "vendor", "product", "1.0", "100", "name", "7", Extensions{Message: "hello world", DeviceReceiptTime: testTime()}},
+			nil,
+		},
+		{
+			"escaped_header",
+			`CEF:1|ven\|dor|pro\\duct|1.0|100|a \| b|High|`,
+			Event{1, "ven|dor", `pro\duct`, "1.0", "100", "a | b", "High", Extensions{}},
+			nil,
+		},
+		{
+			"escaped_extensions",
+			`CEF:1|v|p|1|100|n|Low|msg=a\=b\nc\\d\re act=x=y dpt=443 custom\=key=value path=C:\Windows `,
+			Event{1, "v", "p", "1", "100", "n", "Low", Extensions{
+				Message:          "a=b\nc\\d\re",
+				DeviceAction:     "x=y",
+				DestinationPort:  ptr(uint(443)),
+				CustomExtensions: map[string]string{"custom=key": "value", "path": `C:\Windows `},
+			}},
+			nil,
+		},
+		{
+			"legacy_keys",
+			"CEF:1|v|p|1|100|n|Low|dcvhost=host deviceNtInterface=CORP",
+			Event{1, "v", "p", "1", "100", "n", "Low", Extensions{DeviceHostName: "host", DeviceNtDomain: "CORP"}},
+			nil,
+		},
+		{
+			"missing_prefix",
+			"vendor|product|1.0|100|name|Low|",
+			Event{},
+			MalformedEventErr,
+		},
+		{
+			"short_header",
+			"CEF:1|vendor|product|1.0|100|name",
+			Event{},
+			MalformedEventErr,
+		},
+		{
+			"bad_version",
+			"CEF:x|vendor|product|1.0|100|name|Low|",
+			Event{},
+			InvalidCefVersionErr,
+		},
+		{
+			"extensions_without_key",
+			"CEF:1|vendor|product|1.0|100|name|Low|hello world",
+			Event{1, "vendor", "product", "1.0", "100", "name", "Low", Extensions{}},
+			MalformedEventErr,
+		},
+		{
+			"bad_value",
+			"CEF:1|vendor|product|1.0|100|name|Low|dpt=http msg=kept",
+			Event{1, "vendor", "product", "1.0", "100", "name", "Low", Extensions{Message: "kept"}},
+			MalformedEventErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse_roundTrip(t *testing.T) {
+	tz, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	mac := net.HardwareAddr{0x00, 0x0d, 0x60, 0xaf, 0x1b, 0x61}
+	ip := net.ParseIP("192.0.2.1")
+	e := Extensions{
+		Message: "msg with = and \\ and\nnewline", BaseEventCount: 3, ApplicationProtocol: "HTTPS", EndTime: testTime(),
+		ExternalId: "ext", Type: AggregatedEventType, BytesIn: ptr(uint(1)), BytesOut: ptr(uint(2)), Outcome: "success",
+		TransportProtocol: "TCP", Reason: "reason", DeviceEventCategory: "/cat", EventId: ptr(int64(-5)), RawEvent: "raw",
+		StartTime: testTime(), CustomerExternalId: "cust", CustomerUri: "/cust",
+
+		AgentAddress: ip, AgentHostName: "agent", AgentId: "aid", AgentMacAddress: mac, AgentReceiptTime: testTime(),
+		AgentType: "type", AgentTimeZone: tz, AgentVersion: "1", AgentDnsDomain: "example.com", AgentNtDomain: "CORP",
+		AgentTranslatedAddress: ip, AgentTranslatedZoneExternalId: "atz", AgentTranslatedZoneUri: "/atz",
+		AgentZoneExternalId: "az", AgentZoneUri: "/az",
+
+		SourceHostName: "src", SourceMacAddress: mac, SourceNtDomain: "CORP", SourceDnsDomain: "example.com",
+		SourceServiceName: "svc", SourceTranslatedAddress: ip, SourceTranslatedPort: ptr(uint(3)),
+		SourceProcessId: ptr(4), SourceAddress: ip, SourcePort: ptr(uint(5)), SourceUserName: "user",
+		SourceUserId: "0", SourceUserPrivileges: "Admin", SourceProcessName: "proc", SourceGeoLatitude: ptr(1.5),
+		SourceGeoLongitude: ptr(-2.25), SourceZoneExternalId: "sz", SourceZoneUri: "/sz",
+		SourceTranslatedZoneExternalId: "stz", SourceTranslatedZoneUri: "/stz",
+
+		DestinationDnsDomain: "example.com", DestinationServiceName: "sshd", DestinationTranslatedAddress: ip,
+		DestinationTranslatedPort: ptr(uint(6)), DestinationHostName: "dst", DestinationMacAddress: mac,
+		DestinationNtDomain: "CORP", DestinationProcessId: ptr(uint(7)), DestinationUserPrivileges: "User",
+		DestinationProcessName: "proc", DestinationPort: ptr(uint(8)), DestinationAddress: ip, DestinationUserId: "1",
+		DestinationUserName: "bob", DestinationGeoLatitude: ptr(3.5), DestinationGeoLongitude: ptr(4.5),
+		DestinationZoneExternalId: "dz", DestinationZoneUri: "/dz", DestinationTranslatedZoneExternalId: "dtz",
+		DestinationTranslatedZoneUri: "/dtz",
+
+		DeviceAction: "blocked", DeviceDirection: ptr(uint8(1)), DeviceDnsDomain: "example.com", DeviceExternalId: "dev",
+		DeviceFacility: "fac", DeviceInboundInterface: "eth0", DeviceNtDomain: "CORP", DeviceOutboundInterface: "eth1",
+		DevicePayloadId: "payload", DeviceProcessName: "proc", DeviceTranslatedAddress: ip, DeviceTimeZone: tz,
+		DeviceAddress: ip, DeviceHostName: "host", DeviceMacAddress: mac, DeviceProcessId: ptr(uint(9)),
+		DeviceReceiptTime: testTime(), DeviceZoneExternalId: "vz", DeviceZoneUri: "/vz",
+		DeviceTranslatedZoneExternalId: "vtz", DeviceTranslatedZoneUri: "/vtz",
+
+		FileCreateTime: testTime(), FileHash: "hash", FileId: "1", FileModificationTime: testTime(), FilePath: "/a/b",
+		FilePermission: "0644", FileType: "file", FileName: "b", FileSize: ptr(uint(10)),
+		OldFileCreateTime: testTime(), OldFileHash: "old", OldFileId: "2", OldFileModificationTime: testTime(),
+		OldFileName: "c", OldFilePath: "/a/c", OldFilePermission: "0600", OldFileSize: ptr(uint(11)), OldFileType: "file",
+
+		RequestUrl: url.URL{Scheme: "https", Host: "example.com", Path: "/"}, RequestClientApplication: "curl",
+		RequestContext: "ref", RequestCookies: "a=b", RequestMethod: "GET",
+
+		DeviceCustomFloatingPoint1: Labeled("f1", 1.5), DeviceCustomFloatingPoint2: Labeled("f2", 0.0),
+		DeviceCustomFloatingPoint3: Labeled("f3", -3.25), DeviceCustomFloatingPoint4: Labeled("f4", 4e10),
+		DeviceCustomNumber1: Labeled("n1", int64(1)), DeviceCustomNumber2: Labeled("n2", int64(0)),
+		DeviceCustomNumber3: Labeled("n3", int64(-3)),
+		DeviceCustomString1: Labeled("s1", "one"), DeviceCustomString2: Labeled("s2", "two"),
+		DeviceCustomString3: Labeled("s3", "three"), DeviceCustomString4: Labeled("s4", "four"),
+		DeviceCustomString5: Labeled("s5", "five"), DeviceCustomString6: Labeled("s6 with space", "six"),
+		DeviceCustomDate1: Labeled("d1", testTime()), DeviceCustomDate2: Labeled("d2", testTime()),
+		FlexDate1: Labeled("fd", testTime()), FlexString1: Labeled("fs1", "a"), FlexString2: Labeled("fs2", "b"),
+
+		CustomExtensions: map[string]string{"custom": "value with spaces "},
+	}
+	// Every field must be populated so new fields can't be missed by the parser
+	ev := reflect.ValueOf(e)
+	for i := 0; i < ev.NumField(); i++ {
+		assert.False(t, ev.Field(i).IsZero(), "%s is not populated", ev.Type().Field(i).Name)
+	}
+
+	got, err := Parse("CEF:1|v|p|1|100|n|Low|" + e.String())
+	require.NoError(t, err)
+	assert.Equal(t, e, got.Extensions)
+}
+
+func TestParse_caseInsensitiveKeys(t *testing.T) {
+	line := "CEF:1|v|p|1|100|n|Low|MSG=hi DPT=22 Cs1Label=policy CS1=deny DCVHOST=host Tenant=acme"
+	want := Extensions{
+		Message:             "hi",
+		DestinationPort:     ptr(uint(22)),
+		DeviceCustomString1: Labeled("policy", "deny"),
+		DeviceHostName:      "host",
+		CustomExtensions:    map[string]string{"Tenant": "acme"},
+	}
+	got, err := Parse(line, WithCaseInsensitiveKeys())
+	require.NoError(t, err)
+	assert.Equal(t, want, got.Extensions)
+	assert.Equal(t, "msg=hi dpt=22 dcvhost=host cs1=deny cs1Label=policy Tenant=acme", got.Extensions.String())
+
+	got, err = Parse(line)
+	require.NoError(t, err)
+	assert.Nil(t, got.Extensions.DestinationPort)
+	assert.Equal(t, "22", got.Extensions.CustomExtensions["DPT"])
+
+	got, err = Parse("CEF:1|v|p|1|100|n|Low|DeviceCustomString1=deny DeviceCustomString1Label=policy destinationPort=22 "+
+		"deviceCustomNumber2=7", WithCaseInsensitiveKeys())
+	require.NoError(t, err)
+	assert.Equal(t, "dpt=22 cn2=7 cs1=deny cs1Label=policy", got.Extensions.String())
+	assert.Empty(t, got.Extensions.CustomExtensions)
+
+	// Standard keys & dictionary names mustn't collide once folded
+	for name, key := range dictionaryKeyNames {
+		assert.Contains(t, extensionSetters, key, name)
+	}
+	assert.Len(t, foldedKeyNames(), len(extensionSetters)+len(legacyKeyNames)+len(dictionaryKeyNames))
+}
+
+func TestParse_duplicateKeys(t *testing.T) {
+	line := "CEF:1|v|p|1|100|n|Low|dpt=22 tag=a dpt=443 DPT=8080 tag=b"
+	tests := []struct {
+		name   string
+		line   string
+		policy DuplicatePolicy
+		want   Extensions
+		err    error
+	}{
+		{"keep_last", line, DuplicateKeepLast, Extensions{DestinationPort: ptr(uint(8080)), CustomExtensions: map[string]string{"tag": "b"}}, nil},
+		{"keep_first", line, DuplicateKeepFirst, Extensions{DestinationPort: ptr(uint(22)), CustomExtensions: map[string]string{"tag": "a"}}, nil},
+		{"keep_first_malformed", "CEF:1|v|p|1|100|n|Low|dpt=x dpt=443 dpt=8080", DuplicateKeepFirst,
+			Extensions{DestinationPort: ptr(uint(443))}, MalformedEventErr},
+		{"collect", line, DuplicateCollect, Extensions{DestinationPort: ptr(uint(22)), CustomExtensions: map[string]string{"tag": "a,b"}}, nil},
+		{"reject", line, DuplicateReject, Extensions{DestinationPort: ptr(uint(22)), CustomExtensions: map[string]string{"tag": "a"}}, DuplicateKeyErr},
+		{"reject_malformed", "CEF:1|v|p|1|100|n|Low|dpt=x dpt=443", DuplicateReject, Extensions{DestinationPort: ptr(uint(443))},
+			MalformedEventErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line, WithDuplicateKeys(tt.policy), WithCaseInsensitiveKeys())
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+				assert.ErrorIs(t, err, MalformedEventErr)
+			}
+			assert.Equal(t, tt.want, got.Extensions)
+		})
+	}
+	_, err := Parse("CEF:1|v|p|1|100|n|Low|dpt=x dpt=443", WithDuplicateKeys(DuplicateReject))
+	assert.NotErrorIs(t, err, DuplicateKeyErr, "malformed value counted as the first")
+}
+
+func TestParse_schema(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		custom map[string]string
+		err    error
+	}{
+		{"canonical", "CEF:1|v|p|1|100|n|Low|tenantId=042 tier=pro", map[string]string{"tenantId": "42", "tier": "pro"}, nil},
+		{"violation", "CEF:1|v|p|1|100|n|Low|tenantId=abc", map[string]string{"tenantId": "abc"}, SchemaViolationErr},
+		{"missing required", "CEF:1|v|p|1|100|n|Low|msg=hi", nil, SchemaViolationErr},
+		{"malformed", "CEF:1|v|p|1|100|n|Low|dpt=x tenantId=abc", map[string]string{"tenantId": "abc"}, MalformedEventErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line, WithParseSchema(testSchema(t)))
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+			assert.Equal(t, tt.custom, got.Extensions.CustomExtensions)
+		})
+	}
+}
+
+func Test_parseTimestamp(t *testing.T) {
+	tests := []struct {
+		v       string
+		want    time.Time
+		wantErr bool
+	}{
+		{"1699530320000", testTime(), false},
+		{"Nov 9 2023 11:45:20", testTime(), false},
+		{"Nov 09 2023 11:45:20.000", testTime(), false},
+		{"Nov 9 2023 11:45:20 UTC", testTime(), false},
+		{"2023-11-09T11:45:20Z", testTime(), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.v, func(t *testing.T) {
+			got, err := parseTimestamp(tt.v)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+}
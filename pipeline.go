@@ -0,0 +1,220 @@
+package cefevent
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// EventWriter receives the events leaving a Pipeline. *Logger is an EventWriter, relaying events as LogEvent does.
+// WriteEvent may be called concurrently by a Pipeline with several workers.
+type EventWriter interface {
+	WriteEvent(ctx context.Context, e Event) error
+}
+
+// EventWriterFunc adapts a function to an EventWriter
+type EventWriterFunc func(ctx context.Context, e Event) error
+
+// WriteEvent calls f(ctx, e)
+func (f EventWriterFunc) WriteEvent(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// Stage processes each event passing through a Pipeline. It may modify e, or return false to drop it. Returning an
+// error drops the event too, reporting it as a PipelineError. Pointer fields and CustomExtensions belong to the event, so may be modified in place.
+type Stage func(ctx context.Context, e *Event) (keep bool, err error)
+
+// Pipeline relays the events read by a Scanner through a chain of Stages to an EventWriter, e.g. to build a forwarder
+// normalizing the events of another product:
+//
+//	p := cefevent.NewPipeline(cefevent.NewScanner(os.Stdin), logger, cefevent.WithStages(normalize))
+//	err := p.Run(ctx)
+//
+// Records which fail to parse, fail in a stage or can't be written are dropped and reported to the function set with
+// WithPipelineErrors. Stats counts the events handled.
+type Pipeline struct {
+	in      *Scanner
+	out     EventWriter
+	stages  []Stage
+	workers int
+	onError func(err *PipelineError)
+
+	processed atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// PipelineStats counts the records a Pipeline has handled
+type PipelineStats struct {
+	Processed uint64 // Events written to the EventWriter
+	Dropped   uint64 // Events dropped by a stage, including those taken by Route
+	Errors    uint64 // Records which failed to parse, failed in a stage or couldn't be written
+}
+
+// PipelineError is a record a Pipeline failed to relay, with the record as read so that failing transformations can
+// be debugged
+type PipelineError struct {
+	Record int    // The 1 based number of the record, as Scanner.Record
+	Raw    string // The record as read
+	Err    error
+}
+
+func (e *PipelineError) Error() string {
+	return "record " + strconv.Itoa(e.Record) + ": " + e.Err.Error()
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// PipelineOption configures a Pipeline
+type PipelineOption func(p *Pipeline)
+
+// WithStages appends stages to the Pipeline. Each event passes through the stages in order until one drops it.
+func WithStages(stages ...Stage) PipelineOption {
+	return func(p *Pipeline) {
+		p.stages = append(p.stages, stages...)
+	}
+}
+
+// WithPipelineWorkers processes up to n events concurrently, for stages which block, e.g. on lookups. Events may then
+// be written out of order, and stages and the EventWriter must be safe for concurrent use. Defaults to 1
+func WithPipelineWorkers(n int) PipelineOption {
+	return func(p *Pipeline) {
+		p.workers = max(n, 1)
+	}
+}
+
+// WithPipelineErrors sets a function called with each record the Pipeline fails to relay. With WithPipelineWorkers it
+// may be called concurrently.
+func WithPipelineErrors(fn func(err *PipelineError)) PipelineOption {
+	return func(p *Pipeline) {
+		p.onError = fn
+	}
+}
+
+// NewPipeline creates a Pipeline relaying the events read by in to out
+func NewPipeline(in *Scanner, out EventWriter, opts ...PipelineOption) *Pipeline {
+	p := &Pipeline{in: in, out: out, workers: 1}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// pipelineRecord is a record read by a Pipeline, with its parsed event or the error parsing it
+type pipelineRecord struct {
+	number int
+	text   string
+	event  Event
+	err    error
+}
+
+// Run relays events until the Scanner reaches the end of its input, returning once every event read has been written
+// with the error that ended the scan, or nil. If ctx is done first Run stops taking events, waits for those being
+// processed and returns ctx's error. A read blocked at that point is left to finish in the background, so close the
+// Scanner's reader to stop it, e.g. with context.AfterFunc. Run must not be called more than once.
+func (p *Pipeline) Run(ctx context.Context) error {
+	records := make(chan pipelineRecord, p.workers)
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case r, ok := <-records:
+					if !ok {
+						return
+					}
+					p.process(ctx, r)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	read := make(chan error, 1)
+	go func() {
+		read <- p.read(ctx, records)
+	}()
+	var err error
+	select {
+	case err = <-read:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	wg.Wait()
+	return err
+}
+
+// read sends each record scanned to records, closing it at the end of the input
+func (p *Pipeline) read(ctx context.Context, records chan<- pipelineRecord) error {
+	for p.in.Scan() {
+		event, err := p.in.Event()
+		select {
+		case records <- pipelineRecord{number: p.in.Record(), text: p.in.Text(), event: event, err: err}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	close(records)
+	return p.in.Err()
+}
+
+// process passes a record through the stages and writes it out
+func (p *Pipeline) process(ctx context.Context, r pipelineRecord) {
+	if r.err != nil {
+		p.fail(r, r.err)
+		return
+	}
+	e := r.event
+	for _, stage := range p.stages {
+		keep, err := stage(ctx, &e)
+		if err != nil {
+			p.fail(r, err)
+			return
+		}
+		if !keep {
+			p.dropped.Add(1)
+			return
+		}
+	}
+	if err := p.out.WriteEvent(ctx, e); err != nil {
+		p.fail(r, err)
+		return
+	}
+	p.processed.Add(1)
+}
+
+// fail counts and reports a record which couldn't be relayed
+func (p *Pipeline) fail(r pipelineRecord, err error) {
+	p.failed.Add(1)
+	if p.onError != nil {
+		p.onError(&PipelineError{Record: r.number, Raw: r.text, Err: err})
+	}
+}
+
+// Stats returns the number of records handled so far. Safe to call while Run is in progress.
+func (p *Pipeline) Stats() PipelineStats {
+	return PipelineStats{Processed: p.processed.Load(), Dropped: p.dropped.Load(), Errors: p.failed.Load()}
+}
+
+// WriteEvent logs e like LogEvent, giving up once ctx is done and merging fields from WithContextExtractor like
+// LogContext, so a Logger can be a Pipeline's EventWriter
+func (l *Logger) WriteEvent(ctx context.Context, e Event) (err error) {
+	defer l.recoverPanic(&err)
+	if l.parent != nil {
+		e.Extensions = l.defaults.Merge(e.Extensions)
+		return l.parent.WriteEvent(ctx, e)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for i := len(l.contextExtractors) - 1; i >= 0; i-- {
+		e.Extensions = l.contextExtractors[i](ctx).Merge(e.Extensions)
+	}
+	return l.log(ctx, l.eventDevice(e), e.DeviceEventClassId, e.Name, e.Severity, e.Extensions)
+}
@@ -0,0 +1,176 @@
+package cefevent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder is an EventWriter recording the events written to it
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) WriteEvent(_ context.Context, e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *eventRecorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, e := range r.events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+const pipelineInput = `CEF:0|v|p|1|100|one|Low|src=10.0.0.1
+CEF:0|v|p|1|200|two|High|src=10.0.0.2
+not cef
+CEF:0|v|p|1|100|three|Low|src=10.0.0.3
+`
+
+func TestPipeline_Run(t *testing.T) {
+	tag := func(_ context.Context, e *Event) (bool, error) {
+		e.Extensions.DeviceCustomString1 = Labeled("relay", "r1")
+		return true, nil
+	}
+	dropHigh := func(_ context.Context, e *Event) (bool, error) {
+		return e.Severity != HighSeverity, nil
+	}
+	failThree := func(_ context.Context, e *Event) (bool, error) {
+		if e.Name == "three" {
+			return true, errors.New("failed")
+		}
+		return true, nil
+	}
+	tests := []struct {
+		name   string
+		stages []Stage
+		want   []string
+		tagged bool
+	}{
+		{"no_stages", nil, []string{"one", "two", "three"}, false},
+		{"drop", []Stage{tag, dropHigh}, []string{"one", "three"}, true},
+		{"error", []Stage{failThree, tag}, []string{"one", "two"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &eventRecorder{}
+			p := NewPipeline(NewScanner(strings.NewReader(pipelineInput)), out, WithStages(tt.stages...))
+			require.NoError(t, p.Run(context.Background()))
+			assert.Equal(t, tt.want, out.names())
+			for _, e := range out.events {
+				assert.Equal(t, tt.tagged, e.Extensions.DeviceCustomString1 != nil, e.Name)
+			}
+		})
+	}
+}
+
+func TestPipeline_Run_workers(t *testing.T) {
+	var input strings.Builder
+	for range 100 {
+		input.WriteString("CEF:0|v|p|1|100|n|Low|\n")
+	}
+	out := &eventRecorder{}
+	p := NewPipeline(NewScanner(strings.NewReader(input.String())), out, WithPipelineWorkers(8))
+	require.NoError(t, p.Run(context.Background()))
+	assert.Len(t, out.names(), 100)
+}
+
+func TestPipeline_Run_scanError(t *testing.T) {
+	out := &eventRecorder{}
+	in := NewScanner(io.MultiReader(strings.NewReader(pipelineInput), iotest.ErrReader(errors.New("read failed"))))
+	assert.EqualError(t, NewPipeline(in, out).Run(context.Background()), "read failed")
+	assert.Equal(t, []string{"one", "two", "three"}, out.names())
+}
+
+func TestPipeline_Run_cancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	out := &eventRecorder{}
+	p := NewPipeline(NewScanner(pr), out)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(ctx)
+	}()
+	_, err := io.WriteString(pw, "CEF:0|v|p|1|100|one|Low|\n")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(out.names()) == 1
+	}, 5*time.Second, time.Millisecond)
+
+	// The Scanner is blocked reading the pipe
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return")
+	}
+}
+
+func TestLogger_WriteEvent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator),
+		WithContextExtractor(func(ctx context.Context) Extensions {
+			return Extensions{ExternalId: "r1"}
+		}))
+	p := NewPipeline(NewScanner(strings.NewReader(pipelineInput)), l.With(Extensions{DeviceAction: "relayed"}))
+	require.NoError(t, p.Run(context.Background()))
+	assert.Equal(t, `CEF:1|v|p|1|100|one|Low|act=relayed externalId=r1 src=10.0.0.1
+CEF:1|v|p|1|200|two|High|act=relayed externalId=r1 src=10.0.0.2
+CEF:1|v|p|1|100|three|Low|act=relayed externalId=r1 src=10.0.0.3
+`, buf.String())
+
+	buf.Reset()
+	other := Event{DeviceVendor: "other", DeviceEventClassId: "1", Name: "n", Severity: LowSeverity}
+	require.NoError(t, l.WriteEvent(context.Background(), other))
+	assert.Equal(t, "CEF:1|other|p|1|1|n|Low|externalId=r1\n", buf.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.WriteEvent(ctx, other), context.Canceled)
+}
+
+func TestPipeline_errors(t *testing.T) {
+	var errs []*PipelineError
+	out := EventWriterFunc(func(_ context.Context, e Event) error {
+		if e.Name == "three" {
+			return errors.New("write failed")
+		}
+		return nil
+	})
+	dropHigh := Filter(Not(MatchSeverityAtLeast(HighSeverity)))
+	p := NewPipeline(NewScanner(strings.NewReader(pipelineInput+"CEF:0|v|p|1|100|four|Low|dpt=https\n")), out,
+		WithStages(dropHigh), WithPipelineErrors(func(err *PipelineError) {
+			errs = append(errs, err)
+		}))
+	require.NoError(t, p.Run(context.Background()))
+	assert.Equal(t, PipelineStats{Processed: 1, Dropped: 1, Errors: 3}, p.Stats())
+
+	require.Len(t, errs, 3)
+	assert.Equal(t, 3, errs[0].Record)
+	assert.Equal(t, "not cef", errs[0].Raw)
+	assert.ErrorIs(t, errs[0], MalformedEventErr)
+	assert.Equal(t, "record 4: write failed", errs[1].Error())
+	assert.Equal(t, "CEF:0|v|p|1|100|three|Low|src=10.0.0.3", errs[1].Raw)
+	assert.Equal(t, 5, errs[2].Record)
+	assert.Contains(t, FieldErrors(errs[2]), "dpt")
+}
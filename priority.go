@@ -0,0 +1,69 @@
+package cefevent
+
+import "strconv"
+
+// Facility is a syslog facility, as defined by RFC 5424
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFtp
+	FacilityNtp
+	FacilityAudit
+	FacilityAlert
+	FacilityClock
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Syslog severities used for the PRI value, as defined by RFC 5424
+const (
+	syslogCritical      = 2
+	syslogError         = 3
+	syslogWarning       = 4
+	syslogInformational = 6
+)
+
+// WithSyslogPriority prefixes the syslog header with a PRI value, e.g. "<134>", as many collectors require. The PRI
+// combines facility with a syslog severity derived from the event's severity: Very-High is Critical, High is Error,
+// Medium is Warning and anything lower is Informational. Has no effect with OmitSyslogHeader.
+func WithSyslogPriority(facility Facility) LoggerConfigOption {
+	return func(l *Logger) {
+		l.facility = &facility
+	}
+}
+
+// syslogPriority returns the PRI prefix for an event with the given severity
+func syslogPriority(facility Facility, severity string) string {
+	return "<" + strconv.Itoa(int(facility)*8+syslogSeverity(severity)) + ">"
+}
+
+// syslogSeverity returns the syslog severity for an event severity
+func syslogSeverity(severity string) int {
+	level, _ := severityLevel(severity)
+	switch {
+	case level >= 9:
+		return syslogCritical
+	case level >= 7:
+		return syslogError
+	case level >= 4:
+		return syslogWarning
+	}
+	return syslogInformational
+}
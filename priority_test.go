@@ -0,0 +1,45 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_syslogPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		facility Facility
+		severity string
+		want     string
+	}{
+		{"unknown", FacilityLocal0, UnknownSeverity, "<134>"},
+		{"low", FacilityLocal0, LowSeverity, "<134>"},
+		{"medium", FacilityLocal0, MediumSeverity, "<132>"},
+		{"high", FacilityLocal0, HighSeverity, "<131>"},
+		{"very_high", FacilityLocal0, VeryHighSeverity, "<130>"},
+		{"numeric", FacilityAuth, "5", "<36>"},
+		{"kern", FacilityKern, "10", "<2>"},
+		{"local7", FacilityLocal7, LowSeverity, "<190>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, syslogPriority(tt.facility, tt.severity))
+		})
+	}
+}
+
+func TestWithSyslogPriority(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", WithStaticHostname("host"), WithTimeFunc(testTime),
+		WithSyslogPriority(FacilityLocal4))
+	assert.NoError(t, l.LogHigh("100", "test", Extensions{}))
+	assert.Equal(t, "<163>"+testTime().Format("Jan 2 15:04:05")+" host CEF:1|v|p|1|100|test|High|", buf.String())
+
+	buf.Reset()
+	l = l.Clone(OmitSyslogHeader())
+	l.SetOutput(buf)
+	assert.NoError(t, l.LogHigh("100", "test", Extensions{}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|High|", buf.String())
+}
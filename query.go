@@ -0,0 +1,306 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// InvalidQueryErr error when a query expression can't be compiled
+var InvalidQueryErr = errors.New("invalid query")
+
+// CompileQuery compiles a query expression into a Predicate, e.g. for the filters of a Pipeline:
+//
+//	dst=10.0.0.0/8 AND severity>=High AND NOT (act=allow OR msg CONTAINS "health check")
+//
+// A comparison is a field, looked up as by Event.Field, an operator and a value, which is quoted if it contains spaces,
+// parentheses or operator characters. The operators are:
+//
+//	=, !=          equality, or for a CIDR value such as 10.0.0.0/8 whether the field is an address within it
+//	<, <=, >, >=   numeric comparison, or for severity comparison on the spec's 0-10 scale as MatchSeverityAtLeast
+//	~, !~          whether the field matches a regular expression
+//	CONTAINS       whether the field contains a substring
+//
+// Comparisons are combined with AND, OR and NOT, or &&, || and !, and grouped with parentheses. AND binds tighter than
+// OR. Keywords are case-insensitive; values are case-sensitive. Only != and !~ match events without the field.
+func CompileQuery(query string) (Predicate, error) {
+	p := &queryParser{tokens: tokenizeQuery(query)}
+	p.operand = p.parseComparison
+	return p.parse()
+}
+
+type queryTokenKind int
+
+const (
+	queryEOF    queryTokenKind = iota
+	queryWord                  // a field, value or keyword
+	queryString                // a quoted value
+	queryOp                    // an operator or parenthesis
+	queryError                 // a token which couldn't be read, with the reason as text
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+// queryOps are the operator tokens, longest first so that e.g. ">=" isn't read as ">"
+var queryOps = []string{"!=", "!~", ">=", "<=", "&&", "||", "=", "~", "<", ">", "!", "(", ")"}
+
+// queryComparisons are the operator tokens which compare a field to a value
+var queryComparisons = []string{"=", "!=", "<", "<=", ">", ">=", "~", "!~"}
+
+// tokenizeQuery splits a query into tokens, ending with an EOF or error token
+func tokenizeQuery(s string) []queryToken {
+	var tokens []queryToken
+	i := 0
+next:
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+			i++
+		}
+		if i == len(s) {
+			return append(tokens, queryToken{queryEOF, "", i})
+		}
+		for _, op := range queryOps {
+			if strings.HasPrefix(s[i:], op) {
+				tokens = append(tokens, queryToken{queryOp, op, i})
+				i += len(op)
+				continue next
+			}
+		}
+		if s[i] == '"' {
+			var b strings.Builder
+			for j := i + 1; j < len(s); j++ {
+				switch s[j] {
+				case '\\':
+					if j+1 < len(s) {
+						j++
+					}
+					b.WriteByte(s[j])
+				case '"':
+					tokens = append(tokens, queryToken{queryString, b.String(), i})
+					i = j + 1
+					continue next
+				default:
+					b.WriteByte(s[j])
+				}
+			}
+			return append(tokens, queryToken{queryError, "unterminated string", i})
+		}
+		start := i
+		for i < len(s) && !strings.ContainsRune(" \t\r\n\"()=!~<>&|", rune(s[i])) {
+			i++
+		}
+		if i == start {
+			return append(tokens, queryToken{queryError, fmt.Sprintf("unexpected %q", s[i]), i})
+		}
+		tokens = append(tokens, queryToken{queryWord, s[start:i], start})
+	}
+}
+
+// queryParser is a recursive descent parser over the tokens of a query
+type queryParser struct {
+	tokens []queryToken
+	i      int
+	// operand parses the operands combined by the boolean operators, starting with the word t
+	operand func(t queryToken) (Predicate, error)
+}
+
+// parse parses the whole query
+func (p *queryParser) parse() (Predicate, error) {
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != queryEOF {
+		return nil, p.unexpected(t)
+	}
+	return pred, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.i]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.i]
+	if t.kind != queryEOF {
+		p.i++
+	}
+	return t
+}
+
+// err returns the error reading the tokens, if any
+func (p *queryParser) err() error {
+	if last := p.tokens[len(p.tokens)-1]; last.kind == queryError {
+		return fmt.Errorf("%w: %s at offset %d", InvalidQueryErr, last.text, last.pos)
+	}
+	return nil
+}
+
+func (p *queryParser) unexpected(t queryToken) error {
+	if t.kind == queryEOF {
+		return fmt.Errorf("%w: unexpected end of query", InvalidQueryErr)
+	}
+	return fmt.Errorf("%w: unexpected %q at offset %d", InvalidQueryErr, t.text, t.pos)
+}
+
+// is reports whether t is the operator op or the keyword
+func (t queryToken) is(op, keyword string) bool {
+	return (t.kind == queryOp && t.text == op) || (t.kind == queryWord && strings.EqualFold(t.text, keyword))
+}
+
+func (p *queryParser) parseOr() (Predicate, error) {
+	return p.parseList(p.parseAnd, "||", "OR", Or)
+}
+
+func (p *queryParser) parseAnd() (Predicate, error) {
+	return p.parseList(p.parseUnary, "&&", "AND", And)
+}
+
+// parseList parses operands separated by op or keyword, combining them if there's more than one
+func (p *queryParser) parseList(parse func() (Predicate, error), op, keyword string,
+	combine func(preds ...Predicate) Predicate) (Predicate, error) {
+	var preds []Predicate
+	for {
+		pred, err := parse()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+		if !p.peek().is(op, keyword) {
+			break
+		}
+		p.next()
+	}
+	if len(preds) == 1 {
+		return preds[0], nil
+	}
+	return combine(preds...), nil
+}
+
+func (p *queryParser) parseUnary() (Predicate, error) {
+	t := p.next()
+	switch {
+	case t.is("!", "NOT"):
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(pred), nil
+	case t.is("(", ""):
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); !t.is(")", "") {
+			return nil, p.unexpected(t)
+		}
+		return pred, nil
+	case t.kind != queryWord:
+		return nil, p.unexpected(t)
+	}
+	return p.operand(t)
+}
+
+// parseComparison parses a comparison of the field t
+func (p *queryParser) parseComparison(t queryToken) (Predicate, error) {
+	op := p.next()
+	switch {
+	case op.is("", "CONTAINS"):
+		op.text = "CONTAINS"
+	case op.kind != queryOp || !slices.Contains(queryComparisons, op.text):
+		return nil, p.unexpected(op)
+	}
+	value := p.next()
+	if value.kind != queryWord && value.kind != queryString {
+		return nil, p.unexpected(value)
+	}
+	pred, err := compileComparison(t.text, op.text, value.text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s%s%s: %w", InvalidQueryErr, t.text, op.text, value.text, err)
+	}
+	return pred, nil
+}
+
+// compileComparison returns a Predicate comparing field to value with op
+func compileComparison(field, op, value string) (Predicate, error) {
+	switch op {
+	case "=", "!=":
+		pred := fieldMatch(field, func(v string) bool { return v == value })
+		if _, prefix, err := net.ParseCIDR(value); err == nil {
+			pred = fieldMatch(field, func(v string) bool {
+				ip := net.ParseIP(v)
+				return ip != nil && prefix.Contains(ip)
+			})
+		}
+		if op == "!=" {
+			return Not(pred), nil
+		}
+		return pred, nil
+	case "~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		pred := fieldMatch(field, re.MatchString)
+		if op == "!~" {
+			return Not(pred), nil
+		}
+		return pred, nil
+	case "CONTAINS":
+		return fieldMatch(field, func(v string) bool { return strings.Contains(v, value) }), nil
+	}
+
+	compare := map[string]func(c int) bool{
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+		">":  func(c int) bool { return c > 0 },
+		">=": func(c int) bool { return c >= 0 },
+	}[op]
+	if field == "severity" {
+		want, err := severityLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		return fieldMatch(field, func(v string) bool {
+			level, err := severityLevel(v)
+			return err == nil && compare(level-want) // levels are small, so this can't overflow
+		}), nil
+	}
+	want, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errors.New("value must be a number")
+	}
+	return fieldMatch(field, func(v string) bool {
+		f, err := strconv.ParseFloat(v, 64)
+		return err == nil && compare(cmpFloat(f, want))
+	}), nil
+}
+
+// fieldMatch returns a Predicate matching events with field set to a value matching match
+func fieldMatch(field string, match func(v string) bool) Predicate {
+	return func(e *Event) bool {
+		v, ok := e.Field(field)
+		return ok && match(v)
+	}
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package cefevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileQuery(t *testing.T) {
+	e, err := Parse(`CEF:0|v|p|1|100|login|High|src=10.0.0.1 dst=192.0.2.7 dpt=443 act=deny msg=health check failed ` +
+		`cs1=a"b cs1Label=note`)
+	require.NoError(t, err)
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"equal", "act=deny", true},
+		{"equal_other", "act=allow", false},
+		{"equal_full_name", "deviceAction=deny", true},
+		{"equal_header", "deviceEventClassId=100", true},
+		{"equal_quoted", `cs1="a\"b"`, true},
+		{"equal_missing", "suser=alice", false},
+		{"not_equal", "act!=allow", true},
+		{"not_equal_missing", "suser!=alice", true},
+		{"cidr", "src=10.0.0.0/8", true},
+		{"cidr_other", "dst=10.0.0.0/8", false},
+		{"cidr_not", "dst!=10.0.0.0/8", true},
+		{"cidr_not_address", "act=10.0.0.0/8", false},
+		{"number_greater", "dpt>400", true},
+		{"number_less_equal", "dpt<=443", true},
+		{"number_less", "dpt<443", false},
+		{"number_not_number", "act>1", false},
+		{"severity_at_least", "severity>=High", true},
+		{"severity_numeric", "severity>5", true},
+		{"severity_higher", "severity>=Very-High", false},
+		{"contains", `msg CONTAINS "check"`, true},
+		{"contains_keyword_case", "msg contains failed", true},
+		{"contains_other", "msg CONTAINS passed", false},
+		{"regex", `msg~"^health .* failed$"`, true},
+		{"regex_other", "act~^al", false},
+		{"regex_not", "act!~^al", true},
+		{"and", "src=10.0.0.0/8 AND severity>=High", true},
+		{"and_symbol", "src=10.0.0.0/8 && act=allow", false},
+		{"or", "act=allow OR dpt=443", true},
+		{"or_symbol", "act=allow || dpt=80", false},
+		{"not", "NOT act=allow", true},
+		{"not_symbol", "!act=deny", false},
+		{"precedence", "act=allow AND dpt=80 OR src=10.0.0.1", true},
+		{"parentheses", "act=allow AND (dpt=80 OR src=10.0.0.1)", false},
+		{"nested", "not (act=allow or (dpt=80 and src=10.0.0.1)) and name=login", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := CompileQuery(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p(&e))
+		})
+	}
+}
+
+func TestCompileQuery_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"empty", "", "invalid query: unexpected end of query"},
+		{"no_operator", "act", "invalid query: unexpected end of query"},
+		{"no_value", "act=", "invalid query: unexpected end of query"},
+		{"operator_value", "act==deny", `invalid query: unexpected "=" at offset 4`},
+		{"unterminated_string", `msg="hello`, "invalid query: unterminated string at offset 4"},
+		{"unclosed_parenthesis", "(act=deny", "invalid query: unexpected end of query"},
+		{"trailing", "act=deny)", `invalid query: unexpected ")" at offset 8`},
+		{"missing_operand", "act=deny AND", "invalid query: unexpected end of query"},
+		{"single_ampersand", "act=deny & dpt=443", `invalid query: unexpected '&' at offset 9`},
+		{"regex", `act~"("`, "invalid query: act~(: error parsing regexp: missing closing ): `(`"},
+		{"number", "dpt>https", "invalid query: dpt>https: value must be a number"},
+		{"severity", "severity>=Critical", `invalid query: severity>=Critical: invalid severity`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileQuery(tt.query)
+			assert.ErrorIs(t, err, InvalidQueryErr)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
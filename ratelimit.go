@@ -0,0 +1,180 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InvalidSampleRateErr error when a sampling probability isn't in (0, 1] or a 1-in-N rate is less than 1
+var InvalidSampleRateErr = errors.New("invalid sample rate")
+
+// DroppedEventsClassId is the device event class ID of the summary events written by WithDropSummary
+const DroppedEventsClassId = "events-dropped"
+
+// WithRateLimit drops events logged faster than eventsPerSecond, allowing bursts of up to burst events, so a noisy
+// producer can't flood the SIEM. Unlike WithByteRateLimit, Log doesn't block; dropped events are counted by
+// RateLimitedCount and reported by WithDropSummary.
+func WithRateLimit(eventsPerSecond, burst int) LoggerConfigOption {
+	return func(l *Logger) {
+		l.limiter = l.limiter.cloneOrNew()
+		l.limiter.bucket = newTokenBucket(eventsPerSecond, burst)
+	}
+}
+
+// WithSampling logs each event with the given probability, e.g. 0.1 logs roughly one in ten. Events not sampled are
+// counted by SampledOutCount and reported by WithDropSummary.
+func WithSampling(probability float64) (LoggerConfigOption, error) {
+	if !(probability > 0 && probability <= 1) {
+		return nil, fmt.Errorf("%w: probability %v", InvalidSampleRateErr, probability)
+	}
+	return func(l *Logger) {
+		l.limiter = l.limiter.cloneOrNew()
+		l.limiter.probability, l.limiter.every = probability, 0
+	}, nil
+}
+
+// WithSampleEvery logs the first of every n events, dropping the rest. Events not sampled are counted by
+// SampledOutCount and reported by WithDropSummary.
+func WithSampleEvery(n int) (LoggerConfigOption, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w: 1 in %d", InvalidSampleRateErr, n)
+	}
+	return func(l *Logger) {
+		l.limiter = l.limiter.cloneOrNew()
+		l.limiter.probability, l.limiter.every = 0, uint64(n)
+	}, nil
+}
+
+// WithDropSummary writes a summary event at most once per interval while WithRateLimit or WithSampling are dropping
+// events. Summaries have class DroppedEventsClassId, Low severity & BaseEventCount set to the number of events dropped
+// since the last summary, and aren't themselves rate limited or sampled. Flush & Close write any pending summary.
+func WithDropSummary(interval time.Duration) LoggerConfigOption {
+	return func(l *Logger) {
+		l.limiter = l.limiter.cloneOrNew()
+		l.limiter.summaryInterval = interval
+	}
+}
+
+// eventLimiter rate limits & samples events, counting those dropped
+type eventLimiter struct {
+	bucket          *tokenBucket // nil if not rate limited
+	probability     float64      // 0 unless sampling by probability
+	every           uint64       // 0 unless sampling 1 in every
+	summaryInterval time.Duration
+
+	seen        atomic.Uint64
+	rateLimited atomic.Uint64
+	sampledOut  atomic.Uint64
+
+	mu         sync.Mutex
+	unreported struct{ rateLimited, sampledOut int }
+	timer      *time.Timer
+}
+
+// cloneOrNew returns a copy of the limiter's configuration with zeroed counters, or a new limiter if l is nil
+func (e *eventLimiter) cloneOrNew() *eventLimiter {
+	if e == nil {
+		return &eventLimiter{}
+	}
+	c := &eventLimiter{probability: e.probability, every: e.every, summaryInterval: e.summaryInterval}
+	if e.bucket != nil {
+		c.bucket = e.bucket.clone()
+	}
+	return c
+}
+
+// allow reports whether an event should be logged, counting it if not
+func (e *eventLimiter) allow(l *Logger) bool {
+	switch {
+	case e.every > 0 && (e.seen.Add(1)-1)%e.every != 0,
+		e.probability > 0 && rand.Float64() >= e.probability:
+		e.sampledOut.Add(1)
+		l.dropped(DropSampledOut)
+		e.dropped(l, false)
+		return false
+	case e.bucket != nil && !e.bucket.take(1):
+		e.rateLimited.Add(1)
+		l.dropped(DropRateLimited)
+		e.dropped(l, true)
+		return false
+	}
+	return true
+}
+
+// dropped records an event for the next summary, starting the summary timer if needed
+func (e *eventLimiter) dropped(l *Logger, rateLimited bool) {
+	if e.summaryInterval <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if rateLimited {
+		e.unreported.rateLimited++
+	} else {
+		e.unreported.sampledOut++
+	}
+	if e.timer == nil {
+		e.timer = time.AfterFunc(e.summaryInterval, func() {
+			var err error
+			defer l.recoverPanic(&err) // runs on its own goroutine, where a panic would take down the host application
+			if err = e.summarize(l); err != nil {
+				l.handleError(err)
+			}
+		})
+	}
+}
+
+// summarize writes a summary of the events dropped since the last summary, if any
+func (e *eventLimiter) summarize(l *Logger) error {
+	e.mu.Lock()
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	counts := e.unreported
+	e.unreported.rateLimited, e.unreported.sampledOut = 0, 0
+	e.mu.Unlock()
+	total := counts.rateLimited + counts.sampledOut
+	if total == 0 {
+		return nil
+	}
+	return l.write(context.Background(), l.device(), DroppedEventsClassId, "Events dropped", LowSeverity, Extensions{
+		BaseEventCount: total,
+		Message:        fmt.Sprintf("%d events dropped: %d rate limited, %d sampled out", total, counts.rateLimited, counts.sampledOut),
+	})
+}
+
+// flushDropSummary writes any pending WithDropSummary event
+func (l *Logger) flushDropSummary() error {
+	if l.limiter == nil {
+		return nil
+	}
+	return l.limiter.summarize(l)
+}
+
+// RateLimitedCount returns the number of events dropped by WithRateLimit
+func (l *Logger) RateLimitedCount() uint64 {
+	if l.parent != nil {
+		return l.parent.RateLimitedCount()
+	}
+	if l.limiter == nil {
+		return 0
+	}
+	return l.limiter.rateLimited.Load()
+}
+
+// SampledOutCount returns the number of events dropped by WithSampling or WithSampleEvery
+func (l *Logger) SampledOutCount() uint64 {
+	if l.parent != nil {
+		return l.parent.SampledOutCount()
+	}
+	if l.limiter == nil {
+		return 0
+	}
+	return l.limiter.sampledOut.Load()
+}
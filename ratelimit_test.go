@@ -0,0 +1,100 @@
+package cefevent
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithRateLimit(1, 2))
+	l.limiter.bucket.now = clock.Now
+
+	for range 4 {
+		require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	}
+	assert.Equal(t, uint64(2), l.RateLimitedCount())
+	clock.now = clock.now.Add(time.Second)
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Equal(t, strings.Repeat("CEF:1|v|p|1|100|test|Low|", 3), buf.String())
+	assert.Equal(t, uint64(0), l.SampledOutCount())
+}
+
+func TestWithSampling(t *testing.T) {
+	tests := []struct {
+		name        string
+		probability float64
+		wantErr     bool
+	}{
+		{"all", 1, false},
+		{"some", 0.5, false},
+		{"zero", 0, true},
+		{"over_one", 1.5, true},
+		{"nan", math.NaN(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt, err := WithSampling(tt.probability)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, InvalidSampleRateErr)
+				return
+			}
+			require.NoError(t, err)
+			l := NewLogger(&bytes.Buffer{}, "v", "p", "1", opt)
+			for range 1000 {
+				require.NoError(t, l.LogLow("100", "test", Extensions{}))
+			}
+			sampledOut := l.SampledOutCount()
+			assert.InDelta(t, (1-tt.probability)*1000, float64(sampledOut), 100)
+		})
+	}
+}
+
+func TestWithSampleEvery(t *testing.T) {
+	_, err := WithSampleEvery(0)
+	assert.ErrorIs(t, err, InvalidSampleRateErr)
+
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), MustLoggerConfig(WithSampleEvery(3)))
+	for i := range 7 {
+		require.NoError(t, l.LogLow("100", string(rune('a'+i)), Extensions{}))
+	}
+	assert.Equal(t, "CEF:1|v|p|1|100|a|Low|CEF:1|v|p|1|100|d|Low|CEF:1|v|p|1|100|g|Low|", buf.String())
+	assert.Equal(t, uint64(4), l.SampledOutCount())
+}
+
+func TestWithDropSummary(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithRateLimit(1, 1), MustLoggerConfig(WithSampleEvery(2)),
+		WithDropSummary(time.Hour))
+	for range 6 {
+		require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	}
+	require.NoError(t, l.Flush())
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|"+
+		"CEF:1|v|p|1|events-dropped|Events dropped|Low|msg=5 events dropped: 2 rate limited, 3 sampled out cnt=5", buf.String())
+
+	buf.Reset()
+	require.NoError(t, l.Flush())
+	assert.Empty(t, buf.String(), "summary written twice")
+}
+
+func TestWithDropSummary_interval(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), MustLoggerConfig(WithSampleEvery(2)),
+		WithDropSummary(10*time.Millisecond))
+	for range 2 {
+		require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	}
+	assert.Eventually(t, func() bool {
+		records := w.Records()
+		return len(records) == 2 && strings.Contains(records[1], "msg=1 events dropped: 0 rate limited, 1 sampled out")
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,186 @@
+package cefevent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// InvalidRedactionErr error when a redacted value can't be stored in its field, e.g. a hashed IP address
+var InvalidRedactionErr = errors.New("redacted value invalid for field")
+
+// RedactionRule rewrites a single extension field before events are written. Create with RedactField, DropField or
+// MaskIPv4HostOctet.
+type RedactionRule struct {
+	key    string
+	redact func(v string) string
+}
+
+// RedactField replaces the value of key with redact(value), e.g. RedactField("duser", HashSHA256). Fields whose redacted
+// value is empty are dropped. key is the short key as emitted, e.g. "suser", "cs1" or a CustomExtensions key.
+func RedactField(key string, redact func(v string) string) RedactionRule {
+	if canonical, ok := legacyKeyNames[key]; ok {
+		key = canonical
+	}
+	return RedactionRule{key: key, redact: redact}
+}
+
+// DropField removes key from events. Dropping a labelled custom field such as cs1 drops its label too.
+func DropField(key string) RedactionRule {
+	return RedactField(key, func(string) string { return "" })
+}
+
+// MaskIPv4HostOctet zeroes the last octet of the IPv4 address in key, e.g. 192.0.2.17 becomes 192.0.2.0. IPv6 addresses
+// are dropped, as they can't be masked the same way.
+func MaskIPv4HostOctet(key string) RedactionRule {
+	return RedactField(key, func(v string) string {
+		ip := net.ParseIP(v).To4()
+		if ip == nil {
+			return ""
+		}
+		ip[3] = 0
+		return ip.String()
+	})
+}
+
+// HashSHA256 replaces a value with its hex encoded SHA-256 digest, so events can still be correlated by value. Use
+// SaltedSHA256 for low entropy values such as usernames, which are easily recovered from an unsalted hash.
+func HashSHA256(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
+
+// SaltedSHA256 returns a redaction replacing a value with its hex encoded HMAC-SHA256, keyed with salt
+func SaltedSHA256(salt []byte) func(v string) string {
+	salt = append([]byte(nil), salt...)
+	return func(v string) string {
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(v))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+}
+
+// MaskPartial returns a redaction replacing all but the last keep characters of a value with '*', e.g. MaskPartial(4)
+// turns "4111111111111111" into "************1111". Values of keep characters or fewer are masked entirely.
+func MaskPartial(keep int) func(v string) string {
+	return func(v string) string {
+		n := utf8.RuneCountInString(v)
+		if n <= keep {
+			return strings.Repeat("*", n)
+		}
+		i := 0
+		for j := range v {
+			if i == n-keep {
+				return strings.Repeat("*", i) + v[j:]
+			}
+			i++
+		}
+		return v
+	}
+}
+
+// WithRedaction rewrites or removes fields before events are hashed, suppressed or written, e.g.
+// WithRedaction(RedactField("duser", HashSHA256), MaskIPv4HostOctet("src")). Rules for the same key are applied in
+// order. Log returns an error wrapping InvalidRedactionErr when a redacted value no longer fits its field, such as a
+// hashed port.
+func WithRedaction(rules ...RedactionRule) LoggerConfigOption {
+	return func(l *Logger) {
+		if l.redactions == nil {
+			l.redactions = make(map[string][]func(string) string, len(rules))
+		} else {
+			l.redactions = cloneRedactions(l.redactions)
+		}
+		for _, r := range rules {
+			l.redactions[r.key] = append(l.redactions[r.key], r.redact)
+		}
+	}
+}
+
+// cloneRedactions copies the rule lists, so options applied to a clone don't change the original's rules
+func cloneRedactions(m map[string][]func(string) string) map[string][]func(string) string {
+	c := make(map[string][]func(string) string, len(m))
+	for k, v := range m {
+		c[k] = append([]func(string) string(nil), v...)
+	}
+	return c
+}
+
+// redact applies the Logger's redaction rules to extensions, which is copied before being modified
+func (l *Logger) redact(extensions Extensions) (Extensions, error) {
+	type redacted struct {
+		key   string
+		value string
+	}
+	var fields []redacted
+	extensions.walk(func(key string, v fieldValue) bool {
+		if canonical, ok := legacyKeyNames[key]; ok {
+			key = canonical
+		}
+		if fns, ok := l.redactions[key]; ok {
+			value := v.String()
+			for _, fn := range fns {
+				if value = fn(value); value == "" {
+					break
+				}
+			}
+			fields = append(fields, redacted{key, value})
+		}
+		return true
+	})
+	if len(fields) == 0 {
+		return extensions, nil
+	}
+
+	extensions = extensions.Clone()
+	var errs []error
+	for _, f := range fields {
+		if f.value == "" {
+			extensions.unset(f.key)
+		} else if err := extensions.set(f.key, f.value); err != nil {
+			errs = append(errs, &fieldError{f.key, fmt.Errorf("%w: %w", InvalidRedactionErr, err)})
+		}
+	}
+	return extensions, errors.Join(errs...)
+}
+
+// unset clears the field for key, or removes it from CustomExtensions if key isn't a standard key. e must have been
+// cloned, as CustomExtensions is modified in place.
+func (e *Extensions) unset(key string) {
+	i, ok := extensionFieldIndex()[key]
+	if !ok {
+		delete(e.CustomExtensions, key)
+		return
+	}
+	f := reflect.ValueOf(e).Elem().Field(i)
+	f.Set(reflect.Zero(f.Type()))
+}
+
+// extensionFieldIndex maps each standard key to the index of its Extensions field, found by setting each key in turn
+// on an empty Extensions
+var extensionFieldIndex = sync.OnceValue(func() map[string]int {
+	samples := []string{"1", "192.0.2.1", "00:00:5e:00:53:01", "UTC"}
+	index := make(map[string]int, len(extensionSetters))
+	for key, setter := range extensionSetters {
+		for _, sample := range samples {
+			var e Extensions
+			if setter(&e, sample) != nil {
+				continue
+			}
+			v := reflect.ValueOf(e)
+			for i := 0; i < v.NumField(); i++ {
+				if !v.Field(i).IsZero() {
+					index[key] = i
+				}
+			}
+			break
+		}
+	}
+	return index
+})
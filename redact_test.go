@@ -0,0 +1,127 @@
+package cefevent
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_redact(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      []RedactionRule
+		extensions Extensions
+		want       Extensions
+		wantErr    string
+	}{
+		{
+			"no_match",
+			[]RedactionRule{DropField("suser")},
+			Extensions{Message: "test"},
+			Extensions{Message: "test"},
+			"",
+		},
+		{
+			"drop",
+			[]RedactionRule{DropField("suser"), DropField("src"), DropField("spt"), DropField("cs1"), DropField("k")},
+			Extensions{
+				SourceUserName:      "alice",
+				SourceAddress:       net.IPv4(192, 0, 2, 17),
+				SourcePort:          ptr(uint(443)),
+				DeviceCustomString1: Labeled("l", "v"),
+				CustomExtensions:    map[string]string{"k": "v", "other": "v"},
+			},
+			Extensions{CustomExtensions: map[string]string{"other": "v"}},
+			"",
+		},
+		{
+			"hash",
+			[]RedactionRule{RedactField("duser", HashSHA256), RedactField("k", SaltedSHA256([]byte("salt")))},
+			Extensions{DestinationUserName: "alice", CustomExtensions: map[string]string{"k": "alice"}},
+			Extensions{
+				DestinationUserName: "2bd806c97f0e00af1a1fc3328fa763a9269723c8db8fac4f93af71db186d6e90",
+				CustomExtensions:    map[string]string{"k": "dc663a1de92b83cd9b6522902cf9420e70757f1eb293f2be7d881953cb2dc149"},
+			},
+			"",
+		},
+		{
+			"mask",
+			[]RedactionRule{MaskIPv4HostOctet("src"), MaskIPv4HostOctet("dst"), RedactField("cs1", MaskPartial(4))},
+			Extensions{
+				SourceAddress:       net.IPv4(192, 0, 2, 17),
+				DestinationAddress:  net.ParseIP("2001:db8::1"),
+				DeviceCustomString1: Labeled("card", "4111111111111111"),
+			},
+			Extensions{SourceAddress: net.IPv4(192, 0, 2, 0), DeviceCustomString1: Labeled("card", "************1111")},
+			"",
+		},
+		{
+			"chained",
+			[]RedactionRule{RedactField("msg", MaskPartial(2)), RedactField("msg", HashSHA256)},
+			Extensions{Message: "ab"},
+			Extensions{Message: HashSHA256("**")},
+			"",
+		},
+		{
+			"invalid",
+			[]RedactionRule{RedactField("src", HashSHA256)},
+			Extensions{SourceAddress: net.IPv4(192, 0, 2, 17)},
+			Extensions{},
+			`"src": redacted value invalid for field: "` + HashSHA256("192.0.2.17") + `" is not an IP address`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLogger(&bytes.Buffer{}, "v", "p", "1", WithRedaction(tt.rules...))
+			got, err := l.redact(tt.extensions)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, InvalidRedactionErr)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithRedaction(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithRedaction(DropField("k"), MaskIPv4HostOctet("src")))
+	custom := map[string]string{"k": "secret"}
+	require.NoError(t, l.LogLow("100", "test", Extensions{SourceAddress: net.IPv4(192, 0, 2, 17), CustomExtensions: custom}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|src=192.0.2.0", buf.String())
+	assert.Equal(t, "secret", custom["k"], "caller's map modified")
+
+	// Rules added to a clone don't change the original's
+	buf.Reset()
+	c := l.Clone(WithRedaction(DropField("msg")))
+	require.NoError(t, l.LogLow("100", "test", Extensions{Message: "m"}))
+	require.NoError(t, c.LogLow("100", "test", Extensions{Message: "m"}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|msg=mCEF:1|v|p|1|100|test|Low|", buf.String())
+}
+
+func TestMaskPartial(t *testing.T) {
+	assert.Equal(t, "***d", MaskPartial(1)("abcd"))
+	assert.Equal(t, "**ñb", MaskPartial(2)("aññb"))
+	assert.Equal(t, "***", MaskPartial(4)("abc"))
+	assert.Equal(t, "", MaskPartial(0)(""))
+}
+
+func TestExtensions_unset(t *testing.T) {
+	e := Extensions{
+		BaseEventCount:             2,
+		DeviceTimeZone:             time.UTC,
+		SourceMacAddress:           net.HardwareAddr{0, 0, 0x5e, 0, 0x53, 1},
+		DeviceCustomFloatingPoint1: Labeled("l", 1.5),
+	}
+	for _, key := range []string{"cnt", "dtz", "smac", "cfp1"} {
+		e.unset(key)
+	}
+	assert.Equal(t, Extensions{}, e)
+	assert.Len(t, extensionFieldIndex(), len(extensionSetters), "keys without a field index")
+}
@@ -0,0 +1,87 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// WithRetry retries writes failing with a transient error, so a brief network failure to a remote collector doesn't
+// drop events. Each event is written up to maxAttempts times, waiting backoff before the first retry and doubling the
+// wait for each further retry. Errors are classified by IsTransientError unless set with WithRetryClassifier. Log
+// blocks while retrying, so prefer WithAsync when writing to a remote collector.
+func WithRetry(maxAttempts int, backoff time.Duration) LoggerConfigOption {
+	return func(l *Logger) {
+		retryable := IsTransientError
+		if l.retry != nil {
+			retryable = l.retry.retryable
+		}
+		l.retry = &retryPolicy{maxAttempts: maxAttempts, backoff: backoff, retryable: retryable, sleep: time.Sleep}
+	}
+}
+
+// WithRetryClassifier sets the function WithRetry uses to decide whether a write error is worth retrying
+func WithRetryClassifier(retryable func(err error) bool) LoggerConfigOption {
+	return func(l *Logger) {
+		if l.retry == nil {
+			l.retry = &retryPolicy{maxAttempts: 1, sleep: time.Sleep}
+		} else {
+			c := *l.retry
+			l.retry = &c
+		}
+		l.retry.retryable = retryable
+	}
+}
+
+// IsTransientError reports whether a write error is likely to be temporary: timeouts, refused, reset or broken
+// connections, and a SyslogWriter waiting to reconnect. Other errors are permanent, including short writes, as
+// retrying would duplicate the part of the record already written, and writing to a closed writer.
+func IsTransientError(err error) bool {
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, LoggerClosedErr) {
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, SyslogUnavailableErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// retryPolicy decides how often & when failed writes are retried
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	retryable   func(err error) bool
+
+	// sleep is overridden in tests
+	sleep func(time.Duration)
+}
+
+// do calls write until it succeeds, fails permanently, runs out of attempts or ctx is done, returning the last error
+func (p *retryPolicy) do(ctx context.Context, write func() error) error {
+	wait := p.backoff
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || attempt >= p.maxAttempts || !p.retryable(err) {
+			return err
+		}
+		if ctxErr := sleepContext(ctx, wait, p.sleep); ctxErr != nil {
+			return errors.Join(err, ctxErr)
+		}
+		wait *= 2
+	}
+}
+
+// retryWrite calls write, retrying it as set by WithRetry
+func (l *Logger) retryWrite(ctx context.Context, write func() error) error {
+	if l.retry == nil {
+		return write()
+	}
+	return l.retry.do(ctx, write)
+}
@@ -0,0 +1,101 @@
+package cefevent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter fails its first failures writes with err
+type failingWriter struct {
+	bytes.Buffer
+	failures int
+	err      error
+	attempts int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	f.attempts++
+	if f.attempts <= f.failures {
+		return 0, f.err
+	}
+	return f.Buffer.Write(p)
+}
+
+func TestWithRetry(t *testing.T) {
+	refused := &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.ECONNREFUSED)}
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantSleeps   []time.Duration
+		wantErr      bool
+	}{
+		{"success", 0, nil, 1, nil, false},
+		{"transient", 2, refused, 3, []time.Duration{time.Second, 2 * time.Second}, false},
+		{"exhausted", 5, refused, 4, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, true},
+		{"permanent", 1, net.ErrClosed, 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: testTime()}
+			w := &failingWriter{failures: tt.failures, err: tt.err}
+			l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithRetry(4, time.Second))
+			l.retry.sleep = clock.Sleep
+
+			err := l.LogLow("100", "test", Extensions{})
+			assert.Equal(t, tt.wantAttempts, w.attempts)
+			assert.Equal(t, tt.wantSleeps, clock.sleeps)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", w.String())
+		})
+	}
+}
+
+func TestWithRetryClassifier(t *testing.T) {
+	custom := errors.New("busy")
+	w := &failingWriter{failures: 1, err: custom}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(),
+		WithRetryClassifier(func(err error) bool { return errors.Is(err, custom) }), WithRetry(2, 0))
+	l.retry.sleep = func(time.Duration) {}
+
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	assert.Equal(t, 2, w.attempts)
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"reset", fmt.Errorf("wrapped: %w", syscall.ECONNRESET), true},
+		{"broken_pipe", syscall.EPIPE, true},
+		{"timeout", os.ErrDeadlineExceeded, true},
+		{"syslog_backoff", fmt.Errorf("%w: refused", SyslogUnavailableErr), true},
+		{"closed", net.ErrClosed, false},
+		{"logger_closed", LoggerClosedErr, false},
+		{"short_write", io.ErrShortWrite, false},
+		{"other", errors.New("disk full"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransientError(tt.err))
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package cefevent
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in backup file names, sortable and valid on all file systems
+const backupTimeFormat = "2006-01-02T15-04-05.000000000"
+
+// RotatingFileWriter writes records to a file, rotating it once it would exceed a maximum size or, with
+// WithRotationInterval, once it is old enough. Rotated files are renamed with a timestamp, e.g.
+// cef-2023-11-09T11-45-20.000000000.log, then optionally gzipped and pruned by count & age in the background. Each
+// Write is treated as a whole record, so with a Logger files are only ever rotated between records. It is safe to use
+// from multiple goroutines.
+type RotatingFileWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	interval   time.Duration
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	millMu sync.Mutex
+	mills  sync.WaitGroup
+}
+
+// RotatingFileOption configures a RotatingFileWriter
+type RotatingFileOption func(w *RotatingFileWriter)
+
+// WithRotationInterval rotates the file once it has been written to for d, e.g. 24 * time.Hour for daily files
+func WithRotationInterval(d time.Duration) RotatingFileOption {
+	return func(w *RotatingFileWriter) {
+		w.interval = d
+	}
+}
+
+// NewRotatingFileWriter opens path for appending, creating it and its directory if needed. The file is rotated before
+// a record would take it past maxSizeMB megabytes; a record larger than that is written to a file of its own. Up to
+// maxBackups rotated files no older than maxAgeDays are kept, gzipped if compress is set. A zero limit disables it.
+func NewRotatingFileWriter(path string, maxSizeMB, maxBackups, maxAgeDays int, compress bool, opts ...RotatingFileOption) (*RotatingFileWriter, error) {
+	w := &RotatingFileWriter{
+		path:       path,
+		maxSize:    int64(maxSizeMB) << 20,
+		maxBackups: maxBackups,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		compress:   compress,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p to the file, rotating it first if needed
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && ((w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize) ||
+		(w.interval > 0 && time.Since(w.opened) >= w.interval)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file and starts a new one, e.g. on SIGHUP
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close closes the file, waiting for background compression & pruning to finish
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if !w.closed && w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	w.mills.Wait()
+	return err
+}
+
+// open opens the file for appending. Must be called with w.mu held, or before w is shared.
+func (w *RotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	w.file = f
+	w.size = fi.Size()
+	w.opened = time.Now()
+	return nil
+}
+
+// rotate renames the current file to a backup and opens a new one. Must be called with w.mu held.
+func (w *RotatingFileWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		w.file = nil
+	}
+	if err := os.Rename(w.path, w.backupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.mills.Add(1)
+	go w.mill()
+	return nil
+}
+
+// backupName returns the name a file rotated at t is renamed to
+func (w *RotatingFileWriter) backupName(t time.Time) string {
+	prefix, ext := w.nameParts()
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// nameParts returns the path up to a backup's timestamp and the extension following it
+func (w *RotatingFileWriter) nameParts() (string, string) {
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-", ext
+}
+
+// mill compresses & prunes backups. Failures are retried on the next rotation.
+func (w *RotatingFileWriter) mill() {
+	defer w.mills.Done()
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+
+	backups := w.backups()
+	slices.SortFunc(backups, func(a, b rotatedFile) int { return b.time.Compare(a.time) })
+	cutoff := time.Now().Add(-w.maxAge)
+	for i, b := range backups {
+		if (w.maxBackups > 0 && i >= w.maxBackups) || (w.maxAge > 0 && b.time.Before(cutoff)) {
+			_ = os.Remove(b.path)
+			continue
+		}
+		if w.compress && !strings.HasSuffix(b.path, ".gz") {
+			_ = gzipFile(b.path)
+		}
+	}
+}
+
+// rotatedFile is a backup found on disk
+type rotatedFile struct {
+	path string
+	time time.Time
+}
+
+func (w *RotatingFileWriter) backups() []rotatedFile {
+	prefix, ext := w.nameParts()
+	matches, _ := filepath.Glob(prefix + "*")
+	var files []rotatedFile
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(m, prefix), ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue // not one of ours
+		}
+		files = append(files, rotatedFile{m, t})
+	}
+	return files
+}
+
+// gzipFile replaces path with a gzipped copy at path.gz
+func gzipFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
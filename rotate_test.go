@@ -0,0 +1,136 @@
+package cefevent
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLogFiles returns the contents of each file in dir, decompressing gzipped files
+func readLogFiles(t *testing.T, dir string) map[string]string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		var r io.Reader = f
+		if strings.HasSuffix(e.Name(), ".gz") {
+			zr, err := gzip.NewReader(f)
+			require.NoError(t, err)
+			r = zr
+		}
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		files[e.Name()] = string(b)
+	}
+	return files
+}
+
+func TestRotatingFileWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBackups int
+		compress   bool
+		wantFiles  int
+		wantGz     int
+	}{
+		{"keep_all", 0, false, 4, 0},
+		{"max_backups", 2, false, 3, 0},
+		{"compress", 2, true, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := NewRotatingFileWriter(filepath.Join(dir, "logs", "cef.log"), 1, tt.maxBackups, 0, tt.compress)
+			require.NoError(t, err)
+			w.maxSize = 60
+			l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(NewlineTerminator))
+			for i := 0; i < 8; i++ {
+				require.NoError(t, l.LogLow("100", "test", Extensions{}))
+			}
+			require.NoError(t, w.Close())
+
+			files := readLogFiles(t, filepath.Join(dir, "logs"))
+			assert.Len(t, files, tt.wantFiles)
+			gz := 0
+			for name, content := range files {
+				if strings.HasSuffix(name, ".gz") {
+					gz++
+				}
+				// two whole records per file
+				assert.Equal(t, strings.Repeat("CEF:1|v|p|1|100|test|Low|\n", 2), content, name)
+			}
+			assert.Equal(t, tt.wantGz, gz)
+			assert.Contains(t, files, "cef.log")
+		})
+	}
+}
+
+func TestRotatingFileWriter_oversizedRecord(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotatingFileWriter(filepath.Join(dir, "cef.log"), 1, 0, 0, false)
+	require.NoError(t, err)
+	w.maxSize = 10
+	for _, r := range []string{"a\n", strings.Repeat("b", 20) + "\n", "c\n"} {
+		_, err := w.Write([]byte(r))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	files := readLogFiles(t, dir)
+	assert.Len(t, files, 3)
+	assert.Equal(t, "c\n", files["cef.log"])
+}
+
+func TestRotatingFileWriter_appendsAndPrunesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cef.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o644))
+	old := filepath.Join(dir, "cef-"+time.Now().Add(-72*time.Hour).UTC().Format(backupTimeFormat)+".log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0o644))
+	unrelated := filepath.Join(dir, "cef-notes.log")
+	require.NoError(t, os.WriteFile(unrelated, []byte("keep\n"), 0o644))
+
+	w, err := NewRotatingFileWriter(path, 1, 0, 1, false)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Rotate())
+	require.NoError(t, w.Close())
+
+	files := readLogFiles(t, dir)
+	assert.Len(t, files, 3)
+	assert.NotContains(t, files, filepath.Base(old))
+	assert.Equal(t, "keep\n", files["cef-notes.log"])
+	assert.Equal(t, "", files["cef.log"])
+	delete(files, "cef.log")
+	delete(files, "cef-notes.log")
+	for _, content := range files {
+		assert.Equal(t, "existing\nnew\n", content)
+	}
+}
+
+func TestRotatingFileWriter_interval(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotatingFileWriter(filepath.Join(dir, "cef.log"), 0, 0, 0, false, WithRotationInterval(time.Nanosecond))
+	require.NoError(t, err)
+	for _, r := range []string{"a\n", "b\n"} {
+		time.Sleep(time.Millisecond)
+		_, err := w.Write([]byte(r))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	assert.Len(t, readLogFiles(t, dir), 2)
+
+	_, err = w.Write([]byte("c\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
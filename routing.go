@@ -0,0 +1,119 @@
+package cefevent
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ClassRoute sends events with matching device event class IDs to a different writer than the Logger's default, or to
+// another Logger
+type ClassRoute struct {
+	match  func(deviceEventClassId string) bool
+	out    io.Writer
+	logger *Logger
+}
+
+// RouteClassPrefix routes events whose device event class ID starts with prefix to out
+func RouteClassPrefix(prefix string, out io.Writer) ClassRoute {
+	return ClassRoute{match: classPrefix(prefix), out: out}
+}
+
+// RouteClassRange routes events with a numeric device event class ID between min and max (inclusive) to out.
+// Non-numeric class IDs never match.
+func RouteClassRange(min, max int, out io.Writer) ClassRoute {
+	return ClassRoute{match: classRange(min, max), out: out}
+}
+
+// RouteClassPrefixToLogger routes events whose device event class ID starts with prefix to another Logger, so they can
+// be formatted differently, e.g. with another header vendor & product, format or syslog header. See WithClassRoutes.
+func RouteClassPrefixToLogger(prefix string, to *Logger) ClassRoute {
+	return ClassRoute{match: classPrefix(prefix), logger: to}
+}
+
+// RouteClassRangeToLogger routes events with a numeric device event class ID between min and max (inclusive) to another
+// Logger, as RouteClassPrefixToLogger. Non-numeric class IDs never match.
+func RouteClassRangeToLogger(min, max int, to *Logger) ClassRoute {
+	return ClassRoute{match: classRange(min, max), logger: to}
+}
+
+func classPrefix(prefix string) func(deviceEventClassId string) bool {
+	return func(deviceEventClassId string) bool {
+		return strings.HasPrefix(deviceEventClassId, prefix)
+	}
+}
+
+func classRange(min, max int) func(deviceEventClassId string) bool {
+	return func(deviceEventClassId string) bool {
+		v, err := strconv.Atoi(deviceEventClassId)
+		return err == nil && v >= min && v <= max
+	}
+}
+
+// WithClassRoutes configures a routing table by device event class ID. Routes are checked in order and the first match
+// is used; events matching no route are written to the Logger's default writer.
+//
+// Events taken by a route to another Logger are handed over as logged, before this Logger's pipeline, so the other
+// Logger's header device fields (unless set on the Event passed to LogEvent), severity filter, hooks and format apply
+// instead. It mustn't route them back, and Flush & Close don't flush it. Events the Logger generates itself, such as
+// WithDropSummary summaries, only take routes to writers.
+func WithClassRoutes(routes ...ClassRoute) LoggerConfigOption {
+	return func(l *Logger) {
+		l.routes = append(l.routes, routes...)
+	}
+}
+
+// routeLogger returns the Logger events with the given class ID are routed to, or nil if they're logged by l
+func (l *Logger) routeLogger(deviceEventClassId string) *Logger {
+	for _, r := range l.routes {
+		if r.match(deviceEventClassId) {
+			return r.logger
+		}
+	}
+	return nil
+}
+
+// forward logs an event routed to another Logger. Header device fields are to's unless the event overrode l's.
+func (l *Logger) forward(ctx context.Context, to *Logger, dev device, deviceEventClassId, name, severity string,
+	extensions Extensions) error {
+	for to.parent != nil {
+		extensions = to.defaults.Merge(extensions)
+		to = to.parent
+	}
+	own, fwd := l.device(), to.device()
+	if dev.vendor != own.vendor {
+		fwd.vendor = dev.vendor
+	}
+	if dev.product != own.product {
+		fwd.product = dev.product
+	}
+	if dev.version != own.version {
+		fwd.version = dev.version
+	}
+	return to.log(ctx, fwd, deviceEventClassId, name, severity, extensions)
+}
+
+// outFor returns the writer events with the given class ID should be written to
+func (l *Logger) outFor(deviceEventClassId string) io.Writer {
+	return l.outAt(l.routeIndex(deviceEventClassId))
+}
+
+// routeIndex returns the index of the route to a writer events with the given class ID take, or -1 for the default
+// writer
+func (l *Logger) routeIndex(deviceEventClassId string) int {
+	for i, r := range l.routes {
+		if r.logger == nil && r.match(deviceEventClassId) {
+			return i
+		}
+	}
+	return -1
+}
+
+// outAt returns the writer of the route at index i, or the default writer for -1
+func (l *Logger) outAt(i int) io.Writer {
+	if i < 0 {
+		return l.out
+	}
+	return l.routes[i].out
+}
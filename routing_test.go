@@ -0,0 +1,94 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithClassRoutes(t *testing.T) {
+	audit := &bytes.Buffer{}
+	detections := &bytes.Buffer{}
+	other := &bytes.Buffer{}
+	l := NewLogger(other, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithClassRoutes(
+		RouteClassRange(1000, 1999, audit),
+		RouteClassPrefix("4", detections),
+	))
+	tests := []struct {
+		name               string
+		deviceEventClassId string
+		want               *bytes.Buffer
+	}{
+		{
+			"range",
+			"1500",
+			audit,
+		},
+		{
+			"prefix",
+			"4001",
+			detections,
+		},
+		{
+			"unmatched",
+			"2001",
+			other,
+		},
+		{
+			"non_numeric",
+			"login",
+			other,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit.Reset()
+			detections.Reset()
+			other.Reset()
+			if assert.NoError(t, l.LogLow(tt.deviceEventClassId, "test event", Extensions{})) {
+				assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|"+tt.deviceEventClassId+"|test event|Low|", tt.want.String())
+				assert.Equal(t, len(tt.want.String()), audit.Len()+detections.Len()+other.Len(), "written to more than one route")
+			}
+		})
+	}
+}
+
+func TestWithClassRoutes_logger(t *testing.T) {
+	audit := &bytes.Buffer{}
+	detections := &bytes.Buffer{}
+	other := &bytes.Buffer{}
+	auditLogger := NewLogger(audit, "Acme", "Audit", "2.0", OmitSyslogHeader(), WithStandardKeys())
+	l := NewLogger(other, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithClassRoutes(
+		RouteClassRangeToLogger(1000, 1999, auditLogger.With(Extensions{DeviceFacility: "audit"})),
+		RouteClassPrefix("4", detections),
+		RouteClassPrefixToLogger("4", auditLogger), // shadowed by the writer route
+	), MustLoggerConfig(WithMinSeverity(HighSeverity)))
+	tests := []struct {
+		name   string
+		log    func() error
+		want   *bytes.Buffer
+		record string
+	}{
+		{"routed", func() error { return l.LogLow("1500", "test", Extensions{DeviceHostName: "h"}) }, audit,
+			"CEF:1|Acme|Audit|2.0|1500|test|Low|deviceFacility=audit dvchost=h"},
+		{"override", func() error {
+			return l.LogEvent(Event{DeviceProduct: "Login", DeviceEventClassId: "1001", Name: "test", Severity: LowSeverity})
+		}, audit, "CEF:1|Acme|Login|2.0|1001|test|Low|deviceFacility=audit"},
+		{"writer", func() error { return l.LogHigh("4001", "test", Extensions{}) }, detections,
+			"CEF:1|testVendor|testProduct|1.0|4001|test|High|"},
+		{"unmatched", func() error { return l.LogHigh("2001", "test", Extensions{}) }, other,
+			"CEF:1|testVendor|testProduct|1.0|2001|test|High|"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit.Reset()
+			detections.Reset()
+			other.Reset()
+			if assert.NoError(t, tt.log()) {
+				assert.Equal(t, tt.record, tt.want.String())
+				assert.Equal(t, len(tt.record), audit.Len()+detections.Len()+other.Len(), "written to more than one route")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package cefevent
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Device event class IDs for events logged by LogRPC
+const (
+	RPCServerClassId = "rpc-server"
+	RPCClientClassId = "rpc-client"
+)
+
+// RPCDeadlineLabel labels the deviceCustomDate1 field LogRPC records a call's deadline in
+const RPCDeadlineLabel = "deadline"
+
+// rpcCodeNames are the names of the gRPC status codes, indexed by code
+var rpcCodeNames = [...]string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound", "AlreadyExists",
+	"PermissionDenied", "ResourceExhausted", "FailedPrecondition", "Aborted", "OutOfRange", "Unimplemented",
+	"Internal", "Unavailable", "DataLoss", "Unauthenticated",
+}
+
+// RPCCall describes a completed gRPC call. It is independent of the gRPC module, so interceptors, such as those in the
+// grpcaudit module, fill it in from their arguments, the peer & deadline in the context and the returned status.
+type RPCCall struct {
+	// FullMethod is the method called, e.g. "/pkg.Service/Method"
+	FullMethod string
+	// Peer is the address of the other side of the call: the client for server calls, the server for client calls
+	Peer net.Addr
+	// Code is the status code returned, 0 (OK) for success
+	Code uint32
+	// Message is the status message returned
+	Message string
+	// Start & End are when the call started and finished
+	Start, End time.Time
+	// Deadline is the call's deadline, or zero if it had none
+	Deadline time.Time
+	// Client is whether the call was made by this process rather than served by it
+	Client bool
+}
+
+// LogRPC logs call as a CEF event to l, named after the full method with the service as the destination service
+// name. The peer is the source of server calls and the destination of client calls. Calls failing with
+// Unauthenticated, PermissionDenied, Internal, Unavailable, DataLoss or Unknown are MediumSeverity and others
+// LowSeverity. Calls which didn't return OK have an outcome of "failure" with the code and message as the reason. The
+// start & end times are when the call actually started and finished; a deadline is recorded as deviceCustomDate1,
+// labelled "deadline", so calls which overran it can be found.
+//
+// The grpcaudit module provides gRPC interceptors calling LogRPC, keeping the gRPC dependency out of this module.
+func LogRPC(l *Logger, call RPCCall) error {
+	service := rpcService(call.FullMethod)
+	ext := Extensions{
+		ApplicationProtocol:    "gRPC",
+		TransportProtocol:      "TCP",
+		DestinationServiceName: service,
+		RequestUrl:             url.URL{Path: call.FullMethod},
+		StartTime:              call.Start,
+		EndTime:                call.End,
+		Outcome:                "success",
+	}
+	if !call.Deadline.IsZero() {
+		ext.DeviceCustomDate1 = Labeled(RPCDeadlineLabel, call.Deadline)
+	}
+	if call.Code != 0 {
+		ext.Outcome = "failure"
+		ext.Reason = rpcCodeName(call.Code)
+		if call.Message != "" {
+			ext.Reason += ": " + call.Message
+		}
+	}
+	var proto string
+	if call.Client {
+		ext.DestinationAddress, ext.DestinationPort, proto = addrFields(call.Peer)
+	} else {
+		ext.SourceAddress, ext.SourcePort, proto = addrFields(call.Peer)
+	}
+	if proto != "" {
+		ext.TransportProtocol = proto
+	}
+
+	classId := RPCServerClassId
+	if call.Client {
+		classId = RPCClientClassId
+	}
+	return l.Log(classId, call.FullMethod, rpcSeverity(call.Code), ext)
+}
+
+// rpcService returns the service of a method, e.g. "pkg.Service" for "/pkg.Service/Method"
+func rpcService(fullMethod string) string {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+func rpcCodeName(code uint32) string {
+	if int(code) < len(rpcCodeNames) {
+		return rpcCodeNames[code]
+	}
+	return "Code(" + strconv.FormatUint(uint64(code), 10) + ")"
+}
+
+func rpcSeverity(code uint32) string {
+	switch rpcCodeName(code) {
+	case "Unauthenticated", "PermissionDenied", "Internal", "Unavailable", "DataLoss", "Unknown":
+		return MediumSeverity
+	}
+	return LowSeverity
+}
@@ -0,0 +1,69 @@
+package cefevent
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRPC(t *testing.T) {
+	start := time.Date(2023, 11, 9, 11, 45, 20, 0, time.UTC)
+	peer := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50051}
+	tests := []struct {
+		name string
+		call RPCCall
+		want string
+	}{
+		{
+			"server",
+			RPCCall{FullMethod: "/pkg.Users/Get", Peer: peer, Start: start, End: start.Add(time.Second)},
+			`|rpc-server|/pkg.Users/Get|Low|app=gRPC end=1699530321000 outcome=success proto=TCP start=1699530320000 ` +
+				`destinationServiceName=pkg.Users request=/pkg.Users/Get spt=50051 src=10.0.0.1`,
+		},
+		{
+			"client",
+			RPCCall{FullMethod: "/pkg.Users/Get", Peer: peer, Start: start, End: start.Add(time.Second), Client: true},
+			`|rpc-client|/pkg.Users/Get|Low|app=gRPC end=1699530321000 outcome=success proto=TCP start=1699530320000 ` +
+				`destinationServiceName=pkg.Users dpt=50051 dst=10.0.0.1 request=/pkg.Users/Get`,
+		},
+		{
+			"denied",
+			RPCCall{FullMethod: "/pkg.Users/Delete", Code: 7, Message: "not an admin", Start: start, End: start},
+			`|rpc-server|/pkg.Users/Delete|Medium|app=gRPC end=1699530320000 outcome=failure proto=TCP ` +
+				`reason=PermissionDenied: not an admin start=1699530320000 destinationServiceName=pkg.Users request=/pkg.Users/Delete`,
+		},
+		{
+			"deadline",
+			RPCCall{FullMethod: "/pkg.Users/List", Code: 4, Start: start, End: start.Add(time.Minute)},
+			`|rpc-server|/pkg.Users/List|Low|app=gRPC end=1699530380000 outcome=failure proto=TCP ` +
+				`reason=DeadlineExceeded start=1699530320000 destinationServiceName=pkg.Users request=/pkg.Users/List`,
+		},
+		{
+			"overran_deadline",
+			RPCCall{FullMethod: "/pkg.Users/List", Code: 4, Start: start, End: start.Add(time.Minute),
+				Deadline: start.Add(time.Second)},
+			`|rpc-server|/pkg.Users/List|Low|app=gRPC end=1699530380000 outcome=failure proto=TCP ` +
+				`reason=DeadlineExceeded start=1699530320000 destinationServiceName=pkg.Users request=/pkg.Users/List ` +
+				`deviceCustomDate1=1699530321000 deviceCustomDate1Label=deadline`,
+		},
+		{
+			"unknown_code",
+			RPCCall{FullMethod: "Ping", Code: 99, Start: start, End: start},
+			`|rpc-server|Ping|Low|app=gRPC end=1699530320000 outcome=failure proto=TCP reason=Code(99) ` +
+				`start=1699530320000 request=Ping`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &recordingWriter{}
+			l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+			require.NoError(t, LogRPC(l, tt.call))
+			records := w.Records()
+			require.Len(t, records, 1)
+			assert.Equal(t, "CEF:1|testVendor|testProduct|1.0"+tt.want, records[0])
+		})
+	}
+}
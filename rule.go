@@ -0,0 +1,380 @@
+package cefevent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Rule is a detection rule in a simple format modelled on Sigma, e.g. for a relay to raise alerts at the edge:
+//
+//	{
+//		"id": "admin-login-failed",
+//		"title": "Failed administrator login",
+//		"level": "High",
+//		"detection": {
+//			"failed": {"deviceEventClassId": ["100", "101"], "outcome": "fail*", "suser|startswith": "admin"},
+//			"internal": {"src|cidr": ["10.0.0.0/8", "192.168.0.0/16"]}
+//		},
+//		"condition": "failed and not internal"
+//	}
+//
+// Detection names selections, each matching events with every field it lists, looked up as by Event.Field, equal to
+// any of the field's values. Values are compared case-insensitively and may use the wildcards * and ?, escaped with \.
+// A field may be followed by modifiers separated by |:
+//
+//	contains, startswith, endswith   whether the field contains, starts or ends with a value
+//	re                               whether the field matches a regular expression
+//	cidr                             whether the field is an address within a CIDR range
+//	all                              whether the field matches every value rather than any
+//
+// Condition combines selections with and, or, not and parentheses, as CompileQuery does comparisons. "1 of pattern"
+// and "all of pattern" match any or all of the selections whose names match a pattern such as "failed_*", with "them"
+// naming every selection. A rule with a single selection may omit the condition.
+type Rule struct {
+	ID        string                   `json:"id"`
+	Title     string                   `json:"title,omitempty"`
+	Level     string                   `json:"level,omitempty"` // The severity of correlation events, Medium if unset
+	Detection map[string]RuleSelection `json:"detection"`
+	Condition string                   `json:"condition,omitempty"`
+}
+
+// RuleSelection maps fields, with any modifiers, to the values they may take
+type RuleSelection map[string]RuleValues
+
+// RuleValues are the values of a field in a RuleSelection. In JSON it's a value or an array of values, which may be
+// strings, numbers or booleans.
+type RuleValues []string
+
+// UnmarshalJSON reads a value or an array of values
+func (v *RuleValues) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		raw = []json.RawMessage{b}
+	}
+	values := make(RuleValues, len(raw))
+	for i, r := range raw {
+		var value any
+		if err := json.Unmarshal(r, &value); err != nil {
+			return err
+		}
+		switch value := value.(type) {
+		case string:
+			values[i] = value
+		case float64, bool:
+			values[i] = string(r)
+		default:
+			return fmt.Errorf("rule value must be a string, number or boolean, not %s", r)
+		}
+	}
+	*v = values
+	return nil
+}
+
+// RuleMatch is an event matching a Rule
+type RuleMatch struct {
+	Rule  *Rule
+	Event *Event
+}
+
+// RuleMatcher matches events against Rules, calling back or writing a correlation event for each match
+type RuleMatcher struct {
+	rules        []compiledRule
+	onMatch      func(ctx context.Context, m RuleMatch)
+	correlations EventWriter
+}
+
+// compiledRule is a Rule with its condition compiled
+type compiledRule struct {
+	rule  *Rule
+	match Predicate
+}
+
+// RuleMatcherOption configures a RuleMatcher
+type RuleMatcherOption func(m *RuleMatcher)
+
+// WithRuleCallback sets a function called with each match. It's called synchronously from Stage, in the order of the
+// rules.
+func WithRuleCallback(fn func(ctx context.Context, m RuleMatch)) RuleMatcherOption {
+	return func(m *RuleMatcher) {
+		m.onMatch = fn
+	}
+}
+
+// WithCorrelationEvents writes a correlation event to out for each match. The correlation event has the rule's ID as
+// its device event class ID, its title, or else ID, as its name and its level as severity, and the matching event's
+// extensions with Type set to CorrelationEventType. Its device fields are unset, so a Logger writes its own.
+func WithCorrelationEvents(out EventWriter) RuleMatcherOption {
+	return func(m *RuleMatcher) {
+		m.correlations = out
+	}
+}
+
+// NewRuleMatcher compiles rules into a RuleMatcher, returning an error naming the first invalid rule
+func NewRuleMatcher(rules []Rule, opts ...RuleMatcherOption) (*RuleMatcher, error) {
+	m := &RuleMatcher{rules: make([]compiledRule, len(rules))}
+	for i := range rules {
+		rule := &rules[i]
+		match, err := rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.ID, err)
+		}
+		m.rules[i] = compiledRule{rule, match}
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// LoadRules reads a JSON array of Rules
+func LoadRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Match returns the rules e matches, in order
+func (m *RuleMatcher) Match(e *Event) []*Rule {
+	var matched []*Rule
+	for _, r := range m.rules {
+		if r.match(e) {
+			matched = append(matched, r.rule)
+		}
+	}
+	return matched
+}
+
+// Stage returns a Stage reporting the rules each event matches, as configured by WithRuleCallback and
+// WithCorrelationEvents. Events are kept, so detection can run alongside relaying; follow it with a Filter to relay
+// only matching events. Failing to write a correlation event fails the event.
+func (m *RuleMatcher) Stage() Stage {
+	return func(ctx context.Context, e *Event) (bool, error) {
+		var errs []error
+		for _, rule := range m.Match(e) {
+			if m.onMatch != nil {
+				m.onMatch(ctx, RuleMatch{Rule: rule, Event: e})
+			}
+			if m.correlations != nil {
+				if err := m.correlations.WriteEvent(ctx, rule.correlation(e)); err != nil {
+					errs = append(errs, fmt.Errorf("rule %q: %w", rule.ID, err))
+				}
+			}
+		}
+		return true, errors.Join(errs...)
+	}
+}
+
+// correlation returns the correlation event reporting that e matched r
+func (r *Rule) correlation(e *Event) Event {
+	c := Event{
+		DeviceEventClassId: r.ID,
+		Name:               r.Title,
+		Severity:           r.Level,
+		Extensions:         e.Extensions,
+	}
+	if c.Name == "" {
+		c.Name = r.ID
+	}
+	if c.Severity == "" {
+		c.Severity = MediumSeverity
+	}
+	c.Extensions.Type = CorrelationEventType
+	return c
+}
+
+// compile returns a Predicate matching the events r detects
+func (r *Rule) compile() (Predicate, error) {
+	if r.ID == "" {
+		return nil, errors.New("missing id")
+	}
+	if r.Level != "" {
+		if _, err := severityLevel(r.Level); err != nil {
+			return nil, fmt.Errorf("level %q: %w", r.Level, err)
+		}
+	}
+	selections := make(map[string]Predicate, len(r.Detection))
+	for _, name := range slices.Sorted(maps.Keys(r.Detection)) {
+		match, err := r.Detection[name].compile()
+		if err != nil {
+			return nil, fmt.Errorf("selection %q: %w", name, err)
+		}
+		selections[name] = match
+	}
+
+	condition := r.Condition
+	if condition == "" {
+		if len(selections) != 1 {
+			return nil, errors.New("missing condition")
+		}
+		for _, match := range selections {
+			return match, nil
+		}
+	}
+	p := &queryParser{tokens: tokenizeQuery(condition)}
+	p.operand = func(t queryToken) (Predicate, error) {
+		if (t.text == "1" || strings.EqualFold(t.text, "all")) && p.peek().is("", "of") {
+			p.next()
+			return p.parseSelectionsOf(t.text == "1", selections)
+		}
+		match, ok := selections[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown selection %q", t.text)
+		}
+		return match, nil
+	}
+	match, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("condition: %w", err)
+	}
+	return match, nil
+}
+
+// parseSelectionsOf parses the pattern of "1 of pattern" or "all of pattern", returning a Predicate matching any or
+// all of the selections matching it
+func (p *queryParser) parseSelectionsOf(anyOf bool, selections map[string]Predicate) (Predicate, error) {
+	pattern := p.next()
+	if pattern.kind != queryWord {
+		return nil, p.unexpected(pattern)
+	}
+	var matches []Predicate
+	for _, name := range slices.Sorted(maps.Keys(selections)) {
+		ok, err := path.Match(pattern.text, name)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern.text, err)
+		}
+		if ok || strings.EqualFold(pattern.text, "them") {
+			matches = append(matches, selections[name])
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no selections match %q", pattern.text)
+	}
+	if anyOf {
+		return Or(matches...), nil
+	}
+	return And(matches...), nil
+}
+
+// compile returns a Predicate matching the events with every field of s
+func (s RuleSelection) compile() (Predicate, error) {
+	if len(s) == 0 {
+		return nil, errors.New("no fields")
+	}
+	var matches []Predicate
+	for _, key := range slices.Sorted(maps.Keys(s)) {
+		field, modifiers, _ := strings.Cut(key, "|")
+		match, err := compileRuleField(field, strings.Split(modifiers, "|"), s[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		matches = append(matches, match)
+	}
+	return And(matches...), nil
+}
+
+// compileRuleField returns a Predicate matching events with field equal to any, or with the all modifier every, value,
+// compared as modifiers describe
+func compileRuleField(field string, modifiers []string, values RuleValues) (Predicate, error) {
+	if len(values) == 0 {
+		return nil, errors.New("no values")
+	}
+	all, kind := false, ""
+	for _, modifier := range modifiers {
+		switch modifier {
+		case "":
+		case "all":
+			all = true
+		case "contains", "startswith", "endswith", "re", "cidr":
+			if kind != "" {
+				return nil, fmt.Errorf("modifiers %s and %s conflict", kind, modifier)
+			}
+			kind = modifier
+		default:
+			return nil, fmt.Errorf("unknown modifier %q", modifier)
+		}
+	}
+
+	matches := make([]func(v string) bool, len(values))
+	for i, value := range values {
+		var err error
+		switch kind {
+		case "re":
+			var re *regexp.Regexp
+			re, err = regexp.Compile(value)
+			if err == nil {
+				matches[i] = re.MatchString
+			}
+		case "cidr":
+			var prefix *net.IPNet
+			_, prefix, err = net.ParseCIDR(value)
+			if err == nil {
+				matches[i] = func(v string) bool {
+					ip := net.ParseIP(v)
+					return ip != nil && prefix.Contains(ip)
+				}
+			}
+		case "contains":
+			matches[i], err = compileWildcard("*" + value + "*")
+		case "startswith":
+			matches[i], err = compileWildcard(value + "*")
+		case "endswith":
+			matches[i], err = compileWildcard("*" + value)
+		default:
+			matches[i], err = compileWildcard(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fieldMatch(field, func(v string) bool {
+		if all {
+			return !slices.ContainsFunc(matches, func(match func(string) bool) bool { return !match(v) })
+		}
+		return slices.ContainsFunc(matches, func(match func(string) bool) bool { return match(v) })
+	}), nil
+}
+
+// compileWildcard returns a function matching values case-insensitively against pattern, where * matches any text, ?
+// any character and \ escapes the next character
+func compileWildcard(pattern string) (func(v string) bool, error) {
+	if !strings.ContainsAny(pattern, `*?\`) {
+		return func(v string) bool { return strings.EqualFold(v, pattern) }, nil
+	}
+	var b, literal strings.Builder
+	b.WriteString(`(?is)^`)
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*', '?':
+			b.WriteString(regexp.QuoteMeta(literal.String()))
+			literal.Reset()
+			b.WriteString(map[byte]string{'*': `.*`, '?': `.`}[c])
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			literal.WriteByte(pattern[i])
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	b.WriteString(regexp.QuoteMeta(literal.String()))
+	b.WriteString(`$`)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
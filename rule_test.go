@@ -0,0 +1,170 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule_match(t *testing.T) {
+	e, err := Parse("CEF:0|v|p|1|100|login|High|src=10.0.0.1 suser=Administrator outcome=failure dpt=22 " +
+		"msg=bad password for user app\\=ssh")
+	require.NoError(t, err)
+	tests := []struct {
+		name      string
+		detection map[string]RuleSelection
+		condition string
+		want      bool
+	}{
+		{"equal", map[string]RuleSelection{"s": {"outcome": {"failure"}}}, "", true},
+		{"equal_case_insensitive", map[string]RuleSelection{"s": {"outcome": {"FAILURE"}}}, "", true},
+		{"equal_other", map[string]RuleSelection{"s": {"outcome": {"success"}}}, "", false},
+		{"header", map[string]RuleSelection{"s": {"deviceEventClassId": {"100"}}}, "", true},
+		{"number", map[string]RuleSelection{"s": {"dpt": {"22"}}}, "", true},
+		{"list", map[string]RuleSelection{"s": {"dpt": {"21", "22", "23"}}}, "", true},
+		{"list_other", map[string]RuleSelection{"s": {"dpt": {"80", "443"}}}, "", false},
+		{"fields", map[string]RuleSelection{"s": {"dpt": {"22"}, "outcome": {"failure"}}}, "", true},
+		{"fields_one", map[string]RuleSelection{"s": {"dpt": {"22"}, "outcome": {"success"}}}, "", false},
+		{"missing", map[string]RuleSelection{"s": {"duser": {"*"}}}, "", false},
+		{"wildcard", map[string]RuleSelection{"s": {"suser": {"admin*"}}}, "", true},
+		{"wildcard_one", map[string]RuleSelection{"s": {"outcome": {"fail?re"}}}, "", true},
+		{"wildcard_escaped", map[string]RuleSelection{"s": {"suser": {`admin\*`}}}, "", false},
+		{"contains", map[string]RuleSelection{"s": {"msg|contains": {"PASSWORD"}}}, "", true},
+		{"contains_all", map[string]RuleSelection{"s": {"msg|contains|all": {"bad", "ssh"}}}, "", true},
+		{"contains_all_one", map[string]RuleSelection{"s": {"msg|contains|all": {"bad", "rdp"}}}, "", false},
+		{"startswith", map[string]RuleSelection{"s": {"suser|startswith": {"admin"}}}, "", true},
+		{"endswith", map[string]RuleSelection{"s": {"msg|endswith": {"=ssh"}}}, "", true},
+		{"re", map[string]RuleSelection{"s": {"suser|re": {"^Adm.n"}}}, "", true},
+		{"cidr", map[string]RuleSelection{"s": {"src|cidr": {"192.168.0.0/16", "10.0.0.0/8"}}}, "", true},
+		{"cidr_other", map[string]RuleSelection{"s": {"src|cidr": {"192.168.0.0/16"}}}, "", false},
+		{"condition_not", map[string]RuleSelection{
+			"failed":   {"outcome": {"failure"}},
+			"internal": {"src|cidr": {"10.0.0.0/8"}},
+		}, "failed and not internal", false},
+		{"condition_or", map[string]RuleSelection{
+			"ssh": {"dpt": {"22"}},
+			"rdp": {"dpt": {"3389"}},
+		}, "rdp or (ssh and not rdp)", true},
+		{"one_of", map[string]RuleSelection{
+			"port_ssh": {"dpt": {"22"}},
+			"port_rdp": {"dpt": {"3389"}},
+			"other":    {"outcome": {"success"}},
+		}, "1 of port_*", true},
+		{"all_of", map[string]RuleSelection{
+			"port_ssh": {"dpt": {"22"}},
+			"port_rdp": {"dpt": {"3389"}},
+		}, "all of port_*", false},
+		{"all_of_them", map[string]RuleSelection{
+			"ssh":    {"dpt": {"22"}},
+			"failed": {"outcome": {"fail*"}},
+		}, "all of them", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewRuleMatcher([]Rule{{ID: "r", Detection: tt.detection, Condition: tt.condition}})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, len(m.Match(&e)) == 1)
+		})
+	}
+}
+
+func TestNewRuleMatcher_invalid(t *testing.T) {
+	valid := map[string]RuleSelection{"s": {"dpt": {"22"}}}
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{"id", Rule{Detection: valid}, `rule "": missing id`},
+		{"level", Rule{ID: "r", Level: "Critical", Detection: valid}, `rule "r": level "Critical": invalid severity`},
+		{"no_selections", Rule{ID: "r"}, `rule "r": missing condition`},
+		{"no_fields", Rule{ID: "r", Detection: map[string]RuleSelection{"s": {}}}, `rule "r": selection "s": no fields`},
+		{"no_values", Rule{ID: "r", Detection: map[string]RuleSelection{"s": {"dpt": {}}}},
+			`rule "r": selection "s": dpt: no values`},
+		{"modifier", Rule{ID: "r", Detection: map[string]RuleSelection{"s": {"dpt|gt": {"1"}}}},
+			`rule "r": selection "s": dpt|gt: unknown modifier "gt"`},
+		{"modifiers_conflict", Rule{ID: "r", Detection: map[string]RuleSelection{"s": {"msg|re|contains": {"a"}}}},
+			`rule "r": selection "s": msg|re|contains: modifiers re and contains conflict`},
+		{"re", Rule{ID: "r", Detection: map[string]RuleSelection{"s": {"msg|re": {"("}}}},
+			`rule "r": selection "s": msg|re: error parsing regexp`},
+		{"cidr", Rule{ID: "r", Detection: map[string]RuleSelection{"s": {"src|cidr": {"10.0.0.1"}}}},
+			`rule "r": selection "s": src|cidr: invalid CIDR address: 10.0.0.1`},
+		{"missing_condition", Rule{ID: "r", Detection: map[string]RuleSelection{"a": valid["s"], "b": valid["s"]}},
+			`rule "r": missing condition`},
+		{"unknown_selection", Rule{ID: "r", Detection: valid, Condition: "s and t"},
+			`rule "r": condition: unknown selection "t"`},
+		{"condition_syntax", Rule{ID: "r", Detection: valid, Condition: "s and"},
+			`rule "r": condition: invalid query: unexpected end of query`},
+		{"of_pattern", Rule{ID: "r", Detection: valid, Condition: "1 of port_*"},
+			`rule "r": condition: no selections match "port_*"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRuleMatcher([]Rule{tt.rule})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(`[{
+		"id": "ssh-failed",
+		"title": "Failed SSH login",
+		"level": "High",
+		"detection": {"failed": {"outcome": "fail*", "dpt": [22, 2222], "cs1": true}},
+		"condition": "failed"
+	}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{{ID: "ssh-failed", Title: "Failed SSH login", Level: "High",
+		Detection: map[string]RuleSelection{"failed": {"outcome": {"fail*"}, "dpt": {"22", "2222"}, "cs1": {"true"}}},
+		Condition: "failed"}}, rules)
+
+	_, err = LoadRules(strings.NewReader(`[{"id": "r", "detection": {"s": {"dpt": {"gt": 1}}}}]`))
+	assert.ErrorContains(t, err, `rule value must be a string, number or boolean, not {"gt": 1}`)
+	_, err = LoadRules(strings.NewReader(`[{"id": "r", "severity": "High"}]`))
+	assert.ErrorContains(t, err, `unknown field "severity"`)
+}
+
+func TestRuleMatcher_Stage(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(`[
+		{"id": "denied", "title": "Denied", "level": "High", "detection": {"s": {"act": "deny"}}},
+		{"id": "external", "detection": {"s": {"src|cidr": "10.0.0.0/8"}}, "condition": "not s"}
+	]`))
+	require.NoError(t, err)
+	var matches []string
+	correlations := &eventRecorder{}
+	m, err := NewRuleMatcher(rules, WithCorrelationEvents(correlations),
+		WithRuleCallback(func(_ context.Context, m RuleMatch) {
+			matches = append(matches, m.Rule.ID+":"+m.Event.Name)
+		}))
+	require.NoError(t, err)
+
+	out := &eventRecorder{}
+	input := `CEF:0|v|p|1|100|one|Low|src=10.0.0.1 act=allow
+CEF:0|v|p|1|200|two|High|src=10.0.0.2 act=deny
+CEF:0|v|p|1|100|three|Low|src=192.0.2.1 act=allow
+`
+	p := NewPipeline(NewScanner(strings.NewReader(input)), out, WithStages(m.Stage()))
+	require.NoError(t, p.Run(context.Background()))
+	assert.Equal(t, []string{"one", "two", "three"}, out.names())
+	assert.Equal(t, []string{"denied:two", "external:three"}, matches)
+	require.Len(t, correlations.events, 2)
+	c := correlations.events[0]
+	assert.Equal(t, []string{"denied", "Denied", HighSeverity}, []string{c.DeviceEventClassId, c.Name, c.Severity})
+	assert.EqualValues(t, CorrelationEventType, c.Extensions.Type)
+	assert.Equal(t, "act=deny", c.Extensions.format(nil)[:8])
+	c = correlations.events[1]
+	assert.Equal(t, []string{"external", "external", MediumSeverity}, []string{c.DeviceEventClassId, c.Name, c.Severity})
+
+	failing := EventWriterFunc(func(context.Context, Event) error { return errors.New("failed") })
+	m, err = NewRuleMatcher(rules, WithCorrelationEvents(failing))
+	require.NoError(t, err)
+	e := Event{Extensions: Extensions{DeviceAction: "deny"}}
+	keep, err := m.Stage()(context.Background(), &e)
+	assert.True(t, keep)
+	assert.EqualError(t, err, `rule "denied": failed`+"\n"+`rule "external": failed`)
+}
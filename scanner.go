@@ -0,0 +1,168 @@
+package cefevent
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+)
+
+// Scanner reads CEF records from a stream, parsing each into an Event. Records are newline delimited by default (see
+// ScanRecords), or octet counted as described by RFC 6587 with WithOctetCounting. Blank lines are skipped. A record that
+// fails to parse doesn't stop the scan; its error is returned by Event. Framing errors and read errors end the scan and
+// are returned by Err.
+type Scanner struct {
+	s      *bufio.Scanner
+	record int
+	text   string
+	event  Event
+	err    error
+	parse  []ParseOption
+}
+
+// ScannerOption configures a Scanner
+type ScannerOption func(s *Scanner)
+
+// WithOctetCounting reads records framed as "LEN SP RECORD", as used by syslog over TCP, rather than one per line
+func WithOctetCounting() ScannerOption {
+	return func(s *Scanner) {
+		s.s.Split(ScanOctetCounted)
+	}
+}
+
+// WithMaxRecordSize sets the size in bytes of the largest record that can be read. Larger records end the scan with
+// bufio.ErrTooLong. Defaults to bufio.MaxScanTokenSize
+func WithMaxRecordSize(n int) ScannerOption {
+	return func(s *Scanner) {
+		s.s.Buffer(make([]byte, 0, min(n, 4096)), n)
+	}
+}
+
+// WithScannerSchema validates and canonicalizes the CustomExtensions of each record against s, as WithParseSchema
+// does for Parse. Records violating the schema don't stop the scan; their error is returned by Event.
+func WithScannerSchema(s *Schema) ScannerOption {
+	return func(sc *Scanner) {
+		sc.parse = append(sc.parse, WithParseSchema(s))
+	}
+}
+
+// NewScanner creates a Scanner reading from r
+func NewScanner(r io.Reader, opts ...ScannerOption) *Scanner {
+	s := &Scanner{s: bufio.NewScanner(r)}
+	s.s.Split(ScanRecords)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Scan advances to the next record, returning false at the end of the stream or if an error ends the scan
+func (s *Scanner) Scan() bool {
+	for s.s.Scan() {
+		text := s.s.Text()
+		if len(bytes.TrimSpace(s.s.Bytes())) == 0 {
+			continue
+		}
+		s.record++
+		s.text = text
+		s.event, s.err = Parse(text, s.parse...)
+		return true
+	}
+	return false
+}
+
+// Event returns the current record parsed into an Event, and any error parsing it. The Event may be partially
+// populated when there is an error, as with Parse.
+func (s *Scanner) Event() (Event, error) {
+	return s.event, s.err
+}
+
+// Text returns the current record as read
+func (s *Scanner) Text() string {
+	return s.text
+}
+
+// Record returns the 1 based number of the current record, for reporting errors
+func (s *Scanner) Record() int {
+	return s.record
+}
+
+// All returns an iterator over the remaining records, yielding each parsed Event with its error as Event would, so
+// a stream can be read with range. An error ending the scan is yielded last with a nil Event. Breaking out of the loop
+// stops reading; Scan can resume from the next record.
+func (s *Scanner) All() iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		for s.Scan() {
+			event, err := s.Event()
+			if !yield(&event, err) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Err returns the error that ended the scan, or nil if it reached the end of the stream
+func (s *Scanner) Err() error {
+	return s.s.Err()
+}
+
+// ScanRecords is a bufio.SplitFunc for newline delimited CEF records, for reading records into ParseBytes from a custom
+// scanning loop. A newline escaped with a backslash is part of the record rather than ending it, and a carriage return
+// before the terminating newline is dropped.
+func ScanRecords(data []byte, atEOF bool) (int, []byte, error) {
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '\n':
+			return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), bytes.TrimSuffix(data, []byte{'\r'}), nil
+	}
+	return 0, nil, nil
+}
+
+// ScanOctetCounted is a bufio.SplitFunc for RFC 6587 octet counted framing, as read by WithOctetCounting, for custom
+// scanning loops like ScanRecords. Whitespace between frames is ignored, as some senders terminate each frame with a
+// newline.
+func ScanOctetCounted(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && (data[start] == ' ' || data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+	if start == len(data) {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return start, nil, nil
+	}
+	sp := bytes.IndexByte(data[start:], ' ')
+	if sp < 0 {
+		if len(data)-start > 10 {
+			return 0, nil, fmt.Errorf("%w: invalid frame length", MalformedEventErr)
+		}
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return start, nil, nil
+	}
+	n, err := strconv.Atoi(string(data[start : start+sp]))
+	if err != nil || n < 0 {
+		return 0, nil, fmt.Errorf("%w: invalid frame length %q", MalformedEventErr, data[start:start+sp])
+	}
+	end := start + sp + 1 + n
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return start, nil, nil
+	}
+	return end, data[start+sp+1 : end], nil
+}
@@ -0,0 +1,151 @@
+package cefevent
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type scanned struct {
+	record  int
+	name    string
+	message string
+	err     bool
+}
+
+func scanAll(s *Scanner) []scanned {
+	var got []scanned
+	for s.Scan() {
+		event, err := s.Event()
+		got = append(got, scanned{s.Record(), event.Name, event.Extensions.Message, err != nil})
+	}
+	return got
+}
+
+func TestScanner(t *testing.T) {
+	input := "CEF:1|v|p|1|100|first|Low|msg=one\r\n" +
+		"\n" +
+		"not cef\n" +
+		"Nov 9 11:45:20 host CEF:1|v|p|1|100|third|Low|msg=three"
+	s := NewScanner(strings.NewReader(input))
+	assert.Equal(t, []scanned{
+		{1, "first", "one", false},
+		{2, "", "", true},
+		{3, "third", "three", false},
+	}, scanAll(s))
+	assert.NoError(t, s.Err())
+}
+
+func TestScanner_maxRecordSize(t *testing.T) {
+	s := NewScanner(strings.NewReader("CEF:1|v|p|1|100|n|Low|msg="+strings.Repeat("x", 100)), WithMaxRecordSize(64))
+	assert.False(t, s.Scan())
+	assert.ErrorIs(t, s.Err(), bufio.ErrTooLong)
+}
+
+func TestScanner_octetCounting(t *testing.T) {
+	first := "CEF:1|v|p|1|100|first|Low|msg=line\nbreak"
+	second := "CEF:1|v|p|1|100|second|Low|"
+	input := strconv.Itoa(len(first)) + " " + first + "\n" + strconv.Itoa(len(second)) + " " + second
+	s := NewScanner(strings.NewReader(input), WithOctetCounting())
+	assert.Equal(t, []scanned{
+		{1, "first", "line\nbreak", false},
+		{2, "second", "", false},
+	}, scanAll(s))
+	assert.NoError(t, s.Err())
+}
+
+func TestScanner_schema(t *testing.T) {
+	input := "CEF:1|v|p|1|100|first|Low|msg=one tenantId=1\n" +
+		"CEF:1|v|p|1|100|second|Low|msg=two tenantId=abc\n" +
+		"CEF:1|v|p|1|100|third|Low|msg=three tenantId=3"
+	s := NewScanner(strings.NewReader(input), WithScannerSchema(testSchema(t)))
+	assert.Equal(t, []scanned{
+		{1, "first", "one", false},
+		{2, "second", "two", true},
+		{3, "third", "three", false},
+	}, scanAll(s))
+	assert.NoError(t, s.Err())
+}
+
+func TestScanOctetCounted(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr error
+	}{
+		{"frames", "3 abc 2 de", []string{"abc", "de"}, nil},
+		{"trailing_newlines", "3 abc\n\n", []string{"abc"}, nil},
+		{"truncated", "5 abc", nil, io.ErrUnexpectedEOF},
+		{"bad_length", "x abc", nil, MalformedEventErr},
+		{"missing_space", "12345678901", nil, MalformedEventErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bufio.NewScanner(strings.NewReader(tt.input))
+			s.Split(ScanOctetCounted)
+			var got []string
+			for s.Scan() {
+				got = append(got, s.Text())
+			}
+			assert.Equal(t, tt.want, got)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, s.Err(), tt.wantErr)
+			} else {
+				assert.NoError(t, s.Err())
+			}
+		})
+	}
+}
+
+func TestScanRecords(t *testing.T) {
+	input := "CEF:1|v|p|1|100|first|Low|msg=line\\\nbreak\r\nCEF:1|v|p|1|100|second|Low|msg=a\\\\\nCEF:1|v|p|1|100|third|Low|"
+	s := bufio.NewScanner(strings.NewReader(input))
+	s.Split(ScanRecords)
+	var got []string
+	for s.Scan() {
+		ev, err := ParseBytes(s.Bytes())
+		assert.NoError(t, err)
+		got = append(got, ev.Name+":"+ev.Extensions.Message)
+	}
+	assert.NoError(t, s.Err())
+	assert.Equal(t, []string{"first:line\nbreak", "second:a\\", "third:"}, got)
+}
+
+func TestScanner_All(t *testing.T) {
+	input := "CEF:1|v|p|1|100|first|Low|\nnot cef\nCEF:1|v|p|1|100|third|Low|\nCEF:1|v|p|1|100|fourth|Low|"
+	s := NewScanner(strings.NewReader(input))
+	var names []string
+	var errs int
+	for event, err := range s.All() {
+		if err != nil {
+			errs++
+			continue
+		}
+		names = append(names, event.Name)
+		if event.Name == "third" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"first", "third"}, names)
+	assert.Equal(t, 1, errs)
+
+	// Resumes after breaking
+	for event := range s.All() {
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{"first", "third", "fourth"}, names)
+
+	s = NewScanner(strings.NewReader("CEF:1|v|p|1|100|n|Low|msg="+strings.Repeat("x", 100)), WithMaxRecordSize(64))
+	var yielded int
+	for event, err := range s.All() {
+		yielded++
+		assert.Nil(t, event)
+		assert.ErrorIs(t, err, bufio.ErrTooLong)
+	}
+	assert.Equal(t, 1, yielded)
+}
@@ -0,0 +1,191 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SchemaViolationErr error when a custom extension value doesn't match its registered FieldSpec
+var SchemaViolationErr = errors.New("schema violation")
+
+// FieldType is the expected type of a custom extension value
+type FieldType int
+
+const (
+	StringType FieldType = iota // Any string
+	IntType                     // Base 10 integer
+	FloatType                   // Floating point number
+	BoolType                    // true or false, accepting the forms understood by strconv.ParseBool
+	IPType                      // IPv4 or IPv6 address
+	MACType                     // MAC address
+	TimeType                    // Milliseconds since the unix epoch, or an RFC 3339 timestamp which is converted to millis
+)
+
+// FieldSpec describes the expected form of a custom extension key
+type FieldSpec struct {
+	// Type values are parsed as. Valid values are rewritten in canonical form, e.g. "+007" becomes "7" for IntType
+	Type FieldType
+
+	// Required rejects events where the key is missing
+	Required bool
+
+	// MaxLength maximum length in characters of the canonical value. 0 for no limit
+	MaxLength int
+
+	// Enum restricts the canonical value to one of the listed values if non-empty
+	Enum []string
+
+	// Check is an optional additional constraint on the canonical value
+	Check func(value string) error
+}
+
+// Schema is a registry of custom extension keys and their expected types, shared between producers so custom fields
+// stay consistent. A Schema is safe to use from multiple goroutines.
+type Schema struct {
+	// RejectUnknown rejects custom extension keys that haven't been registered
+	RejectUnknown bool
+
+	mu     sync.RWMutex
+	fields map[string]FieldSpec
+}
+
+// NewSchema creates an empty Schema
+func NewSchema() *Schema {
+	return &Schema{fields: make(map[string]FieldSpec)}
+}
+
+// Register adds or replaces the spec for key. Returns InvalidExtensionKeyErr if key can't be used as an extension key.
+func (s *Schema) Register(key string, spec FieldSpec) error {
+	if !validExtensionKey(key) {
+		return fmt.Errorf("%q: %w", key, InvalidExtensionKeyErr)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields[key] = spec
+	return nil
+}
+
+// Lookup returns the spec registered for key
+func (s *Schema) Lookup(key string) (FieldSpec, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	spec, ok := s.fields[key]
+	return spec, ok
+}
+
+// Apply validates custom against the schema, returning a copy with registered values in canonical form. All
+// violations are returned joined together, each wrapping SchemaViolationErr.
+func (s *Schema) Apply(custom map[string]string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var errs []error
+	out := make(map[string]string, len(custom))
+	for k, v := range custom {
+		spec, ok := s.fields[k]
+		if !ok {
+			if s.RejectUnknown {
+				errs = append(errs, fmt.Errorf("%w: %q is not registered", SchemaViolationErr, k))
+			}
+			out[k] = v
+			continue
+		}
+		canonical, err := spec.apply(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q: %w", SchemaViolationErr, k, err))
+			continue
+		}
+		out[k] = canonical
+	}
+	for k, spec := range s.fields {
+		if _, ok := custom[k]; spec.Required && !ok {
+			errs = append(errs, fmt.Errorf("%w: %q is required", SchemaViolationErr, k))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
+
+// apply parses v according to the spec and checks its constraints, returning the canonical value
+func (f FieldSpec) apply(v string) (string, error) {
+	canonical, err := f.Type.canonicalize(v)
+	if err != nil {
+		return "", err
+	}
+	if f.MaxLength > 0 && utf8.RuneCountInString(canonical) > f.MaxLength {
+		return "", fmt.Errorf("longer than %d characters", f.MaxLength)
+	}
+	if len(f.Enum) > 0 && !slices.Contains(f.Enum, canonical) {
+		return "", fmt.Errorf("%q is not one of %q", canonical, f.Enum)
+	}
+	if f.Check != nil {
+		if err := f.Check(canonical); err != nil {
+			return "", err
+		}
+	}
+	return canonical, nil
+}
+
+// canonicalize parses v as type t, returning it in canonical form
+func (t FieldType) canonicalize(v string) (string, error) {
+	switch t {
+	case StringType:
+		return v, nil
+	case IntType:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not an integer", v)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case FloatType:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", v)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case BoolType:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a boolean", v)
+		}
+		return strconv.FormatBool(b), nil
+	case IPType:
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return "", fmt.Errorf("%q is not an IP address", v)
+		}
+		return ip.String(), nil
+	case MACType:
+		mac, err := net.ParseMAC(v)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a MAC address", v)
+		}
+		return mac.String(), nil
+	case TimeType:
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return strconv.FormatInt(ms, 10), nil
+		}
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a timestamp", v)
+		}
+		return strconv.FormatInt(ts.UnixMilli(), 10), nil
+	}
+	return "", fmt.Errorf("unknown field type %d", t)
+}
+
+// WithSchema validates and canonicalizes CustomExtensions against s before each event is logged. Events violating the
+// schema are not written and Log returns an error wrapping SchemaViolationErr. Keys are checked before any
+// WithCustomExtensionPrefix namespace is applied.
+func WithSchema(s *Schema) LoggerConfigOption {
+	return func(l *Logger) {
+		l.schema = s
+	}
+}
@@ -0,0 +1,105 @@
+package cefevent
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema(t *testing.T) *Schema {
+	s := NewSchema()
+	require.NoError(t, s.Register("tenantId", FieldSpec{Type: IntType, Required: true}))
+	require.NoError(t, s.Register("clientIp", FieldSpec{Type: IPType}))
+	require.NoError(t, s.Register("seenAt", FieldSpec{Type: TimeType}))
+	require.NoError(t, s.Register("tier", FieldSpec{Type: StringType, Enum: []string{"free", "pro"}}))
+	require.NoError(t, s.Register("region", FieldSpec{Type: StringType, MaxLength: 4, Check: func(v string) error {
+		if v == "moon" {
+			return errors.New("not a real region")
+		}
+		return nil
+	}}))
+	return s
+}
+
+func TestSchema_Register_invalidKey(t *testing.T) {
+	assert.ErrorIs(t, NewSchema().Register("bad key", FieldSpec{}), InvalidExtensionKeyErr)
+}
+
+func TestSchema_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		custom  map[string]string
+		want    map[string]string
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			"canonicalized",
+			map[string]string{"tenantId": "+007", "clientIp": "::ffff:10.0.0.1", "seenAt": "2023-11-09T11:45:20Z", "other": "x"},
+			map[string]string{"tenantId": "7", "clientIp": "10.0.0.1", "seenAt": "1699530320000", "other": "x"},
+			assert.NoError,
+		},
+		{
+			"missing_required",
+			map[string]string{"tier": "pro"},
+			nil,
+			func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, SchemaViolationErr, i) && assert.ErrorContains(t, err, `"tenantId" is required`, i)
+			},
+		},
+		{
+			"wrong_type",
+			map[string]string{"tenantId": "acme"},
+			nil,
+			func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, SchemaViolationErr, i) && assert.ErrorContains(t, err, "not an integer", i)
+			},
+		},
+		{
+			"constraints",
+			map[string]string{"tenantId": "1", "tier": "enterprise", "region": "europe"},
+			nil,
+			func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "not one of", i) && assert.ErrorContains(t, err, "longer than 4", i)
+			},
+		},
+		{
+			"check",
+			map[string]string{"tenantId": "1", "region": "moon"},
+			nil,
+			func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorContains(t, err, "not a real region", i)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testSchema(t).Apply(tt.custom)
+			if tt.wantErr(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSchema_Apply_rejectUnknown(t *testing.T) {
+	s := testSchema(t)
+	s.RejectUnknown = true
+	_, err := s.Apply(map[string]string{"tenantId": "1", "other": "x"})
+	assert.ErrorIs(t, err, SchemaViolationErr)
+	assert.ErrorContains(t, err, `"other" is not registered`)
+}
+
+func TestWithSchema(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithSchema(testSchema(t)))
+
+	err := l.LogLow("100", "login", Extensions{CustomExtensions: map[string]string{"tenantId": "abc"}})
+	assert.ErrorIs(t, err, SchemaViolationErr)
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, l.LogLow("100", "login", Extensions{CustomExtensions: map[string]string{"tenantId": "042"}}))
+	assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|100|login|Low|tenantId=42", buf.String())
+}
@@ -0,0 +1,172 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Device event class IDs of the events written by WithSelfMonitoring, which all start with SelfMonitoringClassIdPrefix
+const (
+	SelfMonitoringClassIdPrefix = "cefevent-"
+	LoggerDroppedClassId        = "cefevent-dropped"
+	OutputRecoveredClassId      = "cefevent-output-recovered"
+)
+
+// WithSelfMonitoring writes events about the Logger's own operation, so problems shipping events are visible in the
+// SIEM itself. At most once per interval, it writes:
+//   - a LoggerDroppedClassId event when events were dropped for any of the Drop reasons other than DropSuppressed, with
+//     BaseEventCount set to the number dropped since the last report and Type AggregatedEventType. It is Medium
+//     severity if the WithAsync queue overflowed, Low otherwise.
+//   - an OutputRecoveredClassId event of Medium severity once writes succeed after failing, e.g. when a SyslogWriter
+//     reconnects, with the time writes started failing as StartTime, the number of failed writes as BaseEventCount
+//     and the last error as Reason.
+//
+// Class IDs start with SelfMonitoringClassIdPrefix so they can be routed or filtered. The events aren't rate limited
+// or sampled. Flush & Close write any pending report.
+func WithSelfMonitoring(interval time.Duration) LoggerConfigOption {
+	return func(l *Logger) {
+		l.monitor = &selfMonitor{interval: interval}
+	}
+}
+
+// selfMonitor tracks drops & output failures for WithSelfMonitoring
+type selfMonitor struct {
+	interval time.Duration
+
+	mu    sync.Mutex
+	drops map[string]uint64
+	timer *time.Timer
+	// failedSince is when the current run of failed writes started, zero while writes succeed
+	failedSince time.Time
+	failed      uint64
+	lastErr     error
+	// recovered holds a run of failed writes which has ended & not yet been reported
+	recovered *outputOutage
+}
+
+// outputOutage is a run of failed writes
+type outputOutage struct {
+	start, end time.Time
+	failed     uint64
+	lastErr    error
+}
+
+// dropped records an event dropped for reason
+func (m *selfMonitor) dropped(l *Logger, reason string) {
+	if reason == DropSuppressed {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.drops == nil {
+		m.drops = make(map[string]uint64)
+	}
+	m.drops[reason]++
+	m.schedule(l)
+}
+
+// writeFailed records a failed write
+func (m *selfMonitor) writeFailed(l *Logger, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failedSince.IsZero() {
+		m.failedSince = l.getTime()
+	}
+	m.failed++
+	m.lastErr = err
+}
+
+// written records a successful write, ending any run of failed writes
+func (m *selfMonitor) written(l *Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failedSince.IsZero() {
+		return
+	}
+	outage := &outputOutage{m.failedSince, l.getTime(), m.failed, m.lastErr}
+	if m.recovered != nil {
+		// Merge with an earlier outage which hasn't been reported yet
+		outage.start = m.recovered.start
+		outage.failed += m.recovered.failed
+	}
+	m.recovered = outage
+	m.failedSince, m.failed, m.lastErr = time.Time{}, 0, nil
+	m.schedule(l)
+}
+
+// schedule starts the report timer if needed. m.mu must be held.
+func (m *selfMonitor) schedule(l *Logger) {
+	if m.timer != nil {
+		return
+	}
+	m.timer = time.AfterFunc(m.interval, func() {
+		var err error
+		defer l.recoverPanic(&err) // runs on its own goroutine, where a panic would take down the host application
+		if err = m.report(l); err != nil {
+			l.handleError(err)
+		}
+	})
+}
+
+// report writes the drops & recovered outage since the last report, if any
+func (m *selfMonitor) report(l *Logger) error {
+	m.mu.Lock()
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	drops, recovered := m.drops, m.recovered
+	m.drops, m.recovered = nil, nil
+	m.mu.Unlock()
+
+	var errs []error
+	if len(drops) > 0 {
+		var total uint64
+		counts := make([]string, 0, len(drops))
+		for _, reason := range slices.Sorted(maps.Keys(drops)) {
+			total += drops[reason]
+			counts = append(counts, fmt.Sprintf("%d %s", drops[reason], reason))
+		}
+		severity := LowSeverity
+		if drops[DropQueueFull] > 0 {
+			severity = MediumSeverity
+		}
+		errs = append(errs, l.write(context.Background(), l.device(), LoggerDroppedClassId, "Events dropped", severity, Extensions{
+			BaseEventCount: int(total),
+			Type:           AggregatedEventType,
+			Message:        fmt.Sprintf("%d events dropped: %s", total, strings.Join(counts, ", ")),
+		}))
+	}
+	if recovered != nil {
+		ext := Extensions{
+			BaseEventCount: int(recovered.failed),
+			StartTime:      recovered.start,
+			EndTime:        recovered.end,
+			Message: fmt.Sprintf("output recovered after %d failed writes over %s", recovered.failed,
+				recovered.end.Sub(recovered.start).Round(time.Millisecond)),
+		}
+		if recovered.lastErr != nil {
+			ext.Reason = recovered.lastErr.Error()
+		}
+		errs = append(errs, l.write(context.Background(), l.device(), OutputRecoveredClassId, "Output recovered", MediumSeverity, ext))
+	}
+	return errors.Join(errs...)
+}
+
+// flushMonitor writes any pending WithSelfMonitoring report. The WithAsync queue is drained first, so the report covers
+// every event queued so far and isn't itself dropped for lack of space.
+func (l *Logger) flushMonitor() error {
+	if l.monitor == nil {
+		return nil
+	}
+	if l.async != nil {
+		l.async.flush()
+	}
+	return l.monitor.report(l)
+}
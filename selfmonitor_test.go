@@ -0,0 +1,88 @@
+package cefevent
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSelfMonitoring_dropped(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []LoggerConfigOption
+		want string
+	}{
+		{"rate_limited", []LoggerConfigOption{WithRateLimit(1, 1), MustLoggerConfig(WithSampleEvery(2))},
+			"CEF:1|v|p|1|cefevent-dropped|Events dropped|Low|msg=3 events dropped: 1 rate_limited, 2 sampled_out cnt=3 type=1"},
+		{"suppressed", []LoggerConfigOption{WithRepeatSuppression(time.Hour)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			l := NewLogger(buf, "v", "p", "1", append(tt.opts, OmitSyslogHeader(), WithSelfMonitoring(time.Hour))...)
+			for range 4 {
+				require.NoError(t, l.LogLow("100", "test", Extensions{}))
+			}
+			buf.Reset()
+			require.NoError(t, l.Flush())
+			_, report, _ := strings.Cut(buf.String(), "CEF:1|v|p|1|cefevent-dropped")
+			if tt.want == "" {
+				assert.Empty(t, report)
+				return
+			}
+			assert.Equal(t, tt.want, "CEF:1|v|p|1|cefevent-dropped"+report)
+
+			buf.Reset()
+			require.NoError(t, l.Flush())
+			assert.Empty(t, buf.String(), "report written twice")
+		})
+	}
+}
+
+func TestWithSelfMonitoring_queueFull(t *testing.T) {
+	w := newBlockingWriter()
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithAsync(1, OverflowDropNewest), WithSelfMonitoring(time.Hour))
+	require.NoError(t, l.LogLow("100", "first", Extensions{}))
+	<-w.started
+	for range 3 {
+		require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	}
+	close(w.release)
+	require.NoError(t, l.Close())
+	assert.Contains(t, w.String(),
+		"CEF:1|v|p|1|cefevent-dropped|Events dropped|Medium|msg=2 events dropped: 2 queue_full cnt=2 type=1")
+}
+
+func TestWithSelfMonitoring_outputRecovered(t *testing.T) {
+	w := &failingWriter{failures: 2, err: syscall.ECONNREFUSED}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), WithSelfMonitoring(time.Hour))
+	now := testTime()
+	l.getTime = func() time.Time { return now }
+	for range 3 {
+		_ = l.LogLow("100", "test", Extensions{})
+		now = now.Add(2 * time.Second)
+	}
+	w.Reset()
+	require.NoError(t, l.Flush())
+	assert.Equal(t, "CEF:1|v|p|1|cefevent-output-recovered|Output recovered|Medium|"+
+		"msg=output recovered after 2 failed writes over 4s cnt=2 end=1699530324000 "+
+		"reason=connection refused start=1699530320000", w.String())
+}
+
+func TestWithSelfMonitoring_interval(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "v", "p", "1", OmitSyslogHeader(), MustLoggerConfig(WithSampleEvery(2)),
+		WithSelfMonitoring(10*time.Millisecond))
+	for range 2 {
+		require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	}
+	assert.Eventually(t, func() bool {
+		records := w.Records()
+		return len(records) == 2 && strings.Contains(records[1], "msg=1 events dropped: 1 sampled_out")
+	}, time.Second, time.Millisecond)
+}
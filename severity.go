@@ -36,3 +36,63 @@ func validateSeverity(sev string) error {
 
 	return nil
 }
+
+// severityLevel returns the position of sev on the spec's 0-10 scale so severities can be compared. Named severities map
+// to the bottom of their band (Low 0-3, Medium 4-6, High 7-8, Very-High 9-10) and Unknown sorts below everything.
+func severityLevel(sev string) (int, error) {
+	switch sev {
+	case UnknownSeverity:
+		return -1, nil
+	case LowSeverity:
+		return 0, nil
+	case MediumSeverity:
+		return 4, nil
+	case HighSeverity:
+		return 7, nil
+	case VeryHighSeverity:
+		return 9, nil
+	}
+	v, err := strconv.Atoi(sev)
+	if err != nil || v > 10 || v < 0 {
+		return 0, InvalidSeverityError
+	}
+	return v, nil
+}
+
+// WithMinSeverity skips events less severe than sev, compared on the spec's 0-10 scale (see SetMinSeverity). Returns
+// InvalidSeverityError if sev isn't a valid severity.
+func WithMinSeverity(sev string) (LoggerConfigOption, error) {
+	level, err := severityLevel(sev)
+	if err != nil {
+		return nil, err
+	}
+	return func(l *Logger) {
+		l.minLevel.Store(int32(level) + 1)
+	}, nil
+}
+
+// SetMinSeverity changes the minimum severity of events logged, e.g. to raise verbosity at runtime. Named severities
+// are compared by the bottom of their band, so SetMinSeverity(MediumSeverity) allows "4" and above, and events of
+// Unknown severity are only logged without a minimum. Events are filtered after severity escalation, before formatting.
+// Safe to call while logging. Returns InvalidSeverityError if sev isn't a valid severity.
+func (l *Logger) SetMinSeverity(sev string) error {
+	if l.parent != nil {
+		return l.parent.SetMinSeverity(sev)
+	}
+	level, err := severityLevel(sev)
+	if err != nil {
+		return err
+	}
+	l.minLevel.Store(int32(level) + 1)
+	return nil
+}
+
+// Enabled reports whether events of severity sev would be logged, so callers can skip building expensive Extensions
+// for events that would be dropped. Invalid severities are reported as enabled, so they aren't silently dropped.
+func (l *Logger) Enabled(sev string) bool {
+	if l.parent != nil {
+		return l.parent.Enabled(sev)
+	}
+	level, err := severityLevel(sev)
+	return err != nil || int32(level)+1 >= l.minLevel.Load()
+}
@@ -1,6 +1,7 @@
 package cefevent
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -54,3 +55,97 @@ func Test_validateSeverity(t *testing.T) {
 		})
 	}
 }
+
+func Test_severityLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		sev     string
+		want    int
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			"unknown",
+			UnknownSeverity,
+			-1,
+			assert.NoError,
+		},
+		{
+			"named",
+			HighSeverity,
+			7,
+			assert.NoError,
+		},
+		{
+			"integer",
+			"8",
+			8,
+			assert.NoError,
+		},
+		{
+			"out_of_range",
+			"11",
+			0,
+			func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, InvalidSeverityError, i)
+			},
+		},
+		{
+			"invalid_adj",
+			"severe",
+			0,
+			func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, InvalidSeverityError, i)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := severityLevel(tt.sev)
+			if tt.wantErr(t, err, fmt.Sprintf("severityLevel(%v)", tt.sev)) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithMinSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		min  string
+		sev  string
+		want bool
+	}{
+		{"below_named", MediumSeverity, LowSeverity, false},
+		{"below_numeric", MediumSeverity, "3", false},
+		{"equal", MediumSeverity, "4", true},
+		{"above", "4", HighSeverity, true},
+		{"unknown_filtered", LowSeverity, UnknownSeverity, false},
+		{"unknown_allowed", UnknownSeverity, UnknownSeverity, true},
+		{"invalid", VeryHighSeverity, "11", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			l := NewLogger(buf, "v", "p", "1", MustLoggerConfig(WithMinSeverity(tt.min)))
+			assert.Equal(t, tt.want, l.Enabled(tt.sev))
+			assert.NoError(t, l.Log("100", "test", tt.sev, Extensions{}))
+			assert.Equal(t, tt.want, buf.Len() > 0)
+		})
+	}
+
+	_, err := WithMinSeverity("Severe")
+	assert.ErrorIs(t, err, InvalidSeverityError)
+}
+
+func TestLogger_SetMinSeverity(t *testing.T) {
+	l := NewLogger(&bytes.Buffer{}, "v", "p", "1")
+	child := l.With(Extensions{})
+	assert.True(t, l.Enabled(UnknownSeverity))
+	assert.NoError(t, child.SetMinSeverity(HighSeverity))
+	assert.False(t, l.Enabled(MediumSeverity))
+	assert.False(t, child.Enabled(MediumSeverity))
+	assert.True(t, child.Enabled("7"))
+	assert.ErrorIs(t, l.SetMinSeverity("Severe"), InvalidSeverityError)
+	assert.True(t, l.Clone().Enabled(HighSeverity))
+	assert.False(t, l.Clone().Enabled(MediumSeverity))
+}
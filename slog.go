@@ -0,0 +1,142 @@
+package cefevent
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+)
+
+// SlogClassIdKey is the attribute key which sets an event's device event class ID in SlogHandler
+const SlogClassIdKey = "cefClassId"
+
+// SlogHandler is a slog.Handler writing records as CEF events through a Logger. The record message is the event
+// name, and the level is mapped to a severity: below Warn is Low, below Error is Medium, below Error+4 is High and
+// anything above is Very-High. Attributes whose keys are standard extension keys (e.g. "src" or "suser") set those
+// fields; other attributes, or values that aren't valid for their field, are added to CustomExtensions. Keys within
+// groups are qualified by the group names separated by dots.
+type SlogHandler struct {
+	logger  *Logger
+	level   slog.Leveler
+	classId string
+	prefix  string
+	attrs   []slog.Attr
+}
+
+// SlogHandlerOption configures a SlogHandler
+type SlogHandlerOption func(h *SlogHandler)
+
+// WithSlogLevel sets the minimum level of records that are logged. Defaults to slog.LevelInfo
+func WithSlogLevel(level slog.Leveler) SlogHandlerOption {
+	return func(h *SlogHandler) {
+		h.level = level
+	}
+}
+
+// WithSlogClassId sets the device event class ID used for records without a SlogClassIdKey attribute. Defaults to
+// "slog"
+func WithSlogClassId(classId string) SlogHandlerOption {
+	return func(h *SlogHandler) {
+		h.classId = classId
+	}
+}
+
+// NewSlogHandler creates a SlogHandler writing to l
+func NewSlogHandler(l *Logger, opts ...SlogHandlerOption) *SlogHandler {
+	h := &SlogHandler{logger: l, level: slog.LevelInfo, classId: "slog"}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Enabled implements slog.Handler
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	classId := h.classId
+	var ext Extensions
+	if !r.Time.IsZero() {
+		ext.DeviceReceiptTime = r.Time
+	}
+	add := func(key string, v slog.Value) {
+		if key == SlogClassIdKey {
+			classId = v.String()
+			return
+		}
+		if err := ext.set(key, v.String()); err != nil {
+			if ext.CustomExtensions == nil {
+				ext.CustomExtensions = make(map[string]string)
+			}
+			ext.CustomExtensions[key] = v.String()
+		}
+	}
+	for _, a := range h.attrs {
+		flattenAttr("", a, add)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flattenAttr(h.prefix, a, add)
+		return true
+	})
+	return h.logger.Log(classId, r.Message, slogSeverity(r.Level), ext)
+}
+
+// WithAttrs implements slog.Handler
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = make([]slog.Attr, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(c.attrs, h.attrs)
+	for _, a := range attrs {
+		flattenAttr(h.prefix, a, func(key string, v slog.Value) {
+			c.attrs = append(c.attrs, slog.Attr{Key: key, Value: v})
+		})
+	}
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// flattenAttr calls fn for each non-group value in a, with keys qualified by prefix and any nested group names.
+// Empty attributes are skipped and groups without a key are inlined, as slog.Handler requires.
+func flattenAttr(prefix string, a slog.Attr, fn func(key string, v slog.Value)) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			flattenAttr(prefix, ga, fn)
+		}
+		return
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if v.Kind() == slog.KindTime {
+		v = slog.StringValue(strconv.FormatInt(v.Time().UnixMilli(), 10))
+	}
+	fn(prefix+a.Key, v)
+}
+
+// slogSeverity maps a slog level to a CEF severity
+func slogSeverity(level slog.Level) string {
+	switch {
+	case level < slog.LevelWarn:
+		return LowSeverity
+	case level < slog.LevelError:
+		return MediumSeverity
+	case level < slog.LevelError+4:
+		return HighSeverity
+	}
+	return VeryHighSeverity
+}
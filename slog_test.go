@@ -0,0 +1,56 @@
+package cefevent
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	logger := slog.New(NewSlogHandler(l, WithSlogClassId("app"))).With("suser", "alice")
+
+	logger.Info("login succeeded", "src", net.IPv4(10, 0, 0, 1), "spt", 51234, "outcome", "success")
+	logger.Warn("rate limited", SlogClassIdKey, "rate-limit", "dpt", "http")
+	logger.WithGroup("req").Error("request failed", slog.Group("http", "status", 500), "at", time.UnixMilli(1699530320000))
+	logger.Debug("not logged")
+
+	records := w.Records()
+	require.Len(t, records, 3)
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|app\|login succeeded\|Low\|`+
+		`outcome=success rt=\d+ spt=51234 src=10.0.0.1 suser=alice$`, records[0])
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|rate-limit\|rate limited\|Medium\|rt=\d+ suser=alice dpt=http$`, records[1])
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|app\|request failed\|High\|rt=\d+ suser=alice`, records[2])
+	assert.Contains(t, records[2], "req.http.status=500")
+	assert.Contains(t, records[2], "req.at=1699530320000")
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	h := NewSlogHandler(NewLogger(&recordingWriter{}, "v", "p", "1"), WithSlogLevel(slog.LevelWarn))
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+}
+
+func Test_slogSeverity(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug, LowSeverity},
+		{slog.LevelInfo, LowSeverity},
+		{slog.LevelWarn, MediumSeverity},
+		{slog.LevelError, HighSeverity},
+		{slog.LevelError + 4, VeryHighSeverity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, slogSeverity(tt.level))
+		})
+	}
+}
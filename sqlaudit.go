@@ -0,0 +1,519 @@
+package cefevent
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// SQLOperation classifies database operations for auditing. Values can be combined to select several operations.
+type SQLOperation uint
+
+const (
+	SQLDDL         SQLOperation = 1 << iota // Schema changes: CREATE, ALTER, DROP, TRUNCATE, RENAME, COMMENT
+	SQLPrivileged                           // Access control: GRANT, REVOKE, and user/role management
+	SQLWrite                                // Data changes: INSERT, UPDATE, DELETE, MERGE, REPLACE, UPSERT
+	SQLRead                                 // Queries: SELECT, SHOW, DESCRIBE, EXPLAIN, WITH
+	SQLAuthFailure                          // Failed connection attempts, see WithSQLAuthFailureFunc
+	SQLOther                                // Anything else, e.g. transaction control or vendor specific statements
+
+	// SQLDefaultOperations are audited unless WithSQLOperations is used
+	SQLDefaultOperations = SQLDDL | SQLPrivileged | SQLAuthFailure
+)
+
+// Device event class IDs used for audited SQL operations
+const (
+	SQLDDLClassId         = "sql-ddl"
+	SQLPrivilegedClassId  = "sql-privileged"
+	SQLWriteClassId       = "sql-write"
+	SQLReadClassId        = "sql-read"
+	SQLAuthFailureClassId = "sql-auth-failure"
+	SQLOtherClassId       = "sql-other"
+)
+
+// sqlEventClasses maps operations to their event class
+var sqlEventClasses = map[SQLOperation]EventClass{
+	SQLDDL:         {Id: SQLDDLClassId, Name: "database schema change", Severity: MediumSeverity},
+	SQLPrivileged:  {Id: SQLPrivilegedClassId, Name: "database privilege change", Severity: HighSeverity},
+	SQLWrite:       {Id: SQLWriteClassId, Name: "database write", Severity: LowSeverity},
+	SQLRead:        {Id: SQLReadClassId, Name: "database read", Severity: LowSeverity},
+	SQLAuthFailure: {Id: SQLAuthFailureClassId, Name: "database authentication failure", Severity: MediumSeverity},
+	SQLOther:       {Id: SQLOtherClassId, Name: "database statement", Severity: LowSeverity},
+}
+
+// ClassifySQL returns the operation performed by query, based on its leading keywords
+func ClassifySQL(query string) SQLOperation {
+	words := strings.Fields(strings.ToUpper(stripSQLComments(query)))
+	if len(words) == 0 {
+		return SQLOther
+	}
+	second := ""
+	if len(words) > 1 {
+		second = words[1]
+	}
+	switch words[0] {
+	case "GRANT", "REVOKE":
+		return SQLPrivileged
+	case "CREATE", "ALTER", "DROP":
+		switch second {
+		case "USER", "ROLE", "LOGIN", "GROUP":
+			return SQLPrivileged
+		}
+		return SQLDDL
+	case "TRUNCATE", "RENAME", "COMMENT":
+		return SQLDDL
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "UPSERT":
+		return SQLWrite
+	case "SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "WITH", "VALUES", "TABLE":
+		return SQLRead
+	case "SET":
+		if second == "PASSWORD" || second == "ROLE" {
+			return SQLPrivileged
+		}
+	}
+	return SQLOther
+}
+
+// stripSQLComments removes leading -- and /* */ comments, which often carry query tags
+func stripSQLComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			i := strings.IndexByte(query, '\n')
+			if i < 0 {
+				return ""
+			}
+			query = query[i+1:]
+		case strings.HasPrefix(query, "/*"):
+			i := strings.Index(query, "*/")
+			if i < 0 {
+				return ""
+			}
+			query = query[i+2:]
+		default:
+			return query
+		}
+	}
+}
+
+// SQLAuditOption configures an audited database driver
+type SQLAuditOption func(a *sqlAuditor)
+
+// WithSQLOperations selects which operations are audited. Defaults to SQLDefaultOperations
+func WithSQLOperations(ops SQLOperation) SQLAuditOption {
+	return func(a *sqlAuditor) {
+		a.operations = ops
+	}
+}
+
+// WithSQLDestination sets the destination fields identifying the database on audit events, e.g.
+// WithSQLDestination("postgres", "db.example.com", 5432). A zero port is omitted.
+func WithSQLDestination(serviceName, hostName string, port uint) SQLAuditOption {
+	return func(a *sqlAuditor) {
+		a.serviceName = serviceName
+		a.hostName = hostName
+		if port != 0 {
+			a.port = &port
+		}
+	}
+}
+
+// WithSQLAuthFailureFunc sets how connection errors are identified as authentication failures. By default every
+// failed connection attempt is treated as one, as drivers don't report authentication errors consistently.
+func WithSQLAuthFailureFunc(fn func(err error) bool) SQLAuditOption {
+	return func(a *sqlAuditor) {
+		a.isAuthFailure = fn
+	}
+}
+
+// WrapSQLDriver wraps a database/sql driver so that selected operations are logged as CEF events to l. Register the
+// result with sql.Register, or use WrapSQLConnector with sql.OpenDB. Statements are logged as the event message, with
+// literals and passwords redacted from privileged statements.
+func WrapSQLDriver(d driver.Driver, l *Logger, opts ...SQLAuditOption) driver.Driver {
+	return &auditedDriver{Driver: d, auditor: newSQLAuditor(l, opts)}
+}
+
+// WrapSQLConnector wraps a database/sql connector so that selected operations are logged as CEF events to l. Use the
+// result with sql.OpenDB.
+func WrapSQLConnector(c driver.Connector, l *Logger, opts ...SQLAuditOption) driver.Connector {
+	a := newSQLAuditor(l, opts)
+	return &auditedConnector{Connector: c, driver: &auditedDriver{Driver: c.Driver(), auditor: a}, auditor: a}
+}
+
+// sqlAuditor logs audit events for a wrapped driver
+type sqlAuditor struct {
+	logger        *Logger
+	operations    SQLOperation
+	serviceName   string
+	hostName      string
+	port          *uint
+	isAuthFailure func(err error) bool
+}
+
+func newSQLAuditor(l *Logger, opts []SQLAuditOption) *sqlAuditor {
+	a := &sqlAuditor{logger: l, operations: SQLDefaultOperations}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// statement logs a statement if its operation is audited. Statements the driver skipped are not logged, as
+// database/sql retries them by another path which is audited instead.
+func (a *sqlAuditor) statement(query string, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	op := ClassifySQL(query)
+	if a.operations&op == 0 {
+		return
+	}
+	ext := a.extensions(err)
+	ext.Message = query
+	if op == SQLPrivileged {
+		ext.Message = redactSQLSecrets(query)
+	}
+	a.log(op, ext)
+}
+
+// redactSQLSecrets replaces the string literals in query with '***', along with the value following PASSWORD or
+// IDENTIFIED BY, which some databases accept unquoted. Used for privileged statements, which may set passwords.
+func redactSQLSecrets(query string) string {
+	var b strings.Builder
+	var prev string
+	secretNext := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i = skipSQLString(query, i)
+			b.WriteString("'***'")
+			secretNext = false
+		case c == '$' && dollarQuoteTag(query[i:]) != "":
+			tag := dollarQuoteTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += 2*len(tag) + end
+			}
+			b.WriteString("'***'")
+			secretNext = false
+		case isSQLWordByte(c) || c == '"' || c == '`':
+			start := i
+			if c == '"' || c == '`' {
+				if end := strings.IndexByte(query[i+1:], c); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(query)
+				}
+			} else {
+				for i < len(query) && isSQLWordByte(query[i]) {
+					i++
+				}
+			}
+			word, upper := query[start:i], strings.ToUpper(query[start:i])
+			if secretNext && !sqlPasswordKeywords[upper] {
+				b.WriteString("***")
+				secretNext = false
+				continue
+			}
+			b.WriteString(word)
+			secretNext = upper == "PASSWORD" || (upper == "BY" && prev == "IDENTIFIED")
+			prev = upper
+		default:
+			if c != '=' && c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				secretNext = false
+			}
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// sqlPasswordKeywords may follow PASSWORD without being a password, e.g. MySQL's SET PASSWORD FOR & PASSWORD EXPIRE
+var sqlPasswordKeywords = map[string]bool{"FOR": true, "EXPIRE": true, "HISTORY": true, "REUSE": true, "REQUIRE": true}
+
+// skipSQLString returns the index after the single quoted string starting at query[i]. Doubled quotes and backslash
+// escapes are both skipped, so a string is never cut short, at worst over-redacting.
+func skipSQLString(query string, i int) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarQuoteTag returns the PostgreSQL dollar quote tag s starts with, e.g. "$$" or "$body$", or "" if it doesn't
+// start with one. Positional parameters such as $1 aren't tags.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isSQLWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// connectFailure logs a failed connection attempt if it is an audited authentication failure
+func (a *sqlAuditor) connectFailure(err error) {
+	if err == nil || a.operations&SQLAuthFailure == 0 {
+		return
+	}
+	if a.isAuthFailure != nil && !a.isAuthFailure(err) {
+		return
+	}
+	a.log(SQLAuthFailure, a.extensions(err))
+}
+
+func (a *sqlAuditor) extensions(err error) Extensions {
+	ext := Extensions{
+		DestinationServiceName: a.serviceName,
+		DestinationHostName:    a.hostName,
+		DestinationPort:        a.port,
+		Outcome:                "success",
+	}
+	if err != nil {
+		ext.Outcome = "failure"
+		ext.Reason = err.Error()
+	}
+	return ext
+}
+
+func (a *sqlAuditor) log(op SQLOperation, ext Extensions) {
+	ec := sqlEventClasses[op]
+	if err := a.logger.Log(ec.Id, ec.Name, ec.Severity, ext); err != nil {
+		a.logger.handleError(err)
+	}
+}
+
+type auditedDriver struct {
+	driver.Driver
+	auditor *sqlAuditor
+}
+
+func (d *auditedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		d.auditor.connectFailure(err)
+		return nil, err
+	}
+	return &auditedConn{Conn: c, auditor: d.auditor}, nil
+}
+
+func (d *auditedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &auditedConnector{Connector: c, driver: d, auditor: d.auditor}, nil
+	}
+	return &auditedConnector{Connector: dsnConnector{name: name, driver: d.Driver}, driver: d, auditor: d.auditor}, nil
+}
+
+// dsnConnector adapts a driver without connector support, as database/sql does internally
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type auditedConnector struct {
+	driver.Connector
+	driver  *auditedDriver
+	auditor *sqlAuditor
+}
+
+func (c *auditedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		c.auditor.connectFailure(err)
+		return nil, err
+	}
+	return &auditedConn{Conn: conn, auditor: c.auditor}, nil
+}
+
+func (c *auditedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// auditedConn wraps a driver connection. Optional interfaces are always implemented and fall back to the behaviour
+// database/sql uses when the underlying connection doesn't support them.
+type auditedConn struct {
+	driver.Conn
+	auditor *sqlAuditor
+}
+
+func (c *auditedConn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &auditedStmt{Stmt: s, query: query, auditor: c.auditor}, nil
+}
+
+func (c *auditedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	s, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &auditedStmt{Stmt: s, query: query, auditor: c.auditor}, nil
+}
+
+func (c *auditedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	r, err := ec.ExecContext(ctx, query, args)
+	c.auditor.statement(query, err)
+	return r, err
+}
+
+func (c *auditedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	r, err := qc.QueryContext(ctx, query, args)
+	c.auditor.statement(query, err)
+	return r, err
+}
+
+func (c *auditedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	// Begin can't apply options, so refuse them rather than silently starting a default transaction, as database/sql does
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	return c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx, as database/sql does
+}
+
+func (c *auditedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *auditedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *auditedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *auditedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// auditedStmt wraps a prepared statement, auditing each execution
+type auditedStmt struct {
+	driver.Stmt
+	query   string
+	auditor *sqlAuditor
+}
+
+func (s *auditedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	r, err := s.Stmt.Exec(args) //nolint:staticcheck // implementing the deprecated interface method
+	s.auditor.statement(s.query, err)
+	return r, err
+}
+
+func (s *auditedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	r, err := s.Stmt.Query(args) //nolint:staticcheck // implementing the deprecated interface method
+	s.auditor.statement(s.query, err)
+	return r, err
+}
+
+func (s *auditedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		r, err := ec.ExecContext(ctx, args)
+		s.auditor.statement(s.query, err)
+		return r, err
+	}
+	values, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Exec(values)
+}
+
+func (s *auditedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		r, err := qc.QueryContext(ctx, args)
+		s.auditor.statement(s.query, err)
+		return r, err
+	}
+	values, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(values)
+}
+
+func (s *auditedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValuesToValues converts arguments for drivers without context support, which can't take named parameters
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,239 @@
+package cefevent
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifySQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  SQLOperation
+	}{
+		{"CREATE TABLE users (id int)", SQLDDL},
+		{"  drop index idx", SQLDDL},
+		{"/* tag */ ALTER TABLE t ADD c int", SQLDDL},
+		{"-- migration\ntruncate t", SQLDDL},
+		{"GRANT SELECT ON t TO bob", SQLPrivileged},
+		{"create user bob", SQLPrivileged},
+		{"ALTER ROLE admin WITH LOGIN", SQLPrivileged},
+		{"SET PASSWORD FOR bob = 'x'", SQLPrivileged},
+		{"INSERT INTO t VALUES (1)", SQLWrite},
+		{"select 1", SQLRead},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", SQLRead},
+		{"BEGIN", SQLOther},
+		{"", SQLOther},
+		{"/* unterminated", SQLOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifySQL(tt.query))
+		})
+	}
+}
+
+func Test_redactSQLSecrets(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"GRANT SELECT ON t TO bob", "GRANT SELECT ON t TO bob"},
+		{"ALTER USER bob WITH PASSWORD 's3cr''et'", "ALTER USER bob WITH PASSWORD '***'"},
+		{"CREATE USER 'bob'@'%' IDENTIFIED BY \"pw\"", "CREATE USER '***'@'***' IDENTIFIED BY ***"},
+		{"CREATE USER bob IDENTIFIED BY hunter2 DEFAULT TABLESPACE users", "CREATE USER bob IDENTIFIED BY *** DEFAULT TABLESPACE users"},
+		{"SET PASSWORD FOR bob = 'x\\'y'", "SET PASSWORD FOR bob = '***'"},
+		{"ALTER USER bob PASSWORD EXPIRE", "ALTER USER bob PASSWORD EXPIRE"},
+		{"ALTER LOGIN sa WITH PASSWORD = 'pw', CHECK_POLICY = OFF", "ALTER LOGIN sa WITH PASSWORD = '***', CHECK_POLICY = OFF"},
+		{"ALTER ROLE app PASSWORD $tag$p$w$tag$", "ALTER ROLE app PASSWORD '***'"},
+		{"ALTER ROLE app PASSWORD $1", "ALTER ROLE app PASSWORD $1"},
+		{"ALTER USER bob PASSWORD 'unterminated", "ALTER USER bob PASSWORD '***'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactSQLSecrets(tt.query))
+		})
+	}
+}
+
+// fakeDriver is a minimal driver which records statements and fails those listed in failing
+type fakeDriver struct {
+	openErr error
+	failing map[string]error
+	execs   []string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	if d.openErr != nil {
+		return nil, d.openErr
+	}
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c: c, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.d.execs = append(c.d.execs, query)
+	if err := c.d.failing[query]; err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, nil)
+}
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"n"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func TestWrapSQLDriver(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	d := &fakeDriver{failing: map[string]error{"DROP TABLE t": errors.New("permission denied")}}
+	db := sql.OpenDB(WrapSQLConnector(dsnConnector{driver: d}, l,
+		WithSQLDestination("postgres", "db.example.com", 5432),
+	))
+	defer db.Close()
+
+	_, err := db.Exec("CREATE TABLE t (id int)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	_, err = db.Exec("GRANT ALL ON t TO bob")
+	require.NoError(t, err)
+	_, err = db.Exec("DROP TABLE t")
+	require.Error(t, err)
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	records := w.Records()
+	require.Len(t, records, 3)
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|sql-ddl\|database schema change\|Medium\|msg=CREATE TABLE t \(id int\) outcome=success`, records[0])
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|sql-privileged\|database privilege change\|High\|msg=GRANT ALL ON t TO bob`, records[1])
+	assert.Regexp(t, `^CEF:1\|testVendor\|testProduct\|1.0\|sql-ddl\|.*outcome=failure reason=permission denied`, records[2])
+	for _, r := range records {
+		assert.Contains(t, r, "destinationServiceName=postgres")
+		assert.Contains(t, r, "dhost=db.example.com")
+		assert.Contains(t, r, "dpt=5432")
+	}
+}
+
+func TestWrapSQLDriver_redactsPrivileged(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	db := sql.OpenDB(WrapSQLConnector(dsnConnector{driver: &fakeDriver{}}, l, WithSQLOperations(SQLPrivileged|SQLWrite)))
+	defer db.Close()
+
+	_, err := db.Exec("ALTER USER bob WITH PASSWORD 'hunter2'")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t VALUES ('kept')")
+	require.NoError(t, err)
+
+	records := w.Records()
+	require.Len(t, records, 2)
+	assert.Contains(t, records[0], "msg=ALTER USER bob WITH PASSWORD '***'")
+	assert.Contains(t, records[1], "msg=INSERT INTO t VALUES ('kept')")
+}
+
+func TestWrapSQLDriver_BeginTx(t *testing.T) {
+	db := sql.OpenDB(WrapSQLConnector(dsnConnector{driver: &fakeDriver{}}, NewLogger(io.Discard, "v", "p", "1")))
+	defer db.Close()
+	tests := []struct {
+		name string
+		opts *sql.TxOptions
+		want string
+	}{
+		{"default", nil, "not supported"},
+		{"isolation", &sql.TxOptions{Isolation: sql.LevelSerializable}, "non-default isolation level"},
+		{"read only", &sql.TxOptions{ReadOnly: true}, "read-only transactions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.BeginTx(context.Background(), tt.opts)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestWrapSQLDriver_preparedStatements(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+	d := &fakeDriver{}
+	db := sql.OpenDB(WrapSQLConnector(dsnConnector{driver: d}, l, WithSQLOperations(SQLWrite|SQLRead)))
+	defer db.Close()
+
+	stmt, err := db.Prepare("UPDATE t SET n = 1")
+	require.NoError(t, err)
+	_, err = stmt.Exec()
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+	rows, err := db.Query("SELECT ?", 1)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	_, err = db.Exec("CREATE TABLE t (id int)")
+	require.NoError(t, err)
+
+	records := w.Records()
+	require.Len(t, records, 2)
+	assert.Contains(t, records[0], "|sql-write|database write|Low|msg=UPDATE t SET n \\= 1 outcome=success")
+	assert.Contains(t, records[1], "|sql-read|database read|Low|msg=SELECT ? outcome=success")
+}
+
+func TestWrapSQLDriver_authFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		isAuthFailure func(err error) bool
+		want          int
+	}{
+		{"default", nil, 1},
+		{"classified", func(err error) bool { return true }, 1},
+		{"not auth failure", func(err error) bool { return false }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &recordingWriter{}
+			l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader())
+			d := &fakeDriver{openErr: errors.New("password authentication failed")}
+			var opts []SQLAuditOption
+			if tt.isAuthFailure != nil {
+				opts = append(opts, WithSQLAuthFailureFunc(tt.isAuthFailure))
+			}
+			c, err := WrapSQLDriver(d, l, opts...).(driver.DriverContext).OpenConnector("")
+			require.NoError(t, err)
+			db := sql.OpenDB(c)
+			defer db.Close()
+			require.Error(t, db.Ping())
+
+			records := w.Records()
+			require.Len(t, records, tt.want)
+			if tt.want > 0 {
+				assert.Contains(t, records[0], "|sql-auth-failure|database authentication failure|Medium|outcome=failure reason=password authentication failed")
+			}
+		})
+	}
+}
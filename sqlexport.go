@@ -0,0 +1,136 @@
+package cefevent
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// SQLExporter writes events into a table of a SQL database, one row per event with the columns of ExportColumns, so
+// captured events can be queried with SQL without a SIEM, e.g. with a SQLite driver:
+//
+//	db, err := sql.Open("sqlite", "events.db")
+//	exporter, err := cefevent.NewSQLExporter(ctx, db, "events")
+//	err = cefevent.NewPipeline(cefevent.NewScanner(os.Stdin), exporter).Run(ctx)
+//	err = exporter.Close()
+//
+// The extras column holds JSON, so SQLite can query it with e.g. json_extract(extras, '$.cs1'). Statements use ?
+// placeholders, as SQLite and MySQL do. Events are inserted in batches, one transaction per batch, so Close must be
+// called to insert the last. Safe for concurrent use.
+type SQLExporter struct {
+	db     *sql.DB
+	insert string
+	batch  int
+
+	mu   sync.Mutex
+	rows [][]any
+}
+
+// SQLExporterOption configures an SQLExporter
+type SQLExporterOption func(x *SQLExporter)
+
+// WithSQLExportBatch inserts events n at a time. Defaults to 500
+func WithSQLExportBatch(n int) SQLExporterOption {
+	return func(x *SQLExporter) {
+		x.batch = max(n, 1)
+	}
+}
+
+// NewSQLExporter creates an SQLExporter writing to table, creating it with SQLExportSchema if it doesn't exist. The
+// exporter doesn't close db.
+func NewSQLExporter(ctx context.Context, db *sql.DB, table string, opts ...SQLExporterOption) (*SQLExporter, error) {
+	if _, err := db.ExecContext(ctx, SQLExportSchema(table)); err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(ExportColumns))
+	for i, c := range ExportColumns {
+		columns[i] = quoteSQLIdentifier(c.Name)
+	}
+	x := &SQLExporter{
+		db: db,
+		insert: "INSERT INTO " + quoteSQLIdentifier(table) + " (" + strings.Join(columns, ", ") + ") VALUES (" +
+			strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")",
+		batch: 500,
+	}
+	for _, opt := range opts {
+		opt(x)
+	}
+	return x, nil
+}
+
+// SQLExportSchema returns the SQLite statement creating table, if it doesn't exist, for an SQLExporter. Strings are
+// TEXT, and numbers and times, as milliseconds since the unix epoch, are INTEGER.
+func SQLExportSchema(table string) string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE IF NOT EXISTS " + quoteSQLIdentifier(table) + " (")
+	for i, c := range ExportColumns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteSQLIdentifier(c.Name))
+		if c.Kind == ExportString {
+			b.WriteString(" TEXT")
+		} else {
+			b.WriteString(" INTEGER")
+		}
+		if !c.Optional {
+			b.WriteString(" NOT NULL")
+		}
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// WriteEvent adds e to the batch, inserting the batch once full. If inserting fails the batch is discarded and the
+// error returned.
+func (x *SQLExporter) WriteEvent(ctx context.Context, e Event) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.rows = append(x.rows, ExportValues(e))
+	if len(x.rows) < x.batch {
+		return nil
+	}
+	return x.flush(ctx)
+}
+
+// Flush inserts the events waiting for their batch to fill
+func (x *SQLExporter) Flush(ctx context.Context) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.flush(ctx)
+}
+
+// Close inserts the events waiting for their batch to fill. It doesn't close the database.
+func (x *SQLExporter) Close() error {
+	return x.Flush(context.Background())
+}
+
+func (x *SQLExporter) flush(ctx context.Context) error {
+	if len(x.rows) == 0 {
+		return nil
+	}
+	rows := x.rows
+	x.rows = nil
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, x.insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// quoteSQLIdentifier quotes a table or column name
+func quoteSQLIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,114 @@
+package cefevent
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportDriver is a minimal driver which records the statements executed and transactions committed, failing
+// statements containing failing or with it as an argument
+type exportDriver struct {
+	failing   string
+	execs     []string
+	args      [][]driver.Value
+	commits   int
+	rollbacks int
+}
+
+func (d *exportDriver) Open(string) (driver.Conn, error) {
+	return &exportConn{d: d}, nil
+}
+
+type exportConn struct{ d *exportDriver }
+
+func (c *exportConn) Prepare(query string) (driver.Stmt, error) {
+	return &exportStmt{d: c.d, query: query}, nil
+}
+func (c *exportConn) Close() error              { return nil }
+func (c *exportConn) Begin() (driver.Tx, error) { return exportTx{c.d}, nil }
+
+type exportTx struct{ d *exportDriver }
+
+func (tx exportTx) Commit() error   { tx.d.commits++; return nil }
+func (tx exportTx) Rollback() error { tx.d.rollbacks++; return nil }
+
+type exportStmt struct {
+	d     *exportDriver
+	query string
+}
+
+func (s *exportStmt) Close() error  { return nil }
+func (s *exportStmt) NumInput() int { return -1 }
+func (s *exportStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if f := s.d.failing; f != "" && (strings.Contains(s.query, f) || slices.Contains(args, driver.Value(f))) {
+		return nil, errors.New("constraint failed")
+	}
+	s.d.execs = append(s.d.execs, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+func (s *exportStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestSQLExporter(t *testing.T) {
+	d := &exportDriver{}
+	db := sql.OpenDB(dsnConnector{driver: d})
+	defer db.Close()
+	ctx := context.Background()
+	x, err := NewSQLExporter(ctx, db, `cef"events`, WithSQLExportBatch(2))
+	require.NoError(t, err)
+	require.Len(t, d.execs, 1)
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS "cef""events" ("version" INTEGER NOT NULL, `+
+		`"deviceVendor" TEXT NOT NULL, "deviceProduct" TEXT NOT NULL, "deviceVersion" TEXT NOT NULL, `+
+		`"deviceEventClassId" TEXT NOT NULL, `+
+		`"name" TEXT NOT NULL, "severity" TEXT NOT NULL, "rt" INTEGER, "src" TEXT, "spt" INTEGER, "shost" TEXT, `+
+		`"suser" TEXT, "dst" TEXT, "dpt" INTEGER, "dhost" TEXT, "duser" TEXT, "proto" TEXT, "act" TEXT, `+
+		`"outcome" TEXT, "msg" TEXT, "extras" TEXT NOT NULL)`, d.execs[0])
+
+	p := NewPipeline(NewScanner(strings.NewReader(pipelineInput)), x)
+	require.NoError(t, p.Run(ctx))
+	// The first two events are inserted as a batch, the third waits for Close
+	assert.Equal(t, 1, d.commits)
+	require.NoError(t, x.Close())
+	assert.Equal(t, 2, d.commits)
+	require.Len(t, d.execs, 4)
+	assert.True(t, strings.HasPrefix(d.execs[1], `INSERT INTO "cef""events" ("version", "deviceVendor", `), d.execs[1])
+	assert.True(t, strings.HasSuffix(d.execs[1], `"extras") VALUES (`+strings.Repeat("?, ", 20)+"?)"), d.execs[1])
+	var names []driver.Value
+	for _, args := range d.args[1:] {
+		names = append(names, args[5])
+	}
+	assert.Equal(t, []driver.Value{"one", "two", "three"}, names)
+	require.NoError(t, x.Close())
+	assert.Equal(t, 2, d.commits)
+}
+
+func TestSQLExporter_error(t *testing.T) {
+	d := &exportDriver{failing: "bad"}
+	db := sql.OpenDB(dsnConnector{driver: d})
+	defer db.Close()
+	ctx := context.Background()
+	x, err := NewSQLExporter(ctx, db, "events", WithSQLExportBatch(2))
+	require.NoError(t, err)
+	require.NoError(t, x.WriteEvent(ctx, Event{Name: "bad"}))
+	assert.EqualError(t, x.WriteEvent(ctx, Event{Name: "good"}), "constraint failed")
+	assert.Equal(t, []int{0, 1}, []int{d.commits, d.rollbacks})
+
+	// The failed batch is discarded
+	require.NoError(t, x.WriteEvent(ctx, Event{Name: "good"}))
+	require.NoError(t, x.Flush(ctx))
+	assert.Equal(t, 1, d.commits)
+	assert.Equal(t, "good", d.args[len(d.args)-1][5])
+
+	_, err = NewSQLExporter(ctx, db, "bad")
+	assert.EqualError(t, err, "constraint failed")
+}
@@ -0,0 +1,224 @@
+package cefevent
+
+import (
+	"cmp"
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EventStore keeps events in memory, indexed by device event class ID, source & destination address and time, so
+// recent events can be looked up by equality or range, e.g. to correlate them or answer ad hoc queries. It's an
+// EventWriter, so a Pipeline can fill it. Events are kept until Reset. Safe for concurrent use.
+type EventStore struct {
+	mu           sync.RWMutex
+	events       []Event
+	classes      map[string][]int // indexes of events by class ID, ascending
+	sources      []storeAddr      // sorted by address
+	destinations []storeAddr      // sorted by address
+	times        []storeTime      // sorted by time
+}
+
+// storeAddr indexes an event by address
+type storeAddr struct {
+	addr netip.Addr
+	i    int
+}
+
+// storeTime indexes an event by time
+type storeTime struct {
+	t time.Time
+	i int
+}
+
+// StoreQuery selects events from an EventStore. Unset fields match every event; events must match every set field.
+type StoreQuery struct {
+	ClassIDs []string // Device event class IDs, any of which match
+	// Source & Destination match events with an address in the range, or with a single address prefix such as
+	// 10.0.0.1/32 or netip.PrefixFrom(addr, addr.BitLen()) that address
+	Source      netip.Prefix
+	Destination netip.Prefix
+	// Since & Until match events from Since up to but excluding Until, by their device receipt time, or else their end
+	// or start time. Events with none of these never match a time range.
+	Since, Until time.Time
+	Match        Predicate // Matches any other condition, e.g. a query from CompileQuery
+	Limit        int       // The most events to return, or 0 for all
+}
+
+// NewEventStore creates an empty EventStore
+func NewEventStore() *EventStore {
+	return &EventStore{classes: make(map[string][]int)}
+}
+
+// Add stores e. The store shares e's CustomExtensions, so they mustn't be modified afterwards.
+func (s *EventStore) Add(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := len(s.events)
+	s.events = append(s.events, e)
+	s.classes[e.DeviceEventClassId] = append(s.classes[e.DeviceEventClassId], i)
+	if addr, ok := storeAddrOf(e.Extensions.SourceAddress); ok {
+		s.sources = insertSorted(s.sources, storeAddr{addr, i}, func(a storeAddr) netip.Addr { return a.addr },
+			netip.Addr.Compare)
+	}
+	if addr, ok := storeAddrOf(e.Extensions.DestinationAddress); ok {
+		s.destinations = insertSorted(s.destinations, storeAddr{addr, i}, func(a storeAddr) netip.Addr { return a.addr },
+			netip.Addr.Compare)
+	}
+	if t := storeTimeOf(&e); !t.IsZero() {
+		s.times = insertSorted(s.times, storeTime{t, i}, func(t storeTime) time.Time { return t.t }, time.Time.Compare)
+	}
+}
+
+// WriteEvent stores e, so an EventStore can be a Pipeline's EventWriter
+func (s *EventStore) WriteEvent(_ context.Context, e Event) error {
+	s.Add(e)
+	return nil
+}
+
+// Len returns the number of events stored
+func (s *EventStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.events)
+}
+
+// Reset removes every event
+func (s *EventStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events, s.sources, s.destinations, s.times = nil, nil, nil, nil
+	clear(s.classes)
+}
+
+// Find returns the events matching q in the order they were added. It looks up the narrowest of the indexed
+// conditions, then checks each event it finds against the rest.
+func (s *EventStore) Find(q StoreQuery) []Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidates, indexed := []int(nil), false
+	narrow := func(found []int) {
+		if !indexed || len(found) < len(candidates) {
+			candidates, indexed = found, true
+		}
+	}
+	if len(q.ClassIDs) > 0 {
+		var found []int
+		for _, id := range slices.Compact(slices.Sorted(slices.Values(q.ClassIDs))) {
+			found = append(found, s.classes[id]...)
+		}
+		narrow(found)
+	}
+	if q.Source.IsValid() {
+		narrow(addrRange(s.sources, q.Source))
+	}
+	if q.Destination.IsValid() {
+		narrow(addrRange(s.destinations, q.Destination))
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		narrow(s.timeRange(q.Since, q.Until))
+	}
+	if indexed {
+		slices.Sort(candidates)
+	} else {
+		candidates = make([]int, len(s.events))
+		for i := range candidates {
+			candidates[i] = i
+		}
+	}
+
+	var events []Event
+	for _, i := range candidates {
+		if q.Limit > 0 && len(events) == q.Limit {
+			break
+		}
+		if e := &s.events[i]; q.matches(e) {
+			events = append(events, *e)
+		}
+	}
+	return events
+}
+
+// matches reports whether e matches every condition of q
+func (q *StoreQuery) matches(e *Event) bool {
+	if len(q.ClassIDs) > 0 && !slices.Contains(q.ClassIDs, e.DeviceEventClassId) {
+		return false
+	}
+	for _, c := range []struct {
+		prefix netip.Prefix
+		ip     net.IP
+	}{{q.Source, e.Extensions.SourceAddress}, {q.Destination, e.Extensions.DestinationAddress}} {
+		if c.prefix.IsValid() {
+			if addr, ok := storeAddrOf(c.ip); !ok || !c.prefix.Contains(addr) {
+				return false
+			}
+		}
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		t := storeTimeOf(e)
+		if t.IsZero() || t.Before(q.Since) || (!q.Until.IsZero() && !t.Before(q.Until)) {
+			return false
+		}
+	}
+	return q.Match == nil || q.Match(e)
+}
+
+// addrRange returns the indexes of the events with an address within prefix
+func addrRange(index []storeAddr, prefix netip.Prefix) []int {
+	prefix = prefix.Masked()
+	lo, _ := slices.BinarySearchFunc(index, prefix.Addr(), func(a storeAddr, addr netip.Addr) int {
+		return a.addr.Compare(addr)
+	})
+	var found []int
+	for _, a := range index[lo:] {
+		if !prefix.Contains(a.addr) {
+			break
+		}
+		found = append(found, a.i)
+	}
+	return found
+}
+
+// timeRange returns the indexes of the events from since up to until, or the end of time if until is zero
+func (s *EventStore) timeRange(since, until time.Time) []int {
+	search := func(t time.Time) int {
+		i, _ := slices.BinarySearchFunc(s.times, t, func(st storeTime, t time.Time) int { return st.t.Compare(t) })
+		return i
+	}
+	lo, hi := search(since), len(s.times)
+	if !until.IsZero() {
+		hi = max(search(until), lo)
+	}
+	found := make([]int, 0, hi-lo)
+	for _, t := range s.times[lo:hi] {
+		found = append(found, t.i)
+	}
+	return found
+}
+
+// insertSorted inserts v into s, after any equal values, keeping s sorted by key
+func insertSorted[T, K any](s []T, v T, key func(T) K, compare func(a, b K) int) []T {
+	// Treating equal values as less finds the first greater value, so events with equal keys stay in order
+	i, _ := slices.BinarySearchFunc(s, key(v), func(e T, k K) int { return cmp.Or(compare(key(e), k), -1) })
+	return slices.Insert(s, i, v)
+}
+
+// storeAddrOf returns ip as a netip.Addr, unmapping IPv4 addresses held as IPv6
+func storeAddrOf(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	return addr.Unmap(), ok
+}
+
+// storeTimeOf returns the time the EventStore indexes e by
+func storeTimeOf(e *Event) time.Time {
+	for _, t := range []time.Time{e.Extensions.DeviceReceiptTime, e.Extensions.EndTime, e.Extensions.StartTime} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
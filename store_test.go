@@ -0,0 +1,109 @@
+package cefevent
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeInput are events at one minute intervals from 2023-11-09T11:45:00Z, bar "five" which has no time
+const storeInput = `CEF:0|v|p|1|100|one|Low|src=10.0.0.1 dst=192.0.2.1 rt=1699530300000
+CEF:0|v|p|1|200|two|High|src=10.0.0.2 dst=192.0.2.1 rt=1699530360000
+CEF:0|v|p|1|100|three|High|src=10.1.0.1 dst=2001:db8::1 end=1699530420000
+CEF:0|v|p|1|300|four|Low|src=192.0.2.1 start=1699530480000
+CEF:0|v|p|1|100|five|Low|dst=10.0.0.1
+`
+
+func newTestStore(t *testing.T) *EventStore {
+	t.Helper()
+	s := NewEventStore()
+	p := NewPipeline(NewScanner(strings.NewReader(storeInput)), s)
+	require.NoError(t, p.Run(context.Background()))
+	return s
+}
+
+func storeNames(events []Event) []string {
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestEventStore_Find(t *testing.T) {
+	s := newTestStore(t)
+	start := time.Date(2023, 11, 9, 11, 45, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		q    StoreQuery
+		want []string
+	}{
+		{"all", StoreQuery{}, []string{"one", "two", "three", "four", "five"}},
+		{"class_id", StoreQuery{ClassIDs: []string{"100"}}, []string{"one", "three", "five"}},
+		{"class_ids", StoreQuery{ClassIDs: []string{"300", "200", "300"}}, []string{"two", "four"}},
+		{"class_id_unknown", StoreQuery{ClassIDs: []string{"400"}}, nil},
+		{"source", StoreQuery{Source: netip.MustParsePrefix("10.0.0.2/32")}, []string{"two"}},
+		{"source_range", StoreQuery{Source: netip.MustParsePrefix("10.0.0.0/8")}, []string{"one", "two", "three"}},
+		{"source_unmasked_range", StoreQuery{Source: netip.MustParsePrefix("10.0.0.7/16")}, []string{"one", "two"}},
+		{"destination", StoreQuery{Destination: netip.MustParsePrefix("192.0.2.1/32")}, []string{"one", "two"}},
+		{"destination_ipv6", StoreQuery{Destination: netip.MustParsePrefix("2001:db8::/32")}, []string{"three"}},
+		{"since", StoreQuery{Since: start.Add(time.Minute)}, []string{"two", "three", "four"}},
+		{"until", StoreQuery{Until: start.Add(2 * time.Minute)}, []string{"one", "two"}},
+		{"between", StoreQuery{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)},
+			[]string{"two", "three"}},
+		{"empty_range", StoreQuery{Since: start.Add(time.Hour), Until: start}, nil},
+		{"combined", StoreQuery{ClassIDs: []string{"100", "200"}, Source: netip.MustParsePrefix("10.0.0.0/8"),
+			Since: start.Add(time.Minute)}, []string{"two", "three"}},
+		{"match", StoreQuery{Source: netip.MustParsePrefix("10.0.0.0/8"), Match: MatchSeverityAtLeast(HighSeverity)},
+			[]string{"two", "three"}},
+		{"limit", StoreQuery{ClassIDs: []string{"100"}, Limit: 2}, []string{"one", "three"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, storeNames(s.Find(tt.q)))
+		})
+	}
+}
+
+func TestEventStore_order(t *testing.T) {
+	s := NewEventStore()
+	base := time.Date(2023, 11, 9, 0, 0, 0, 0, time.UTC)
+	for i, offset := range []int{3, 1, 2, 1, 0} {
+		e := Event{Name: string(rune('a' + i))}
+		e.Extensions.DeviceReceiptTime = base.Add(time.Duration(offset) * time.Minute)
+		s.Add(e)
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, storeNames(s.Find(StoreQuery{Since: base.Add(time.Minute),
+		Until: base.Add(3 * time.Minute)})))
+	assert.Equal(t, 5, s.Len())
+
+	s.Reset()
+	assert.Equal(t, 0, s.Len())
+	assert.Empty(t, s.Find(StoreQuery{Since: base}))
+}
+
+func TestEventStore_concurrent(t *testing.T) {
+	s := NewEventStore()
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				e, err := Parse("CEF:0|v|p|1|100|n|Low|src=10.0.0." + string(rune('1'+i)))
+				assert.NoError(t, err)
+				s.Add(e)
+				s.Find(StoreQuery{Source: netip.MustParsePrefix("10.0.0.0/24")})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, s.Find(StoreQuery{Source: netip.MustParsePrefix("10.0.0.1/32")}), 100)
+	assert.Len(t, s.Find(StoreQuery{Source: netip.MustParsePrefix("10.0.0.0/24")}), 400)
+}
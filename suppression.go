@@ -0,0 +1,118 @@
+package cefevent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRepeatSuppression collapses identical events repeated within window of their first occurrence. The first event is
+// written immediately, later repeats are held back, and once the window elapses (or a different event is logged) a
+// single summary event is written with Type set to AggregatedEventType and BaseEventCount to the number of suppressed
+// repeats, even if that is 1. Flush & Close write any pending summary.
+func WithRepeatSuppression(window time.Duration) LoggerConfigOption {
+	return func(l *Logger) {
+		l.suppressor = &repeatSuppressor{window: window}
+	}
+}
+
+// repeatSuppressor tracks the most recently written event and how many times it has since been repeated
+type repeatSuppressor struct {
+	window time.Duration
+
+	mu                 sync.Mutex
+	key                string
+	dev                device
+	deviceEventClassId string
+	name               string
+	severity           string
+	extensions         Extensions
+	first              time.Time
+	repeats            int
+	timer              *time.Timer
+	generation         uint64
+}
+
+// suppress reports whether the event repeats the last one written and should be held back. Any pending summary for a
+// different, earlier event is written before returning false.
+func (s *repeatSuppressor) suppress(l *Logger, dev device, deviceEventClassId, name, severity string, extensions Extensions) bool {
+	key := dev.vendor + "|" + dev.product + "|" + dev.version + "|" + deviceEventClassId + "|" + name + "|" + severity + "|" + extensions.String()
+	count := extensions.BaseEventCount
+	if count < 1 {
+		count = 1
+	}
+	now := l.getTime()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key == s.key && now.Sub(s.first) < s.window {
+		s.repeats += count
+		if s.timer == nil {
+			gen := s.generation
+			s.timer = time.AfterFunc(s.first.Add(s.window).Sub(now), func() {
+				s.expire(l, gen)
+			})
+		}
+		return true
+	}
+	// A failed summary shouldn't fail whichever event happened to trigger the flush, so report it separately
+	if err := s.flushLocked(l); err != nil {
+		l.handleError(err)
+	}
+	s.key = key
+	s.dev = dev
+	s.deviceEventClassId = deviceEventClassId
+	s.name = name
+	s.severity = severity
+	s.extensions = extensions.Clone() // the caller may reuse its maps & pointers while repeats are held back
+	s.first = now
+	return false
+}
+
+// expire is called once the suppression window for generation gen has elapsed
+func (s *repeatSuppressor) expire(l *Logger, gen uint64) {
+	var err error
+	defer l.recoverPanic(&err) // runs on its own goroutine, where a panic would take down the host application
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen != s.generation {
+		return // superseded by a newer event
+	}
+	if err = s.flushLocked(l); err != nil {
+		l.handleError(err)
+	}
+	s.key = ""
+}
+
+// flush writes the summary event for any suppressed repeats
+func (s *repeatSuppressor) flush(l *Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked(l)
+}
+
+// flushLocked writes the summary event for any suppressed repeats and resets the repeat state. Must be called with
+// s.mu held.
+func (s *repeatSuppressor) flushLocked(l *Logger) error {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.generation++
+	if s.repeats == 0 {
+		return nil
+	}
+	summary := s.extensions
+	summary.Type = AggregatedEventType
+	summary.BaseEventCount = s.repeats
+	s.repeats = 0
+	return l.write(context.Background(), s.dev, s.deviceEventClassId, s.name, s.severity, summary)
+}
+
+// flushSuppressed writes any pending WithRepeatSuppression summary
+func (l *Logger) flushSuppressed() error {
+	if l.suppressor == nil {
+		return nil
+	}
+	return l.suppressor.flush(l)
+}
@@ -0,0 +1,87 @@
+package cefevent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWriter keeps each call to Write as a separate record
+type recordingWriter struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (r *recordingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, string(p))
+	return len(p), nil
+}
+
+func (r *recordingWriter) Records() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.records...)
+}
+
+func TestWithRepeatSuppression_differentEvent(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithRepeatSuppression(time.Hour))
+	l.getTime = testTime
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.LogLow("100", "login", Extensions{Outcome: "failure"}))
+	}
+	require.NoError(t, l.LogLow("101", "logout", Extensions{}))
+
+	assert.Equal(t, []string{
+		"CEF:1|testVendor|testProduct|1.0|100|login|Low|outcome=failure",
+		"CEF:1|testVendor|testProduct|1.0|100|login|Low|cnt=2 type=1 outcome=failure",
+		"CEF:1|testVendor|testProduct|1.0|101|logout|Low|",
+	}, w.Records())
+}
+
+func TestWithRepeatSuppression_windowExpires(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithRepeatSuppression(20*time.Millisecond))
+	l.getTime = testTime
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.LogHigh("200", "scan", Extensions{}))
+	}
+	require.Eventually(t, func() bool {
+		return len(w.Records()) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{
+		"CEF:1|testVendor|testProduct|1.0|200|scan|High|",
+		"CEF:1|testVendor|testProduct|1.0|200|scan|High|cnt=3 type=1",
+	}, w.Records())
+
+	// Once the window has been summarised the next repeat is written straight away
+	require.NoError(t, l.LogHigh("200", "scan", Extensions{}))
+	assert.Len(t, w.Records(), 3)
+}
+
+func TestWithRepeatSuppression_flush(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithRepeatSuppression(time.Hour))
+	l.getTime = testTime
+
+	custom := map[string]string{"user": "alice"}
+	require.NoError(t, l.LogLow("100", "login", Extensions{CustomExtensions: custom}))
+	custom["user"] = "bob" // reusing the map mustn't change the held back event
+	require.NoError(t, l.LogLow("100", "login", Extensions{CustomExtensions: map[string]string{"user": "alice"}}))
+	require.NoError(t, l.Flush())
+
+	assert.Equal(t, []string{
+		"CEF:1|testVendor|testProduct|1.0|100|login|Low|user=alice",
+		"CEF:1|testVendor|testProduct|1.0|100|login|Low|cnt=1 type=1 user=alice",
+	}, w.Records(), "a single repeat is still marked as a summary")
+
+	require.NoError(t, l.Close())
+	assert.Len(t, w.Records(), 2)
+}
@@ -0,0 +1,267 @@
+package cefevent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SyslogUnavailableErr error when writing to a SyslogWriter while it is waiting to reconnect
+var SyslogUnavailableErr = errors.New("syslog server unavailable")
+
+// SyslogFraming is how records are delimited on a stream transport, as described by RFC 6587
+type SyslogFraming int
+
+const (
+	NewlineFraming       SyslogFraming = iota // Each record is terminated by a newline
+	OctetCountingFraming                      // Each record is prefixed by its length, "LEN SP RECORD"
+)
+
+// SyslogWriter sends each record written to it to a syslog server, e.g. an ArcSight or QRadar collector, over UDP,
+// TCP or TLS, or to the local syslog daemon over a unix socket. Use it as a Logger's output. If the connection fails
+// it is re-established on the next Write, backing off exponentially while the server is unreachable; writes made while
+// backing off fail with SyslogUnavailableErr rather than blocking. It is safe to use from multiple goroutines.
+type SyslogWriter struct {
+	network     string
+	addr        string
+	tlsConfig   *tls.Config
+	framing     SyslogFraming
+	datagram    bool
+	dialTimeout time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxSize     int
+
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+	lastErr error
+	closed  bool
+}
+
+// SyslogOption configures a SyslogWriter
+type SyslogOption func(w *SyslogWriter)
+
+// WithSyslogFraming sets the framing used over TCP & TLS. Defaults to NewlineFraming for TCP and
+// OctetCountingFraming for TLS, per RFC 5425. Ignored for UDP, where each record is one datagram.
+func WithSyslogFraming(framing SyslogFraming) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.framing = framing
+	}
+}
+
+// WithSyslogDialTimeout sets the timeout for connecting, including the TLS handshake. Defaults to 10s
+func WithSyslogDialTimeout(d time.Duration) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.dialTimeout = d
+	}
+}
+
+// WithSyslogBackoff sets the delay before the first reconnection attempt after a failure, doubling with each further
+// failure up to max. Defaults to 1s and 1m
+func WithSyslogBackoff(min, max time.Duration) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.minBackoff = min
+		w.maxBackoff = max
+	}
+}
+
+// WithSyslogMaxSize truncates records longer than n bytes, for servers which reject or mangle longer messages, such as
+// syslog daemons limited to 8KiB. 0, the default, doesn't truncate except for NewLocalSyslogWriter.
+func WithSyslogMaxSize(n int) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.maxSize = n
+	}
+}
+
+// localSyslogPaths are the local syslog daemon's sockets on Linux, macOS & the BSDs
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// localSyslogMaxSize is the default message size limit of rsyslog & syslog-ng
+const localSyslogMaxSize = 8192
+
+// NewLocalSyslogWriter connects to the host's syslog daemon over its unix socket, e.g. /dev/log, preferring a datagram
+// socket. Records are truncated to 8KiB unless set with WithSyslogMaxSize. Add the syslog PRI with
+// WithSyslogPriority. If the daemon restarts, the socket is reconnected on the next Write.
+func NewLocalSyslogWriter(opts ...SyslogOption) (*SyslogWriter, error) {
+	opts = append([]SyslogOption{WithSyslogMaxSize(localSyslogMaxSize)}, opts...)
+	var errs []error
+	for _, path := range localSyslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			w, err := NewSyslogWriter(network, path, nil, opts...)
+			if err == nil {
+				return w, nil
+			}
+			errs = append(errs, err)
+		}
+	}
+	return nil, fmt.Errorf("no local syslog daemon: %w", errors.Join(errs...))
+}
+
+// NewSyslogWriter connects to the syslog server at addr. network is "udp", "tcp" or "tls", or "unixgram" or "unix" for
+// a local socket path; tlsConfig is only used for "tls", and may be nil to use the system roots. For mutual TLS set
+// the client certificate in tlsConfig.Certificates.
+func NewSyslogWriter(network, addr string, tlsConfig *tls.Config, opts ...SyslogOption) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network:     network,
+		addr:        addr,
+		tlsConfig:   tlsConfig,
+		dialTimeout: 10 * time.Second,
+		minBackoff:  time.Second,
+		maxBackoff:  time.Minute,
+	}
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		w.datagram = true
+	case "tcp", "tcp4", "tcp6", "unix":
+	case "tls":
+		w.framing = OctetCountingFraming
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	conn, err := w.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog server: %w", err)
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *SyslogWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.dialTimeout}
+	if w.network != "tls" {
+		return dialer.Dial(w.network, w.addr)
+	}
+	cfg := w.tlsConfig
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	if cfg.ServerName == "" {
+		cfg = cfg.Clone()
+		cfg.ServerName, _, _ = net.SplitHostPort(w.addr)
+	}
+	return tls.DialWithDialer(dialer, "tcp", w.addr, cfg)
+}
+
+// Write sends p as a single record, after removing any trailing newline
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteContext(context.Background(), p)
+}
+
+// WriteContext is Write, abandoning the write once ctx is done. An abandoned write may have sent part of the record,
+// so the connection is closed and reopened by the next write.
+func (w *SyslogWriter) WriteContext(ctx context.Context, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, net.ErrClosed
+	}
+	frame := w.frame(p)
+
+	// A stale connection often only fails on write, e.g. after the server restarts, so reconnect & retry once
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+		conn := w.conn
+		stop := context.AfterFunc(ctx, func() {
+			_ = conn.SetWriteDeadline(time.Unix(1, 0))
+		})
+		_, err := conn.Write(frame)
+		if !stop() || err != nil {
+			_ = conn.Close()
+			w.conn = nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, fmt.Errorf("failed to write to syslog server: %w", ctx.Err())
+			}
+			w.lastErr = err
+			continue
+		}
+		return len(p), nil
+	}
+	return 0, fmt.Errorf("failed to write to syslog server: %w", w.lastErr)
+}
+
+// frame returns the bytes sent for record p
+func (w *SyslogWriter) frame(p []byte) []byte {
+	p = trimRecord(p)
+	if w.maxSize > 0 && len(p) > w.maxSize {
+		p = truncateRecord(p, w.maxSize)
+	}
+	switch {
+	case w.datagram:
+		return p
+	case w.framing == OctetCountingFraming:
+		return appendOctetCounted(nil, p)
+	}
+	frame := make([]byte, 0, len(p)+1)
+	frame = append(frame, p...)
+	return append(frame, '\n')
+}
+
+// truncateRecord cuts p to at most n bytes, without splitting a UTF-8 character or leaving a dangling escape
+func truncateRecord(p []byte, n int) []byte {
+	for n > 0 && !utf8.RuneStart(p[n]) {
+		n--
+	}
+	p = p[:n]
+	escapes := 0
+	for i := len(p) - 1; i >= 0 && p[i] == '\\'; i-- {
+		escapes++
+	}
+	if escapes%2 == 1 {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// connect reconnects if there is no connection & the backoff has elapsed
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+	if time.Now().Before(w.retryAt) {
+		return fmt.Errorf("%w: %v", SyslogUnavailableErr, w.lastErr)
+	}
+	conn, err := w.dial()
+	if err != nil {
+		if w.backoff == 0 {
+			w.backoff = w.minBackoff
+		} else {
+			w.backoff = min(2*w.backoff, w.maxBackoff)
+		}
+		w.retryAt = time.Now().Add(w.backoff)
+		w.lastErr = err
+		return fmt.Errorf("failed to connect to syslog server: %w", err)
+	}
+	w.conn = conn
+	w.backoff = 0
+	return nil
+}
+
+// Close closes the connection, after which Write returns net.ErrClosed
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
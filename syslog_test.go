@@ -0,0 +1,332 @@
+package cefevent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptRecords accepts connections on ln, sending each record read to the returned channel
+func acceptRecords(t *testing.T, ln net.Listener, octetCounted bool) <-chan string {
+	records := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if tc, ok := conn.(*tls.Conn); ok {
+					assert.NoError(t, tc.Handshake())
+					assert.Len(t, tc.ConnectionState().PeerCertificates, 1)
+				}
+				var opts []ScannerOption
+				if octetCounted {
+					opts = append(opts, WithOctetCounting())
+				}
+				s := NewScanner(conn, opts...)
+				for s.Scan() {
+					records <- s.Text()
+				}
+			}()
+		}
+	}()
+	return records
+}
+
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestSyslogWriter_stream(t *testing.T) {
+	cert, pool := testCertificate(t)
+	tests := []struct {
+		name         string
+		network      string
+		opts         []SyslogOption
+		octetCounted bool
+	}{
+		{"tcp", "tcp", nil, false},
+		{"tcp_octet_counting", "tcp", []SyslogOption{WithSyslogFraming(OctetCountingFraming)}, true},
+		{"tls", "tls", nil, true},
+		{"tls_newline", "tls", []SyslogOption{WithSyslogFraming(NewlineFraming)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			var clientConfig *tls.Config
+			if tt.network == "tls" {
+				ln = tls.NewListener(ln, &tls.Config{
+					Certificates: []tls.Certificate{cert},
+					ClientAuth:   tls.RequireAnyClientCert,
+				})
+				clientConfig = &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{cert}}
+			}
+			defer ln.Close()
+			records := acceptRecords(t, ln, tt.octetCounted)
+
+			w, err := NewSyslogWriter(tt.network, ln.Addr().String(), clientConfig, tt.opts...)
+			require.NoError(t, err)
+			defer w.Close()
+			l := NewLogger(w, "v", "p", "1", WithStaticHostname("host"), WithTimeFunc(testTime))
+			require.NoError(t, l.LogLow("100", "first", Extensions{Message: "a\nb"}))
+			require.NoError(t, l.LogLow("100", "second", Extensions{}))
+
+			assert.Contains(t, <-records, "|first|")
+			assert.Contains(t, <-records, "|second|")
+		})
+	}
+}
+
+func TestSyslogWriter_udp(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	w, err := NewSyslogWriter("udp", pc.LocalAddr().String(), nil)
+	require.NoError(t, err)
+	defer w.Close()
+	n, err := w.Write([]byte("CEF:1|v|p|1|100|test|Low|\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 26, n)
+
+	buf := make([]byte, 1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err = pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", string(buf[:n]))
+}
+
+func TestSyslogWriter_reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	w, err := NewSyslogWriter("tcp", addr, nil, WithSyslogBackoff(time.Hour, time.Hour))
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Drop the first connection & stop listening, so writes fail then reconnecting fails
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, ln.Close())
+	assert.Eventually(t, func() bool {
+		_, err := w.Write([]byte("test"))
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = w.Write([]byte("test"))
+	assert.ErrorIs(t, err, SyslogUnavailableErr)
+
+	// Once the backoff has elapsed the next write reconnects
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+	records := acceptRecords(t, ln, false)
+	w.mu.Lock()
+	w.retryAt = time.Time{}
+	w.mu.Unlock()
+	_, err = w.Write([]byte("reconnected"))
+	require.NoError(t, err)
+	assert.Equal(t, "reconnected", <-records)
+}
+
+func TestSyslogWriter_frame(t *testing.T) {
+	tests := []struct {
+		name   string
+		w      *SyslogWriter
+		record string
+		want   string
+	}{
+		{"newline", &SyslogWriter{}, "CEF:1|a\n", "CEF:1|a\n"},
+		{"newline_added", &SyslogWriter{}, "CEF:1|a", "CEF:1|a\n"},
+		{"octet_counting", &SyslogWriter{framing: OctetCountingFraming}, "CEF:1|a\r\n", "7 CEF:1|a"},
+		{"datagram", &SyslogWriter{datagram: true, framing: OctetCountingFraming}, "CEF:1|a\n", "CEF:1|a"},
+		{"truncated", &SyslogWriter{datagram: true, maxSize: 5}, "CEF:1|a\n", "CEF:1"},
+		{"truncated_multibyte", &SyslogWriter{datagram: true, maxSize: 5}, "CEF:ñ\n", "CEF:"},
+		{"truncated_escape", &SyslogWriter{datagram: true, maxSize: 6}, `CEF:1\|a`, "CEF:1"},
+		{"truncated_escaped_backslash", &SyslogWriter{datagram: true, maxSize: 7}, `CEF:1\\a`, `CEF:1\\`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(tt.w.frame([]byte(tt.record))))
+		})
+	}
+}
+
+func TestNewSyslogWriter_errors(t *testing.T) {
+	_, err := NewSyslogWriter("unixpacket", "/dev/log", nil)
+	assert.ErrorContains(t, err, "unsupported syslog network")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	_, err = NewSyslogWriter("tcp", addr, nil, WithSyslogDialTimeout(time.Second))
+	assert.ErrorContains(t, err, "failed to connect to syslog server")
+}
+
+func TestSyslogWriter_Close(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	acceptRecords(t, ln, false)
+
+	w, err := NewSyslogWriter("tcp", ln.Addr().String(), nil)
+	require.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("test"))
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+// shortTempDir returns a temporary directory with a path short enough for a unix socket
+func shortTempDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "cef")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+// listenUnixgram listens for datagrams on path
+func listenUnixgram(t *testing.T, path string) net.PacketConn {
+	pc, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+	return pc
+}
+
+// readDatagram reads one datagram from pc
+func readDatagram(t *testing.T, pc net.PacketConn) string {
+	buf := make([]byte, 1<<16)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestSyslogWriter_unixgram(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "log")
+	pc := listenUnixgram(t, path)
+
+	w, err := NewSyslogWriter("unixgram", path, nil)
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("<134>CEF:1|v|p|1|100|test|Low|\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "<134>CEF:1|v|p|1|100|test|Low|", readDatagram(t, pc))
+
+	// The daemon restarting replaces the socket
+	require.NoError(t, pc.Close())
+	require.NoError(t, os.Remove(path))
+	pc = listenUnixgram(t, path)
+	defer pc.Close()
+	_, err = w.Write([]byte("CEF:1|v|p|1|100|again|Low|"))
+	require.NoError(t, err)
+	assert.Equal(t, "CEF:1|v|p|1|100|again|Low|", readDatagram(t, pc))
+}
+
+func TestSyslogWriter_unix(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "log")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer ln.Close()
+	records := acceptRecords(t, ln, false)
+
+	w, err := NewSyslogWriter("unix", path, nil)
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("CEF:1|v|p|1|100|test|Low|"))
+	require.NoError(t, err)
+	select {
+	case r := <-records:
+		assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", r)
+	case <-time.After(5 * time.Second):
+		t.Fatal("record not received")
+	}
+}
+
+func TestNewLocalSyslogWriter(t *testing.T) {
+	dir := shortTempDir(t)
+	path := filepath.Join(dir, "log")
+	defer func(paths []string) { localSyslogPaths = paths }(localSyslogPaths)
+	localSyslogPaths = []string{filepath.Join(dir, "missing"), path}
+
+	_, err := NewLocalSyslogWriter()
+	assert.ErrorContains(t, err, "no local syslog daemon")
+
+	pc := listenUnixgram(t, path)
+	defer pc.Close()
+	w, err := NewLocalSyslogWriter()
+	require.NoError(t, err)
+	defer w.Close()
+
+	l := NewLogger(w, "v", "p", "1", WithSyslogPriority(FacilityLocal0), WithHostnameFunc(func() (string, error) {
+		return "host", nil
+	}), WithTimeFunc(testTime))
+	require.NoError(t, l.LogLow("100", strings.Repeat("x", 10000), Extensions{}))
+	got := readDatagram(t, pc)
+	assert.Len(t, got, localSyslogMaxSize)
+	assert.True(t, strings.HasPrefix(got, "<134>"), got)
+}
+
+func TestSyslogWriter_WriteContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn // never read, so writes block once the socket buffers are full
+		}
+	}()
+	w, err := NewSyslogWriter("tcp", ln.Addr().String(), nil)
+	require.NoError(t, err)
+	defer w.Close()
+	conn := <-accepted
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	record := bytes.Repeat([]byte("a"), 1<<20)
+	for i := 0; i < 1000 && err == nil; i++ {
+		_, err = w.WriteContext(ctx, record)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = w.WriteContext(cancelled, []byte("record"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
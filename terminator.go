@@ -0,0 +1,54 @@
+package cefevent
+
+import (
+	"io"
+	"net"
+	"strings"
+)
+
+// RecordTerminator is what a Logger writes after each record
+type RecordTerminator int
+
+const (
+	NoTerminator      RecordTerminator = iota // Nothing, the default
+	NewlineTerminator                         // "\n"
+	CRLFTerminator                            // "\r\n"
+	AutoTerminator                            // "\n", or nothing for datagram sockets where each packet is one record
+)
+
+// WithRecordTerminator sets what is written after each record, e.g. NewlineTerminator so that events written to a file
+// or TCP stream stay one per line. By default nothing is written, as suits datagram sockets & writers which frame
+// records themselves. Ignored by the JSON format, whose records always end with "\n".
+func WithRecordTerminator(t RecordTerminator) LoggerConfigOption {
+	return func(l *Logger) {
+		l.terminator = t
+	}
+}
+
+// recordTerminator returns the bytes ending records written to out
+func (l *Logger) recordTerminator(out io.Writer) string {
+	if l.format == JSON {
+		return ""
+	}
+	switch l.terminator {
+	case NewlineTerminator:
+		return "\n"
+	case CRLFTerminator:
+		return "\r\n"
+	case AutoTerminator:
+		if !isDatagram(out) {
+			return "\n"
+		}
+	}
+	return ""
+}
+
+// isDatagram reports whether each Write to w is sent as a separate packet
+func isDatagram(w io.Writer) bool {
+	conn, ok := w.(net.Conn)
+	if !ok {
+		return false
+	}
+	network := conn.LocalAddr().Network()
+	return strings.HasPrefix(network, "udp") || strings.HasPrefix(network, "ip") || network == "unixgram"
+}
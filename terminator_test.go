@@ -0,0 +1,61 @@
+package cefevent
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRecordTerminator(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []LoggerConfigOption
+		want string
+	}{
+		{"default", nil, "CEF:1|v|p|1|100|test|Low|CEF:1|v|p|1|100|test|Low|"},
+		{"none", []LoggerConfigOption{WithRecordTerminator(NoTerminator)}, "CEF:1|v|p|1|100|test|Low|CEF:1|v|p|1|100|test|Low|"},
+		{"newline", []LoggerConfigOption{WithRecordTerminator(NewlineTerminator)}, "CEF:1|v|p|1|100|test|Low|\nCEF:1|v|p|1|100|test|Low|\n"},
+		{"crlf", []LoggerConfigOption{WithRecordTerminator(CRLFTerminator)}, "CEF:1|v|p|1|100|test|Low|\r\nCEF:1|v|p|1|100|test|Low|\r\n"},
+		{"auto_stream", []LoggerConfigOption{WithRecordTerminator(AutoTerminator)}, "CEF:1|v|p|1|100|test|Low|\nCEF:1|v|p|1|100|test|Low|\n"},
+		{"async", []LoggerConfigOption{WithRecordTerminator(NewlineTerminator), WithAsync(10, OverflowBlock)},
+			"CEF:1|v|p|1|100|test|Low|\nCEF:1|v|p|1|100|test|Low|\n"},
+		{"json", []LoggerConfigOption{WithFormat(JSON), WithRecordTerminator(CRLFTerminator)},
+			`{"version":1,"deviceVendor":"v","deviceProduct":"p","deviceVersion":"1","deviceEventClassId":"100","name":"test","severity":"Low"}` + "\n" +
+				`{"version":1,"deviceVendor":"v","deviceProduct":"p","deviceVersion":"1","deviceEventClassId":"100","name":"test","severity":"Low"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			l := NewLogger(buf, "v", "p", "1", append([]LoggerConfigOption{OmitSyslogHeader()}, tt.opts...)...)
+			require.NoError(t, l.LogLow("100", "test", Extensions{}))
+			require.NoError(t, l.LogLow("100", "test", Extensions{}))
+			require.NoError(t, l.Flush())
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWithRecordTerminator_autoDatagram(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	l := NewLogger(conn, "v", "p", "1", OmitSyslogHeader(), WithRecordTerminator(AutoTerminator))
+	require.NoError(t, l.LogLow("100", "test", Extensions{}))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", string(buf[:n]))
+}
+
+func TestLogger_LogShortWrite_terminator(t *testing.T) {
+	l := NewLogger(shortWriter{}, "v", "p", "1", WithRecordTerminator(NewlineTerminator))
+	assert.ErrorIs(t, l.LogLow("100", "test", Extensions{}), io.ErrShortWrite)
+}
@@ -0,0 +1,135 @@
+package cefevent
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithByteRateLimit limits the bytes per second written by the Logger across all of its writers, so a burst of events
+// can't saturate a constrained link. Up to burst bytes may be written at once; Log blocks until the event fits within
+// the limit. Events larger than burst are written once the bucket is full rather than rejected.
+func WithByteRateLimit(bytesPerSecond, burst int) LoggerConfigOption {
+	return func(l *Logger) {
+		l.throttle = newTokenBucket(bytesPerSecond, burst)
+	}
+}
+
+// NewThrottledWriter wraps w, limiting it to bytesPerSecond with bursts of up to burst bytes. Each Write blocks until it
+// fits within the limit. Useful to throttle a single sink, e.g. one ClassRoute, rather than the whole Logger.
+func NewThrottledWriter(w io.Writer, bytesPerSecond, burst int) io.Writer {
+	return &throttledWriter{w: w, bucket: newTokenBucket(bytesPerSecond, burst)}
+}
+
+type throttledWriter struct {
+	w      io.Writer
+	bucket *tokenBucket
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	t.bucket.wait(len(p))
+	return t.w.Write(p)
+}
+
+// tokenBucket is a token bucket rate limiter. Callers reserve tokens up front, going into debt if necessary, and then
+// sleep until the debt would have been repaid, so waiting callers are served in order.
+type tokenBucket struct {
+	rate  float64 // tokens added per second
+	burst float64
+
+	// now & sleep are overridden in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		now:    time.Now,
+		sleep:  time.Sleep,
+		tokens: float64(burst),
+	}
+}
+
+// clone returns a full bucket with the same limits
+func (b *tokenBucket) clone() *tokenBucket {
+	return &tokenBucket{
+		rate:   b.rate,
+		burst:  b.burst,
+		now:    b.now,
+		sleep:  b.sleep,
+		tokens: b.burst,
+	}
+}
+
+// wait blocks until n tokens are available and consumes them
+func (b *tokenBucket) wait(n int) {
+	_ = b.waitContext(context.Background(), n)
+}
+
+// waitContext is wait, giving up once ctx is done. The tokens are returned to the bucket if it gives up.
+func (b *tokenBucket) waitContext(ctx context.Context, n int) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	need := float64(n)
+	if need > b.burst {
+		need = b.burst // oversized writes wait for a full bucket instead of forever
+	}
+	b.tokens -= need
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, delay, b.sleep); err != nil {
+		b.mu.Lock()
+		b.tokens += need
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// take consumes n tokens if they're available, without waiting
+func (b *tokenBucket) take(n int) bool {
+	if b.rate <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
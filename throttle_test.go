@@ -0,0 +1,88 @@
+package cefevent
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock whose sleeps advance time instantly
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+func TestTokenBucket_wait(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	b := newTokenBucket(100, 200)
+	b.now = clock.Now
+	b.sleep = clock.Sleep
+
+	b.wait(150) // within burst
+	b.wait(50)
+	assert.Empty(t, clock.sleeps)
+
+	b.wait(50) // bucket empty, needs half a second of refill
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, clock.sleeps)
+
+	clock.now = clock.now.Add(time.Hour) // refill caps at burst
+	clock.sleeps = nil
+	b.wait(1000)
+	assert.Empty(t, clock.sleeps, "oversized writes only need a full bucket")
+	b.wait(100)
+	assert.Equal(t, []time.Duration{time.Second}, clock.sleeps)
+}
+
+func TestWithByteRateLimit(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithByteRateLimit(10, 60))
+	l.throttle.now = clock.Now
+	l.throttle.sleep = clock.Sleep
+
+	require.NoError(t, l.LogLow("100", "first", Extensions{})) // 47 bytes
+	assert.Empty(t, clock.sleeps)
+	require.NoError(t, l.LogLow("100", "second", Extensions{})) // 48 bytes, 35 over budget
+	assert.Equal(t, []time.Duration{3500 * time.Millisecond}, clock.sleeps)
+	assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|100|first|Low|CEF:1|testVendor|testProduct|1.0|100|second|Low|", buf.String())
+}
+
+func TestNewThrottledWriter(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	buf := &bytes.Buffer{}
+	w := NewThrottledWriter(buf, 1, 4)
+	w.(*throttledWriter).bucket.now = clock.Now
+	w.(*throttledWriter).bucket.sleep = clock.Sleep
+
+	_, err := w.Write([]byte("abcdef"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("gh"))
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefgh", buf.String())
+	assert.Equal(t, []time.Duration{2 * time.Second}, clock.sleeps)
+}
+
+func TestTokenBucket_take(t *testing.T) {
+	clock := &fakeClock{now: testTime()}
+	b := newTokenBucket(2, 3)
+	b.now = clock.Now
+
+	assert.True(t, b.take(2))
+	assert.True(t, b.take(1))
+	assert.False(t, b.take(1), "bucket empty")
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	assert.True(t, b.take(1), "refilled one token")
+	assert.False(t, b.take(1))
+}
@@ -0,0 +1,96 @@
+package cefevent
+
+import (
+	"sync"
+	"time"
+)
+
+// WithMonotonicTimestamps ensures the rt and end extension timestamps, and the syslog header timestamp, never go
+// backwards between events emitted by the Logger. Timestamps earlier than one already emitted are clamped to the latest
+// emitted value, which keeps SIEM correlation engines happy across clock steps.
+func WithMonotonicTimestamps() LoggerConfigOption {
+	return func(l *Logger) {
+		l.monotonic = &monotonicClock{}
+	}
+}
+
+// monotonicClock tracks the latest timestamps emitted by a Logger
+type monotonicClock struct {
+	mu         sync.Mutex
+	lastHeader time.Time
+	lastRt     time.Time
+	lastEnd    time.Time
+}
+
+// clampHeader returns the syslog header time to emit for t
+func (c *monotonicClock) clampHeader(t time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return clampTime(&c.lastHeader, t)
+}
+
+// clampExtensions returns extensions with rt & end clamped so they don't go backwards
+func (c *monotonicClock) clampExtensions(extensions Extensions) Extensions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !extensions.DeviceReceiptTime.IsZero() {
+		extensions.DeviceReceiptTime = clampTime(&c.lastRt, extensions.DeviceReceiptTime)
+	}
+	if !extensions.EndTime.IsZero() {
+		extensions.EndTime = clampTime(&c.lastEnd, extensions.EndTime)
+	}
+	return extensions
+}
+
+// clampTime returns the later of t and *last, recording it as the new *last
+func clampTime(last *time.Time, t time.Time) time.Time {
+	if t.Before(*last) {
+		return *last
+	}
+	*last = t
+	return t
+}
+
+// WithClockOffset adds a fixed offset to every emitted timestamp, including the syslog header, to correct for a host
+// clock known to drift.
+func WithClockOffset(offset time.Duration) LoggerConfigOption {
+	return WithClockOffsetFunc(func() time.Duration {
+		return offset
+	})
+}
+
+// WithClockOffsetFunc adds the offset returned by fn to every emitted timestamp, including the syslog header. fn is
+// called once per event, so it can track an offset measured at runtime (e.g. against an NTP server).
+func WithClockOffsetFunc(fn func() time.Duration) LoggerConfigOption {
+	return func(l *Logger) {
+		l.clockOffset = fn
+	}
+}
+
+// offsetExtensions returns extensions with offset added to each populated timestamp
+func offsetExtensions(extensions Extensions, offset time.Duration) Extensions {
+	for _, t := range []*time.Time{
+		&extensions.StartTime,
+		&extensions.EndTime,
+		&extensions.AgentReceiptTime,
+		&extensions.DeviceReceiptTime,
+		&extensions.FileCreateTime,
+		&extensions.FileModificationTime,
+		&extensions.OldFileCreateTime,
+		&extensions.OldFileModificationTime,
+	} {
+		if !t.IsZero() {
+			*t = t.Add(offset)
+		}
+	}
+	for _, f := range []**CustomField[time.Time]{
+		&extensions.DeviceCustomDate1,
+		&extensions.DeviceCustomDate2,
+		&extensions.FlexDate1,
+	} {
+		if *f != nil && !(*f).Value.IsZero() {
+			*f = Labeled((*f).Label, (*f).Value.Add(offset))
+		}
+	}
+	return extensions
+}
@@ -0,0 +1,58 @@
+package cefevent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMonotonicTimestamps(t *testing.T) {
+	w := &recordingWriter{}
+	now := testTime()
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", WithMonotonicTimestamps())
+	l.getTime = func() time.Time {
+		return now
+	}
+	l.getHostname = testHostname
+
+	require.NoError(t, l.LogLow("100", "first", Extensions{DeviceReceiptTime: now, EndTime: now}))
+	now = now.Add(-time.Hour) // clock stepped backwards
+	require.NoError(t, l.LogLow("100", "second", Extensions{DeviceReceiptTime: now}))
+	require.NoError(t, l.LogLow("100", "third", Extensions{EndTime: testTime().Add(time.Second)}))
+
+	assert.Equal(t, []string{
+		"Nov 9 11:45:20 testhost CEF:1|testVendor|testProduct|1.0|100|first|Low|end=1699530320000 rt=1699530320000",
+		"Nov 9 11:45:20 testhost CEF:1|testVendor|testProduct|1.0|100|second|Low|rt=1699530320000",
+		"Nov 9 11:45:20 testhost CEF:1|testVendor|testProduct|1.0|100|third|Low|end=1699530321000",
+	}, w.Records())
+}
+
+func TestWithClockOffset(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", WithClockOffset(-90*time.Second))
+	l.getTime = testTime
+	l.getHostname = testHostname
+
+	require.NoError(t, l.LogLow("100", "skewed", Extensions{DeviceReceiptTime: testTime(), FileCreateTime: testTime(), AgentReceiptTime: testTime()}))
+	assert.Equal(t, []string{
+		"Nov 9 11:43:50 testhost CEF:1|testVendor|testProduct|1.0|100|skewed|Low|art=1699530230000 rt=1699530230000 fileCreateTime=1699530230000",
+	}, w.Records())
+}
+
+func TestWithClockOffsetFunc(t *testing.T) {
+	w := &recordingWriter{}
+	offset := time.Second
+	l := NewLogger(w, "testVendor", "testProduct", "1.0", OmitSyslogHeader(), WithClockOffsetFunc(func() time.Duration {
+		return offset
+	}))
+
+	require.NoError(t, l.LogLow("100", "skewed", Extensions{EndTime: testTime()}))
+	offset = 2 * time.Second
+	require.NoError(t, l.LogLow("100", "skewed", Extensions{EndTime: testTime()}))
+	assert.Equal(t, []string{
+		"CEF:1|testVendor|testProduct|1.0|100|skewed|Low|end=1699530321000",
+		"CEF:1|testVendor|testProduct|1.0|100|skewed|Low|end=1699530322000",
+	}, w.Records())
+}
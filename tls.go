@@ -0,0 +1,52 @@
+package cefevent
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"strconv"
+)
+
+// Custom extension keys set by AddTLSFields
+const (
+	TLSVersionKey            = "tlsVersion"            // Negotiated protocol version, e.g. "TLS 1.3"
+	TLSCipherSuiteKey        = "tlsCipherSuite"        // Negotiated cipher suite, e.g. "TLS_AES_128_GCM_SHA256"
+	TLSServerNameKey         = "tlsServerName"         // Server name requested by the client through SNI
+	TLSNegotiatedProtocolKey = "tlsNegotiatedProtocol" // Application protocol negotiated with ALPN, e.g. "h2"
+	TLSResumedKey            = "tlsResumed"            // Whether the session was resumed
+	TLSPeerSubjectKey        = "tlsPeerSubject"        // Distinguished name of the peer's leaf certificate
+	TLSPeerIssuerKey         = "tlsPeerIssuer"         // Distinguished name of the peer certificate's issuer
+	TLSPeerSerialKey         = "tlsPeerSerial"         // Serial number of the peer's leaf certificate, in hex
+	TLSPeerFingerprintKey    = "tlsPeerFingerprint"    // SHA-256 fingerprint of the peer's leaf certificate, in hex
+	TLSPeerVerifiedKey       = "tlsPeerVerified"       // Whether the peer certificate was verified against a trusted root
+)
+
+// AddTLSFields returns ext with custom extensions describing a TLS handshake added. Peer certificate fields are only
+// set if the peer presented a certificate, so on a server they record client certificate authentication. The
+// caller's CustomExtensions map is not modified.
+func AddTLSFields(ext Extensions, state tls.ConnectionState) Extensions {
+	custom := make(map[string]string, len(ext.CustomExtensions)+10)
+	for k, v := range ext.CustomExtensions {
+		custom[k] = v
+	}
+	custom[TLSVersionKey] = tls.VersionName(state.Version)
+	custom[TLSCipherSuiteKey] = tls.CipherSuiteName(state.CipherSuite)
+	if state.ServerName != "" {
+		custom[TLSServerNameKey] = state.ServerName
+	}
+	if state.NegotiatedProtocol != "" {
+		custom[TLSNegotiatedProtocolKey] = state.NegotiatedProtocol
+	}
+	custom[TLSResumedKey] = strconv.FormatBool(state.DidResume)
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		fingerprint := sha256.Sum256(cert.Raw)
+		custom[TLSPeerSubjectKey] = cert.Subject.String()
+		custom[TLSPeerIssuerKey] = cert.Issuer.String()
+		custom[TLSPeerSerialKey] = cert.SerialNumber.Text(16)
+		custom[TLSPeerFingerprintKey] = hex.EncodeToString(fingerprint[:])
+		custom[TLSPeerVerifiedKey] = strconv.FormatBool(len(state.VerifiedChains) > 0)
+	}
+	ext.CustomExtensions = custom
+	return ext
+}
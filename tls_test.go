@@ -0,0 +1,84 @@
+package cefevent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddTLSFields(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0xbeef),
+		Subject:      pkix.Name{CommonName: "client", Organization: []string{"Example"}},
+		NotBefore:    testTime(),
+		NotAfter:     testTime().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	fingerprint := sha256.Sum256(der)
+
+	tests := []struct {
+		name  string
+		state tls.ConnectionState
+		want  map[string]string
+	}{
+		{
+			"no peer certificate",
+			tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			map[string]string{
+				"existing":       "value",
+				"tlsVersion":     "TLS 1.2",
+				"tlsCipherSuite": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+				"tlsResumed":     "false",
+			},
+		},
+		{
+			"client certificate",
+			tls.ConnectionState{
+				Version:            tls.VersionTLS13,
+				CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+				ServerName:         "api.example.com",
+				NegotiatedProtocol: "h2",
+				DidResume:          true,
+				PeerCertificates:   []*x509.Certificate{cert},
+				VerifiedChains:     [][]*x509.Certificate{{cert}},
+			},
+			map[string]string{
+				"existing":              "value",
+				"tlsVersion":            "TLS 1.3",
+				"tlsCipherSuite":        "TLS_AES_128_GCM_SHA256",
+				"tlsServerName":         "api.example.com",
+				"tlsNegotiatedProtocol": "h2",
+				"tlsResumed":            "true",
+				"tlsPeerSubject":        "CN=client,O=Example",
+				"tlsPeerIssuer":         "CN=client,O=Example",
+				"tlsPeerSerial":         "beef",
+				"tlsPeerFingerprint":    hex.EncodeToString(fingerprint[:]),
+				"tlsPeerVerified":       "true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			custom := map[string]string{"existing": "value"}
+			got := AddTLSFields(Extensions{CustomExtensions: custom}, tt.state)
+			assert.Equal(t, tt.want, got.CustomExtensions)
+			assert.Equal(t, map[string]string{"existing": "value"}, custom)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package cefevent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// InvalidTraceKeyErr error when a trace correlation key names a standard field that can't hold a trace or span ID
+var InvalidTraceKeyErr = errors.New("invalid trace correlation key")
+
+// Labels of the custom fields set by WithTraceCorrelation
+const (
+	TraceIdLabel = "traceId"
+	SpanIdLabel  = "spanId"
+)
+
+// TraceExtractor returns the IDs of the trace & span active in ctx, or empty strings if there isn't one. With
+// OpenTelemetry:
+//
+//	func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	}
+type TraceExtractor func(ctx context.Context) (traceId, spanId string)
+
+// WithTraceCorrelation adds the IDs of the trace & span active in the context passed to LogContext to each event, so
+// SIEM events can be joined with distributed traces. traceKey & spanKey may be string custom fields such as
+// flexString1 or cs5, which are labelled TraceIdLabel & SpanIdLabel, other standard string keys such as externalId, or
+// custom extension keys. spanKey may be empty to omit the span ID. Returns InvalidTraceKeyErr for standard keys which
+// aren't strings.
+func WithTraceCorrelation(extract TraceExtractor, traceKey, spanKey string) (LoggerConfigOption, error) {
+	keys := []string{traceKey}
+	if spanKey != "" {
+		keys = append(keys, spanKey)
+	}
+	for _, key := range keys {
+		var e Extensions
+		if key == "" || e.set(key, "4bf92f3577b34da6a3ce929d0e0e4736") != nil {
+			return nil, fmt.Errorf("%w: %q", InvalidTraceKeyErr, key)
+		}
+	}
+	return WithContextExtractor(func(ctx context.Context) Extensions {
+		var e Extensions
+		traceId, spanId := extract(ctx)
+		setTraceField(&e, traceKey, TraceIdLabel, traceId)
+		setTraceField(&e, spanKey, SpanIdLabel, spanId)
+		return e
+	}), nil
+}
+
+// setTraceField sets key to id, labelling it if key is a custom field. Does nothing if key or id are empty.
+func setTraceField(e *Extensions, key, label, id string) {
+	if key == "" || id == "" {
+		return
+	}
+	_ = e.set(key, id) // validated by WithTraceCorrelation
+	if setLabel, ok := extensionSetters[key+"Label"]; ok {
+		_ = setLabel(e, label)
+	}
+}
+
+// BaggageLookup returns the value of the baggage member named member in ctx, or an empty string if there isn't one.
+// With OpenTelemetry:
+//
+//	func(ctx context.Context, member string) string {
+//		return baggage.FromContext(ctx).Member(member).Value()
+//	}
+type BaggageLookup func(ctx context.Context, member string) string
+
+// WithBaggage copies the listed baggage members of the context passed to LogContext into custom extensions named after
+// them, so correlation IDs set by upstream services reach the SIEM. Members which aren't set are omitted. Returns an
+// error wrapping InvalidExtensionKeyErr or ReservedExtensionKeyErr for members which can't be custom extension keys.
+func WithBaggage(lookup BaggageLookup, members ...string) (LoggerConfigOption, error) {
+	for _, m := range members {
+		if !validExtensionKey(m) {
+			return nil, &fieldError{m, InvalidExtensionKeyErr}
+		}
+		if isStandardKey(m) {
+			return nil, &fieldError{m, ReservedExtensionKeyErr}
+		}
+	}
+	members = slices.Clone(members)
+	return WithContextExtractor(func(ctx context.Context) Extensions {
+		var e Extensions
+		for _, m := range members {
+			if v := lookup(ctx, m); v != "" {
+				if e.CustomExtensions == nil {
+					e.CustomExtensions = make(map[string]string, len(members))
+				}
+				e.CustomExtensions[m] = v
+			}
+		}
+		return e
+	}), nil
+}
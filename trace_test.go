@@ -0,0 +1,94 @@
+package cefevent
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+// testTraceExtractor returns the IDs stored under spanKey, like trace.SpanContextFromContext
+func testTraceExtractor(ctx context.Context) (string, string) {
+	ids, ok := ctx.Value(spanKey{}).([2]string)
+	if !ok {
+		return "", ""
+	}
+	return ids[0], ids[1]
+}
+
+func TestWithTraceCorrelation(t *testing.T) {
+	ctx := context.WithValue(context.Background(), spanKey{}, [2]string{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"})
+	tests := []struct {
+		name     string
+		traceKey string
+		spanKey  string
+		ctx      context.Context
+		want     string
+	}{
+		{"flex strings", "flexString1", "flexString2", ctx,
+			"flexString1=4bf92f3577b34da6a3ce929d0e0e4736 flexString1Label=traceId flexString2=00f067aa0ba902b7 flexString2Label=spanId"},
+		{"custom keys", "traceId", "spanId", ctx, "spanId=00f067aa0ba902b7 traceId=4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"standard key without span", "externalId", "", ctx, "externalId=4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"no active span", "flexString1", "flexString2", context.Background(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			opt, err := WithTraceCorrelation(testTraceExtractor, tt.traceKey, tt.spanKey)
+			require.NoError(t, err)
+			l := NewLogger(buf, "v", "p", "1", opt, OmitSyslogHeader())
+			require.NoError(t, l.LogContext(tt.ctx, "100", "test", LowSeverity, Extensions{}))
+			assert.Equal(t, "CEF:1|v|p|1|100|test|Low|"+tt.want, buf.String())
+		})
+	}
+}
+
+func TestWithTraceCorrelation_errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		traceKey string
+		spanKey  string
+	}{
+		{"missing trace key", "", "spanId"},
+		{"numeric field", "cn1", "spanId"},
+		{"address field", "traceId", "src"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := WithTraceCorrelation(testTraceExtractor, tt.traceKey, tt.spanKey)
+			assert.ErrorIs(t, err, InvalidTraceKeyErr)
+		})
+	}
+}
+
+type baggageKey struct{}
+
+// testBaggage returns members stored under baggageKey, like baggage.FromContext
+func testBaggage(ctx context.Context, member string) string {
+	members, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return members[member]
+}
+
+func TestWithBaggage(t *testing.T) {
+	opt, err := WithBaggage(testBaggage, "tenant", "session")
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), opt)
+
+	ctx := context.WithValue(context.Background(), baggageKey{}, map[string]string{"tenant": "acme", "other": "x"})
+	require.NoError(t, l.LogContext(ctx, "100", "test", LowSeverity, Extensions{Message: "hi"}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|msg=hi tenant=acme", buf.String())
+
+	buf.Reset()
+	require.NoError(t, l.LogContext(context.Background(), "100", "test", LowSeverity, Extensions{}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|", buf.String())
+
+	_, err = WithBaggage(testBaggage, "msg")
+	assert.ErrorIs(t, err, ReservedExtensionKeyErr)
+	_, err = WithBaggage(testBaggage, "")
+	assert.ErrorIs(t, err, InvalidExtensionKeyErr)
+}
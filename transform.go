@@ -0,0 +1,142 @@
+package cefevent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// InvalidTransformErr error when a transformed value can't be stored in its field, e.g. text moved to an address field
+var InvalidTransformErr = errors.New("transformed value invalid for field")
+
+// RenameField is a Stage moving the value of the extension field from to the field to, replacing any value to had,
+// e.g. RenameField("cs1", "suser") for a producer putting the user name in a custom field. Keys are extension keys as
+// accepted by Event.Field. Events without from are unchanged, and events whose value can't be stored in to fail with
+// an error wrapping InvalidTransformErr.
+func RenameField(from, to string) Stage {
+	from, to = standardKey(from), standardKey(to)
+	return func(_ context.Context, e *Event) (bool, error) {
+		v, ok := e.Extensions.field(from)
+		if !ok {
+			return true, nil
+		}
+		e.Extensions.unset(from)
+		return true, setTransformed(&e.Extensions, to, v)
+	}
+}
+
+// RewriteField is a Stage replacing the value of the extension field key with rewrite(value). Returning "" removes the
+// field. Events without key are unchanged, and events whose new value can't be stored fail with an error wrapping
+// InvalidTransformErr.
+func RewriteField(key string, rewrite func(v string) string) Stage {
+	key = standardKey(key)
+	return func(_ context.Context, e *Event) (bool, error) {
+		v, ok := e.Extensions.field(key)
+		if !ok {
+			return true, nil
+		}
+		return true, setTransformed(&e.Extensions, key, rewrite(v))
+	}
+}
+
+// ReplaceField is a Stage replacing matches of re in the value of the extension field key with repl, expanded as by
+// regexp.Regexp.ReplaceAllString, e.g. ReplaceField("msg", regexp.MustCompile(`password=\S+`), "password=***")
+func ReplaceField(key string, re *regexp.Regexp, repl string) Stage {
+	return RewriteField(key, func(v string) string {
+		return re.ReplaceAllString(v, repl)
+	})
+}
+
+// DropFields is a Stage removing the given extension fields. A custom field such as cs1 is removed with its label, and
+// vice versa.
+func DropFields(keys ...string) Stage {
+	standard := make([]string, len(keys))
+	for i, key := range keys {
+		standard[i] = standardKey(key)
+	}
+	return func(_ context.Context, e *Event) (bool, error) {
+		for _, key := range standard {
+			e.Extensions.unset(key)
+		}
+		return true, nil
+	}
+}
+
+// setTransformed stores v in the field key, removing it if v is empty
+func setTransformed(e *Extensions, key, v string) error {
+	if v == "" {
+		e.unset(key)
+		return nil
+	}
+	if err := e.set(key, v); err != nil {
+		return &fieldError{key, fmt.Errorf("%w: %w", InvalidTransformErr, err)}
+	}
+	return nil
+}
+
+// TransformRule configures a transform stage from a config file, see LoadTransforms. Exactly one of Rename, Rewrite
+// and Drop must be set.
+type TransformRule struct {
+	// Rename is a field to move to the field To, as RenameField
+	Rename string `json:"rename,omitempty"`
+	To     string `json:"to,omitempty"`
+	// Rewrite is a field to replace matches of the regular expression Pattern in with Replace, as ReplaceField
+	Rewrite string `json:"rewrite,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Replace string `json:"replace,omitempty"`
+	// Drop are fields to remove, as DropFields
+	Drop []string `json:"drop,omitempty"`
+}
+
+// Stage returns the transform stage r configures
+func (r TransformRule) Stage() (Stage, error) {
+	switch {
+	case r.Rename != "" && r.Rewrite == "" && r.Drop == nil:
+		if r.To == "" {
+			return nil, fmt.Errorf("rename %q: missing to", r.Rename)
+		}
+		return RenameField(r.Rename, r.To), nil
+	case r.Rewrite != "" && r.Rename == "" && r.Drop == nil:
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite %q: %w", r.Rewrite, err)
+		}
+		return ReplaceField(r.Rewrite, re, r.Replace), nil
+	case r.Drop != nil && r.Rename == "" && r.Rewrite == "":
+		return DropFields(r.Drop...), nil
+	}
+	return nil, errors.New("transform must have one of rename, rewrite or drop")
+}
+
+// LoadTransforms reads a JSON array of TransformRules, returning their stages in order, e.g.
+//
+//	[
+//		{"rename": "cs1", "to": "suser"},
+//		{"rewrite": "msg", "pattern": "password=\\S+", "replace": "password=***"},
+//		{"drop": ["cs2", "cs2Label"]}
+//	]
+func LoadTransforms(r io.Reader) ([]Stage, error) {
+	var rules []TransformRule
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, err
+	}
+	return TransformStages(rules)
+}
+
+// TransformStages returns the stages configured by rules, in order
+func TransformStages(rules []TransformRule) ([]Stage, error) {
+	stages := make([]Stage, len(rules))
+	for i, rule := range rules {
+		stage, err := rule.Stage()
+		if err != nil {
+			return nil, fmt.Errorf("transform %d: %w", i+1, err)
+		}
+		stages[i] = stage
+	}
+	return stages, nil
+}
@@ -0,0 +1,132 @@
+package cefevent
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransforms(t *testing.T) {
+	const line = "CEF:0|v|p|1|100|login|Low|msg=password=hunter2 ok src=10.0.0.1 cs1=alice cs1Label=user user=bob"
+	tests := []struct {
+		name    string
+		stage   Stage
+		want    string
+		wantErr string
+	}{
+		{
+			"rename_custom_to_standard",
+			RenameField("cs1", "suser"),
+			"msg=password\\=hunter2 ok src=10.0.0.1 suser=alice user=bob",
+			"",
+		},
+		{
+			"rename_full_names",
+			RenameField("sourceAddress", "destinationAddress"),
+			"msg=password\\=hunter2 ok dst=10.0.0.1 cs1=alice cs1Label=user user=bob",
+			"",
+		},
+		{
+			"rename_extension",
+			RenameField("user", "duser"),
+			"msg=password\\=hunter2 ok duser=bob src=10.0.0.1 cs1=alice cs1Label=user",
+			"",
+		},
+		{
+			"rename_missing",
+			RenameField("dst", "src"),
+			"msg=password\\=hunter2 ok src=10.0.0.1 cs1=alice cs1Label=user user=bob",
+			"",
+		},
+		{
+			"rename_invalid",
+			RenameField("user", "dst"),
+			"",
+			`"dst": transformed value invalid for field: "bob" is not an IP address`,
+		},
+		{
+			"rewrite",
+			RewriteField("user", strings.ToUpper),
+			"msg=password\\=hunter2 ok src=10.0.0.1 cs1=alice cs1Label=user user=BOB",
+			"",
+		},
+		{
+			"rewrite_empty",
+			RewriteField("src", func(string) string { return "" }),
+			"msg=password\\=hunter2 ok cs1=alice cs1Label=user user=bob",
+			"",
+		},
+		{
+			"replace",
+			ReplaceField("msg", regexp.MustCompile(`password=\S+`), "password=***"),
+			"msg=password\\=*** ok src=10.0.0.1 cs1=alice cs1Label=user user=bob",
+			"",
+		},
+		{
+			"replace_expand",
+			ReplaceField("src", regexp.MustCompile(`^10\.0\.(\d+)\.(\d+)$`), "192.168.$1.$2"),
+			"msg=password\\=hunter2 ok src=192.168.0.1 cs1=alice cs1Label=user user=bob",
+			"",
+		},
+		{
+			"drop",
+			DropFields("msg", "deviceCustomString1Label", "user", "missing"),
+			"src=10.0.0.1",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(line)
+			require.NoError(t, err)
+			keep, err := tt.stage(context.Background(), &e)
+			assert.True(t, keep)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, InvalidTransformErr)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, e.Extensions.format(nil))
+		})
+	}
+}
+
+func TestLoadTransforms(t *testing.T) {
+	stages, err := LoadTransforms(strings.NewReader(`[
+		{"rename": "cs1", "to": "suser"},
+		{"rewrite": "msg", "pattern": "password=\\S+", "replace": "password=***"},
+		{"drop": ["src"]}
+	]`))
+	require.NoError(t, err)
+	out := &eventRecorder{}
+	in := NewScanner(strings.NewReader("CEF:0|v|p|1|100|login|Low|msg=password=hunter2 src=10.0.0.1 cs1=alice cs1Label=u\n"))
+	require.NoError(t, NewPipeline(in, out, WithStages(stages...)).Run(context.Background()))
+	require.Len(t, out.events, 1)
+	assert.Equal(t, "msg=password\\=*** suser=alice", out.events[0].Extensions.format(nil))
+}
+
+func TestLoadTransforms_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"syntax", `[{"rename": }]`, "invalid character"},
+		{"unknown_field", `[{"renmae": "cs1", "to": "suser"}]`, `unknown field "renmae"`},
+		{"empty", `[{}]`, "transform 1: transform must have one of rename, rewrite or drop"},
+		{"several", `[{"drop": ["a"]}, {"rename": "a", "to": "b", "drop": ["c"]}]`, "transform 2: transform must have"},
+		{"missing_to", `[{"rename": "cs1"}]`, `transform 1: rename "cs1": missing to`},
+		{"bad_pattern", `[{"rewrite": "msg", "pattern": "("}]`, `transform 1: rewrite "msg": error parsing regexp`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTransforms(strings.NewReader(tt.config))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldTooLongErr error when a field is longer than ArcSight stores without truncating
+var FieldTooLongErr = errors.New("field too long")
+
+// maxFieldLength is the longest string extension value ArcSight stores, unless listed in longFieldLengths
+const maxFieldLength = 1023
+
+// longFieldLengths lists the extension keys ArcSight allows to be longer than maxFieldLength
+var longFieldLengths = map[string]int{
+	"cs1":            4000,
+	"cs2":            4000,
+	"cs3":            4000,
+	"cs4":            4000,
+	"cs5":            4000,
+	"cs6":            4000,
+	"rawEvent":       4000,
+	"requestContext": 2048,
+}
+
+// TruncationPolicy decides how WithTruncation handles values longer than ArcSight accepts
+type TruncationPolicy struct {
+	// Reject makes Log return an error wrapping FieldTooLongErr for each over-long field rather than truncating
+	Reject bool
+	// MarkerKey if set, is added to the CustomExtensions of truncated events with a comma separated list of the
+	// truncated keys, e.g. "truncated=msg,cs1"
+	MarkerKey string
+	// OnOversized if set, is called with each over-long field, whether it is truncated or rejected, so the code logging
+	// it can be found and fixed. It is called from Log, so must be quick and safe to call from multiple goroutines.
+	OnOversized func(field OversizedField)
+}
+
+// OversizedField describes a value longer than ArcSight accepts, see TruncationPolicy.OnOversized
+type OversizedField struct {
+	// DeviceEventClassId & Name identify the event, with Name as logged
+	DeviceEventClassId, Name string
+	// Key is the extension key, or "name" for the event's name when Header is set
+	Key string
+	// Header is set when the field is the header's Name rather than an extension
+	Header bool
+	// Length is the value's length and Limit the longest accepted, in characters
+	Length, Limit int
+}
+
+// WithTruncation enforces ArcSight's field length limits: 512 characters for the name, 4000 for cs1-6 & rawEvent, 2048
+// for requestContext and 1023 for other string extensions, including CustomExtensions. Over-long values are cut to the
+// limit or rejected as set by policy, rather than being mangled by the SIEM. Values which no longer parse once
+// truncated, such as URLs, are always rejected.
+func WithTruncation(policy TruncationPolicy) LoggerConfigOption {
+	return func(l *Logger) {
+		l.truncation = &policy
+	}
+}
+
+// fieldLengthLimit returns the longest value accepted for an extension key
+func fieldLengthLimit(key string) int {
+	if n, ok := longFieldLengths[key]; ok {
+		return n
+	}
+	return maxFieldLength
+}
+
+// truncate applies the truncation policy to an event's name & extensions. extensions is copied before being modified.
+func (p *TruncationPolicy) truncate(deviceEventClassId, name string, extensions Extensions) (string, Extensions, error) {
+	type overlong struct {
+		key    string
+		header bool // the header Name, which a custom extension key can't be mistaken for
+		value  string
+		length int
+		limit  int
+	}
+	var fields []overlong
+	if n := utf8.RuneCountInString(name); n > maxNameLength {
+		fields = append(fields, overlong{"name", true, name, n, maxNameLength})
+	}
+	extensions.walk(func(key string, v fieldValue) bool {
+		if v.kind != stringKind {
+			return true
+		}
+		if n, limit := utf8.RuneCountInString(v.s), fieldLengthLimit(key); n > limit {
+			fields = append(fields, overlong{key, false, v.s, n, limit})
+		}
+		return true
+	})
+	if len(fields) == 0 {
+		return name, extensions, nil
+	}
+
+	if p.OnOversized != nil {
+		for _, f := range fields {
+			p.OnOversized(OversizedField{deviceEventClassId, name, f.key, f.header, f.length, f.limit})
+		}
+	}
+	extensions = extensions.Clone()
+	var errs []error
+	var truncated []string
+	for _, f := range fields {
+		if p.Reject {
+			errs = append(errs, &fieldError{f.key, fmt.Errorf("limit %d: %w", f.limit, FieldTooLongErr)})
+			continue
+		}
+		v := truncateString(f.value, f.limit)
+		switch _, standard := extensionSetters[f.key]; {
+		case f.header:
+			name = v
+		case standard:
+			if err := extensions.set(f.key, v); err != nil {
+				errs = append(errs, &fieldError{f.key, fmt.Errorf("limit %d: %w: %w", f.limit, FieldTooLongErr, err)})
+				continue
+			}
+		default:
+			extensions.CustomExtensions[f.key] = v
+		}
+		truncated = append(truncated, f.key)
+	}
+	if len(errs) > 0 {
+		return name, extensions, errors.Join(errs...)
+	}
+	if p.MarkerKey != "" {
+		if extensions.CustomExtensions == nil {
+			extensions.CustomExtensions = make(map[string]string, 1)
+		}
+		extensions.CustomExtensions[p.MarkerKey] = strings.Join(truncated, ",")
+	}
+	return name, extensions, nil
+}
+
+// truncateString returns the first n characters of s
+func truncateString(s string, n int) string {
+	i := 0
+	for j := range s {
+		if i == n {
+			return s[:j]
+		}
+		i++
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+package cefevent
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncationPolicy_truncate(t *testing.T) {
+	long := strings.Repeat("x", 5000)
+	tests := []struct {
+		name       string
+		policy     TruncationPolicy
+		evName     string
+		extensions Extensions
+		wantName   string
+		want       Extensions
+		wantErrs   []string
+	}{
+		{
+			"within_limits",
+			TruncationPolicy{MarkerKey: "truncated"},
+			strings.Repeat("n", 512),
+			Extensions{Message: strings.Repeat("m", 1023), DeviceCustomString1: Labeled("l", long[:4000])},
+			strings.Repeat("n", 512),
+			Extensions{Message: strings.Repeat("m", 1023), DeviceCustomString1: Labeled("l", long[:4000])},
+			nil,
+		},
+		{
+			"truncate",
+			TruncationPolicy{},
+			long,
+			Extensions{
+				Message:             long,
+				DeviceCustomString1: Labeled("l", long),
+				CustomExtensions:    map[string]string{"k": long},
+			},
+			long[:512],
+			Extensions{
+				Message:             long[:1023],
+				DeviceCustomString1: Labeled("l", long[:4000]),
+				CustomExtensions:    map[string]string{"k": long[:1023]},
+			},
+			nil,
+		},
+		{
+			"marker",
+			TruncationPolicy{MarkerKey: "truncated"},
+			"test",
+			Extensions{Message: long, SourceHostName: long},
+			"test",
+			Extensions{Message: long[:1023], SourceHostName: long[:1023], CustomExtensions: map[string]string{"truncated": "msg,shost"}},
+			nil,
+		},
+		{
+			"custom_name_key",
+			TruncationPolicy{},
+			"test",
+			Extensions{CustomExtensions: map[string]string{"name": long}},
+			"test",
+			Extensions{CustomExtensions: map[string]string{"name": long[:1023]}},
+			nil,
+		},
+		{
+			"multibyte",
+			TruncationPolicy{},
+			"test",
+			Extensions{Message: strings.Repeat("ñ", 1024)},
+			"test",
+			Extensions{Message: strings.Repeat("ñ", 1023)},
+			nil,
+		},
+		{
+			"reject",
+			TruncationPolicy{Reject: true},
+			long,
+			Extensions{Message: long},
+			"",
+			Extensions{},
+			[]string{`"name": limit 512: field too long`, `"msg": limit 1023: field too long`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, extensions, err := tt.policy.truncate("100", tt.evName, tt.extensions)
+			if tt.wantErrs != nil {
+				assert.ErrorIs(t, err, FieldTooLongErr)
+				assert.EqualError(t, err, strings.Join(tt.wantErrs, "\n"))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.want, extensions)
+		})
+	}
+}
+
+func TestTruncationPolicy_truncate_unparseable(t *testing.T) {
+	// Truncating the escaped path leaves a partial escape sequence
+	u := url.URL{Scheme: "https", Host: "example.com", Path: "/" + strings.Repeat("%", 400)}
+	_, _, err := (&TruncationPolicy{}).truncate("100", "test", Extensions{RequestUrl: u})
+	assert.ErrorIs(t, err, FieldTooLongErr)
+	assert.ErrorContains(t, err, "invalid URL escape")
+}
+
+func TestWithTruncation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "v", "p", "1", OmitSyslogHeader(), WithTruncation(TruncationPolicy{MarkerKey: "truncated"}))
+	custom := map[string]string{"k": strings.Repeat("x", 1024)}
+	require.NoError(t, l.LogLow("100", "test", Extensions{CustomExtensions: custom}))
+	assert.Equal(t, "CEF:1|v|p|1|100|test|Low|k="+strings.Repeat("x", 1023)+" truncated=k", buf.String())
+	assert.Len(t, custom["k"], 1024, "caller's map modified")
+}
+
+func TestTruncationPolicy_OnOversized(t *testing.T) {
+	var got []OversizedField
+	for _, reject := range []bool{false, true} {
+		policy := TruncationPolicy{Reject: reject, OnOversized: func(f OversizedField) {
+			got = append(got, f)
+		}}
+		name := strings.Repeat("n", 513)
+		_, _, _ = policy.truncate("100", name, Extensions{
+			Message:          strings.Repeat("m", 1024),
+			RequestContext:   strings.Repeat("r", 2000),
+			CustomExtensions: map[string]string{"k": strings.Repeat("x", 1030)},
+		})
+		assert.Equal(t, []OversizedField{
+			{"100", name, "name", true, 513, 512},
+			{"100", name, "msg", false, 1024, 1023},
+			{"100", name, "k", false, 1030, 1023},
+		}, got)
+		got = nil
+	}
+}
+
+func Test_truncateString(t *testing.T) {
+	assert.Equal(t, "ab", truncateString("abc", 2))
+	assert.Equal(t, "abc", truncateString("abc", 3))
+	assert.Equal(t, "añ", truncateString("añb", 2))
+	assert.Equal(t, "", truncateString("abc", 0))
+}
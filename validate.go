@@ -0,0 +1,168 @@
+package cefevent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// InvalidPortErr error when a port field is outside 0-65535
+var InvalidPortErr = errors.New("invalid port")
+
+// InvalidDeviceDirectionErr error when DeviceDirection is not 0 (inbound) or 1 (outbound)
+var InvalidDeviceDirectionErr = errors.New("invalid device direction")
+
+// InvalidExtensionKeyErr error when a CustomExtensions key is empty or contains whitespace or '='
+var InvalidExtensionKeyErr = errors.New("invalid extension key")
+
+// ReservedExtensionKeyErr error when a CustomExtensions key is a standard dictionary key, e.g. "msg"
+var ReservedExtensionKeyErr = errors.New("reserved extension key")
+
+// HeaderFieldTooLongErr error when a header field is longer than ArcSight accepts
+var HeaderFieldTooLongErr = errors.New("header field too long")
+
+// Longest header field values ArcSight accepts, in characters
+const (
+	maxDeviceVendorLength       = 63
+	maxDeviceProductLength      = 63
+	maxDeviceVersionLength      = 31
+	maxDeviceEventClassIdLength = 1023
+	maxNameLength               = 512
+)
+
+// WithDryRun makes Log format and validate events without writing anything. Log returns every problem found with the
+// event joined into a single error (check for specific problems with errors.Is), or nil if the event is valid. Useful
+// for vetting new instrumentation in staging without polluting the SIEM. Repeat suppression is bypassed so that every
+// event is checked.
+func WithDryRun() LoggerConfigOption {
+	return func(l *Logger) {
+		l.dryRun = true
+	}
+}
+
+// WithStrictValidation makes Log reject events which violate the CEF spec rather than writing them, returning every
+// problem found joined into a single error (check for specific problems with errors.Is). Checks the same rules as
+// WithDryRun, plus the lengths of the header fields.
+func WithStrictValidation() LoggerConfigOption {
+	return func(l *Logger) {
+		l.strict = true
+	}
+}
+
+// checkHeader checks the header fields against the lengths ArcSight accepts, returning a fieldError for each problem
+func checkHeader(deviceVendor, deviceProduct, deviceVersion, deviceEventClassId, name string) []error {
+	var errs []error
+	for _, h := range []struct {
+		field string
+		value string
+		max   int
+	}{
+		{"deviceVendor", deviceVendor, maxDeviceVendorLength},
+		{"deviceProduct", deviceProduct, maxDeviceProductLength},
+		{"deviceVersion", deviceVersion, maxDeviceVersionLength},
+		{"deviceEventClassId", deviceEventClassId, maxDeviceEventClassIdLength},
+		{"name", name, maxNameLength},
+	} {
+		if n := utf8.RuneCountInString(h.value); n > h.max {
+			errs = append(errs, &fieldError{h.field, fmt.Errorf("%d characters, limit %d: %w", n, h.max, HeaderFieldTooLongErr)})
+		}
+	}
+	return errs
+}
+
+// validateEvent checks an event against the CEF spec, returning all problems found joined together
+func validateEvent(severity string, extensions Extensions) error {
+	return errors.Join(checkEvent(severity, extensions)...)
+}
+
+// checkEvent checks an event against the CEF spec, returning a fieldError for each problem found
+func checkEvent(severity string, extensions Extensions) []error {
+	var errs []error
+	if err := validateSeverity(severity); err != nil {
+		errs = append(errs, &fieldError{"severity", fmt.Errorf("%q: %w", severity, err)})
+	}
+	return append(errs, checkExtensions(extensions)...)
+}
+
+// checkExtensions checks extension fields against the CEF spec, returning a fieldError for each problem found
+func checkExtensions(extensions Extensions) []error {
+	var errs []error
+	for _, p := range []struct {
+		key  string
+		port *uint
+	}{
+		{"sourceTranslatedPort", extensions.SourceTranslatedPort},
+		{"destinationTranslatedPort", extensions.DestinationTranslatedPort},
+		{"dpt", extensions.DestinationPort},
+		{"spt", extensions.SourcePort},
+	} {
+		if p.port != nil && *p.port > 65535 {
+			errs = append(errs, &fieldError{p.key, fmt.Errorf("%d: %w", *p.port, InvalidPortErr)})
+		}
+	}
+	if extensions.DeviceDirection != nil && *extensions.DeviceDirection > 1 {
+		errs = append(errs, &fieldError{"deviceDirection", fmt.Errorf("%d: %w", *extensions.DeviceDirection, InvalidDeviceDirectionErr)})
+	}
+	errs = append(errs, extensions.labelErrors()...)
+	for k := range extensions.CustomExtensions {
+		if !validExtensionKey(k) {
+			errs = append(errs, &fieldError{k, InvalidExtensionKeyErr})
+		} else if isStandardKey(k) {
+			errs = append(errs, &fieldError{k, ReservedExtensionKeyErr})
+		}
+	}
+	return errs
+}
+
+// fieldError is a problem with a single field of an event
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (f *fieldError) Error() string {
+	return strconv.Quote(f.field) + ": " + f.err.Error()
+}
+
+func (f *fieldError) Unwrap() error {
+	return f.err
+}
+
+// FieldErrors returns the problem with each field from an error returned by Parse, or by Log with WithDryRun or
+// WithStrictValidation, keyed by extension key or header field name. Parse decodes every field it can, so a relay can
+// forward the Event and flag these rather than drop it. Returns nil if err has no per-field problems, e.g. when the header
+// is malformed.
+func FieldErrors(err error) map[string]error {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var fields map[string]error
+	for _, err := range errs {
+		var fe *fieldError
+		if errors.As(err, &fe) {
+			if fields == nil {
+				fields = make(map[string]error)
+			}
+			fields[fe.field] = fe.err
+		}
+	}
+	return fields
+}
+
+// isStandardKey reports whether k is a standard dictionary key, in its current or legacy spelling
+func isStandardKey(k string) bool {
+	_, standard := extensionSetters[k]
+	_, legacy := legacyKeyNames[k]
+	return standard || legacy
+}
+
+// validExtensionKey reports whether k can be used as an extension key
+func validExtensionKey(k string) bool {
+	return k != "" && !strings.ContainsFunc(k, func(r rune) bool {
+		return r == '=' || unicode.IsSpace(r)
+	})
+}
@@ -0,0 +1,130 @@
+package cefevent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		severity   string
+		extensions Extensions
+		wantErrs   []error
+	}{
+		{
+			"valid",
+			HighSeverity,
+			Extensions{DestinationPort: ptr(uint(65535)), DeviceDirection: ptr(uint8(1)), CustomExtensions: map[string]string{"k": "v"}},
+			nil,
+		},
+		{
+			"bad_severity",
+			"11",
+			Extensions{},
+			[]error{InvalidSeverityError},
+		},
+		{
+			"bad_source_port",
+			LowSeverity,
+			Extensions{SourcePort: ptr(uint(65536))},
+			[]error{InvalidPortErr},
+		},
+		{
+			"missing_label",
+			LowSeverity,
+			Extensions{FlexString1: Labeled("", "value")},
+			[]error{MissingLabelErr},
+		},
+		{
+			"reserved_key",
+			LowSeverity,
+			Extensions{CustomExtensions: map[string]string{"msg": "v", "sourceHostName": "h"}},
+			[]error{ReservedExtensionKeyErr},
+		},
+		{
+			"multiple",
+			LowSeverity,
+			Extensions{
+				DestinationPort:           ptr(uint(70000)),
+				DestinationTranslatedPort: ptr(uint(65536)),
+				DeviceDirection:           ptr(uint8(2)),
+				CustomExtensions:          map[string]string{"bad key": "v"},
+			},
+			[]error{InvalidPortErr, InvalidDeviceDirectionErr, InvalidExtensionKeyErr},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEvent(tt.severity, tt.extensions)
+			if tt.wantErrs == nil {
+				assert.NoError(t, err)
+			}
+			for _, want := range tt.wantErrs {
+				assert.ErrorIs(t, err, want)
+			}
+		})
+	}
+}
+
+func TestWithDryRun(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", WithDryRun(), WithRepeatSuppression(0))
+	assert.NoError(t, l.LogLow("100", "valid", Extensions{}))
+	err := l.Log("100", "invalid", "Catastrophic", Extensions{DestinationPort: ptr(uint(70000))})
+	assert.ErrorIs(t, err, InvalidSeverityError)
+	assert.ErrorIs(t, err, InvalidPortErr)
+	assert.Empty(t, buf.String())
+}
+
+func Test_checkHeader(t *testing.T) {
+	assert.Empty(t, checkHeader(strings.Repeat("v", 63), "p", strings.Repeat("1", 31), "100", strings.Repeat("ñ", 512)))
+	errs := checkHeader(strings.Repeat("v", 64), strings.Repeat("p", 64), strings.Repeat("1", 32),
+		strings.Repeat("c", 1024), strings.Repeat("n", 513))
+	assert.Len(t, errs, 5)
+	for _, err := range errs {
+		assert.ErrorIs(t, err, HeaderFieldTooLongErr)
+	}
+	assert.EqualError(t, errs[4], `"name": 513 characters, limit 512: header field too long`)
+}
+
+func TestWithStrictValidation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, "testVendor", "testProduct", "1.0", WithStrictValidation(), OmitSyslogHeader())
+	err := l.Log("100", strings.Repeat("n", 513), "Catastrophic", Extensions{
+		DestinationPort:  ptr(uint(70000)),
+		DeviceDirection:  ptr(uint8(2)),
+		CustomExtensions: map[string]string{"msg": "v"},
+	})
+	assert.ErrorIs(t, err, HeaderFieldTooLongErr)
+	assert.ErrorIs(t, err, InvalidSeverityError)
+	assert.ErrorIs(t, err, InvalidPortErr)
+	assert.ErrorIs(t, err, InvalidDeviceDirectionErr)
+	assert.ErrorIs(t, err, ReservedExtensionKeyErr)
+	assert.Empty(t, buf.String())
+
+	// Prefixed custom keys don't collide
+	l = l.Clone(WithCustomExtensionPrefix("acme_"))
+	assert.NoError(t, l.LogLow("100", "valid", Extensions{CustomExtensions: map[string]string{"msg": "v"}}))
+	assert.Equal(t, "CEF:1|testVendor|testProduct|1.0|100|valid|Low|acme_msg=v", buf.String())
+}
+
+func TestFieldErrors(t *testing.T) {
+	ev, err := Parse("CEF:1|v|p|1|100|n|Low|msg=kept src=not-an-ip rt=yesterday dpt=22")
+	fields := FieldErrors(err)
+	assert.Len(t, fields, 2)
+	assert.ErrorContains(t, fields["src"], "not an IP address")
+	assert.ErrorIs(t, fields["rt"], MalformedEventErr)
+	assert.Equal(t, Extensions{Message: "kept", DestinationPort: ptr(uint(22))}, ev.Extensions)
+
+	l := NewLogger(&bytes.Buffer{}, "testVendor", "testProduct", "1.0", WithStrictValidation())
+	err = l.LogLow("100", "n", Extensions{DestinationPort: ptr(uint(70000))})
+	assert.ErrorIs(t, FieldErrors(err)["dpt"], InvalidPortErr)
+
+	_, err = Parse("CEF:1|v|p")
+	assert.Nil(t, FieldErrors(err))
+	assert.Nil(t, FieldErrors(nil))
+}